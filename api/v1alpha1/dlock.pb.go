@@ -9,7 +9,9 @@ package v1alpha1
 import (
 	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
 	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
-	_ "google.golang.org/protobuf/types/known/emptypb"
+	durationpb "google.golang.org/protobuf/types/known/durationpb"
+	emptypb "google.golang.org/protobuf/types/known/emptypb"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
 	reflect "reflect"
 	sync "sync"
 	unsafe "unsafe"
@@ -22,11 +24,63 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+type LockMode int32
+
+const (
+	// exclusive
+	LockMode_EX LockMode = 0
+	// protected read, shared with other readers
+	LockMode_PR LockMode = 1
+)
+
+// Enum value maps for LockMode.
+var (
+	LockMode_name = map[int32]string{
+		0: "EX",
+		1: "PR",
+	}
+	LockMode_value = map[string]int32{
+		"EX": 0,
+		"PR": 1,
+	}
+)
+
+func (x LockMode) Enum() *LockMode {
+	p := new(LockMode)
+	*p = x
+	return p
+}
+
+func (x LockMode) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (LockMode) Descriptor() protoreflect.EnumDescriptor {
+	return file_api_v1alpha1_dlock_proto_enumTypes[0].Descriptor()
+}
+
+func (LockMode) Type() protoreflect.EnumType {
+	return &file_api_v1alpha1_dlock_proto_enumTypes[0]
+}
+
+func (x LockMode) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use LockMode.Descriptor instead.
+func (LockMode) EnumDescriptor() ([]byte, []int) {
+	return file_api_v1alpha1_dlock_proto_rawDescGZIP(), []int{0}
+}
+
 type LockEvent int32
 
 const (
 	LockEvent_Acquired LockEvent = 0
 	LockEvent_Failed   LockEvent = 1
+	// sent by blocking Lock requests once they wait for the lock
+	LockEvent_Queued LockEvent = 2
+	// sent by blocking Lock requests every time their position in the queue of the lock changes
+	LockEvent_Position LockEvent = 3
 )
 
 // Enum value maps for LockEvent.
@@ -34,10 +88,14 @@ var (
 	LockEvent_name = map[int32]string{
 		0: "Acquired",
 		1: "Failed",
+		2: "Queued",
+		3: "Position",
 	}
 	LockEvent_value = map[string]int32{
 		"Acquired": 0,
 		"Failed":   1,
+		"Queued":   2,
+		"Position": 3,
 	}
 )
 
@@ -52,11 +110,11 @@ func (x LockEvent) String() string {
 }
 
 func (LockEvent) Descriptor() protoreflect.EnumDescriptor {
-	return file_api_v1alpha1_dlock_proto_enumTypes[0].Descriptor()
+	return file_api_v1alpha1_dlock_proto_enumTypes[1].Descriptor()
 }
 
 func (LockEvent) Type() protoreflect.EnumType {
-	return &file_api_v1alpha1_dlock_proto_enumTypes[0]
+	return &file_api_v1alpha1_dlock_proto_enumTypes[1]
 }
 
 func (x LockEvent) Number() protoreflect.EnumNumber {
@@ -65,15 +123,141 @@ func (x LockEvent) Number() protoreflect.EnumNumber {
 
 // Deprecated: Use LockEvent.Descriptor instead.
 func (LockEvent) EnumDescriptor() ([]byte, []int) {
-	return file_api_v1alpha1_dlock_proto_rawDescGZIP(), []int{0}
+	return file_api_v1alpha1_dlock_proto_rawDescGZIP(), []int{1}
+}
+
+type WatchEventType int32
+
+const (
+	WatchEventType_LockAcquired WatchEventType = 0
+	WatchEventType_LockReleased WatchEventType = 1
+	// the holder lost the lock without releasing it, because its acquisition expired or was force released
+	WatchEventType_LockExpired WatchEventType = 2
+)
+
+// Enum value maps for WatchEventType.
+var (
+	WatchEventType_name = map[int32]string{
+		0: "LockAcquired",
+		1: "LockReleased",
+		2: "LockExpired",
+	}
+	WatchEventType_value = map[string]int32{
+		"LockAcquired": 0,
+		"LockReleased": 1,
+		"LockExpired":  2,
+	}
+)
+
+func (x WatchEventType) Enum() *WatchEventType {
+	p := new(WatchEventType)
+	*p = x
+	return p
+}
+
+func (x WatchEventType) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (WatchEventType) Descriptor() protoreflect.EnumDescriptor {
+	return file_api_v1alpha1_dlock_proto_enumTypes[2].Descriptor()
+}
+
+func (WatchEventType) Type() protoreflect.EnumType {
+	return &file_api_v1alpha1_dlock_proto_enumTypes[2]
+}
+
+func (x WatchEventType) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use WatchEventType.Descriptor instead.
+func (WatchEventType) EnumDescriptor() ([]byte, []int) {
+	return file_api_v1alpha1_dlock_proto_rawDescGZIP(), []int{2}
+}
+
+type GraphNodeState int32
+
+const (
+	// the node was not locked & released through the graph yet
+	GraphNodeState_NodePending GraphNodeState = 0
+	// the node is locked through the graph
+	GraphNodeState_NodeHeld GraphNodeState = 1
+	// the node was locked & released through the graph
+	GraphNodeState_NodeDone GraphNodeState = 2
+)
+
+// Enum value maps for GraphNodeState.
+var (
+	GraphNodeState_name = map[int32]string{
+		0: "NodePending",
+		1: "NodeHeld",
+		2: "NodeDone",
+	}
+	GraphNodeState_value = map[string]int32{
+		"NodePending": 0,
+		"NodeHeld":    1,
+		"NodeDone":    2,
+	}
+)
+
+func (x GraphNodeState) Enum() *GraphNodeState {
+	p := new(GraphNodeState)
+	*p = x
+	return p
+}
+
+func (x GraphNodeState) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (GraphNodeState) Descriptor() protoreflect.EnumDescriptor {
+	return file_api_v1alpha1_dlock_proto_enumTypes[3].Descriptor()
+}
+
+func (GraphNodeState) Type() protoreflect.EnumType {
+	return &file_api_v1alpha1_dlock_proto_enumTypes[3]
+}
+
+func (x GraphNodeState) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use GraphNodeState.Descriptor instead.
+func (GraphNodeState) EnumDescriptor() ([]byte, []int) {
+	return file_api_v1alpha1_dlock_proto_rawDescGZIP(), []int{3}
 }
 
 type LockRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Key           string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
-	TryLock       bool                   `protobuf:"varint,2,opt,name=tryLock,proto3" json:"tryLock,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Key     string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	TryLock bool                   `protobuf:"varint,2,opt,name=tryLock,proto3" json:"tryLock,omitempty"`
+	Mode    LockMode               `protobuf:"varint,3,opt,name=mode,proto3,enum=dlock.LockMode" json:"mode,omitempty"`
+	// identifies the holder of the lock, defaults to the server's hostname & pid when unset
+	Metadata *LockMetadata `protobuf:"bytes,4,opt,name=metadata,proto3" json:"metadata,omitempty"`
+	// timings of the lock, the backend's defaults are used when they are unset.
+	// The server rejects timings outside of the bounds of its configuration.
+	Ttl               *durationpb.Duration `protobuf:"bytes,5,opt,name=ttl,proto3" json:"ttl,omitempty"`
+	KeepaliveInterval *durationpb.Duration `protobuf:"bytes,6,opt,name=keepaliveInterval,proto3" json:"keepaliveInterval,omitempty"`
+	RetryDelay        *durationpb.Duration `protobuf:"bytes,7,opt,name=retryDelay,proto3" json:"retryDelay,omitempty"`
+	// fair blocking acquisitions are granted in the order they started waiting
+	Fair bool `protobuf:"varint,8,opt,name=fair,proto3" json:"fair,omitempty"`
+	// keys locked all-or-nothing along with the key, in their sorted order
+	Keys []string `protobuf:"bytes,9,rep,name=keys,proto3" json:"keys,omitempty"`
+	// reentrant locks can be locked again by the owner of the metadata with the reentrant token of the lock while
+	// it holds them, through the server that issued the token, only its last release unlocking the key.
+	// Only exclusive locks are reentrant.
+	Reentrant bool `protobuf:"varint,10,opt,name=reentrant,proto3" json:"reentrant,omitempty"`
+	// namespace of the keys, the default namespace is used when unset
+	Namespace string `protobuf:"bytes,11,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	// graph of the namespace whose node is locked, the node being the key. Graph nodes are locked exclusively,
+	// without keys nor reentrancy.
+	Graph string `protobuf:"bytes,12,opt,name=graph,proto3" json:"graph,omitempty"`
+	// token issued by the server on the Acquired event of a reentrant lock, reentering the lock when it is set.
+	// The owner & token must hold every key of the request through this server.
+	ReentrantToken string `protobuf:"bytes,13,opt,name=reentrantToken,proto3" json:"reentrantToken,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
 }
 
 func (x *LockRequest) Reset() {
@@ -120,16 +304,167 @@ func (x *LockRequest) GetTryLock() bool {
 	return false
 }
 
-type LockResponse struct {
+func (x *LockRequest) GetMode() LockMode {
+	if x != nil {
+		return x.Mode
+	}
+	return LockMode_EX
+}
+
+func (x *LockRequest) GetMetadata() *LockMetadata {
+	if x != nil {
+		return x.Metadata
+	}
+	return nil
+}
+
+func (x *LockRequest) GetTtl() *durationpb.Duration {
+	if x != nil {
+		return x.Ttl
+	}
+	return nil
+}
+
+func (x *LockRequest) GetKeepaliveInterval() *durationpb.Duration {
+	if x != nil {
+		return x.KeepaliveInterval
+	}
+	return nil
+}
+
+func (x *LockRequest) GetRetryDelay() *durationpb.Duration {
+	if x != nil {
+		return x.RetryDelay
+	}
+	return nil
+}
+
+func (x *LockRequest) GetFair() bool {
+	if x != nil {
+		return x.Fair
+	}
+	return false
+}
+
+func (x *LockRequest) GetKeys() []string {
+	if x != nil {
+		return x.Keys
+	}
+	return nil
+}
+
+func (x *LockRequest) GetReentrant() bool {
+	if x != nil {
+		return x.Reentrant
+	}
+	return false
+}
+
+func (x *LockRequest) GetNamespace() string {
+	if x != nil {
+		return x.Namespace
+	}
+	return ""
+}
+
+func (x *LockRequest) GetGraph() string {
+	if x != nil {
+		return x.Graph
+	}
+	return ""
+}
+
+func (x *LockRequest) GetReentrantToken() string {
+	if x != nil {
+		return x.ReentrantToken
+	}
+	return ""
+}
+
+type LockMetadata struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Event         LockEvent              `protobuf:"varint,1,opt,name=event,proto3,enum=dlock.LockEvent" json:"event,omitempty"`
+	Owner         string                 `protobuf:"bytes,1,opt,name=owner,proto3" json:"owner,omitempty"`
+	Hostname      string                 `protobuf:"bytes,2,opt,name=hostname,proto3" json:"hostname,omitempty"`
+	Pid           int64                  `protobuf:"varint,3,opt,name=pid,proto3" json:"pid,omitempty"`
+	Labels        map[string]string      `protobuf:"bytes,4,rep,name=labels,proto3" json:"labels,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
+func (x *LockMetadata) Reset() {
+	*x = LockMetadata{}
+	mi := &file_api_v1alpha1_dlock_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LockMetadata) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LockMetadata) ProtoMessage() {}
+
+func (x *LockMetadata) ProtoReflect() protoreflect.Message {
+	mi := &file_api_v1alpha1_dlock_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LockMetadata.ProtoReflect.Descriptor instead.
+func (*LockMetadata) Descriptor() ([]byte, []int) {
+	return file_api_v1alpha1_dlock_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *LockMetadata) GetOwner() string {
+	if x != nil {
+		return x.Owner
+	}
+	return ""
+}
+
+func (x *LockMetadata) GetHostname() string {
+	if x != nil {
+		return x.Hostname
+	}
+	return ""
+}
+
+func (x *LockMetadata) GetPid() int64 {
+	if x != nil {
+		return x.Pid
+	}
+	return 0
+}
+
+func (x *LockMetadata) GetLabels() map[string]string {
+	if x != nil {
+		return x.Labels
+	}
+	return nil
+}
+
+type LockResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Event LockEvent              `protobuf:"varint,1,opt,name=event,proto3,enum=dlock.LockEvent" json:"event,omitempty"`
+	// monotonically increasing token for the key, only set on the Acquired event of exclusive locks
+	FencingToken uint64 `protobuf:"varint,2,opt,name=fencingToken,proto3" json:"fencingToken,omitempty"`
+	// number of acquisitions waiting for the lock ahead of this one, set on Queued & Position events
+	Position int64 `protobuf:"varint,3,opt,name=position,proto3" json:"position,omitempty"`
+	// token of reentrant locks, only set on the Acquired event, see LockRequest
+	ReentrantToken string `protobuf:"bytes,4,opt,name=reentrantToken,proto3" json:"reentrantToken,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
 func (x *LockResponse) Reset() {
 	*x = LockResponse{}
-	mi := &file_api_v1alpha1_dlock_proto_msgTypes[1]
+	mi := &file_api_v1alpha1_dlock_proto_msgTypes[2]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -141,7 +476,7 @@ func (x *LockResponse) String() string {
 func (*LockResponse) ProtoMessage() {}
 
 func (x *LockResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_api_v1alpha1_dlock_proto_msgTypes[1]
+	mi := &file_api_v1alpha1_dlock_proto_msgTypes[2]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -154,7 +489,7 @@ func (x *LockResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use LockResponse.ProtoReflect.Descriptor instead.
 func (*LockResponse) Descriptor() ([]byte, []int) {
-	return file_api_v1alpha1_dlock_proto_rawDescGZIP(), []int{1}
+	return file_api_v1alpha1_dlock_proto_rawDescGZIP(), []int{2}
 }
 
 func (x *LockResponse) GetEvent() LockEvent {
@@ -164,22 +499,1537 @@ func (x *LockResponse) GetEvent() LockEvent {
 	return LockEvent_Acquired
 }
 
-var File_api_v1alpha1_dlock_proto protoreflect.FileDescriptor
+func (x *LockResponse) GetFencingToken() uint64 {
+	if x != nil {
+		return x.FencingToken
+	}
+	return 0
+}
 
-const file_api_v1alpha1_dlock_proto_rawDesc = "" +
-	"\n" +
-	"\x18api/v1alpha1/dlock.proto\x12\x05dlock\x1a\x1bgoogle/protobuf/empty.proto\"9\n" +
-	"\vLockRequest\x12\x10\n" +
-	"\x03key\x18\x01 \x01(\tR\x03key\x12\x18\n" +
-	"\atryLock\x18\x02 \x01(\bR\atryLock\"6\n" +
-	"\fLockResponse\x12&\n" +
-	"\x05event\x18\x01 \x01(\x0e2\x10.dlock.LockEventR\x05event*%\n" +
-	"\tLockEvent\x12\f\n" +
-	"\bAcquired\x10\x00\x12\n" +
-	"\n" +
-	"\x06Failed\x10\x012<\n" +
-	"\x05Dlock\x123\n" +
-	"\x04Lock\x12\x12.dlock.LockRequest\x1a\x13.dlock.LockResponse\"\x000\x01B0Z.github.com/alexandreLamarre/dlock/api/v1alpha1b\x06proto3"
+func (x *LockResponse) GetPosition() int64 {
+	if x != nil {
+		return x.Position
+	}
+	return 0
+}
+
+func (x *LockResponse) GetReentrantToken() string {
+	if x != nil {
+		return x.ReentrantToken
+	}
+	return ""
+}
+
+type AcquireRequest struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Key     string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	TryLock bool                   `protobuf:"varint,2,opt,name=tryLock,proto3" json:"tryLock,omitempty"`
+	// TTL of the lease, which is also the TTL of its lock. Defaults to the default TTL of the namespace, or to the
+	// server's default lease TTL when unset. The server rejects TTLs outside of the bounds of its configuration.
+	Ttl  *durationpb.Duration `protobuf:"bytes,3,opt,name=ttl,proto3" json:"ttl,omitempty"`
+	Mode LockMode             `protobuf:"varint,4,opt,name=mode,proto3,enum=dlock.LockMode" json:"mode,omitempty"`
+	// identifies the holder of the lock, defaults to the server's hostname & pid when unset
+	Metadata *LockMetadata `protobuf:"bytes,5,opt,name=metadata,proto3" json:"metadata,omitempty"`
+	// see LockRequest
+	Reentrant      bool   `protobuf:"varint,6,opt,name=reentrant,proto3" json:"reentrant,omitempty"`
+	Namespace      string `protobuf:"bytes,7,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	ReentrantToken string `protobuf:"bytes,8,opt,name=reentrantToken,proto3" json:"reentrantToken,omitempty"`
+	// see LockRequest
+	KeepaliveInterval *durationpb.Duration `protobuf:"bytes,9,opt,name=keepaliveInterval,proto3" json:"keepaliveInterval,omitempty"`
+	RetryDelay        *durationpb.Duration `protobuf:"bytes,10,opt,name=retryDelay,proto3" json:"retryDelay,omitempty"`
+	Fair              bool                 `protobuf:"varint,11,opt,name=fair,proto3" json:"fair,omitempty"`
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *AcquireRequest) Reset() {
+	*x = AcquireRequest{}
+	mi := &file_api_v1alpha1_dlock_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AcquireRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AcquireRequest) ProtoMessage() {}
+
+func (x *AcquireRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_v1alpha1_dlock_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AcquireRequest.ProtoReflect.Descriptor instead.
+func (*AcquireRequest) Descriptor() ([]byte, []int) {
+	return file_api_v1alpha1_dlock_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *AcquireRequest) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *AcquireRequest) GetTryLock() bool {
+	if x != nil {
+		return x.TryLock
+	}
+	return false
+}
+
+func (x *AcquireRequest) GetTtl() *durationpb.Duration {
+	if x != nil {
+		return x.Ttl
+	}
+	return nil
+}
+
+func (x *AcquireRequest) GetMode() LockMode {
+	if x != nil {
+		return x.Mode
+	}
+	return LockMode_EX
+}
+
+func (x *AcquireRequest) GetMetadata() *LockMetadata {
+	if x != nil {
+		return x.Metadata
+	}
+	return nil
+}
+
+func (x *AcquireRequest) GetReentrant() bool {
+	if x != nil {
+		return x.Reentrant
+	}
+	return false
+}
+
+func (x *AcquireRequest) GetNamespace() string {
+	if x != nil {
+		return x.Namespace
+	}
+	return ""
+}
+
+func (x *AcquireRequest) GetReentrantToken() string {
+	if x != nil {
+		return x.ReentrantToken
+	}
+	return ""
+}
+
+func (x *AcquireRequest) GetKeepaliveInterval() *durationpb.Duration {
+	if x != nil {
+		return x.KeepaliveInterval
+	}
+	return nil
+}
+
+func (x *AcquireRequest) GetRetryDelay() *durationpb.Duration {
+	if x != nil {
+		return x.RetryDelay
+	}
+	return nil
+}
+
+func (x *AcquireRequest) GetFair() bool {
+	if x != nil {
+		return x.Fair
+	}
+	return false
+}
+
+type AcquireResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// false only when tryLock is set and the lock is held by someone else
+	Acquired     bool                 `protobuf:"varint,1,opt,name=acquired,proto3" json:"acquired,omitempty"`
+	LeaseId      string               `protobuf:"bytes,2,opt,name=leaseId,proto3" json:"leaseId,omitempty"`
+	Ttl          *durationpb.Duration `protobuf:"bytes,3,opt,name=ttl,proto3" json:"ttl,omitempty"`
+	FencingToken uint64               `protobuf:"varint,4,opt,name=fencingToken,proto3" json:"fencingToken,omitempty"`
+	// token of reentrant locks, see LockRequest
+	ReentrantToken string `protobuf:"bytes,5,opt,name=reentrantToken,proto3" json:"reentrantToken,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *AcquireResponse) Reset() {
+	*x = AcquireResponse{}
+	mi := &file_api_v1alpha1_dlock_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AcquireResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AcquireResponse) ProtoMessage() {}
+
+func (x *AcquireResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_v1alpha1_dlock_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AcquireResponse.ProtoReflect.Descriptor instead.
+func (*AcquireResponse) Descriptor() ([]byte, []int) {
+	return file_api_v1alpha1_dlock_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *AcquireResponse) GetAcquired() bool {
+	if x != nil {
+		return x.Acquired
+	}
+	return false
+}
+
+func (x *AcquireResponse) GetLeaseId() string {
+	if x != nil {
+		return x.LeaseId
+	}
+	return ""
+}
+
+func (x *AcquireResponse) GetTtl() *durationpb.Duration {
+	if x != nil {
+		return x.Ttl
+	}
+	return nil
+}
+
+func (x *AcquireResponse) GetFencingToken() uint64 {
+	if x != nil {
+		return x.FencingToken
+	}
+	return 0
+}
+
+func (x *AcquireResponse) GetReentrantToken() string {
+	if x != nil {
+		return x.ReentrantToken
+	}
+	return ""
+}
+
+type ExtendRequest struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	LeaseId string                 `protobuf:"bytes,1,opt,name=leaseId,proto3" json:"leaseId,omitempty"`
+	// defaults to the lease's current TTL when unset
+	Ttl           *durationpb.Duration `protobuf:"bytes,2,opt,name=ttl,proto3" json:"ttl,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ExtendRequest) Reset() {
+	*x = ExtendRequest{}
+	mi := &file_api_v1alpha1_dlock_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExtendRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExtendRequest) ProtoMessage() {}
+
+func (x *ExtendRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_v1alpha1_dlock_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExtendRequest.ProtoReflect.Descriptor instead.
+func (*ExtendRequest) Descriptor() ([]byte, []int) {
+	return file_api_v1alpha1_dlock_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *ExtendRequest) GetLeaseId() string {
+	if x != nil {
+		return x.LeaseId
+	}
+	return ""
+}
+
+func (x *ExtendRequest) GetTtl() *durationpb.Duration {
+	if x != nil {
+		return x.Ttl
+	}
+	return nil
+}
+
+type ExtendResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Ttl           *durationpb.Duration   `protobuf:"bytes,1,opt,name=ttl,proto3" json:"ttl,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ExtendResponse) Reset() {
+	*x = ExtendResponse{}
+	mi := &file_api_v1alpha1_dlock_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExtendResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExtendResponse) ProtoMessage() {}
+
+func (x *ExtendResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_v1alpha1_dlock_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExtendResponse.ProtoReflect.Descriptor instead.
+func (*ExtendResponse) Descriptor() ([]byte, []int) {
+	return file_api_v1alpha1_dlock_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *ExtendResponse) GetTtl() *durationpb.Duration {
+	if x != nil {
+		return x.Ttl
+	}
+	return nil
+}
+
+type ReleaseRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	LeaseId       string                 `protobuf:"bytes,1,opt,name=leaseId,proto3" json:"leaseId,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReleaseRequest) Reset() {
+	*x = ReleaseRequest{}
+	mi := &file_api_v1alpha1_dlock_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReleaseRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReleaseRequest) ProtoMessage() {}
+
+func (x *ReleaseRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_v1alpha1_dlock_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReleaseRequest.ProtoReflect.Descriptor instead.
+func (*ReleaseRequest) Descriptor() ([]byte, []int) {
+	return file_api_v1alpha1_dlock_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *ReleaseRequest) GetLeaseId() string {
+	if x != nil {
+		return x.LeaseId
+	}
+	return ""
+}
+
+type TransferRequest struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	LeaseId string                 `protobuf:"bytes,1,opt,name=leaseId,proto3" json:"leaseId,omitempty"`
+	// owner in the metadata of the waiting acquisition
+	ToOwner       string `protobuf:"bytes,2,opt,name=toOwner,proto3" json:"toOwner,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TransferRequest) Reset() {
+	*x = TransferRequest{}
+	mi := &file_api_v1alpha1_dlock_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TransferRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TransferRequest) ProtoMessage() {}
+
+func (x *TransferRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_v1alpha1_dlock_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TransferRequest.ProtoReflect.Descriptor instead.
+func (*TransferRequest) Descriptor() ([]byte, []int) {
+	return file_api_v1alpha1_dlock_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *TransferRequest) GetLeaseId() string {
+	if x != nil {
+		return x.LeaseId
+	}
+	return ""
+}
+
+func (x *TransferRequest) GetToOwner() string {
+	if x != nil {
+		return x.ToOwner
+	}
+	return ""
+}
+
+type SemaphoreRequest struct {
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	Key        string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	TryAcquire bool                   `protobuf:"varint,2,opt,name=tryAcquire,proto3" json:"tryAcquire,omitempty"`
+	// number of units to acquire, at least 1
+	Weight int64 `protobuf:"varint,3,opt,name=weight,proto3" json:"weight,omitempty"`
+	// total number of units of the semaphore, every holder of the key is expected to agree on it
+	Capacity int64 `protobuf:"varint,4,opt,name=capacity,proto3" json:"capacity,omitempty"`
+	// namespace of the key, the default namespace is used when unset
+	Namespace string `protobuf:"bytes,5,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	// see LockRequest
+	Ttl               *durationpb.Duration `protobuf:"bytes,6,opt,name=ttl,proto3" json:"ttl,omitempty"`
+	KeepaliveInterval *durationpb.Duration `protobuf:"bytes,7,opt,name=keepaliveInterval,proto3" json:"keepaliveInterval,omitempty"`
+	RetryDelay        *durationpb.Duration `protobuf:"bytes,8,opt,name=retryDelay,proto3" json:"retryDelay,omitempty"`
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *SemaphoreRequest) Reset() {
+	*x = SemaphoreRequest{}
+	mi := &file_api_v1alpha1_dlock_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SemaphoreRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SemaphoreRequest) ProtoMessage() {}
+
+func (x *SemaphoreRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_v1alpha1_dlock_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SemaphoreRequest.ProtoReflect.Descriptor instead.
+func (*SemaphoreRequest) Descriptor() ([]byte, []int) {
+	return file_api_v1alpha1_dlock_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *SemaphoreRequest) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *SemaphoreRequest) GetTryAcquire() bool {
+	if x != nil {
+		return x.TryAcquire
+	}
+	return false
+}
+
+func (x *SemaphoreRequest) GetWeight() int64 {
+	if x != nil {
+		return x.Weight
+	}
+	return 0
+}
+
+func (x *SemaphoreRequest) GetCapacity() int64 {
+	if x != nil {
+		return x.Capacity
+	}
+	return 0
+}
+
+func (x *SemaphoreRequest) GetNamespace() string {
+	if x != nil {
+		return x.Namespace
+	}
+	return ""
+}
+
+func (x *SemaphoreRequest) GetTtl() *durationpb.Duration {
+	if x != nil {
+		return x.Ttl
+	}
+	return nil
+}
+
+func (x *SemaphoreRequest) GetKeepaliveInterval() *durationpb.Duration {
+	if x != nil {
+		return x.KeepaliveInterval
+	}
+	return nil
+}
+
+func (x *SemaphoreRequest) GetRetryDelay() *durationpb.Duration {
+	if x != nil {
+		return x.RetryDelay
+	}
+	return nil
+}
+
+type ListLocksRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// lists every lock when unset
+	Prefix string `protobuf:"bytes,1,opt,name=prefix,proto3" json:"prefix,omitempty"`
+	// namespace of the locks, the default namespace is used when unset
+	Namespace     string `protobuf:"bytes,2,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListLocksRequest) Reset() {
+	*x = ListLocksRequest{}
+	mi := &file_api_v1alpha1_dlock_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListLocksRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListLocksRequest) ProtoMessage() {}
+
+func (x *ListLocksRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_v1alpha1_dlock_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListLocksRequest.ProtoReflect.Descriptor instead.
+func (*ListLocksRequest) Descriptor() ([]byte, []int) {
+	return file_api_v1alpha1_dlock_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *ListLocksRequest) GetPrefix() string {
+	if x != nil {
+		return x.Prefix
+	}
+	return ""
+}
+
+func (x *ListLocksRequest) GetNamespace() string {
+	if x != nil {
+		return x.Namespace
+	}
+	return ""
+}
+
+type ListLocksResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Locks         []*LockInfo            `protobuf:"bytes,1,rep,name=locks,proto3" json:"locks,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListLocksResponse) Reset() {
+	*x = ListLocksResponse{}
+	mi := &file_api_v1alpha1_dlock_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListLocksResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListLocksResponse) ProtoMessage() {}
+
+func (x *ListLocksResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_v1alpha1_dlock_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListLocksResponse.ProtoReflect.Descriptor instead.
+func (*ListLocksResponse) Descriptor() ([]byte, []int) {
+	return file_api_v1alpha1_dlock_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *ListLocksResponse) GetLocks() []*LockInfo {
+	if x != nil {
+		return x.Locks
+	}
+	return nil
+}
+
+type DescribeLockRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Key           string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Namespace     string                 `protobuf:"bytes,2,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DescribeLockRequest) Reset() {
+	*x = DescribeLockRequest{}
+	mi := &file_api_v1alpha1_dlock_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DescribeLockRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DescribeLockRequest) ProtoMessage() {}
+
+func (x *DescribeLockRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_v1alpha1_dlock_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DescribeLockRequest.ProtoReflect.Descriptor instead.
+func (*DescribeLockRequest) Descriptor() ([]byte, []int) {
+	return file_api_v1alpha1_dlock_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *DescribeLockRequest) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *DescribeLockRequest) GetNamespace() string {
+	if x != nil {
+		return x.Namespace
+	}
+	return ""
+}
+
+type WatchRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// watches every lock when unset
+	Prefix        string `protobuf:"bytes,1,opt,name=prefix,proto3" json:"prefix,omitempty"`
+	Namespace     string `protobuf:"bytes,2,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchRequest) Reset() {
+	*x = WatchRequest{}
+	mi := &file_api_v1alpha1_dlock_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchRequest) ProtoMessage() {}
+
+func (x *WatchRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_v1alpha1_dlock_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchRequest.ProtoReflect.Descriptor instead.
+func (*WatchRequest) Descriptor() ([]byte, []int) {
+	return file_api_v1alpha1_dlock_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *WatchRequest) GetPrefix() string {
+	if x != nil {
+		return x.Prefix
+	}
+	return ""
+}
+
+func (x *WatchRequest) GetNamespace() string {
+	if x != nil {
+		return x.Namespace
+	}
+	return ""
+}
+
+type WatchResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Type          WatchEventType         `protobuf:"varint,1,opt,name=type,proto3,enum=dlock.WatchEventType" json:"type,omitempty"`
+	Key           string                 `protobuf:"bytes,2,opt,name=key,proto3" json:"key,omitempty"`
+	Holder        *LockHolder            `protobuf:"bytes,3,opt,name=holder,proto3" json:"holder,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchResponse) Reset() {
+	*x = WatchResponse{}
+	mi := &file_api_v1alpha1_dlock_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchResponse) ProtoMessage() {}
+
+func (x *WatchResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_v1alpha1_dlock_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchResponse.ProtoReflect.Descriptor instead.
+func (*WatchResponse) Descriptor() ([]byte, []int) {
+	return file_api_v1alpha1_dlock_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *WatchResponse) GetType() WatchEventType {
+	if x != nil {
+		return x.Type
+	}
+	return WatchEventType_LockAcquired
+}
+
+func (x *WatchResponse) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *WatchResponse) GetHolder() *LockHolder {
+	if x != nil {
+		return x.Holder
+	}
+	return nil
+}
+
+type CampaignRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Name  string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	// value of the candidate, reported to observers of the election once it is elected
+	Value string `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+	// identifies the candidate, defaults to the server's hostname & pid when unset
+	Metadata      *LockMetadata `protobuf:"bytes,3,opt,name=metadata,proto3" json:"metadata,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CampaignRequest) Reset() {
+	*x = CampaignRequest{}
+	mi := &file_api_v1alpha1_dlock_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CampaignRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CampaignRequest) ProtoMessage() {}
+
+func (x *CampaignRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_v1alpha1_dlock_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CampaignRequest.ProtoReflect.Descriptor instead.
+func (*CampaignRequest) Descriptor() ([]byte, []int) {
+	return file_api_v1alpha1_dlock_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *CampaignRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *CampaignRequest) GetValue() string {
+	if x != nil {
+		return x.Value
+	}
+	return ""
+}
+
+func (x *CampaignRequest) GetMetadata() *LockMetadata {
+	if x != nil {
+		return x.Metadata
+	}
+	return nil
+}
+
+type LeaderRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LeaderRequest) Reset() {
+	*x = LeaderRequest{}
+	mi := &file_api_v1alpha1_dlock_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LeaderRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LeaderRequest) ProtoMessage() {}
+
+func (x *LeaderRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_v1alpha1_dlock_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LeaderRequest.ProtoReflect.Descriptor instead.
+func (*LeaderRequest) Descriptor() ([]byte, []int) {
+	return file_api_v1alpha1_dlock_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *LeaderRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+type LeaderResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Value         string                 `protobuf:"bytes,1,opt,name=value,proto3" json:"value,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LeaderResponse) Reset() {
+	*x = LeaderResponse{}
+	mi := &file_api_v1alpha1_dlock_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LeaderResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LeaderResponse) ProtoMessage() {}
+
+func (x *LeaderResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_v1alpha1_dlock_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LeaderResponse.ProtoReflect.Descriptor instead.
+func (*LeaderResponse) Descriptor() ([]byte, []int) {
+	return file_api_v1alpha1_dlock_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *LeaderResponse) GetValue() string {
+	if x != nil {
+		return x.Value
+	}
+	return ""
+}
+
+type LockInfo struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Key   string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	// empty when the lock is not held
+	Holders []*LockHolder `protobuf:"bytes,2,rep,name=holders,proto3" json:"holders,omitempty"`
+	// number of blocking acquisitions waiting for the lock
+	Waiters       int64 `protobuf:"varint,3,opt,name=waiters,proto3" json:"waiters,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LockInfo) Reset() {
+	*x = LockInfo{}
+	mi := &file_api_v1alpha1_dlock_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LockInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LockInfo) ProtoMessage() {}
+
+func (x *LockInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_api_v1alpha1_dlock_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LockInfo.ProtoReflect.Descriptor instead.
+func (*LockInfo) Descriptor() ([]byte, []int) {
+	return file_api_v1alpha1_dlock_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *LockInfo) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *LockInfo) GetHolders() []*LockHolder {
+	if x != nil {
+		return x.Holders
+	}
+	return nil
+}
+
+func (x *LockInfo) GetWaiters() int64 {
+	if x != nil {
+		return x.Waiters
+	}
+	return 0
+}
+
+type LockHolder struct {
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	Metadata   *LockMetadata          `protobuf:"bytes,1,opt,name=metadata,proto3" json:"metadata,omitempty"`
+	Mode       LockMode               `protobuf:"varint,2,opt,name=mode,proto3,enum=dlock.LockMode" json:"mode,omitempty"`
+	AcquiredAt *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=acquiredAt,proto3" json:"acquiredAt,omitempty"`
+	// time remaining before the acquisition expires if its holder stops keeping it alive, unset when
+	// the backend does not expire acquisitions on a deadline
+	Ttl           *durationpb.Duration `protobuf:"bytes,4,opt,name=ttl,proto3" json:"ttl,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LockHolder) Reset() {
+	*x = LockHolder{}
+	mi := &file_api_v1alpha1_dlock_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LockHolder) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LockHolder) ProtoMessage() {}
+
+func (x *LockHolder) ProtoReflect() protoreflect.Message {
+	mi := &file_api_v1alpha1_dlock_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LockHolder.ProtoReflect.Descriptor instead.
+func (*LockHolder) Descriptor() ([]byte, []int) {
+	return file_api_v1alpha1_dlock_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *LockHolder) GetMetadata() *LockMetadata {
+	if x != nil {
+		return x.Metadata
+	}
+	return nil
+}
+
+func (x *LockHolder) GetMode() LockMode {
+	if x != nil {
+		return x.Mode
+	}
+	return LockMode_EX
+}
+
+func (x *LockHolder) GetAcquiredAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.AcquiredAt
+	}
+	return nil
+}
+
+func (x *LockHolder) GetTtl() *durationpb.Duration {
+	if x != nil {
+		return x.Ttl
+	}
+	return nil
+}
+
+type ForceReleaseRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Key   string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	// recorded in the audit log of the server
+	Reason        string `protobuf:"bytes,2,opt,name=reason,proto3" json:"reason,omitempty"`
+	Namespace     string `protobuf:"bytes,3,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ForceReleaseRequest) Reset() {
+	*x = ForceReleaseRequest{}
+	mi := &file_api_v1alpha1_dlock_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ForceReleaseRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ForceReleaseRequest) ProtoMessage() {}
+
+func (x *ForceReleaseRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_v1alpha1_dlock_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ForceReleaseRequest.ProtoReflect.Descriptor instead.
+func (*ForceReleaseRequest) Descriptor() ([]byte, []int) {
+	return file_api_v1alpha1_dlock_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *ForceReleaseRequest) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *ForceReleaseRequest) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+func (x *ForceReleaseRequest) GetNamespace() string {
+	if x != nil {
+		return x.Namespace
+	}
+	return ""
+}
+
+type ForceReleaseResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Evicted       []*LockHolder          `protobuf:"bytes,1,rep,name=evicted,proto3" json:"evicted,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ForceReleaseResponse) Reset() {
+	*x = ForceReleaseResponse{}
+	mi := &file_api_v1alpha1_dlock_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ForceReleaseResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ForceReleaseResponse) ProtoMessage() {}
+
+func (x *ForceReleaseResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_v1alpha1_dlock_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ForceReleaseResponse.ProtoReflect.Descriptor instead.
+func (*ForceReleaseResponse) Descriptor() ([]byte, []int) {
+	return file_api_v1alpha1_dlock_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *ForceReleaseResponse) GetEvicted() []*LockHolder {
+	if x != nil {
+		return x.Evicted
+	}
+	return nil
+}
+
+type SubmitGraphRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Name  string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	// namespace of the keys of the nodes, the default namespace is used when unset
+	Namespace string `protobuf:"bytes,2,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	// the states of the nodes are ignored
+	Nodes []*GraphNode `protobuf:"bytes,3,rep,name=nodes,proto3" json:"nodes,omitempty"`
+	// run of the graph, scoping the done markers of its nodes in the lock backend : graphs submitted to different
+	// servers with the same namespace, name & run share the progress of their nodes. The progress of graphs without
+	// a run is kept to the server they are submitted to.
+	Run           string `protobuf:"bytes,4,opt,name=run,proto3" json:"run,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SubmitGraphRequest) Reset() {
+	*x = SubmitGraphRequest{}
+	mi := &file_api_v1alpha1_dlock_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SubmitGraphRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SubmitGraphRequest) ProtoMessage() {}
+
+func (x *SubmitGraphRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_v1alpha1_dlock_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SubmitGraphRequest.ProtoReflect.Descriptor instead.
+func (*SubmitGraphRequest) Descriptor() ([]byte, []int) {
+	return file_api_v1alpha1_dlock_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *SubmitGraphRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *SubmitGraphRequest) GetNamespace() string {
+	if x != nil {
+		return x.Namespace
+	}
+	return ""
+}
+
+func (x *SubmitGraphRequest) GetNodes() []*GraphNode {
+	if x != nil {
+		return x.Nodes
+	}
+	return nil
+}
+
+func (x *SubmitGraphRequest) GetRun() string {
+	if x != nil {
+		return x.Run
+	}
+	return ""
+}
+
+type GraphRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Namespace     string                 `protobuf:"bytes,2,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GraphRequest) Reset() {
+	*x = GraphRequest{}
+	mi := &file_api_v1alpha1_dlock_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GraphRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GraphRequest) ProtoMessage() {}
+
+func (x *GraphRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_v1alpha1_dlock_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GraphRequest.ProtoReflect.Descriptor instead.
+func (*GraphRequest) Descriptor() ([]byte, []int) {
+	return file_api_v1alpha1_dlock_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *GraphRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *GraphRequest) GetNamespace() string {
+	if x != nil {
+		return x.Namespace
+	}
+	return ""
+}
+
+type GraphNode struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Key   string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	// keys of the nodes that must be done or held before the node can be locked
+	Predecessors  []string       `protobuf:"bytes,2,rep,name=predecessors,proto3" json:"predecessors,omitempty"`
+	State         GraphNodeState `protobuf:"varint,3,opt,name=state,proto3,enum=dlock.GraphNodeState" json:"state,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GraphNode) Reset() {
+	*x = GraphNode{}
+	mi := &file_api_v1alpha1_dlock_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GraphNode) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GraphNode) ProtoMessage() {}
+
+func (x *GraphNode) ProtoReflect() protoreflect.Message {
+	mi := &file_api_v1alpha1_dlock_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GraphNode.ProtoReflect.Descriptor instead.
+func (*GraphNode) Descriptor() ([]byte, []int) {
+	return file_api_v1alpha1_dlock_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *GraphNode) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *GraphNode) GetPredecessors() []string {
+	if x != nil {
+		return x.Predecessors
+	}
+	return nil
+}
+
+func (x *GraphNode) GetState() GraphNodeState {
+	if x != nil {
+		return x.State
+	}
+	return GraphNodeState_NodePending
+}
+
+var File_api_v1alpha1_dlock_proto protoreflect.FileDescriptor
+
+const file_api_v1alpha1_dlock_proto_rawDesc = "" +
+	"\n" +
+	"\x18api/v1alpha1/dlock.proto\x12\x05dlock\x1a\x1bgoogle/protobuf/empty.proto\x1a\x1egoogle/protobuf/duration.proto\x1a\x1fgoogle/protobuf/timestamp.proto\"\xe2\x03\n" +
+	"\vLockRequest\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x18\n" +
+	"\atryLock\x18\x02 \x01(\bR\atryLock\x12#\n" +
+	"\x04mode\x18\x03 \x01(\x0e2\x0f.dlock.LockModeR\x04mode\x12/\n" +
+	"\bmetadata\x18\x04 \x01(\v2\x13.dlock.LockMetadataR\bmetadata\x12+\n" +
+	"\x03ttl\x18\x05 \x01(\v2\x19.google.protobuf.DurationR\x03ttl\x12G\n" +
+	"\x11keepaliveInterval\x18\x06 \x01(\v2\x19.google.protobuf.DurationR\x11keepaliveInterval\x129\n" +
+	"\n" +
+	"retryDelay\x18\a \x01(\v2\x19.google.protobuf.DurationR\n" +
+	"retryDelay\x12\x12\n" +
+	"\x04fair\x18\b \x01(\bR\x04fair\x12\x12\n" +
+	"\x04keys\x18\t \x03(\tR\x04keys\x12\x1c\n" +
+	"\treentrant\x18\n" +
+	" \x01(\bR\treentrant\x12\x1c\n" +
+	"\tnamespace\x18\v \x01(\tR\tnamespace\x12\x14\n" +
+	"\x05graph\x18\f \x01(\tR\x05graph\x12&\n" +
+	"\x0ereentrantToken\x18\r \x01(\tR\x0ereentrantToken\"\xc6\x01\n" +
+	"\fLockMetadata\x12\x14\n" +
+	"\x05owner\x18\x01 \x01(\tR\x05owner\x12\x1a\n" +
+	"\bhostname\x18\x02 \x01(\tR\bhostname\x12\x10\n" +
+	"\x03pid\x18\x03 \x01(\x03R\x03pid\x127\n" +
+	"\x06labels\x18\x04 \x03(\v2\x1f.dlock.LockMetadata.LabelsEntryR\x06labels\x1a9\n" +
+	"\vLabelsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\x9e\x01\n" +
+	"\fLockResponse\x12&\n" +
+	"\x05event\x18\x01 \x01(\x0e2\x10.dlock.LockEventR\x05event\x12\"\n" +
+	"\ffencingToken\x18\x02 \x01(\x04R\ffencingToken\x12\x1a\n" +
+	"\bposition\x18\x03 \x01(\x03R\bposition\x12&\n" +
+	"\x0ereentrantToken\x18\x04 \x01(\tR\x0ereentrantToken\"\xbb\x03\n" +
+	"\x0eAcquireRequest\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x18\n" +
+	"\atryLock\x18\x02 \x01(\bR\atryLock\x12+\n" +
+	"\x03ttl\x18\x03 \x01(\v2\x19.google.protobuf.DurationR\x03ttl\x12#\n" +
+	"\x04mode\x18\x04 \x01(\x0e2\x0f.dlock.LockModeR\x04mode\x12/\n" +
+	"\bmetadata\x18\x05 \x01(\v2\x13.dlock.LockMetadataR\bmetadata\x12\x1c\n" +
+	"\treentrant\x18\x06 \x01(\bR\treentrant\x12\x1c\n" +
+	"\tnamespace\x18\a \x01(\tR\tnamespace\x12&\n" +
+	"\x0ereentrantToken\x18\b \x01(\tR\x0ereentrantToken\x12G\n" +
+	"\x11keepaliveInterval\x18\t \x01(\v2\x19.google.protobuf.DurationR\x11keepaliveInterval\x129\n" +
+	"\n" +
+	"retryDelay\x18\n" +
+	" \x01(\v2\x19.google.protobuf.DurationR\n" +
+	"retryDelay\x12\x12\n" +
+	"\x04fair\x18\v \x01(\bR\x04fair\"\xc0\x01\n" +
+	"\x0fAcquireResponse\x12\x1a\n" +
+	"\bacquired\x18\x01 \x01(\bR\bacquired\x12\x18\n" +
+	"\aleaseId\x18\x02 \x01(\tR\aleaseId\x12+\n" +
+	"\x03ttl\x18\x03 \x01(\v2\x19.google.protobuf.DurationR\x03ttl\x12\"\n" +
+	"\ffencingToken\x18\x04 \x01(\x04R\ffencingToken\x12&\n" +
+	"\x0ereentrantToken\x18\x05 \x01(\tR\x0ereentrantToken\"V\n" +
+	"\rExtendRequest\x12\x18\n" +
+	"\aleaseId\x18\x01 \x01(\tR\aleaseId\x12+\n" +
+	"\x03ttl\x18\x02 \x01(\v2\x19.google.protobuf.DurationR\x03ttl\"=\n" +
+	"\x0eExtendResponse\x12+\n" +
+	"\x03ttl\x18\x01 \x01(\v2\x19.google.protobuf.DurationR\x03ttl\"*\n" +
+	"\x0eReleaseRequest\x12\x18\n" +
+	"\aleaseId\x18\x01 \x01(\tR\aleaseId\"E\n" +
+	"\x0fTransferRequest\x12\x18\n" +
+	"\aleaseId\x18\x01 \x01(\tR\aleaseId\x12\x18\n" +
+	"\atoOwner\x18\x02 \x01(\tR\atoOwner\"\xc7\x02\n" +
+	"\x10SemaphoreRequest\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x1e\n" +
+	"\n" +
+	"tryAcquire\x18\x02 \x01(\bR\n" +
+	"tryAcquire\x12\x16\n" +
+	"\x06weight\x18\x03 \x01(\x03R\x06weight\x12\x1a\n" +
+	"\bcapacity\x18\x04 \x01(\x03R\bcapacity\x12\x1c\n" +
+	"\tnamespace\x18\x05 \x01(\tR\tnamespace\x12+\n" +
+	"\x03ttl\x18\x06 \x01(\v2\x19.google.protobuf.DurationR\x03ttl\x12G\n" +
+	"\x11keepaliveInterval\x18\a \x01(\v2\x19.google.protobuf.DurationR\x11keepaliveInterval\x129\n" +
+	"\n" +
+	"retryDelay\x18\b \x01(\v2\x19.google.protobuf.DurationR\n" +
+	"retryDelay\"H\n" +
+	"\x10ListLocksRequest\x12\x16\n" +
+	"\x06prefix\x18\x01 \x01(\tR\x06prefix\x12\x1c\n" +
+	"\tnamespace\x18\x02 \x01(\tR\tnamespace\":\n" +
+	"\x11ListLocksResponse\x12%\n" +
+	"\x05locks\x18\x01 \x03(\v2\x0f.dlock.LockInfoR\x05locks\"E\n" +
+	"\x13DescribeLockRequest\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x1c\n" +
+	"\tnamespace\x18\x02 \x01(\tR\tnamespace\"D\n" +
+	"\fWatchRequest\x12\x16\n" +
+	"\x06prefix\x18\x01 \x01(\tR\x06prefix\x12\x1c\n" +
+	"\tnamespace\x18\x02 \x01(\tR\tnamespace\"w\n" +
+	"\rWatchResponse\x12)\n" +
+	"\x04type\x18\x01 \x01(\x0e2\x15.dlock.WatchEventTypeR\x04type\x12\x10\n" +
+	"\x03key\x18\x02 \x01(\tR\x03key\x12)\n" +
+	"\x06holder\x18\x03 \x01(\v2\x11.dlock.LockHolderR\x06holder\"l\n" +
+	"\x0fCampaignRequest\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value\x12/\n" +
+	"\bmetadata\x18\x03 \x01(\v2\x13.dlock.LockMetadataR\bmetadata\"#\n" +
+	"\rLeaderRequest\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\"&\n" +
+	"\x0eLeaderResponse\x12\x14\n" +
+	"\x05value\x18\x01 \x01(\tR\x05value\"c\n" +
+	"\bLockInfo\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12+\n" +
+	"\aholders\x18\x02 \x03(\v2\x11.dlock.LockHolderR\aholders\x12\x18\n" +
+	"\awaiters\x18\x03 \x01(\x03R\awaiters\"\xcb\x01\n" +
+	"\n" +
+	"LockHolder\x12/\n" +
+	"\bmetadata\x18\x01 \x01(\v2\x13.dlock.LockMetadataR\bmetadata\x12#\n" +
+	"\x04mode\x18\x02 \x01(\x0e2\x0f.dlock.LockModeR\x04mode\x12:\n" +
+	"\n" +
+	"acquiredAt\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"acquiredAt\x12+\n" +
+	"\x03ttl\x18\x04 \x01(\v2\x19.google.protobuf.DurationR\x03ttl\"]\n" +
+	"\x13ForceReleaseRequest\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x16\n" +
+	"\x06reason\x18\x02 \x01(\tR\x06reason\x12\x1c\n" +
+	"\tnamespace\x18\x03 \x01(\tR\tnamespace\"C\n" +
+	"\x14ForceReleaseResponse\x12+\n" +
+	"\aevicted\x18\x01 \x03(\v2\x11.dlock.LockHolderR\aevicted\"\x80\x01\n" +
+	"\x12SubmitGraphRequest\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x1c\n" +
+	"\tnamespace\x18\x02 \x01(\tR\tnamespace\x12&\n" +
+	"\x05nodes\x18\x03 \x03(\v2\x10.dlock.GraphNodeR\x05nodes\x12\x10\n" +
+	"\x03run\x18\x04 \x01(\tR\x03run\"@\n" +
+	"\fGraphRequest\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x1c\n" +
+	"\tnamespace\x18\x02 \x01(\tR\tnamespace\"n\n" +
+	"\tGraphNode\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\"\n" +
+	"\fpredecessors\x18\x02 \x03(\tR\fpredecessors\x12+\n" +
+	"\x05state\x18\x03 \x01(\x0e2\x15.dlock.GraphNodeStateR\x05state*\x1a\n" +
+	"\bLockMode\x12\x06\n" +
+	"\x02EX\x10\x00\x12\x06\n" +
+	"\x02PR\x10\x01*?\n" +
+	"\tLockEvent\x12\f\n" +
+	"\bAcquired\x10\x00\x12\n" +
+	"\n" +
+	"\x06Failed\x10\x01\x12\n" +
+	"\n" +
+	"\x06Queued\x10\x02\x12\f\n" +
+	"\bPosition\x10\x03*E\n" +
+	"\x0eWatchEventType\x12\x10\n" +
+	"\fLockAcquired\x10\x00\x12\x10\n" +
+	"\fLockReleased\x10\x01\x12\x0f\n" +
+	"\vLockExpired\x10\x02*=\n" +
+	"\x0eGraphNodeState\x12\x0f\n" +
+	"\vNodePending\x10\x00\x12\f\n" +
+	"\bNodeHeld\x10\x01\x12\f\n" +
+	"\bNodeDone\x10\x022\x92\a\n" +
+	"\x05Dlock\x123\n" +
+	"\x04Lock\x12\x12.dlock.LockRequest\x1a\x13.dlock.LockResponse\"\x000\x01\x12:\n" +
+	"\aAcquire\x12\x15.dlock.AcquireRequest\x1a\x16.dlock.AcquireResponse\"\x00\x127\n" +
+	"\x06Extend\x12\x14.dlock.ExtendRequest\x1a\x15.dlock.ExtendResponse\"\x00\x12:\n" +
+	"\aRelease\x12\x15.dlock.ReleaseRequest\x1a\x16.google.protobuf.Empty\"\x00\x12<\n" +
+	"\bTransfer\x12\x16.dlock.TransferRequest\x1a\x16.google.protobuf.Empty\"\x00\x12=\n" +
+	"\tSemaphore\x12\x17.dlock.SemaphoreRequest\x1a\x13.dlock.LockResponse\"\x000\x01\x12@\n" +
+	"\tListLocks\x12\x17.dlock.ListLocksRequest\x1a\x18.dlock.ListLocksResponse\"\x00\x12=\n" +
+	"\fDescribeLock\x12\x1a.dlock.DescribeLockRequest\x1a\x0f.dlock.LockInfo\"\x00\x126\n" +
+	"\x05Watch\x12\x13.dlock.WatchRequest\x1a\x14.dlock.WatchResponse\"\x000\x01\x12;\n" +
+	"\bCampaign\x12\x16.dlock.CampaignRequest\x1a\x13.dlock.LockResponse\"\x000\x01\x127\n" +
+	"\x06Leader\x12\x14.dlock.LeaderRequest\x1a\x15.dlock.LeaderResponse\"\x00\x12:\n" +
+	"\aObserve\x12\x14.dlock.LeaderRequest\x1a\x15.dlock.LeaderResponse\"\x000\x01\x12B\n" +
+	"\vSubmitGraph\x12\x19.dlock.SubmitGraphRequest\x1a\x16.google.protobuf.Empty\"\x00\x129\n" +
+	"\fObserveGraph\x12\x13.dlock.GraphRequest\x1a\x10.dlock.GraphNode\"\x000\x01\x12<\n" +
+	"\vDeleteGraph\x12\x13.dlock.GraphRequest\x1a\x16.google.protobuf.Empty\"\x002W\n" +
+	"\n" +
+	"DlockAdmin\x12I\n" +
+	"\fForceRelease\x12\x1a.dlock.ForceReleaseRequest\x1a\x1b.dlock.ForceReleaseResponse\"\x00B0Z.github.com/alexandreLamarre/dlock/api/v1alpha1b\x06proto3"
 
 var (
 	file_api_v1alpha1_dlock_proto_rawDescOnce sync.Once
@@ -193,22 +2043,111 @@ func file_api_v1alpha1_dlock_proto_rawDescGZIP() []byte {
 	return file_api_v1alpha1_dlock_proto_rawDescData
 }
 
-var file_api_v1alpha1_dlock_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
-var file_api_v1alpha1_dlock_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
+var file_api_v1alpha1_dlock_proto_enumTypes = make([]protoimpl.EnumInfo, 4)
+var file_api_v1alpha1_dlock_proto_msgTypes = make([]protoimpl.MessageInfo, 26)
 var file_api_v1alpha1_dlock_proto_goTypes = []any{
-	(LockEvent)(0),       // 0: dlock.LockEvent
-	(*LockRequest)(nil),  // 1: dlock.LockRequest
-	(*LockResponse)(nil), // 2: dlock.LockResponse
+	(LockMode)(0),                 // 0: dlock.LockMode
+	(LockEvent)(0),                // 1: dlock.LockEvent
+	(WatchEventType)(0),           // 2: dlock.WatchEventType
+	(GraphNodeState)(0),           // 3: dlock.GraphNodeState
+	(*LockRequest)(nil),           // 4: dlock.LockRequest
+	(*LockMetadata)(nil),          // 5: dlock.LockMetadata
+	(*LockResponse)(nil),          // 6: dlock.LockResponse
+	(*AcquireRequest)(nil),        // 7: dlock.AcquireRequest
+	(*AcquireResponse)(nil),       // 8: dlock.AcquireResponse
+	(*ExtendRequest)(nil),         // 9: dlock.ExtendRequest
+	(*ExtendResponse)(nil),        // 10: dlock.ExtendResponse
+	(*ReleaseRequest)(nil),        // 11: dlock.ReleaseRequest
+	(*TransferRequest)(nil),       // 12: dlock.TransferRequest
+	(*SemaphoreRequest)(nil),      // 13: dlock.SemaphoreRequest
+	(*ListLocksRequest)(nil),      // 14: dlock.ListLocksRequest
+	(*ListLocksResponse)(nil),     // 15: dlock.ListLocksResponse
+	(*DescribeLockRequest)(nil),   // 16: dlock.DescribeLockRequest
+	(*WatchRequest)(nil),          // 17: dlock.WatchRequest
+	(*WatchResponse)(nil),         // 18: dlock.WatchResponse
+	(*CampaignRequest)(nil),       // 19: dlock.CampaignRequest
+	(*LeaderRequest)(nil),         // 20: dlock.LeaderRequest
+	(*LeaderResponse)(nil),        // 21: dlock.LeaderResponse
+	(*LockInfo)(nil),              // 22: dlock.LockInfo
+	(*LockHolder)(nil),            // 23: dlock.LockHolder
+	(*ForceReleaseRequest)(nil),   // 24: dlock.ForceReleaseRequest
+	(*ForceReleaseResponse)(nil),  // 25: dlock.ForceReleaseResponse
+	(*SubmitGraphRequest)(nil),    // 26: dlock.SubmitGraphRequest
+	(*GraphRequest)(nil),          // 27: dlock.GraphRequest
+	(*GraphNode)(nil),             // 28: dlock.GraphNode
+	nil,                           // 29: dlock.LockMetadata.LabelsEntry
+	(*durationpb.Duration)(nil),   // 30: google.protobuf.Duration
+	(*timestamppb.Timestamp)(nil), // 31: google.protobuf.Timestamp
+	(*emptypb.Empty)(nil),         // 32: google.protobuf.Empty
 }
 var file_api_v1alpha1_dlock_proto_depIdxs = []int32{
-	0, // 0: dlock.LockResponse.event:type_name -> dlock.LockEvent
-	1, // 1: dlock.Dlock.Lock:input_type -> dlock.LockRequest
-	2, // 2: dlock.Dlock.Lock:output_type -> dlock.LockResponse
-	2, // [2:3] is the sub-list for method output_type
-	1, // [1:2] is the sub-list for method input_type
-	1, // [1:1] is the sub-list for extension type_name
-	1, // [1:1] is the sub-list for extension extendee
-	0, // [0:1] is the sub-list for field type_name
+	0,  // 0: dlock.LockRequest.mode:type_name -> dlock.LockMode
+	5,  // 1: dlock.LockRequest.metadata:type_name -> dlock.LockMetadata
+	30, // 2: dlock.LockRequest.ttl:type_name -> google.protobuf.Duration
+	30, // 3: dlock.LockRequest.keepaliveInterval:type_name -> google.protobuf.Duration
+	30, // 4: dlock.LockRequest.retryDelay:type_name -> google.protobuf.Duration
+	29, // 5: dlock.LockMetadata.labels:type_name -> dlock.LockMetadata.LabelsEntry
+	1,  // 6: dlock.LockResponse.event:type_name -> dlock.LockEvent
+	30, // 7: dlock.AcquireRequest.ttl:type_name -> google.protobuf.Duration
+	0,  // 8: dlock.AcquireRequest.mode:type_name -> dlock.LockMode
+	5,  // 9: dlock.AcquireRequest.metadata:type_name -> dlock.LockMetadata
+	30, // 10: dlock.AcquireRequest.keepaliveInterval:type_name -> google.protobuf.Duration
+	30, // 11: dlock.AcquireRequest.retryDelay:type_name -> google.protobuf.Duration
+	30, // 12: dlock.AcquireResponse.ttl:type_name -> google.protobuf.Duration
+	30, // 13: dlock.ExtendRequest.ttl:type_name -> google.protobuf.Duration
+	30, // 14: dlock.ExtendResponse.ttl:type_name -> google.protobuf.Duration
+	30, // 15: dlock.SemaphoreRequest.ttl:type_name -> google.protobuf.Duration
+	30, // 16: dlock.SemaphoreRequest.keepaliveInterval:type_name -> google.protobuf.Duration
+	30, // 17: dlock.SemaphoreRequest.retryDelay:type_name -> google.protobuf.Duration
+	22, // 18: dlock.ListLocksResponse.locks:type_name -> dlock.LockInfo
+	2,  // 19: dlock.WatchResponse.type:type_name -> dlock.WatchEventType
+	23, // 20: dlock.WatchResponse.holder:type_name -> dlock.LockHolder
+	5,  // 21: dlock.CampaignRequest.metadata:type_name -> dlock.LockMetadata
+	23, // 22: dlock.LockInfo.holders:type_name -> dlock.LockHolder
+	5,  // 23: dlock.LockHolder.metadata:type_name -> dlock.LockMetadata
+	0,  // 24: dlock.LockHolder.mode:type_name -> dlock.LockMode
+	31, // 25: dlock.LockHolder.acquiredAt:type_name -> google.protobuf.Timestamp
+	30, // 26: dlock.LockHolder.ttl:type_name -> google.protobuf.Duration
+	23, // 27: dlock.ForceReleaseResponse.evicted:type_name -> dlock.LockHolder
+	28, // 28: dlock.SubmitGraphRequest.nodes:type_name -> dlock.GraphNode
+	3,  // 29: dlock.GraphNode.state:type_name -> dlock.GraphNodeState
+	4,  // 30: dlock.Dlock.Lock:input_type -> dlock.LockRequest
+	7,  // 31: dlock.Dlock.Acquire:input_type -> dlock.AcquireRequest
+	9,  // 32: dlock.Dlock.Extend:input_type -> dlock.ExtendRequest
+	11, // 33: dlock.Dlock.Release:input_type -> dlock.ReleaseRequest
+	12, // 34: dlock.Dlock.Transfer:input_type -> dlock.TransferRequest
+	13, // 35: dlock.Dlock.Semaphore:input_type -> dlock.SemaphoreRequest
+	14, // 36: dlock.Dlock.ListLocks:input_type -> dlock.ListLocksRequest
+	16, // 37: dlock.Dlock.DescribeLock:input_type -> dlock.DescribeLockRequest
+	17, // 38: dlock.Dlock.Watch:input_type -> dlock.WatchRequest
+	19, // 39: dlock.Dlock.Campaign:input_type -> dlock.CampaignRequest
+	20, // 40: dlock.Dlock.Leader:input_type -> dlock.LeaderRequest
+	20, // 41: dlock.Dlock.Observe:input_type -> dlock.LeaderRequest
+	26, // 42: dlock.Dlock.SubmitGraph:input_type -> dlock.SubmitGraphRequest
+	27, // 43: dlock.Dlock.ObserveGraph:input_type -> dlock.GraphRequest
+	27, // 44: dlock.Dlock.DeleteGraph:input_type -> dlock.GraphRequest
+	24, // 45: dlock.DlockAdmin.ForceRelease:input_type -> dlock.ForceReleaseRequest
+	6,  // 46: dlock.Dlock.Lock:output_type -> dlock.LockResponse
+	8,  // 47: dlock.Dlock.Acquire:output_type -> dlock.AcquireResponse
+	10, // 48: dlock.Dlock.Extend:output_type -> dlock.ExtendResponse
+	32, // 49: dlock.Dlock.Release:output_type -> google.protobuf.Empty
+	32, // 50: dlock.Dlock.Transfer:output_type -> google.protobuf.Empty
+	6,  // 51: dlock.Dlock.Semaphore:output_type -> dlock.LockResponse
+	15, // 52: dlock.Dlock.ListLocks:output_type -> dlock.ListLocksResponse
+	22, // 53: dlock.Dlock.DescribeLock:output_type -> dlock.LockInfo
+	18, // 54: dlock.Dlock.Watch:output_type -> dlock.WatchResponse
+	6,  // 55: dlock.Dlock.Campaign:output_type -> dlock.LockResponse
+	21, // 56: dlock.Dlock.Leader:output_type -> dlock.LeaderResponse
+	21, // 57: dlock.Dlock.Observe:output_type -> dlock.LeaderResponse
+	32, // 58: dlock.Dlock.SubmitGraph:output_type -> google.protobuf.Empty
+	28, // 59: dlock.Dlock.ObserveGraph:output_type -> dlock.GraphNode
+	32, // 60: dlock.Dlock.DeleteGraph:output_type -> google.protobuf.Empty
+	25, // 61: dlock.DlockAdmin.ForceRelease:output_type -> dlock.ForceReleaseResponse
+	46, // [46:62] is the sub-list for method output_type
+	30, // [30:46] is the sub-list for method input_type
+	30, // [30:30] is the sub-list for extension type_name
+	30, // [30:30] is the sub-list for extension extendee
+	0,  // [0:30] is the sub-list for field type_name
 }
 
 func init() { file_api_v1alpha1_dlock_proto_init() }
@@ -221,10 +2160,10 @@ func file_api_v1alpha1_dlock_proto_init() {
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_api_v1alpha1_dlock_proto_rawDesc), len(file_api_v1alpha1_dlock_proto_rawDesc)),
-			NumEnums:      1,
-			NumMessages:   2,
+			NumEnums:      4,
+			NumMessages:   26,
 			NumExtensions: 0,
-			NumServices:   1,
+			NumServices:   2,
 		},
 		GoTypes:           file_api_v1alpha1_dlock_proto_goTypes,
 		DependencyIndexes: file_api_v1alpha1_dlock_proto_depIdxs,
@@ -11,6 +11,7 @@ import (
 	grpc "google.golang.org/grpc"
 	codes "google.golang.org/grpc/codes"
 	status "google.golang.org/grpc/status"
+	emptypb "google.golang.org/protobuf/types/known/emptypb"
 )
 
 // This is a compile-time assertion to ensure that this generated file
@@ -19,7 +20,21 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	Dlock_Lock_FullMethodName = "/dlock.Dlock/Lock"
+	Dlock_Lock_FullMethodName         = "/dlock.Dlock/Lock"
+	Dlock_Acquire_FullMethodName      = "/dlock.Dlock/Acquire"
+	Dlock_Extend_FullMethodName       = "/dlock.Dlock/Extend"
+	Dlock_Release_FullMethodName      = "/dlock.Dlock/Release"
+	Dlock_Transfer_FullMethodName     = "/dlock.Dlock/Transfer"
+	Dlock_Semaphore_FullMethodName    = "/dlock.Dlock/Semaphore"
+	Dlock_ListLocks_FullMethodName    = "/dlock.Dlock/ListLocks"
+	Dlock_DescribeLock_FullMethodName = "/dlock.Dlock/DescribeLock"
+	Dlock_Watch_FullMethodName        = "/dlock.Dlock/Watch"
+	Dlock_Campaign_FullMethodName     = "/dlock.Dlock/Campaign"
+	Dlock_Leader_FullMethodName       = "/dlock.Dlock/Leader"
+	Dlock_Observe_FullMethodName      = "/dlock.Dlock/Observe"
+	Dlock_SubmitGraph_FullMethodName  = "/dlock.Dlock/SubmitGraph"
+	Dlock_ObserveGraph_FullMethodName = "/dlock.Dlock/ObserveGraph"
+	Dlock_DeleteGraph_FullMethodName  = "/dlock.Dlock/DeleteGraph"
 )
 
 // DlockClient is the client API for Dlock service.
@@ -27,6 +42,34 @@ const (
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type DlockClient interface {
 	Lock(ctx context.Context, in *LockRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[LockResponse], error)
+	// Lease based API, for clients that cannot hold a long-lived stream.
+	// Leases are released by the server if they are not extended within their TTL.
+	Acquire(ctx context.Context, in *AcquireRequest, opts ...grpc.CallOption) (*AcquireResponse, error)
+	Extend(ctx context.Context, in *ExtendRequest, opts ...grpc.CallOption) (*ExtendResponse, error)
+	Release(ctx context.Context, in *ReleaseRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// Hands the lease's lock over to the earliest blocking acquisition of the owner waiting for it,
+	// without releasing it in between. The lease is released once the lock is transferred.
+	Transfer(ctx context.Context, in *TransferRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// Holds units of a counting semaphore for the lifetime of the stream, like Lock.
+	Semaphore(ctx context.Context, in *SemaphoreRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[LockResponse], error)
+	// Introspection of the holders & waiters of locks, semaphores are not listed.
+	ListLocks(ctx context.Context, in *ListLocksRequest, opts ...grpc.CallOption) (*ListLocksResponse, error)
+	DescribeLock(ctx context.Context, in *DescribeLockRequest, opts ...grpc.CallOption) (*LockInfo, error)
+	// Streams the acquisitions, releases & expiries of the locks under the prefix, until the stream is done.
+	Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[WatchResponse], error)
+	// Leader elections, a candidate is the leader from the Acquired event of its stream
+	// until the stream is done, like Lock.
+	Campaign(ctx context.Context, in *CampaignRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[LockResponse], error)
+	Leader(ctx context.Context, in *LeaderRequest, opts ...grpc.CallOption) (*LeaderResponse, error)
+	// Streams the value of each new leader, starting with the current one.
+	Observe(ctx context.Context, in *LeaderRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[LeaderResponse], error)
+	// Lock dependency graphs, whose nodes are locked by Lock requests setting the graph field once each of their
+	// predecessors is done or held. The progress of a graph is tracked by the server it is submitted to.
+	SubmitGraph(ctx context.Context, in *SubmitGraphRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// Streams every node of the graph, then each node whose state changed, until every node is done.
+	ObserveGraph(ctx context.Context, in *GraphRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[GraphNode], error)
+	// Deletes the graph, pending Lock requests on its nodes fail.
+	DeleteGraph(ctx context.Context, in *GraphRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
 }
 
 type dlockClient struct {
@@ -56,11 +99,224 @@ func (c *dlockClient) Lock(ctx context.Context, in *LockRequest, opts ...grpc.Ca
 // This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
 type Dlock_LockClient = grpc.ServerStreamingClient[LockResponse]
 
+func (c *dlockClient) Acquire(ctx context.Context, in *AcquireRequest, opts ...grpc.CallOption) (*AcquireResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AcquireResponse)
+	err := c.cc.Invoke(ctx, Dlock_Acquire_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *dlockClient) Extend(ctx context.Context, in *ExtendRequest, opts ...grpc.CallOption) (*ExtendResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ExtendResponse)
+	err := c.cc.Invoke(ctx, Dlock_Extend_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *dlockClient) Release(ctx context.Context, in *ReleaseRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, Dlock_Release_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *dlockClient) Transfer(ctx context.Context, in *TransferRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, Dlock_Transfer_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *dlockClient) Semaphore(ctx context.Context, in *SemaphoreRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[LockResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Dlock_ServiceDesc.Streams[1], Dlock_Semaphore_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[SemaphoreRequest, LockResponse]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Dlock_SemaphoreClient = grpc.ServerStreamingClient[LockResponse]
+
+func (c *dlockClient) ListLocks(ctx context.Context, in *ListLocksRequest, opts ...grpc.CallOption) (*ListLocksResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListLocksResponse)
+	err := c.cc.Invoke(ctx, Dlock_ListLocks_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *dlockClient) DescribeLock(ctx context.Context, in *DescribeLockRequest, opts ...grpc.CallOption) (*LockInfo, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(LockInfo)
+	err := c.cc.Invoke(ctx, Dlock_DescribeLock_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *dlockClient) Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[WatchResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Dlock_ServiceDesc.Streams[2], Dlock_Watch_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WatchRequest, WatchResponse]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Dlock_WatchClient = grpc.ServerStreamingClient[WatchResponse]
+
+func (c *dlockClient) Campaign(ctx context.Context, in *CampaignRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[LockResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Dlock_ServiceDesc.Streams[3], Dlock_Campaign_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[CampaignRequest, LockResponse]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Dlock_CampaignClient = grpc.ServerStreamingClient[LockResponse]
+
+func (c *dlockClient) Leader(ctx context.Context, in *LeaderRequest, opts ...grpc.CallOption) (*LeaderResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(LeaderResponse)
+	err := c.cc.Invoke(ctx, Dlock_Leader_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *dlockClient) Observe(ctx context.Context, in *LeaderRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[LeaderResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Dlock_ServiceDesc.Streams[4], Dlock_Observe_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[LeaderRequest, LeaderResponse]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Dlock_ObserveClient = grpc.ServerStreamingClient[LeaderResponse]
+
+func (c *dlockClient) SubmitGraph(ctx context.Context, in *SubmitGraphRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, Dlock_SubmitGraph_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *dlockClient) ObserveGraph(ctx context.Context, in *GraphRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[GraphNode], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Dlock_ServiceDesc.Streams[5], Dlock_ObserveGraph_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[GraphRequest, GraphNode]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Dlock_ObserveGraphClient = grpc.ServerStreamingClient[GraphNode]
+
+func (c *dlockClient) DeleteGraph(ctx context.Context, in *GraphRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, Dlock_DeleteGraph_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // DlockServer is the server API for Dlock service.
 // All implementations should embed UnimplementedDlockServer
 // for forward compatibility.
 type DlockServer interface {
 	Lock(*LockRequest, grpc.ServerStreamingServer[LockResponse]) error
+	// Lease based API, for clients that cannot hold a long-lived stream.
+	// Leases are released by the server if they are not extended within their TTL.
+	Acquire(context.Context, *AcquireRequest) (*AcquireResponse, error)
+	Extend(context.Context, *ExtendRequest) (*ExtendResponse, error)
+	Release(context.Context, *ReleaseRequest) (*emptypb.Empty, error)
+	// Hands the lease's lock over to the earliest blocking acquisition of the owner waiting for it,
+	// without releasing it in between. The lease is released once the lock is transferred.
+	Transfer(context.Context, *TransferRequest) (*emptypb.Empty, error)
+	// Holds units of a counting semaphore for the lifetime of the stream, like Lock.
+	Semaphore(*SemaphoreRequest, grpc.ServerStreamingServer[LockResponse]) error
+	// Introspection of the holders & waiters of locks, semaphores are not listed.
+	ListLocks(context.Context, *ListLocksRequest) (*ListLocksResponse, error)
+	DescribeLock(context.Context, *DescribeLockRequest) (*LockInfo, error)
+	// Streams the acquisitions, releases & expiries of the locks under the prefix, until the stream is done.
+	Watch(*WatchRequest, grpc.ServerStreamingServer[WatchResponse]) error
+	// Leader elections, a candidate is the leader from the Acquired event of its stream
+	// until the stream is done, like Lock.
+	Campaign(*CampaignRequest, grpc.ServerStreamingServer[LockResponse]) error
+	Leader(context.Context, *LeaderRequest) (*LeaderResponse, error)
+	// Streams the value of each new leader, starting with the current one.
+	Observe(*LeaderRequest, grpc.ServerStreamingServer[LeaderResponse]) error
+	// Lock dependency graphs, whose nodes are locked by Lock requests setting the graph field once each of their
+	// predecessors is done or held. The progress of a graph is tracked by the server it is submitted to.
+	SubmitGraph(context.Context, *SubmitGraphRequest) (*emptypb.Empty, error)
+	// Streams every node of the graph, then each node whose state changed, until every node is done.
+	ObserveGraph(*GraphRequest, grpc.ServerStreamingServer[GraphNode]) error
+	// Deletes the graph, pending Lock requests on its nodes fail.
+	DeleteGraph(context.Context, *GraphRequest) (*emptypb.Empty, error)
 }
 
 // UnimplementedDlockServer should be embedded to have
@@ -73,6 +329,48 @@ type UnimplementedDlockServer struct{}
 func (UnimplementedDlockServer) Lock(*LockRequest, grpc.ServerStreamingServer[LockResponse]) error {
 	return status.Errorf(codes.Unimplemented, "method Lock not implemented")
 }
+func (UnimplementedDlockServer) Acquire(context.Context, *AcquireRequest) (*AcquireResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Acquire not implemented")
+}
+func (UnimplementedDlockServer) Extend(context.Context, *ExtendRequest) (*ExtendResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Extend not implemented")
+}
+func (UnimplementedDlockServer) Release(context.Context, *ReleaseRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Release not implemented")
+}
+func (UnimplementedDlockServer) Transfer(context.Context, *TransferRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Transfer not implemented")
+}
+func (UnimplementedDlockServer) Semaphore(*SemaphoreRequest, grpc.ServerStreamingServer[LockResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method Semaphore not implemented")
+}
+func (UnimplementedDlockServer) ListLocks(context.Context, *ListLocksRequest) (*ListLocksResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListLocks not implemented")
+}
+func (UnimplementedDlockServer) DescribeLock(context.Context, *DescribeLockRequest) (*LockInfo, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DescribeLock not implemented")
+}
+func (UnimplementedDlockServer) Watch(*WatchRequest, grpc.ServerStreamingServer[WatchResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method Watch not implemented")
+}
+func (UnimplementedDlockServer) Campaign(*CampaignRequest, grpc.ServerStreamingServer[LockResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method Campaign not implemented")
+}
+func (UnimplementedDlockServer) Leader(context.Context, *LeaderRequest) (*LeaderResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Leader not implemented")
+}
+func (UnimplementedDlockServer) Observe(*LeaderRequest, grpc.ServerStreamingServer[LeaderResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method Observe not implemented")
+}
+func (UnimplementedDlockServer) SubmitGraph(context.Context, *SubmitGraphRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SubmitGraph not implemented")
+}
+func (UnimplementedDlockServer) ObserveGraph(*GraphRequest, grpc.ServerStreamingServer[GraphNode]) error {
+	return status.Errorf(codes.Unimplemented, "method ObserveGraph not implemented")
+}
+func (UnimplementedDlockServer) DeleteGraph(context.Context, *GraphRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteGraph not implemented")
+}
 func (UnimplementedDlockServer) testEmbeddedByValue() {}
 
 // UnsafeDlockServer may be embedded to opt out of forward compatibility for this service.
@@ -104,19 +402,404 @@ func _Dlock_Lock_Handler(srv interface{}, stream grpc.ServerStream) error {
 // This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
 type Dlock_LockServer = grpc.ServerStreamingServer[LockResponse]
 
+func _Dlock_Acquire_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AcquireRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DlockServer).Acquire(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Dlock_Acquire_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DlockServer).Acquire(ctx, req.(*AcquireRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Dlock_Extend_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ExtendRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DlockServer).Extend(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Dlock_Extend_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DlockServer).Extend(ctx, req.(*ExtendRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Dlock_Release_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReleaseRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DlockServer).Release(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Dlock_Release_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DlockServer).Release(ctx, req.(*ReleaseRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Dlock_Transfer_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TransferRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DlockServer).Transfer(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Dlock_Transfer_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DlockServer).Transfer(ctx, req.(*TransferRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Dlock_Semaphore_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SemaphoreRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(DlockServer).Semaphore(m, &grpc.GenericServerStream[SemaphoreRequest, LockResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Dlock_SemaphoreServer = grpc.ServerStreamingServer[LockResponse]
+
+func _Dlock_ListLocks_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListLocksRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DlockServer).ListLocks(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Dlock_ListLocks_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DlockServer).ListLocks(ctx, req.(*ListLocksRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Dlock_DescribeLock_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DescribeLockRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DlockServer).DescribeLock(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Dlock_DescribeLock_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DlockServer).DescribeLock(ctx, req.(*DescribeLockRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Dlock_Watch_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(DlockServer).Watch(m, &grpc.GenericServerStream[WatchRequest, WatchResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Dlock_WatchServer = grpc.ServerStreamingServer[WatchResponse]
+
+func _Dlock_Campaign_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(CampaignRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(DlockServer).Campaign(m, &grpc.GenericServerStream[CampaignRequest, LockResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Dlock_CampaignServer = grpc.ServerStreamingServer[LockResponse]
+
+func _Dlock_Leader_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LeaderRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DlockServer).Leader(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Dlock_Leader_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DlockServer).Leader(ctx, req.(*LeaderRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Dlock_Observe_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(LeaderRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(DlockServer).Observe(m, &grpc.GenericServerStream[LeaderRequest, LeaderResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Dlock_ObserveServer = grpc.ServerStreamingServer[LeaderResponse]
+
+func _Dlock_SubmitGraph_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SubmitGraphRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DlockServer).SubmitGraph(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Dlock_SubmitGraph_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DlockServer).SubmitGraph(ctx, req.(*SubmitGraphRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Dlock_ObserveGraph_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(GraphRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(DlockServer).ObserveGraph(m, &grpc.GenericServerStream[GraphRequest, GraphNode]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Dlock_ObserveGraphServer = grpc.ServerStreamingServer[GraphNode]
+
+func _Dlock_DeleteGraph_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GraphRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DlockServer).DeleteGraph(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Dlock_DeleteGraph_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DlockServer).DeleteGraph(ctx, req.(*GraphRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // Dlock_ServiceDesc is the grpc.ServiceDesc for Dlock service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
 var Dlock_ServiceDesc = grpc.ServiceDesc{
 	ServiceName: "dlock.Dlock",
 	HandlerType: (*DlockServer)(nil),
-	Methods:     []grpc.MethodDesc{},
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Acquire",
+			Handler:    _Dlock_Acquire_Handler,
+		},
+		{
+			MethodName: "Extend",
+			Handler:    _Dlock_Extend_Handler,
+		},
+		{
+			MethodName: "Release",
+			Handler:    _Dlock_Release_Handler,
+		},
+		{
+			MethodName: "Transfer",
+			Handler:    _Dlock_Transfer_Handler,
+		},
+		{
+			MethodName: "ListLocks",
+			Handler:    _Dlock_ListLocks_Handler,
+		},
+		{
+			MethodName: "DescribeLock",
+			Handler:    _Dlock_DescribeLock_Handler,
+		},
+		{
+			MethodName: "Leader",
+			Handler:    _Dlock_Leader_Handler,
+		},
+		{
+			MethodName: "SubmitGraph",
+			Handler:    _Dlock_SubmitGraph_Handler,
+		},
+		{
+			MethodName: "DeleteGraph",
+			Handler:    _Dlock_DeleteGraph_Handler,
+		},
+	},
 	Streams: []grpc.StreamDesc{
 		{
 			StreamName:    "Lock",
 			Handler:       _Dlock_Lock_Handler,
 			ServerStreams: true,
 		},
+		{
+			StreamName:    "Semaphore",
+			Handler:       _Dlock_Semaphore_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "Watch",
+			Handler:       _Dlock_Watch_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "Campaign",
+			Handler:       _Dlock_Campaign_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "Observe",
+			Handler:       _Dlock_Observe_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "ObserveGraph",
+			Handler:       _Dlock_ObserveGraph_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "api/v1alpha1/dlock.proto",
+}
+
+const (
+	DlockAdmin_ForceRelease_FullMethodName = "/dlock.DlockAdmin/ForceRelease"
+)
+
+// DlockAdminClient is the client API for DlockAdmin service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Operator only APIs, served separately so that they can be restricted independently of Dlock.
+type DlockAdminClient interface {
+	// Evicts every holder of a lock, e.g. when a holder is wedged while keeping its lock alive.
+	ForceRelease(ctx context.Context, in *ForceReleaseRequest, opts ...grpc.CallOption) (*ForceReleaseResponse, error)
+}
+
+type dlockAdminClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewDlockAdminClient(cc grpc.ClientConnInterface) DlockAdminClient {
+	return &dlockAdminClient{cc}
+}
+
+func (c *dlockAdminClient) ForceRelease(ctx context.Context, in *ForceReleaseRequest, opts ...grpc.CallOption) (*ForceReleaseResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ForceReleaseResponse)
+	err := c.cc.Invoke(ctx, DlockAdmin_ForceRelease_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// DlockAdminServer is the server API for DlockAdmin service.
+// All implementations should embed UnimplementedDlockAdminServer
+// for forward compatibility.
+//
+// Operator only APIs, served separately so that they can be restricted independently of Dlock.
+type DlockAdminServer interface {
+	// Evicts every holder of a lock, e.g. when a holder is wedged while keeping its lock alive.
+	ForceRelease(context.Context, *ForceReleaseRequest) (*ForceReleaseResponse, error)
+}
+
+// UnimplementedDlockAdminServer should be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedDlockAdminServer struct{}
+
+func (UnimplementedDlockAdminServer) ForceRelease(context.Context, *ForceReleaseRequest) (*ForceReleaseResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ForceRelease not implemented")
+}
+func (UnimplementedDlockAdminServer) testEmbeddedByValue() {}
+
+// UnsafeDlockAdminServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to DlockAdminServer will
+// result in compilation errors.
+type UnsafeDlockAdminServer interface {
+	mustEmbedUnimplementedDlockAdminServer()
+}
+
+func RegisterDlockAdminServer(s grpc.ServiceRegistrar, srv DlockAdminServer) {
+	// If the following call pancis, it indicates UnimplementedDlockAdminServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&DlockAdmin_ServiceDesc, srv)
+}
+
+func _DlockAdmin_ForceRelease_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ForceReleaseRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DlockAdminServer).ForceRelease(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DlockAdmin_ForceRelease_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DlockAdminServer).ForceRelease(ctx, req.(*ForceReleaseRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// DlockAdmin_ServiceDesc is the grpc.ServiceDesc for DlockAdmin service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var DlockAdmin_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "dlock.DlockAdmin",
+	HandlerType: (*DlockAdminServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ForceRelease",
+			Handler:    _DlockAdmin_ForceRelease_Handler,
+		},
 	},
+	Streams:  []grpc.StreamDesc{},
 	Metadata: "api/v1alpha1/dlock.proto",
 }
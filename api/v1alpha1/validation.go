@@ -1,10 +1,233 @@
 package v1alpha1
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+func validateMode(mode LockMode) error {
+	if _, ok := LockMode_name[int32(mode)]; !ok {
+		return fmt.Errorf("unknown lock mode %d", mode)
+	}
+	return nil
+}
 
 func (in *LockRequest) Validate() error {
+	if in.Key == "" && len(in.Keys) == 0 {
+		return errors.New("key is required")
+	}
+	if slices.Contains(in.Keys, "") {
+		return errors.New("keys must not be empty")
+	}
+	if err := in.Metadata.Validate(); err != nil {
+		return err
+	}
+	if err := errors.Join(
+		validatePositive("ttl", in.Ttl),
+		validatePositive("keepaliveInterval", in.KeepaliveInterval),
+		validatePositive("retryDelay", in.RetryDelay),
+	); err != nil {
+		return err
+	}
+	if in.Ttl != nil && in.KeepaliveInterval != nil && in.KeepaliveInterval.AsDuration() >= in.Ttl.AsDuration() {
+		return errors.New("keepaliveInterval must be less than the ttl")
+	}
+	if err := validateReentrant(in.Reentrant, in.ReentrantToken, in.Mode, in.Metadata); err != nil {
+		return err
+	}
+	if err := validateGraphNode(in); err != nil {
+		return err
+	}
+	return validateMode(in.Mode)
+}
+
+func validateGraphNode(in *LockRequest) error {
+	if in.Graph == "" {
+		return nil
+	}
+	if in.Key == "" || len(in.Keys) > 0 {
+		return errors.New("graph nodes are locked one key at a time")
+	}
+	if in.Mode != LockMode_EX || in.Reentrant {
+		return errors.New("graph nodes are only locked exclusively, without reentrancy")
+	}
+	return nil
+}
+
+func validateReentrant(reentrant bool, token string, mode LockMode, md *LockMetadata) error {
+	if !reentrant {
+		if token != "" {
+			return errors.New("reentrantToken requires a reentrant lock")
+		}
+		return nil
+	}
+	if md.GetOwner() == "" {
+		return errors.New("reentrant locks require an owner")
+	}
+	if mode != LockMode_EX {
+		return errors.New("only exclusive locks are reentrant")
+	}
+	return nil
+}
+
+// validatePositive validates optional durations, that must be positive when they are set
+func validatePositive(name string, d *durationpb.Duration) error {
+	if d == nil {
+		return nil
+	}
+	if err := d.CheckValid(); err != nil {
+		return fmt.Errorf("invalid %s : %w", name, err)
+	}
+	if d.AsDuration() <= 0 {
+		return fmt.Errorf("%s must be positive", name)
+	}
+	return nil
+}
+
+func (in *AcquireRequest) Validate() error {
 	if in.Key == "" {
 		return errors.New("key is required")
 	}
+	if in.Ttl != nil {
+		if err := in.Ttl.CheckValid(); err != nil {
+			return err
+		}
+		if in.Ttl.AsDuration() < 0 {
+			return errors.New("ttl must be positive")
+		}
+	}
+	if err := in.Metadata.Validate(); err != nil {
+		return err
+	}
+	if err := errors.Join(
+		validatePositive("keepaliveInterval", in.KeepaliveInterval),
+		validatePositive("retryDelay", in.RetryDelay),
+	); err != nil {
+		return err
+	}
+	if in.GetTtl().AsDuration() > 0 && in.KeepaliveInterval != nil && in.KeepaliveInterval.AsDuration() >= in.Ttl.AsDuration() {
+		return errors.New("keepaliveInterval must be less than the ttl")
+	}
+	if err := validateReentrant(in.Reentrant, in.ReentrantToken, in.Mode, in.Metadata); err != nil {
+		return err
+	}
+	return validateMode(in.Mode)
+}
+
+func (in *ExtendRequest) Validate() error {
+	if in.LeaseId == "" {
+		return errors.New("leaseId is required")
+	}
+	if in.Ttl != nil {
+		if err := in.Ttl.CheckValid(); err != nil {
+			return err
+		}
+		if in.Ttl.AsDuration() < 0 {
+			return errors.New("ttl must be positive")
+		}
+	}
+	return nil
+}
+
+func (in *ReleaseRequest) Validate() error {
+	if in.LeaseId == "" {
+		return errors.New("leaseId is required")
+	}
+	return nil
+}
+
+func (in *TransferRequest) Validate() error {
+	if in.LeaseId == "" {
+		return errors.New("leaseId is required")
+	}
+	if in.ToOwner == "" {
+		return errors.New("toOwner is required")
+	}
+	return nil
+}
+
+func (in *SemaphoreRequest) Validate() error {
+	if in.Key == "" {
+		return errors.New("key is required")
+	}
+	if in.Capacity < 1 {
+		return errors.New("capacity must be positive")
+	}
+	if in.Weight < 1 || in.Weight > in.Capacity {
+		return errors.New("weight must be between 1 and the capacity")
+	}
+	if err := errors.Join(
+		validatePositive("ttl", in.Ttl),
+		validatePositive("keepaliveInterval", in.KeepaliveInterval),
+		validatePositive("retryDelay", in.RetryDelay),
+	); err != nil {
+		return err
+	}
+	if in.Ttl != nil && in.KeepaliveInterval != nil && in.KeepaliveInterval.AsDuration() >= in.Ttl.AsDuration() {
+		return errors.New("keepaliveInterval must be less than the ttl")
+	}
+	return nil
+}
+
+func (in *LockMetadata) Validate() error {
+	if in == nil {
+		return nil
+	}
+	if in.Pid < 0 {
+		return errors.New("pid must be positive")
+	}
+	return nil
+}
+
+func (in *DescribeLockRequest) Validate() error {
+	if in.Key == "" {
+		return errors.New("key is required")
+	}
+	return nil
+}
+
+func (in *ForceReleaseRequest) Validate() error {
+	if in.Key == "" {
+		return errors.New("key is required")
+	}
+	return nil
+}
+
+func (in *CampaignRequest) Validate() error {
+	if in.Name == "" {
+		return errors.New("name is required")
+	}
+	return in.Metadata.Validate()
+}
+
+func (in *LeaderRequest) Validate() error {
+	if in.Name == "" {
+		return errors.New("name is required")
+	}
+	return nil
+}
+
+func (in *SubmitGraphRequest) Validate() error {
+	if in.Name == "" {
+		return errors.New("name is required")
+	}
+	if len(in.Nodes) == 0 {
+		return errors.New("nodes are required")
+	}
+	for _, node := range in.Nodes {
+		if node.Key == "" {
+			return errors.New("node keys are required")
+		}
+	}
+	return nil
+}
+
+func (in *GraphRequest) Validate() error {
+	if in.Name == "" {
+		return errors.New("name is required")
+	}
 	return nil
 }
@@ -0,0 +1,28 @@
+package v1alpha1_test
+
+import (
+	"github.com/alexandreLamarre/dlock/api/v1alpha1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Request validation", Label("unit"), func() {
+	DescribeTable("should validate semaphore requests",
+		func(req *v1alpha1.SemaphoreRequest, expected string) {
+			err := req.Validate()
+			if expected == "" {
+				Expect(err).NotTo(HaveOccurred())
+			} else {
+				Expect(err).To(MatchError(ContainSubstring(expected)))
+			}
+		},
+		Entry("a single unit", &v1alpha1.SemaphoreRequest{Key: "sem", Weight: 1, Capacity: 3}, ""),
+		Entry("every unit", &v1alpha1.SemaphoreRequest{Key: "sem", Weight: 3, Capacity: 3}, ""),
+		Entry("no key", &v1alpha1.SemaphoreRequest{Weight: 1, Capacity: 3}, "key is required"),
+		Entry("no capacity", &v1alpha1.SemaphoreRequest{Key: "sem", Weight: 1}, "capacity must be positive"),
+		Entry("no weight", &v1alpha1.SemaphoreRequest{Key: "sem", Capacity: 3}, "weight must be between"),
+		Entry("a negative weight", &v1alpha1.SemaphoreRequest{Key: "sem", Weight: -1, Capacity: 3}, "weight must be between"),
+		Entry("a weight above the capacity", &v1alpha1.SemaphoreRequest{Key: "sem", Weight: 4, Capacity: 3},
+			"weight must be between"),
+	)
+})
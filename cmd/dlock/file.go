@@ -0,0 +1,7 @@
+//go:build file
+
+package main
+
+import (
+	_ "github.com/alexandreLamarre/dlock/internal/lock/backend/file"
+)
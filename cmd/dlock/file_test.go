@@ -0,0 +1,48 @@
+//go:build file
+
+package main_test
+
+import (
+	"context"
+	"path/filepath"
+
+	"github.com/alexandreLamarre/dlock/pkg/config/v1alpha1"
+	"github.com/alexandreLamarre/dlock/pkg/constants"
+	"github.com/alexandreLamarre/dlock/pkg/lock/broker"
+	"github.com/alexandreLamarre/dlock/pkg/logger"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+var _ = Describe("File broker", Label("unit"), func() {
+	newBroker := func(spec *v1alpha1.FileClientSpec) broker.LockBroker {
+		return broker.NewLockBroker(logger.NewNop(), &v1alpha1.LockServerConfig{
+			FileClientSpec: spec,
+		}, noop.NewTracerProvider().Tracer(""))
+	}
+
+	It("should register the file broker", func() {
+		fBroker, ok := broker.GetLockBroker(constants.FileLockManager)
+		Expect(ok).To(BeTrue())
+		Expect(fBroker).NotTo(BeNil())
+	})
+
+	It("should build the file backend from its config", func() {
+		dir := filepath.Join(GinkgoT().TempDir(), "locks")
+		lm, err := newBroker(&v1alpha1.FileClientSpec{Dir: dir}).LockManager(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(dir).To(BeADirectory())
+
+		locker := lm.NewLock("broker")
+		acquired, _, err := locker.TryLock(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(acquired).To(BeTrue())
+		Expect(locker.Unlock()).To(Succeed())
+	})
+
+	It("should reject a config without a directory", func() {
+		_, err := newBroker(&v1alpha1.FileClientSpec{}).LockManager(context.Background())
+		Expect(err).To(MatchError(ContainSubstring("lock directory is required")))
+	})
+})
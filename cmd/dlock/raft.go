@@ -0,0 +1,7 @@
+//go:build raft
+
+package main
+
+import (
+	_ "github.com/alexandreLamarre/dlock/internal/lock/backend/raft"
+)
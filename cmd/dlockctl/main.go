@@ -7,21 +7,28 @@ import (
 	"io"
 	"log/slog"
 	"net/url"
+	"os"
 	"os/exec"
+	"slices"
 	"strings"
 	"sync"
+	"text/tabwriter"
 	"time"
 
 	"github.com/alexandreLamarre/dlock/api/v1alpha1"
 	"github.com/alexandreLamarre/dlock/pkg/constants"
 	"github.com/alexandreLamarre/dlock/pkg/logger"
+	"github.com/alexandreLamarre/dlock/pkg/util"
 	"github.com/alexandreLamarre/dlock/pkg/version"
+	backoffv2 "github.com/lestrrat-go/backoff/v2"
 	"github.com/spf13/cobra"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
 	healthv1 "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
 )
 
 func main() {
@@ -30,10 +37,24 @@ func main() {
 	}
 }
 
+// FencingTokenEnv is the environment variable exposing the fencing token of the lock to the command it guards
+const FencingTokenEnv = "DLOCK_FENCING_TOKEN"
+
+// ReentrantTokenEnv is the environment variable exposing the token of a reentrant lock to the command it guards,
+// so that the reentrant locks of the command reenter it
+const ReentrantTokenEnv = "DLOCK_REENTRANT_TOKEN"
+
 var (
 	serverAddr string
 	client     v1alpha1.DlockClient
 	lg         *slog.Logger
+
+	// TLS of the connection to the server, which is plaintext when none are set
+	caCert     string
+	clientCert string
+	clientKey  string
+	// bearer token authenticating requests, a static token or a JWT
+	token string
 )
 
 func BuildRootCmd() *cobra.Command {
@@ -41,7 +62,8 @@ func BuildRootCmd() *cobra.Command {
 		Version: version.FriendlyVersion(),
 		PersistentPreRun: func(cmd *cobra.Command, args []string) {
 			var err error
-			lg = logger.New()
+			// logs go to stderr so that command output, e.g. lease IDs, can be captured by scripts
+			lg = logger.New(logger.WithWriter(os.Stderr))
 			client, err = getDlockClient(serverAddr)
 			if err != nil {
 				panic(err)
@@ -49,25 +71,126 @@ func BuildRootCmd() *cobra.Command {
 		},
 	}
 	cmd.PersistentFlags().StringVarP(&serverAddr, "addr", "a", constants.DefaultDlockGrpcAddr, "dlock server address")
+	cmd.PersistentFlags().StringVar(&caCert, "cacert", "", "path to the CA certificate verifying the server, connects over TLS")
+	cmd.PersistentFlags().StringVar(&clientCert, "cert", "", "path to the client certificate for mutual TLS, connects over TLS")
+	cmd.PersistentFlags().StringVar(&clientKey, "key", "", "path to the private key of the client certificate")
+	cmd.PersistentFlags().StringVar(&token, "token", os.Getenv("DLOCK_TOKEN"), "bearer token authenticating requests, defaults to $DLOCK_TOKEN")
 	cmd.AddCommand(BuildLockCmd())
+	cmd.AddCommand(BuildSemaphoreCmd())
+	cmd.AddCommand(BuildAcquireCmd())
+	cmd.AddCommand(BuildExtendCmd())
+	cmd.AddCommand(BuildReleaseCmd())
+	cmd.AddCommand(BuildTransferCmd())
+	cmd.AddCommand(BuildListCmd())
+	cmd.AddCommand(BuildDescribeCmd())
+	cmd.AddCommand(BuildWatchCmd())
+	cmd.AddCommand(BuildElectCmd())
+	cmd.AddCommand(BuildLeaderCmd())
+	cmd.AddCommand(BuildGraphCmd())
 	cmd.AddCommand(BuildDlockHealthCmd())
 	return cmd
 }
 
+// parseLockMode parses a lock mode by its name, e.g. EX or PR
+func parseLockMode(mode string) (v1alpha1.LockMode, error) {
+	m, ok := v1alpha1.LockMode_value[strings.ToUpper(mode)]
+	if !ok {
+		return 0, fmt.Errorf("unknown lock mode '%s'", mode)
+	}
+	return v1alpha1.LockMode(m), nil
+}
+
+// metadataFlags identifies the holder of the locks acquired by dlockctl, with the hostname & pid of dlockctl itself
+type metadataFlags struct {
+	owner  string
+	labels map[string]string
+}
+
+func (m *metadataFlags) register(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&m.owner, "dlock.owner", "o", "", "identity of the holder reported by 'list' & 'describe'")
+	cmd.Flags().StringToStringVar(&m.labels, "dlock.label", map[string]string{}, "labels reported by 'list' & 'describe', e.g. job=backup")
+}
+
+func (m *metadataFlags) metadata() *v1alpha1.LockMetadata {
+	hostname, _ := os.Hostname()
+	return &v1alpha1.LockMetadata{
+		Owner:    m.owner,
+		Hostname: hostname,
+		Pid:      int64(os.Getpid()),
+		Labels:   m.labels,
+	}
+}
+
+// namespaceFlag selects the namespace of the keys, the server's default namespace is used when it is unset
+func namespaceFlag(cmd *cobra.Command, namespace *string) {
+	cmd.Flags().StringVar(namespace, "dlock.namespace", "", "namespace of the keys, defaults to the server's default namespace")
+}
+
+// timingFlags sets the timings of the locks acquired by dlockctl, the backend's defaults are used when they are unset
+type timingFlags struct {
+	ttl               time.Duration
+	keepaliveInterval time.Duration
+	retryDelay        time.Duration
+}
+
+func (t *timingFlags) register(cmd *cobra.Command) {
+	cmd.Flags().DurationVarP(&t.ttl, "dlock.ttl", "t", 0, "TTL after which the lock expires once dlockctl stops keeping it alive")
+	cmd.Flags().DurationVar(&t.keepaliveInterval, "dlock.keepalive", 0, "interval at which the lock is kept alive")
+	cmd.Flags().DurationVar(&t.retryDelay, "dlock.retry", 0, "delay between the attempts of blocking acquisitions")
+}
+
+// apply sets the timings of a request that are set by the flags
+func (t *timingFlags) apply(ttl, keepaliveInterval, retryDelay **durationpb.Duration) {
+	if t.ttl > 0 {
+		*ttl = durationpb.New(t.ttl)
+	}
+	if t.keepaliveInterval > 0 {
+		*keepaliveInterval = durationpb.New(t.keepaliveInterval)
+	}
+	if t.retryDelay > 0 {
+		*retryDelay = durationpb.New(t.retryDelay)
+	}
+}
+
 func BuildLockCmd() *cobra.Command {
-	var key string
+	var keys []string
 	var block bool
+	var mode string
+	var md metadataFlags
+	var timings timingFlags
+	var fair bool
+	var reentrant bool
+	var namespace string
+	var graph string
 	cmd := &cobra.Command{
 		Use:   "lock",
 		Short: "acquired a distributed lock at the given key and run the command",
 		Args:  cobra.ArbitraryArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			lg := lg.With("key", key, "block", block)
+			lg := lg.With("key", strings.Join(keys, ","), "block", block, "mode", mode)
+			lockMode, err := parseLockMode(mode)
+			if err != nil {
+				return err
+			}
 
 			lockRequest := &v1alpha1.LockRequest{
-				Key:     key,
-				TryLock: !block,
+				TryLock:   !block,
+				Mode:      lockMode,
+				Metadata:  md.metadata(),
+				Fair:      fair,
+				Reentrant: reentrant,
+				Namespace: namespace,
+				Graph:     graph,
 			}
+			if reentrant {
+				lockRequest.ReentrantToken = os.Getenv(ReentrantTokenEnv)
+			}
+			if len(keys) == 1 {
+				lockRequest.Key = keys[0]
+			} else {
+				lockRequest.Keys = keys
+			}
+			timings.apply(&lockRequest.Ttl, &lockRequest.KeepaliveInterval, &lockRequest.RetryDelay)
 			if err := lockRequest.Validate(); err != nil {
 				return fmt.Errorf("invalid lock request: %w", err)
 			}
@@ -79,88 +202,635 @@ func BuildLockCmd() *cobra.Command {
 				return err
 			}
 			lg.Info("acquired lock client")
+			return runLocked(cmd, args, lg, client)
 
-			ctxca, ca := context.WithCancel(cmd.Context())
-			defer ca()
+		},
+	}
+	cmd.Flags().StringArrayVarP(&keys, "dlock.key", "k", nil, "key to lock, repeated keys are locked all-or-nothing")
+	cmd.Flags().BoolVarP(&block, "dlock.block", "b", false, "whether or not to block on lock acquisition")
+	cmd.Flags().StringVarP(&mode, "dlock.mode", "m", v1alpha1.LockMode_EX.String(), "lock mode : EX (exclusive) or PR (shared read)")
+	cmd.Flags().BoolVar(&fair, "dlock.fair", false, "whether or not blocking acquisitions are granted in the order they started waiting")
+	cmd.Flags().BoolVar(&reentrant, "dlock.reentrant", false, "whether or not the lock can be acquired again by its owner (--dlock.owner) while it holds it, "+
+		"by the reentrant locks of the command reentering it through "+ReentrantTokenEnv)
+	cmd.Flags().StringVar(&graph, "dlock.graph", "", "graph whose node on the key is locked once its predecessors are done or held, see 'graph submit'")
+	md.register(cmd)
+	timings.register(cmd)
+	namespaceFlag(cmd, &namespace)
+	return cmd
+}
+
+// runLocked runs the command once the lock held by the stream is acquired, until either the command exits
+// or the lock expires
+func runLocked(cmd *cobra.Command, args []string, lg *slog.Logger, client v1alpha1.Dlock_LockClient) error {
+	ctxca, ca := context.WithCancel(cmd.Context())
+	defer ca()
 
-			acquired := make(chan struct{})
-			var wg sync.WaitGroup
-			wg.Add(1)
-			go func() {
-				defer func() {
-					sendErr := client.CloseSend()
-					if sendErr != nil {
-						lg.Error("failed to close send stream")
-					}
-					wg.Done()
-				}()
-				var execCmd *exec.Cmd
-				if len(args) > 0 {
-					execCmd = exec.CommandContext(cmd.Context(), args[0], args[1:]...)
-					execCmd.Stdout = cmd.OutOrStdout()
-					execCmd.Stderr = cmd.ErrOrStderr()
-					select {
-					case <-ctxca.Done():
-						lg.Info("cancelling command")
-					case <-acquired:
-						close(acquired)
-						lg.Info(fmt.Sprintf("running command : '%s'", strings.Join(args, " ")))
-						if err := execCmd.Run(); err != nil {
-							lg.With(logger.Err(err)).Error("command failed")
-						}
-						lg.Info(fmt.Sprintf("command '%s' finished", strings.Join(args, " ")))
-					}
-				} else {
-					<-cmd.Context().Done()
-					lg.Info("no command provided, blocking until lock expires or is cancelled by user")
+	acquired := make(chan *v1alpha1.LockResponse)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer func() {
+			sendErr := client.CloseSend()
+			if sendErr != nil {
+				lg.Error("failed to close send stream")
+			}
+			wg.Done()
+		}()
+		var execCmd *exec.Cmd
+		if len(args) > 0 {
+			execCmd = exec.CommandContext(cmd.Context(), args[0], args[1:]...)
+			execCmd.Stdout = cmd.OutOrStdout()
+			execCmd.Stderr = cmd.ErrOrStderr()
+			select {
+			case <-ctxca.Done():
+				lg.Info("cancelling command")
+			case resp := <-acquired:
+				close(acquired)
+				execCmd.Env = append(os.Environ(), fmt.Sprintf("%s=%d", FencingTokenEnv, resp.FencingToken))
+				if resp.ReentrantToken != "" {
+					execCmd.Env = append(execCmd.Env, fmt.Sprintf("%s=%s", ReentrantTokenEnv, resp.ReentrantToken))
 				}
-			}()
-
-			go func() {
-				defer ca()
-				for {
-					lg.Info("waiting to receive lock event")
-				RETRY:
-					resp, err := client.Recv()
-					lg.Info("received lock event")
-					if errors.Is(err, io.EOF) {
-						lg.Info("stream closed")
-						break
-					}
-					if err != nil {
-						errLg := lg.With(logger.Err(err))
-						st, ok := status.FromError(err)
-						if ok && st.Code() == codes.Canceled {
-							errLg.Error("lock expired from remote backend")
-							break
-						}
-						if ok && st.Code() == codes.Unavailable {
-							errLg.Error("lock server unavailable, stopping...")
-							break
-						}
-						if errors.Is(err, io.EOF) {
-							errLg.Error("stream closed")
-							break
-						}
-						errLg.Error("failed to receive lock event")
-						goto RETRY
-					}
-					if resp.Event == v1alpha1.LockEvent_Acquired {
-						lg.Info("lock acquired")
-						acquired <- struct{}{}
-					} else if resp.Event == v1alpha1.LockEvent_Failed {
-						lg.Error("lock acquisition failed")
-						break
-					}
+				lg.Info(fmt.Sprintf("running command : '%s'", strings.Join(args, " ")))
+				if err := execCmd.Run(); err != nil {
+					lg.With(logger.Err(err)).Error("command failed")
 				}
-			}()
-			wg.Wait()
-			return nil
+				lg.Info(fmt.Sprintf("command '%s' finished", strings.Join(args, " ")))
+			}
+		} else {
+			<-cmd.Context().Done()
+			lg.Info("no command provided, blocking until lock expires or is cancelled by user")
+		}
+	}()
+
+	go func() {
+		defer ca()
+		for {
+			lg.Info("waiting to receive lock event")
+		RETRY:
+			resp, err := client.Recv()
+			lg.Info("received lock event")
+			if errors.Is(err, io.EOF) {
+				lg.Info("stream closed")
+				break
+			}
+			if err != nil {
+				errLg := lg.With(logger.Err(err))
+				st, ok := status.FromError(err)
+				if ok && st.Code() == codes.Canceled {
+					errLg.Error("lock expired from remote backend")
+					break
+				}
+				if ok && st.Code() == codes.Unavailable {
+					errLg.Error("lock server unavailable, stopping...")
+					break
+				}
+				if errors.Is(err, io.EOF) {
+					errLg.Error("stream closed")
+					break
+				}
+				errLg.Error("failed to receive lock event")
+				goto RETRY
+			}
+			if resp.Event == v1alpha1.LockEvent_Acquired {
+				lg.Info("lock acquired", "fencingToken", resp.FencingToken)
+				acquired <- resp
+			} else if resp.Event == v1alpha1.LockEvent_Failed {
+				lg.Error("lock acquisition failed")
+				break
+			} else if resp.Event == v1alpha1.LockEvent_Queued {
+				lg.Info(fmt.Sprintf("lock is held, waiting behind %d others", resp.Position))
+			} else if resp.Event == v1alpha1.LockEvent_Position {
+				lg.Info(fmt.Sprintf("waiting behind %d others", resp.Position))
+			}
+		}
+	}()
+	wg.Wait()
+	return nil
+}
+
+func BuildSemaphoreCmd() *cobra.Command {
+	var key string
+	var block bool
+	var weight int64
+	var capacity int64
+	var timings timingFlags
+	var namespace string
+	cmd := &cobra.Command{
+		Use:   "semaphore",
+		Short: "acquires units of a distributed counting semaphore at the given key and run the command",
+		Args:  cobra.ArbitraryArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			lg := lg.With("key", key, "block", block, "weight", weight, "capacity", capacity)
 
+			semRequest := &v1alpha1.SemaphoreRequest{
+				Key:        key,
+				TryAcquire: !block,
+				Weight:     weight,
+				Capacity:   capacity,
+				Namespace:  namespace,
+			}
+			timings.apply(&semRequest.Ttl, &semRequest.KeepaliveInterval, &semRequest.RetryDelay)
+			if err := semRequest.Validate(); err != nil {
+				return fmt.Errorf("invalid semaphore request: %w", err)
+			}
+
+			lg.Info("acquiring semaphore...")
+			client, err := client.Semaphore(cmd.Context(), semRequest)
+			if err != nil {
+				lg.Error("failed to acquire semaphore client")
+				return err
+			}
+			lg.Info("acquired semaphore client")
+			return runLocked(cmd, args, lg, client)
+		},
+	}
+	cmd.Flags().StringVarP(&key, "dlock.key", "k", "", "key of the semaphore")
+	cmd.Flags().BoolVarP(&block, "dlock.block", "b", false, "whether or not to block on semaphore acquisition")
+	cmd.Flags().Int64VarP(&weight, "dlock.weight", "n", 1, "number of units to acquire")
+	cmd.Flags().Int64VarP(&capacity, "dlock.capacity", "c", 1, "total number of units of the semaphore")
+	timings.register(cmd)
+	namespaceFlag(cmd, &namespace)
+	return cmd
+}
+
+func BuildAcquireCmd() *cobra.Command {
+	var key string
+	var block bool
+	var ttl time.Duration
+	var mode string
+	var fair bool
+	var md metadataFlags
+	var namespace string
+	cmd := &cobra.Command{
+		Use:   "acquire",
+		Short: "acquires a lease on a distributed lock at the given key and prints its lease ID",
+		Long: "acquires a lease on a distributed lock at the given key and prints its lease ID. " +
+			"The lease is released by the server if it is not extended before its TTL elapses",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			lg := lg.With("key", key, "block", block, "mode", mode)
+			lockMode, err := parseLockMode(mode)
+			if err != nil {
+				return err
+			}
+			req := &v1alpha1.AcquireRequest{
+				Key:       key,
+				TryLock:   !block,
+				Mode:      lockMode,
+				Metadata:  md.metadata(),
+				Namespace: namespace,
+				Fair:      fair,
+			}
+			if ttl > 0 {
+				req.Ttl = durationpb.New(ttl)
+			}
+			if err := req.Validate(); err != nil {
+				return fmt.Errorf("invalid acquire request: %w", err)
+			}
+			resp, err := client.Acquire(cmd.Context(), req)
+			if err != nil {
+				lg.With(logger.Err(err)).Error("failed to acquire lease")
+				return err
+			}
+			if !resp.Acquired {
+				return errors.New("lock is held by someone else")
+			}
+			lg.With(
+				"lease", resp.LeaseId,
+				"ttl", resp.Ttl.AsDuration(),
+				"fencingToken", resp.FencingToken,
+			).Info("lease acquired")
+			fmt.Fprintln(cmd.OutOrStdout(), resp.LeaseId)
+			return nil
 		},
 	}
 	cmd.Flags().StringVarP(&key, "dlock.key", "k", "", "key to lock")
 	cmd.Flags().BoolVarP(&block, "dlock.block", "b", false, "whether or not to block on lock acquisition")
+	cmd.Flags().DurationVarP(&ttl, "dlock.ttl", "t", 0, "TTL of the lease & its lock, defaults to the namespace's default TTL or the server's default lease TTL")
+	cmd.Flags().StringVarP(&mode, "dlock.mode", "m", v1alpha1.LockMode_EX.String(), "lock mode : EX (exclusive) or PR (shared read)")
+	cmd.Flags().BoolVar(&fair, "dlock.fair", false, "whether or not blocking acquisitions are granted in the order they started waiting")
+	md.register(cmd)
+	namespaceFlag(cmd, &namespace)
+	return cmd
+}
+
+func BuildExtendCmd() *cobra.Command {
+	var leaseID string
+	var ttl time.Duration
+	cmd := &cobra.Command{
+		Use:   "extend",
+		Short: "extends the TTL of a lease acquired with 'acquire'",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			req := &v1alpha1.ExtendRequest{
+				LeaseId: leaseID,
+			}
+			if ttl > 0 {
+				req.Ttl = durationpb.New(ttl)
+			}
+			if err := req.Validate(); err != nil {
+				return fmt.Errorf("invalid extend request: %w", err)
+			}
+			resp, err := client.Extend(cmd.Context(), req)
+			if err != nil {
+				lg.With("lease", leaseID, logger.Err(err)).Error("failed to extend lease")
+				return err
+			}
+			lg.With("lease", leaseID, "ttl", resp.Ttl.AsDuration()).Info("lease extended")
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&leaseID, "dlock.lease", "l", "", "lease ID returned by 'acquire'")
+	cmd.Flags().DurationVarP(&ttl, "dlock.ttl", "t", 0, "new TTL of the lease, defaults to its current TTL")
+	return cmd
+}
+
+func BuildReleaseCmd() *cobra.Command {
+	var leaseID, key, reason, namespace string
+	var force bool
+	cmd := &cobra.Command{
+		Use:   "release",
+		Short: "releases a lease acquired with 'acquire', or force releases the lock on a key",
+		Long: "Releases a lease acquired with 'acquire'.\n" +
+			"With --force, evicts every holder of the lock on the key instead, for operators to recover from wedged holders",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if force {
+				return forceRelease(cmd, namespace, key, reason)
+			}
+			req := &v1alpha1.ReleaseRequest{
+				LeaseId: leaseID,
+			}
+			if err := req.Validate(); err != nil {
+				return fmt.Errorf("invalid release request: %w", err)
+			}
+			if _, err := client.Release(cmd.Context(), req); err != nil {
+				lg.With("lease", leaseID, logger.Err(err)).Error("failed to release lease")
+				return err
+			}
+			lg.With("lease", leaseID).Info("lease released")
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&leaseID, "dlock.lease", "l", "", "lease ID returned by 'acquire'")
+	cmd.Flags().BoolVarP(&force, "force", "f", false, "evict every holder of the lock on the key")
+	cmd.Flags().StringVarP(&key, "dlock.key", "k", "", "key of the lock to force release")
+	cmd.Flags().StringVar(&reason, "reason", "", "reason recorded in the audit log of the server")
+	namespaceFlag(cmd, &namespace)
+	cmd.MarkFlagsMutuallyExclusive("dlock.lease", "force")
+	return cmd
+}
+
+func BuildTransferCmd() *cobra.Command {
+	var leaseID, toOwner string
+	cmd := &cobra.Command{
+		Use:   "transfer",
+		Short: "hands the lock of a lease acquired with 'acquire' over to a blocking acquisition of the owner",
+		Long: "Hands the lock of a lease acquired with 'acquire' over to the earliest blocking acquisition waiting for it " +
+			"whose owner is --to, without releasing the lock in between. The lease is released once the lock is transferred",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			req := &v1alpha1.TransferRequest{
+				LeaseId: leaseID,
+				ToOwner: toOwner,
+			}
+			if err := req.Validate(); err != nil {
+				return fmt.Errorf("invalid transfer request: %w", err)
+			}
+			if _, err := client.Transfer(cmd.Context(), req); err != nil {
+				lg.With("lease", leaseID, "to", toOwner, logger.Err(err)).Error("failed to transfer lease")
+				return err
+			}
+			lg.With("lease", leaseID, "to", toOwner).Info("lock transferred")
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&leaseID, "dlock.lease", "l", "", "lease ID returned by 'acquire'")
+	cmd.Flags().StringVar(&toOwner, "to", "", "owner of the blocking acquisition to transfer the lock to")
+	return cmd
+}
+
+// forceRelease prints the evicted holders
+func forceRelease(cmd *cobra.Command, namespace, key, reason string) error {
+	req := &v1alpha1.ForceReleaseRequest{
+		Key:       key,
+		Reason:    reason,
+		Namespace: namespace,
+	}
+	if err := req.Validate(); err != nil {
+		return fmt.Errorf("invalid force release request: %w", err)
+	}
+	admin, err := getAdminClient(serverAddr)
+	if err != nil {
+		lg.With(logger.Err(err)).Error("failed to acquire admin client")
+		return err
+	}
+	resp, err := admin.ForceRelease(cmd.Context(), req)
+	if err != nil {
+		lg.With("key", key, logger.Err(err)).Error("failed to force release lock")
+		return err
+	}
+	lg.With("key", key, "evicted", len(resp.Evicted)).Warn("lock force released")
+	return printLocks(cmd.OutOrStdout(), &v1alpha1.LockInfo{Key: key, Holders: resp.Evicted})
+}
+
+func BuildListCmd() *cobra.Command {
+	var namespace string
+	cmd := &cobra.Command{
+		Use:   "list [prefix]",
+		Short: "lists the locks held or waited on, whose key starts with the prefix",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			req := &v1alpha1.ListLocksRequest{
+				Namespace: namespace,
+			}
+			if len(args) > 0 {
+				req.Prefix = args[0]
+			}
+			resp, err := client.ListLocks(cmd.Context(), req)
+			if err != nil {
+				lg.With("prefix", req.Prefix, logger.Err(err)).Error("failed to list locks")
+				return err
+			}
+			return printLocks(cmd.OutOrStdout(), resp.Locks...)
+		},
+	}
+	namespaceFlag(cmd, &namespace)
+	return cmd
+}
+
+func BuildDescribeCmd() *cobra.Command {
+	var namespace string
+	cmd := &cobra.Command{
+		Use:   "describe <key>",
+		Short: "describes the holders & waiters of the lock at the given key",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			req := &v1alpha1.DescribeLockRequest{
+				Key:       args[0],
+				Namespace: namespace,
+			}
+			if err := req.Validate(); err != nil {
+				return fmt.Errorf("invalid describe request: %w", err)
+			}
+			info, err := client.DescribeLock(cmd.Context(), req)
+			if err != nil {
+				lg.With("key", req.Key, logger.Err(err)).Error("failed to describe lock")
+				return err
+			}
+			return printLocks(cmd.OutOrStdout(), info)
+		},
+	}
+	namespaceFlag(cmd, &namespace)
+	return cmd
+}
+
+func BuildWatchCmd() *cobra.Command {
+	var namespace string
+	cmd := &cobra.Command{
+		Use:   "watch [prefix]",
+		Short: "prints the acquisitions, releases & expiries of the locks whose key starts with the prefix",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			req := &v1alpha1.WatchRequest{
+				Namespace: namespace,
+			}
+			if len(args) > 0 {
+				req.Prefix = args[0]
+			}
+			stream, err := client.Watch(cmd.Context(), req)
+			if err != nil {
+				lg.With("prefix", req.Prefix, logger.Err(err)).Error("failed to watch locks")
+				return err
+			}
+			for {
+				resp, err := stream.Recv()
+				if errors.Is(err, io.EOF) {
+					return nil
+				}
+				if err != nil {
+					return err
+				}
+				printWatchEvent(cmd.OutOrStdout(), resp)
+			}
+		},
+	}
+	namespaceFlag(cmd, &namespace)
+	return cmd
+}
+
+// printWatchEvent prints a line per event as soon as it is received, so columns are separated by spaces
+// rather than aligned
+func printWatchEvent(out io.Writer, ev *v1alpha1.WatchResponse) {
+	md := ev.GetHolder().GetMetadata()
+	fmt.Fprintf(
+		out,
+		"%s %s %s %s %s %s %d\n",
+		time.Now().Format(time.RFC3339),
+		strings.ToLower(strings.TrimPrefix(ev.Type.String(), "Lock")),
+		ev.Key,
+		ev.GetHolder().GetMode(),
+		orDash(md.GetOwner()),
+		orDash(md.GetHostname()),
+		md.GetPid(),
+	)
+}
+
+// printLocks prints a row per holder of each lock, or a single row for locks that are only waited on
+func printLocks(out io.Writer, locks ...*v1alpha1.LockInfo) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "KEY\tMODE\tOWNER\tHOSTNAME\tPID\tACQUIRED\tTTL\tWAITERS\tLABELS")
+	for _, info := range locks {
+		if len(info.Holders) == 0 {
+			fmt.Fprintf(w, "%s\t-\t-\t-\t-\t-\t-\t%d\t-\n", info.Key, info.Waiters)
+		}
+		for _, h := range info.Holders {
+			md := h.GetMetadata()
+			labels := make([]string, 0, len(md.GetLabels()))
+			for k, v := range md.GetLabels() {
+				labels = append(labels, k+"="+v)
+			}
+			slices.Sort(labels)
+			ttl := "-"
+			if h.Ttl != nil {
+				ttl = h.Ttl.AsDuration().Round(time.Second).String()
+			}
+			fmt.Fprintf(
+				w,
+				"%s\t%s\t%s\t%s\t%d\t%s\t%s\t%d\t%s\n",
+				info.Key,
+				h.Mode,
+				orDash(md.GetOwner()),
+				orDash(md.GetHostname()),
+				md.GetPid(),
+				h.AcquiredAt.AsTime().Local().Format(time.RFC3339),
+				ttl,
+				info.Waiters,
+				orDash(strings.Join(labels, ",")),
+			)
+		}
+	}
+	return w.Flush()
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func BuildElectCmd() *cobra.Command {
+	var name, value string
+	var md metadataFlags
+	cmd := &cobra.Command{
+		Use:   "elect",
+		Short: "campaigns to be the leader of the election, and runs the command only while it is the leader",
+		Long: "Campaigns to be the leader of the election, and runs the command once elected.\n" +
+			"The command is killed if the leadership is lost, and dlockctl campaigns again to run it once re-elected.\n" +
+			"dlockctl resigns and exits once the command exits on its own",
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if value == "" {
+				value, _ = os.Hostname()
+			}
+			req := &v1alpha1.CampaignRequest{
+				Name:     name,
+				Value:    value,
+				Metadata: md.metadata(),
+			}
+			if err := req.Validate(); err != nil {
+				return fmt.Errorf("invalid campaign request: %w", err)
+			}
+			lg := lg.With("election", name, "value", value)
+			retry := campaignRetryInterval()
+			for {
+				elected, done, err := campaign(cmd, args, lg, req)
+				if done || cmd.Context().Err() != nil {
+					return err
+				}
+				if elected {
+					// the delay grows again from its minimum once the candidate was elected
+					retry = campaignRetryInterval()
+				}
+				delay := retry.Next()
+				lg.With("delay", delay, logger.Err(err)).Warn("campaign failed or leadership lost, campaigning again...")
+				select {
+				case <-cmd.Context().Done():
+					return err
+				case <-time.After(delay):
+				}
+			}
+		},
+	}
+	cmd.Flags().StringVarP(&name, "dlock.key", "k", "", "name of the election")
+	cmd.Flags().StringVarP(&value, "dlock.value", "v", "", "value reported to observers of the election once elected, defaults to the hostname")
+	md.register(cmd)
+	return cmd
+}
+
+// campaignRetryInterval returns the delays between the campaigns of dlockctl elect, which grow exponentially
+// with jitter while campaigns fail so that candidates do not hammer the server
+func campaignRetryInterval() backoffv2.IntervalGenerator {
+	return backoffv2.NewExponentialInterval(
+		backoffv2.WithMinInterval(100*time.Millisecond),
+		backoffv2.WithMaxInterval(30*time.Second),
+		backoffv2.WithJitterFactor(0.2),
+	)
+}
+
+// campaign runs the command once elected, done is set when the command exited on its own
+func campaign(
+	cmd *cobra.Command,
+	args []string,
+	lg *slog.Logger,
+	req *v1alpha1.CampaignRequest,
+) (elected, done bool, err error) {
+	// cancelling the stream resigns
+	ctxca, ca := context.WithCancel(cmd.Context())
+	defer ca()
+	lg.Info("campaigning...")
+	stream, err := client.Campaign(ctxca, req)
+	if err != nil {
+		return false, false, err
+	}
+	resp, err := stream.Recv()
+	if err != nil {
+		return false, false, err
+	}
+	if resp.Event != v1alpha1.LockEvent_Acquired {
+		return false, false, fmt.Errorf("campaign failed")
+	}
+	lg.Info("elected leader")
+
+	lost := make(chan struct{})
+	go func() {
+		defer close(lost)
+		for {
+			if _, err := stream.Recv(); err != nil {
+				return
+			}
+		}
+	}()
+	ctxLeader, caLeader := context.WithCancel(ctxca)
+	defer caLeader()
+	go func() {
+		select {
+		case <-lost:
+			caLeader()
+		case <-ctxLeader.Done():
+		}
+	}()
+	execCmd := exec.CommandContext(ctxLeader, args[0], args[1:]...)
+	execCmd.Stdout = cmd.OutOrStdout()
+	execCmd.Stderr = cmd.ErrOrStderr()
+	lg.Info(fmt.Sprintf("running command : '%s'", strings.Join(args, " ")))
+	err = execCmd.Run()
+	select {
+	case <-lost:
+		return true, false, err
+	default:
+	}
+	lg.Info(fmt.Sprintf("command '%s' finished, resigning", strings.Join(args, " ")))
+	return true, true, err
+}
+
+func BuildLeaderCmd() *cobra.Command {
+	var watch bool
+	cmd := &cobra.Command{
+		Use:   "leader <name>",
+		Short: "prints the value of the leader of the election",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			req := &v1alpha1.LeaderRequest{
+				Name: args[0],
+			}
+			if err := req.Validate(); err != nil {
+				return fmt.Errorf("invalid leader request: %w", err)
+			}
+			if !watch {
+				resp, err := client.Leader(cmd.Context(), req)
+				if err != nil {
+					lg.With("election", req.Name, logger.Err(err)).Error("failed to get leader")
+					return err
+				}
+				fmt.Fprintln(cmd.OutOrStdout(), resp.Value)
+				return nil
+			}
+			stream, err := client.Observe(cmd.Context(), req)
+			if err != nil {
+				lg.With("election", req.Name, logger.Err(err)).Error("failed to observe election")
+				return err
+			}
+			for {
+				resp, err := stream.Recv()
+				if errors.Is(err, io.EOF) {
+					return nil
+				}
+				if err != nil {
+					return err
+				}
+				fmt.Fprintln(cmd.OutOrStdout(), resp.Value)
+			}
+		},
+	}
+	cmd.Flags().BoolVarP(&watch, "watch", "w", false, "prints the value of each new leader")
 	return cmd
 }
 
@@ -198,6 +868,14 @@ func getHealthClient(addr string) (healthv1.HealthClient, error) {
 	return healthv1.NewHealthClient(cc), nil
 }
 
+func getAdminClient(addr string) (v1alpha1.DlockAdminClient, error) {
+	cc, err := setupConn(addr)
+	if err != nil {
+		return nil, err
+	}
+	return v1alpha1.NewDlockAdminClient(cc), nil
+}
+
 func getDlockClient(addr string) (v1alpha1.DlockClient, error) {
 	cc, err := setupConn(addr)
 	if err != nil {
@@ -211,9 +889,169 @@ func setupConn(remoteAddr string) (*grpc.ClientConn, error) {
 	if err != nil {
 		return nil, err
 	}
-	conn, err := grpc.NewClient(remoteUrl.Host, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	creds, err := transportCredentials()
+	if err != nil {
+		return nil, err
+	}
+	opts := []grpc.DialOption{grpc.WithTransportCredentials(creds)}
+	if token != "" {
+		opts = append(opts, grpc.WithPerRPCCredentials(bearerToken(token)))
+	}
+	conn, err := grpc.NewClient(remoteUrl.Host, opts...)
 	if err != nil {
 		return conn, err
 	}
 	return conn, nil
 }
+
+// transportCredentials connects over TLS once any certificate flag is set, the server being verified against the
+// system's CA certificates unless --cacert is set
+func transportCredentials() (credentials.TransportCredentials, error) {
+	if caCert == "" && clientCert == "" && clientKey == "" {
+		return insecure.NewCredentials(), nil
+	}
+	config, err := util.LoadClientTLSConfig(caCert, clientCert, clientKey)
+	if err != nil {
+		return nil, err
+	}
+	return credentials.NewTLS(config), nil
+}
+
+// bearerToken sets the authorization header of requests
+type bearerToken string
+
+var _ credentials.PerRPCCredentials = bearerToken("")
+
+func (t bearerToken) GetRequestMetadata(context.Context, ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + string(t)}, nil
+}
+
+// RequireTransportSecurity is false so that tokens can be sent to servers behind a TLS terminating proxy
+func (t bearerToken) RequireTransportSecurity() bool {
+	return false
+}
+
+func BuildGraphCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "graph",
+		Short: "manages lock dependency graphs, whose nodes are locked with 'lock --dlock.graph'",
+	}
+	cmd.AddCommand(BuildGraphSubmitCmd())
+	cmd.AddCommand(BuildGraphObserveCmd())
+	cmd.AddCommand(BuildGraphDeleteCmd())
+	return cmd
+}
+
+// parseGraphNode parses a node as its key, followed by '=' and the comma separated keys of its predecessors if it has any
+func parseGraphNode(node string) *v1alpha1.GraphNode {
+	key, preds, ok := strings.Cut(node, "=")
+	ret := &v1alpha1.GraphNode{
+		Key: key,
+	}
+	if ok && preds != "" {
+		ret.Predecessors = strings.Split(preds, ",")
+	}
+	return ret
+}
+
+func BuildGraphSubmitCmd() *cobra.Command {
+	var nodes []string
+	var namespace string
+	var run string
+	cmd := &cobra.Command{
+		Use:   "submit <name>",
+		Short: "submits a graph of lock keys, whose nodes can only be locked once their predecessors are done or held",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			req := &v1alpha1.SubmitGraphRequest{
+				Name:      args[0],
+				Namespace: namespace,
+				Run:       run,
+			}
+			for _, node := range nodes {
+				req.Nodes = append(req.Nodes, parseGraphNode(node))
+			}
+			if err := req.Validate(); err != nil {
+				return fmt.Errorf("invalid submit graph request: %w", err)
+			}
+			if _, err := client.SubmitGraph(cmd.Context(), req); err != nil {
+				lg.With("graph", req.Name, logger.Err(err)).Error("failed to submit graph")
+				return err
+			}
+			lg.With("graph", req.Name, "nodes", len(req.Nodes)).Info("graph submitted")
+			return nil
+		},
+	}
+	cmd.Flags().StringArrayVar(&nodes, "node", nil, "node of the graph, as its key followed by its predecessors if any, e.g. deploy=build,test")
+	cmd.Flags().StringVar(&run, "run", "", "run of the graph, graphs submitted to other servers with the same name & run share their progress")
+	namespaceFlag(cmd, &namespace)
+	return cmd
+}
+
+func BuildGraphObserveCmd() *cobra.Command {
+	var namespace string
+	cmd := &cobra.Command{
+		Use:   "observe <name>",
+		Short: "prints the state of every node of the graph, then each change, until every node is done",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			req := &v1alpha1.GraphRequest{
+				Name:      args[0],
+				Namespace: namespace,
+			}
+			if err := req.Validate(); err != nil {
+				return fmt.Errorf("invalid observe graph request: %w", err)
+			}
+			stream, err := client.ObserveGraph(cmd.Context(), req)
+			if err != nil {
+				lg.With("graph", req.Name, logger.Err(err)).Error("failed to observe graph")
+				return err
+			}
+			for {
+				node, err := stream.Recv()
+				if errors.Is(err, io.EOF) {
+					return nil
+				}
+				if err != nil {
+					return err
+				}
+				fmt.Fprintf(
+					cmd.OutOrStdout(),
+					"%s %s %s %s\n",
+					time.Now().Format(time.RFC3339),
+					node.Key,
+					strings.ToLower(strings.TrimPrefix(node.State.String(), "Node")),
+					orDash(strings.Join(node.Predecessors, ",")),
+				)
+			}
+		},
+	}
+	namespaceFlag(cmd, &namespace)
+	return cmd
+}
+
+func BuildGraphDeleteCmd() *cobra.Command {
+	var namespace string
+	cmd := &cobra.Command{
+		Use:   "delete <name>",
+		Short: "deletes the graph, pending acquisitions of its nodes fail",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			req := &v1alpha1.GraphRequest{
+				Name:      args[0],
+				Namespace: namespace,
+			}
+			if err := req.Validate(); err != nil {
+				return fmt.Errorf("invalid delete graph request: %w", err)
+			}
+			if _, err := client.DeleteGraph(cmd.Context(), req); err != nil {
+				lg.With("graph", req.Name, logger.Err(err)).Error("failed to delete graph")
+				return err
+			}
+			lg.With("graph", req.Name).Info("graph deleted")
+			return nil
+		},
+	}
+	namespaceFlag(cmd, &namespace)
+	return cmd
+}
@@ -0,0 +1,359 @@
+package etcd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/alexandreLamarre/dlock/pkg/lock"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/client/v3/concurrency"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// barrierSuffix & latchSuffix are appended to the lock manager's prefix, so that the keys of barriers & latches
+// never collide with the keys of locks
+const (
+	barrierSuffix = ".barrier"
+	latchSuffix   = ".latch"
+)
+
+// etcdBarrier is a double barrier like recipe.DoubleBarrier : participants enter the barrier with a key under
+// its waiters prefix, bound to a session of their own, and the participant completing the barrier creates its
+// ready key. Participants then leave the barrier in the background, the last one deleting the ready key, so that
+// the next participants never mistake the ready key of a previous round for their own.
+type etcdBarrier struct {
+	lg *slog.Logger
+
+	client  *clientv3.Client
+	prefix  string
+	n       int64
+	options *lock.LockOptions
+}
+
+var _ lock.Barrier = (*etcdBarrier)(nil)
+
+func (e *EtcdLockManager) NewBarrier(key string, n int64, opts ...lock.LockOption) lock.Barrier {
+	options := lock.DefaultLockOptions()
+	options.Apply(opts...)
+	return &etcdBarrier{
+		lg:      e.lg.With("barrier", key, "participants", n),
+		client:  e.client,
+		prefix:  path.Join(e.prefix+barrierSuffix, key),
+		n:       n,
+		options: options,
+	}
+}
+
+func (b *etcdBarrier) waitersPrefix() string {
+	return b.prefix + "/waiters/"
+}
+
+func (b *etcdBarrier) readyKey() string {
+	return b.prefix + "/ready"
+}
+
+// Wait registers the participant with a key bound to the lease of its session, the etcd client keeping it alive
+// while the participant waits, so the key of a participant that crashes is deleted once its lease expires
+func (b *etcdBarrier) Wait(ctx context.Context) error {
+	if err := lock.ValidateParticipants(b.n); err != nil {
+		return err
+	}
+	if b.options.Tracer != nil {
+		ctxSpan, span := b.options.Tracer.Start(ctx, "Wait/etcd-barrier", trace.WithAttributes())
+		defer span.End()
+		ctx = ctxSpan
+	}
+	session, err := concurrency.NewSession(b.client, sessionOptions(b.options)...)
+	if err != nil {
+		return err
+	}
+	key := b.waitersPrefix() + fmt.Sprintf("%x", session.Lease())
+	readyRev, err := b.enter(ctx, session, key)
+	if err != nil {
+		// revoking the session's lease deletes the participant's key
+		if closeErr := session.Close(); closeErr != nil {
+			b.lg.Warn("failed to close etcd session", "err", closeErr.Error())
+		}
+		return err
+	}
+	go func() {
+		if err := b.leave(session, key, readyRev); err != nil {
+			b.lg.Warn("failed to leave barrier", "err", err.Error())
+		}
+		if err := session.Close(); err != nil {
+			b.lg.Warn("failed to close etcd session", "err", err.Error())
+		}
+	}()
+	return nil
+}
+
+// enter registers the participant once the participants of the previous round left, and returns the create
+// revision of the ready key once enough participants entered
+func (b *etcdBarrier) enter(ctx context.Context, session *concurrency.Session, key string) (int64, error) {
+	resp, err := b.client.Get(ctx, b.readyKey())
+	if err != nil {
+		return 0, err
+	}
+	if len(resp.Kvs) > 0 {
+		if err := b.waitEvent(ctx, session, b.readyKey(), resp.Header.Revision+1, mvccpb.DELETE, false); err != nil {
+			return 0, err
+		}
+	}
+	put, err := b.client.Put(ctx, key, "", clientv3.WithLease(session.Lease()))
+	if err != nil {
+		return 0, err
+	}
+	count, err := b.client.Get(ctx, b.waitersPrefix(), clientv3.WithPrefix(), clientv3.WithCountOnly())
+	if err != nil {
+		return 0, err
+	}
+	if count.Count >= b.n {
+		txn, err := b.client.Txn(ctx).
+			If(clientv3.Compare(clientv3.CreateRevision(b.readyKey()), "=", 0)).
+			Then(clientv3.OpPut(b.readyKey(), "", clientv3.WithLease(session.Lease()))).
+			Else(clientv3.OpGet(b.readyKey())).
+			Commit()
+		if err != nil {
+			return 0, err
+		}
+		if txn.Succeeded {
+			return txn.Header.Revision, nil
+		}
+		if kvs := txn.Responses[0].GetResponseRange().Kvs; len(kvs) > 0 {
+			return kvs[0].CreateRevision, nil
+		}
+	}
+	var readyRev int64
+	err = b.watch(ctx, session, b.readyKey(), put.Header.Revision+1, false, func(ev *clientv3.Event) bool {
+		if ev.Type == mvccpb.PUT {
+			readyRev = ev.Kv.CreateRevision
+			return true
+		}
+		return false
+	})
+	return readyRev, err
+}
+
+// leave deletes the key of the participant and waits until every participant that entered before the barrier
+// was reached left, the last one deleting the ready key
+func (b *etcdBarrier) leave(session *concurrency.Session, key string, readyRev int64) error {
+	ctx := session.Ctx()
+	if _, err := b.client.Delete(ctx, key); err != nil {
+		return err
+	}
+	for {
+		resp, err := b.client.Get(ctx, b.waitersPrefix(),
+			clientv3.WithPrefix(), clientv3.WithCountOnly(), clientv3.WithMaxCreateRev(readyRev))
+		if err != nil {
+			return err
+		}
+		if resp.Count == 0 {
+			_, err := b.client.Txn(ctx).
+				If(clientv3.Compare(clientv3.CreateRevision(b.readyKey()), "=", readyRev)).
+				Then(clientv3.OpDelete(b.readyKey())).
+				Commit()
+			return err
+		}
+		if err := b.waitEvent(ctx, session, b.waitersPrefix(), resp.Header.Revision+1, mvccpb.DELETE, true); err != nil {
+			return err
+		}
+	}
+}
+
+func (b *etcdBarrier) waitEvent(
+	ctx context.Context,
+	session *concurrency.Session,
+	key string,
+	rev int64,
+	typ mvccpb.Event_EventType,
+	prefix bool,
+) error {
+	return b.watch(ctx, session, key, rev, prefix, func(ev *clientv3.Event) bool {
+		return ev.Type == typ
+	})
+}
+
+// watch watches the key from the revision until an event matches, or the session of the participant expires
+func (b *etcdBarrier) watch(
+	ctx context.Context,
+	session *concurrency.Session,
+	key string,
+	rev int64,
+	prefix bool,
+	match func(ev *clientv3.Event) bool,
+) error {
+	ctxca, ca := context.WithCancel(ctx)
+	defer ca()
+	opts := []clientv3.OpOption{clientv3.WithRev(rev)}
+	if prefix {
+		opts = append(opts, clientv3.WithPrefix())
+	}
+	wch := b.client.Watch(ctxca, key, opts...)
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-session.Done():
+			return lock.ErrBarrierExpired
+		case wr, ok := <-wch:
+			if !ok {
+				return ctx.Err()
+			}
+			if wr.Err() != nil {
+				return wr.Err()
+			}
+			for _, ev := range wr.Events {
+				if match(ev) {
+					return nil
+				}
+			}
+		}
+	}
+}
+
+// etcdLatch holds the count of the latch in a key bound to a lease of LatchRetention,
+// every count down binding it to a new lease & awaiting processes keeping the current lease alive
+type etcdLatch struct {
+	lg *slog.Logger
+
+	client  *clientv3.Client
+	key     string
+	n       int64
+	options *lock.LockOptions
+}
+
+var _ lock.CountDownLatch = (*etcdLatch)(nil)
+
+func (e *EtcdLockManager) NewCountDownLatch(key string, n int64, opts ...lock.LockOption) lock.CountDownLatch {
+	options := lock.DefaultLockOptions()
+	options.Apply(opts...)
+	return &etcdLatch{
+		lg:      e.lg.With("latch", key, "count", n),
+		client:  e.client,
+		key:     path.Join(e.prefix+latchSuffix, key),
+		n:       n,
+		options: options,
+	}
+}
+
+// count returns the count of the latch, latches without a count being at their initial count
+func (l *etcdLatch) count(kvs []*mvccpb.KeyValue) (int64, error) {
+	if len(kvs) == 0 {
+		return l.n, nil
+	}
+	count, err := strconv.ParseInt(string(kvs[0].Value), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid count for latch %s : %w", l.key, err)
+	}
+	return count, nil
+}
+
+func (l *etcdLatch) CountDown(ctx context.Context) error {
+	if err := lock.ValidateParticipants(l.n); err != nil {
+		return err
+	}
+	if l.options.Tracer != nil {
+		ctxSpan, span := l.options.Tracer.Start(ctx, "CountDown/etcd-latch", trace.WithAttributes())
+		defer span.End()
+		ctx = ctxSpan
+	}
+	for {
+		resp, err := l.client.Get(ctx, l.key)
+		if err != nil {
+			return err
+		}
+		count, err := l.count(resp.Kvs)
+		if err != nil || count == 0 {
+			return err
+		}
+		var rev int64
+		if len(resp.Kvs) > 0 {
+			rev = resp.Kvs[0].ModRevision
+		}
+		lease, err := l.client.Grant(ctx, max(int64(math.Ceil(lock.LatchRetention.Seconds())), 1))
+		if err != nil {
+			return err
+		}
+		txn, err := l.client.Txn(ctx).
+			If(clientv3.Compare(clientv3.ModRevision(l.key), "=", rev)).
+			Then(clientv3.OpPut(l.key, strconv.FormatInt(count-1, 10), clientv3.WithLease(lease.ID))).
+			Commit()
+		if err == nil && txn.Succeeded {
+			return nil
+		}
+		if _, revokeErr := l.client.Revoke(ctx, lease.ID); revokeErr != nil {
+			l.lg.Warn("failed to revoke unused latch lease", "err", revokeErr.Error())
+		}
+		if err != nil {
+			return err
+		}
+		// another process counted the latch down since we read its count
+	}
+}
+
+func (l *etcdLatch) Await(ctx context.Context) error {
+	if err := lock.ValidateParticipants(l.n); err != nil {
+		return err
+	}
+	if l.options.Tracer != nil {
+		ctxSpan, span := l.options.Tracer.Start(ctx, "Await/etcd-latch", trace.WithAttributes())
+		defer span.End()
+		ctx = ctxSpan
+	}
+	resp, err := l.client.Get(ctx, l.key)
+	if err != nil {
+		return err
+	}
+	count, err := l.count(resp.Kvs)
+	if err != nil || count == 0 {
+		return err
+	}
+	var lease clientv3.LeaseID
+	if len(resp.Kvs) > 0 {
+		lease = clientv3.LeaseID(resp.Kvs[0].Lease)
+	}
+	ctxca, ca := context.WithCancel(ctx)
+	defer ca()
+	wch := l.client.Watch(ctxca, l.key, clientv3.WithRev(resp.Header.Revision+1))
+	t := time.NewTicker(max(lock.LatchRetention/3, time.Second))
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+			if lease == clientv3.NoLease {
+				continue
+			}
+			if _, err := l.client.KeepAliveOnce(ctx, lease); err != nil && !errors.Is(err, rpctypes.ErrLeaseNotFound) {
+				l.lg.Warn("failed to keep latch alive", "err", err.Error())
+			}
+		case wr, ok := <-wch:
+			if !ok {
+				return ctx.Err()
+			}
+			if wr.Err() != nil {
+				return wr.Err()
+			}
+			for _, ev := range wr.Events {
+				if ev.Type == mvccpb.DELETE {
+					lease = clientv3.NoLease
+					continue
+				}
+				count, err := l.count([]*mvccpb.KeyValue{ev.Kv})
+				if err != nil || count == 0 {
+					return err
+				}
+				lease = clientv3.LeaseID(ev.Kv.Lease)
+			}
+		}
+	}
+}
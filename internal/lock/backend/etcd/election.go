@@ -0,0 +1,186 @@
+package etcd
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"path"
+	"sync"
+
+	"github.com/alexandreLamarre/dlock/pkg/election"
+	"github.com/alexandreLamarre/dlock/pkg/lock"
+	"github.com/samber/lo"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/client/v3/concurrency"
+)
+
+// electionSuffix is appended to the lock manager's prefix, so that the keys of elections
+// never collide with the keys of locks
+const electionSuffix = ".election"
+
+// etcdElection is a concurrency.Election, each campaign holding its own session like locks do
+type etcdElection struct {
+	lg *slog.Logger
+
+	client *clientv3.Client
+	// candidates are keys under the prefix, the leader being the candidate with the lowest create revision
+	prefix  string
+	options *lock.LockOptions
+
+	mu          sync.Mutex
+	campaigning bool
+	session     *concurrency.Session
+	election    *concurrency.Election
+	resigned    chan struct{}
+}
+
+var _ election.Election = (*etcdElection)(nil)
+
+func (e *EtcdLockManager) NewElection(name string, opts ...lock.LockOption) election.Election {
+	options := lock.DefaultLockOptions()
+	options.Apply(opts...)
+	return &etcdElection{
+		lg:      e.lg.With("election", name),
+		client:  e.client,
+		prefix:  path.Join(e.prefix+electionSuffix, name),
+		options: options,
+	}
+}
+
+func (e *etcdElection) Campaign(ctx context.Context, value string) (<-chan struct{}, error) {
+	e.mu.Lock()
+	if e.campaigning {
+		e.mu.Unlock()
+		return nil, election.ErrCampaigning
+	}
+	e.campaigning = true
+	e.mu.Unlock()
+
+	session, err := concurrency.NewSession(e.client, sessionOptions(e.options)...)
+	if err == nil {
+		el := concurrency.NewElection(session, e.prefix)
+		if err = el.Campaign(ctx, value); err == nil {
+			resigned := make(chan struct{})
+			e.mu.Lock()
+			defer e.mu.Unlock()
+			e.session, e.election, e.resigned = session, el, resigned
+			return lo.Async(func() struct{} {
+				select {
+				case <-session.Done():
+					e.lost(session)
+				case <-resigned:
+				}
+				return struct{}{}
+			}), nil
+		}
+		// revoking the session's lease deletes the candidate's key
+		if closeErr := session.Close(); closeErr != nil {
+			e.lg.Warn("failed to close etcd session", "err", closeErr.Error())
+		}
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.campaigning = false
+	return nil, err
+}
+
+// lost resets the election once the session of the leader expired, so that it can campaign again
+func (e *etcdElection) lost(session *concurrency.Session) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.session == session {
+		e.session, e.election, e.resigned = nil, nil, nil
+		e.campaigning = false
+	}
+}
+
+func (e *etcdElection) Resign(ctx context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.election == nil {
+		return election.ErrNotLeader
+	}
+	session, el := e.session, e.election
+	close(e.resigned)
+	e.session, e.election, e.resigned = nil, nil, nil
+	e.campaigning = false
+	return errors.Join(el.Resign(ctx), session.Close())
+}
+
+func (e *etcdElection) Leader(ctx context.Context) (string, error) {
+	resp, err := e.client.Get(ctx, e.prefix+"/", clientv3.WithFirstCreate()...)
+	if err != nil {
+		return "", err
+	}
+	if len(resp.Kvs) == 0 {
+		return "", election.ErrNoLeader
+	}
+	return string(resp.Kvs[0].Value), nil
+}
+
+// Observe watches the key of each leader until it is deleted, like concurrency.Election.Observe
+// which requires a session of its own
+func (e *etcdElection) Observe(ctx context.Context) <-chan string {
+	ch := make(chan string)
+	go func() {
+		defer close(ch)
+		for {
+			leader, err := e.nextLeader(ctx)
+			if err != nil {
+				return
+			}
+			select {
+			case ch <- string(leader.Value):
+			case <-ctx.Done():
+				return
+			}
+			if !e.waitDeleted(ctx, leader) {
+				return
+			}
+		}
+	}()
+	return ch
+}
+
+// nextLeader returns the key of the current leader, waiting for a candidate when there is none
+func (e *etcdElection) nextLeader(ctx context.Context) (*mvccpb.KeyValue, error) {
+	resp, err := e.client.Get(ctx, e.prefix+"/", clientv3.WithFirstCreate()...)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Kvs) > 0 {
+		return resp.Kvs[0], nil
+	}
+	ctxca, ca := context.WithCancel(ctx)
+	defer ca()
+	wch := e.client.Watch(ctxca, e.prefix+"/", clientv3.WithRev(resp.Header.Revision), clientv3.WithPrefix())
+	for wr := range wch {
+		if wr.Err() != nil {
+			return nil, wr.Err()
+		}
+		for _, ev := range wr.Events {
+			if ev.Type == mvccpb.PUT {
+				return ev.Kv, nil
+			}
+		}
+	}
+	return nil, ctx.Err()
+}
+
+func (e *etcdElection) waitDeleted(ctx context.Context, leader *mvccpb.KeyValue) bool {
+	ctxca, ca := context.WithCancel(ctx)
+	defer ca()
+	wch := e.client.Watch(ctxca, string(leader.Key), clientv3.WithRev(leader.ModRevision+1))
+	for wr := range wch {
+		if wr.Err() != nil {
+			return false
+		}
+		for _, ev := range wr.Events {
+			if ev.Type == mvccpb.DELETE {
+				return true
+			}
+		}
+	}
+	return false
+}
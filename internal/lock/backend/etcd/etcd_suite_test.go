@@ -30,6 +30,7 @@ var lmF = future.New[lock.LockManager]()
 var lmSet = future.New[lo.Tuple3[
 	lock.LockManager, lock.LockManager, lock.LockManager,
 ]]()
+var crashF = future.New[integration.Crash]()
 
 var _ = BeforeSuite(func() {
 	if Label("integration").MatchesLabelFilter(GinkgoLabelFilter()) {
@@ -87,6 +88,13 @@ var _ = BeforeSuite(func() {
 		lmSet.Set(lo.Tuple3[lock.LockManager, lock.LockManager, lock.LockManager]{
 			A: lmX, B: lmY, C: lmZ,
 		})
+		crashF.Set(func() (lock.LockManager, func()) {
+			crashed, err := etcd.NewEtcdClient(context.Background(), conf)
+			Expect(err).To(Succeed())
+			return etcd.NewEtcdLockManager(crashed, "test", nil, logger.NewNop()), func() {
+				_ = crashed.Close()
+			}
+		})
 
 		Expect(err).NotTo(HaveOccurred())
 		Expect(err).To(Succeed())
@@ -95,6 +103,7 @@ var _ = BeforeSuite(func() {
 })
 
 var _ = Describe("Etcd Lock Manager", Ordered, Label("integration", "slow"), integration.LockManagerTestSuite(lmF, lmSet))
+var _ = Describe("Etcd Crashes", Ordered, Label("integration", "slow"), integration.CrashTestSuite(lmSet, crashF))
 var _ = Describe("Etcd Broker", Label("unit"), func() {
 	When("we register the lock broker", func() {
 		It("should register the Etcd lock manager as a broker", func() {
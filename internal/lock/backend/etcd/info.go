@@ -0,0 +1,212 @@
+package etcd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alexandreLamarre/dlock/pkg/lock"
+	"github.com/alexandreLamarre/dlock/pkg/logger"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// infoSuffix is appended to the lock manager's prefix, the HolderInfo of each holder is stored under it
+// with the lease of the holder's session, so that it never outlives the holder
+const infoSuffix = ".info"
+
+func infoKey(prefix, key string, lease clientv3.LeaseID) string {
+	return fmt.Sprintf("%s%s/%s/%x", prefix, infoSuffix, key, int64(lease))
+}
+
+func (e *etcdMutex) infoKey() string {
+	if e.holderLease != clientv3.NoLease {
+		return infoKey(e.prefix, e.key, e.holderLease)
+	}
+	return infoKey(e.prefix, e.key, e.session.Lease())
+}
+
+// putInfo stores the HolderInfo of the acquired mutex, failing to do so does not release the mutex
+func (e *etcdMutex) putInfo(ctx context.Context) {
+	if e.capacity > 0 {
+		return
+	}
+	data, err := json.Marshal(e.Holder(e.shared))
+	if err == nil {
+		_, err = e.session.Client().Put(ctx, e.infoKey(), string(data), clientv3.WithLease(e.session.Lease()))
+	}
+	if err != nil {
+		e.lg.With(logger.Err(err)).Warn("failed to store holder info")
+	}
+}
+
+// holderKey is the key of a holder of the lock, held with the lease of its session.
+// Keys are named after the lease of the session that created them, which no longer holds them once transferred.
+type holderKey struct {
+	lease  clientv3.LeaseID
+	name   clientv3.LeaseID
+	shared bool
+}
+
+// holders returns the holders of the lock and its number of waiters, see queue
+func holders(ctx context.Context, client *clientv3.Client, prefix, key string) ([]holderKey, int, error) {
+	ret, waiters, err := queue(ctx, client, prefix, key)
+	return ret, len(waiters), err
+}
+
+// position returns the number of waiters of the lock ahead of the key of the session's lease
+func position(ctx context.Context, client *clientv3.Client, prefix, key string, lease clientv3.LeaseID, shared bool) (int, error) {
+	_, waiters, err := queue(ctx, client, prefix, key)
+	if err != nil {
+		return 0, err
+	}
+	name := fmt.Sprintf("%x", int64(lease))
+	if shared {
+		name = readerMarker + name
+	}
+	i := slices.Index(waiters, name)
+	if i < 0 {
+		return 0, lock.ErrNotQueued
+	}
+	return i, nil
+}
+
+// queue orders the keys of the lock by create revision, like its mutexes : the holders are either the
+// first writer or the readers ahead of any writer, the keys behind them are waiters
+func queue(ctx context.Context, client *clientv3.Client, prefix, key string) ([]holderKey, []string, error) {
+	pfx := prefix + "/" + key + "/"
+	resp, err := client.Get(
+		ctx,
+		pfx,
+		clientv3.WithPrefix(),
+		clientv3.WithSort(clientv3.SortByCreateRevision, clientv3.SortAscend),
+		clientv3.WithKeysOnly(),
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+	ret := []holderKey{}
+	waiters := []string{}
+	holding := true
+	for _, kv := range resp.Kvs {
+		name := strings.TrimPrefix(string(kv.Key), pfx)
+		shared := strings.HasPrefix(name, readerMarker)
+		if !holding {
+			waiters = append(waiters, name)
+			continue
+		}
+		if !shared {
+			holding = false
+			if len(ret) > 0 {
+				waiters = append(waiters, name)
+				continue
+			}
+		}
+		lease, err := strconv.ParseInt(strings.TrimPrefix(name, readerMarker), 16, 64)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid lock key %s : %w", kv.Key, err)
+		}
+		ret = append(ret, holderKey{lease: clientv3.LeaseID(kv.Lease), name: clientv3.LeaseID(lease), shared: shared})
+	}
+	return ret, waiters, nil
+}
+
+func describe(ctx context.Context, client *clientv3.Client, prefix, key string) (lock.LockInfo, error) {
+	keys, waiters, err := holders(ctx, client, prefix, key)
+	if err != nil {
+		return lock.LockInfo{}, err
+	}
+	return describeKeys(ctx, client, prefix, key, keys, waiters)
+}
+
+func describeKeys(
+	ctx context.Context,
+	client *clientv3.Client,
+	prefix, key string,
+	keys []holderKey,
+	waiters int,
+) (lock.LockInfo, error) {
+	info := lock.LockInfo{
+		Key:     key,
+		Holders: []lock.HolderInfo{},
+		Waiters: waiters,
+	}
+	for _, k := range keys {
+		holder, err := describeHolder(ctx, client, prefix, key, k)
+		if err != nil {
+			return info, err
+		}
+		holder.Shared = k.shared
+		info.Holders = append(info.Holders, holder)
+	}
+	return info, nil
+}
+
+// forceRelease revokes the leases of the lock's holders, which closes their sessions
+func forceRelease(ctx context.Context, client *clientv3.Client, prefix, key string) (lock.LockInfo, error) {
+	keys, waiters, err := holders(ctx, client, prefix, key)
+	if err != nil {
+		return lock.LockInfo{}, err
+	}
+	info, err := describeKeys(ctx, client, prefix, key, keys, waiters)
+	if err != nil {
+		return info, err
+	}
+	for _, k := range keys {
+		if _, err := client.Revoke(ctx, k.lease); err != nil && !errors.Is(err, rpctypes.ErrLeaseNotFound) {
+			return info, err
+		}
+	}
+	return info, nil
+}
+
+func describeHolder(ctx context.Context, client *clientv3.Client, prefix, key string, k holderKey) (lock.HolderInfo, error) {
+	holder := lock.HolderInfo{}
+	resp, err := client.Get(ctx, infoKey(prefix, key, k.name))
+	if err != nil {
+		return holder, err
+	}
+	// the holder may not have stored its info yet
+	if len(resp.Kvs) > 0 {
+		if err := json.Unmarshal(resp.Kvs[0].Value, &holder); err != nil {
+			return holder, fmt.Errorf("invalid holder info for %s : %w", key, err)
+		}
+	}
+	ttl, err := client.TimeToLive(ctx, k.lease)
+	if err != nil {
+		return holder, err
+	}
+	holder.TTL = max(0, time.Duration(ttl.TTL)*time.Second)
+	return holder, nil
+}
+
+// keys returns the keys of the locks under the prefix that are held or waited on
+func keys(ctx context.Context, client *clientv3.Client, prefix, keyPrefix string) ([]string, error) {
+	resp, err := client.Get(
+		ctx,
+		prefix+"/"+keyPrefix,
+		clientv3.WithPrefix(),
+		clientv3.WithSort(clientv3.SortByKey, clientv3.SortAscend),
+		clientv3.WithKeysOnly(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	ret := []string{}
+	for _, kv := range resp.Kvs {
+		name := strings.TrimPrefix(string(kv.Key), prefix+"/")
+		idx := strings.LastIndex(name, "/")
+		if idx < 0 {
+			continue
+		}
+		if key := name[:idx]; len(ret) == 0 || ret[len(ret)-1] != key {
+			ret = append(ret, key)
+		}
+	}
+	return ret, nil
+}
@@ -5,6 +5,8 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"math"
+	"sync/atomic"
 
 	"github.com/alexandreLamarre/dlock/pkg/lock"
 	clientv3 "go.etcd.io/etcd/client/v3"
@@ -24,6 +26,7 @@ type EtcdLock struct {
 
 	client *clientv3.Client
 	mutex  *etcdMutex
+	token  atomic.Uint64
 }
 
 func NewEtcdLock(
@@ -42,18 +45,31 @@ func NewEtcdLock(
 	}
 }
 
+// sessionOptions sets the TTL of the lease of the session holding an acquisition, in seconds.
+// The etcd client keeps leases alive every third of their TTL, and blocking acquisitions watch the keys
+// they wait on, so KeepaliveInterval & RetryDelay do not apply to etcd.
+func sessionOptions(opts *lock.LockOptions) []concurrency.SessionOption {
+	if opts.TTL <= 0 {
+		return nil
+	}
+	return []concurrency.SessionOption{
+		concurrency.WithTTL(max(int(math.Ceil(opts.TTL.Seconds())), 1)),
+	}
+}
+
 func (e *EtcdLock) newSession(_ context.Context) (*concurrency.Session, error) {
 	e.lg.Debug("attempting to create new etcd session...")
-	session, err := concurrency.NewSession(e.client)
+	session, err := concurrency.NewSession(e.client, sessionOptions(e.options)...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create etcd session: %w", err)
 	}
 	return session, nil
 }
 
-var _ lock.Lock = (*EtcdLock)(nil)
+var _ lock.RWLock = (*EtcdLock)(nil)
+var _ lock.Transferer = (*EtcdLock)(nil)
 
-func (e *EtcdLock) acquire(ctx context.Context) (<-chan struct{}, error) {
+func (e *EtcdLock) acquire(ctx context.Context, shared bool) (<-chan struct{}, error) {
 	session, err := e.newSession(ctx)
 	if err != nil {
 		return nil, err
@@ -62,20 +78,25 @@ func (e *EtcdLock) acquire(ctx context.Context) (<-chan struct{}, error) {
 		e.lg,
 		e.prefix,
 		e.key,
+		shared,
 		session,
 		e.options,
 	)
+	defer e.options.NotifyPosition(ctx, func(ctx context.Context) (int, error) {
+		return position(ctx, e.client, e.prefix, e.key, session.Lease(), shared)
+	})()
 	var curErr error
 	done, err := mutex.lock(ctx)
 	curErr = err
 	if err == nil {
 		e.mutex = &mutex
+		e.token.Store(mutex.fencingToken)
 		return done, nil
 	}
 	return nil, curErr
 }
 
-func (e *EtcdLock) tryAcquire(ctx context.Context) (<-chan struct{}, error) {
+func (e *EtcdLock) tryAcquire(ctx context.Context, shared bool) (<-chan struct{}, error) {
 	session, err := e.newSession(ctx)
 	if err != nil {
 		return nil, err
@@ -84,6 +105,7 @@ func (e *EtcdLock) tryAcquire(ctx context.Context) (<-chan struct{}, error) {
 		e.lg,
 		e.prefix,
 		e.key,
+		shared,
 		session,
 		e.options,
 	)
@@ -91,13 +113,40 @@ func (e *EtcdLock) tryAcquire(ctx context.Context) (<-chan struct{}, error) {
 	var curErr = err
 	if err == nil {
 		e.mutex = &mutex
+		e.token.Store(mutex.fencingToken)
 		return done, nil
 	}
 	return nil, curErr
 }
 
 func (e *EtcdLock) Lock(ctx context.Context) (<-chan struct{}, error) {
-	e.lg.Debug("trying to acquire blocking lock")
+	return e.lock(ctx, false)
+}
+
+func (e *EtcdLock) TryLock(ctx context.Context) (acquired bool, done <-chan struct{}, err error) {
+	return e.tryLock(ctx, false)
+}
+
+func (e *EtcdLock) Unlock() error {
+	return e.unlock(false)
+}
+
+// RLock acquires the read side of the lock, reader keys share the mutex prefix
+// and are ordered with writers by their create revision
+func (e *EtcdLock) RLock(ctx context.Context) (<-chan struct{}, error) {
+	return e.lock(ctx, true)
+}
+
+func (e *EtcdLock) TryRLock(ctx context.Context) (acquired bool, done <-chan struct{}, err error) {
+	return e.tryLock(ctx, true)
+}
+
+func (e *EtcdLock) RUnlock() error {
+	return e.unlock(true)
+}
+
+func (e *EtcdLock) lock(ctx context.Context, shared bool) (<-chan struct{}, error) {
+	e.lg.Debug("trying to acquire blocking lock", "shared", shared)
 	var closureDone <-chan struct{}
 	if e.options.Tracer != nil {
 		ctxSpan, span := e.options.Tracer.Start(ctx, "Lock/etcd-lock", trace.WithAttributes())
@@ -105,7 +154,7 @@ func (e *EtcdLock) Lock(ctx context.Context) (<-chan struct{}, error) {
 		ctx = ctxSpan
 	}
 	if err := e.scheduler.Schedule(func() error {
-		done, err := e.acquire(ctx)
+		done, err := e.acquire(ctx, shared)
 		if err != nil {
 			return err
 		}
@@ -118,8 +167,8 @@ func (e *EtcdLock) Lock(ctx context.Context) (<-chan struct{}, error) {
 	return closureDone, nil
 }
 
-func (e *EtcdLock) TryLock(ctx context.Context) (acquired bool, done <-chan struct{}, err error) {
-	e.lg.Debug("trying to acquire non-blocking lock")
+func (e *EtcdLock) tryLock(ctx context.Context, shared bool) (acquired bool, done <-chan struct{}, err error) {
+	e.lg.Debug("trying to acquire non-blocking lock", "shared", shared)
 	var closureDone <-chan struct{}
 	if e.options.Tracer != nil {
 		ctxSpan, span := e.options.Tracer.Start(ctx, "Lock/etcd-lock", trace.WithAttributes())
@@ -127,7 +176,7 @@ func (e *EtcdLock) TryLock(ctx context.Context) (acquired bool, done <-chan stru
 		ctx = ctxSpan
 	}
 	if err := e.scheduler.Schedule(func() error {
-		done, err := e.tryAcquire(ctx)
+		done, err := e.tryAcquire(ctx, shared)
 		if err != nil {
 			return err
 		}
@@ -144,14 +193,17 @@ func (e *EtcdLock) TryLock(ctx context.Context) (acquired bool, done <-chan stru
 	return true, closureDone, nil
 }
 
-func (e *EtcdLock) Unlock() error {
-	e.lg.Debug("starting unlock")
+func (e *EtcdLock) unlock(shared bool) error {
+	e.lg.Debug("starting unlock", "shared", shared)
 
 	if err := e.scheduler.Done(func() error {
 		e.lg.Debug("inside scheduler done")
 		if e.mutex == nil {
 			panic("never acquired")
 		}
+		if e.mutex.shared != shared {
+			return lock.ErrLockMode
+		}
 		mutex := *e.mutex
 		go func() {
 			if err := mutex.unlock(); err != nil {
@@ -159,6 +211,7 @@ func (e *EtcdLock) Unlock() error {
 			}
 		}()
 		e.mutex = nil
+		e.token.Store(0)
 		return nil
 	}); err != nil {
 		return err
@@ -166,6 +219,27 @@ func (e *EtcdLock) Unlock() error {
 	return nil
 }
 
+func (e *EtcdLock) Transfer(ctx context.Context, toOwner string) error {
+	return e.scheduler.Done(func() error {
+		if e.mutex == nil {
+			panic("never acquired")
+		}
+		if e.mutex.shared {
+			return lock.ErrLockMode
+		}
+		if err := e.mutex.transfer(ctx, toOwner); err != nil {
+			return err
+		}
+		e.mutex = nil
+		e.token.Store(0)
+		return nil
+	})
+}
+
+func (e *EtcdLock) FencingToken() uint64 {
+	return e.token.Load()
+}
+
 func (e *EtcdLock) Key() string {
 	return e.key
 }
@@ -19,7 +19,7 @@ import (
 func init() {
 	broker.RegisterLockBroker(
 		constants.EtcdLockManager,
-		func(ctx context.Context, l broker.LockBroker) (lock.LockManager, error) {
+		func(ctx context.Context, l broker.LockBroker) (broker.LockManagers, error) {
 			l.Lg.Info("acquiring etcd client...")
 			cli, err := NewEtcdClient(ctx, l.Config.EtcdClientSpec)
 			if err != nil {
@@ -39,7 +39,9 @@ func init() {
 				return nil, errors.Join(errs...)
 			}
 			l.Lg.Info("acquired etcd client")
-			return NewEtcdLockManager(cli, "lock", l.Tracer, l.Lg), nil
+			return func(prefix string) (lock.LockManager, error) {
+				return NewEtcdLockManager(cli, prefix, l.Tracer, l.Lg), nil
+			}, nil
 		})
 }
 
@@ -50,8 +52,12 @@ type EtcdLockManager struct {
 	tracer trace.Tracer
 
 	lg *slog.Logger
+
+	reentrant *lock.ReentrantLocks
 }
 
+var _ lock.BarrierManager = (*EtcdLockManager)(nil)
+
 func NewEtcdLockManager(
 	client *clientv3.Client,
 	prefix string,
@@ -59,10 +65,11 @@ func NewEtcdLockManager(
 	lg *slog.Logger,
 ) *EtcdLockManager {
 	lm := &EtcdLockManager{
-		client: client,
-		prefix: prefix,
-		tracer: tracer,
-		lg:     lg,
+		client:    client,
+		prefix:    prefix,
+		tracer:    tracer,
+		lg:        lg,
+		reentrant: lock.NewReentrantLocks(),
 	}
 	return lm
 }
@@ -89,6 +96,21 @@ func (e *EtcdLockManager) Health(ctx context.Context) (conditions []string, err
 // made so we cannot re-use sessions in that case either -- since the session  will be orphaned for all locks
 // if the session is re-used.
 func (e *EtcdLockManager) NewLock(key string, opts ...lock.LockOption) lock.Lock {
+	options := lock.DefaultLockOptions()
+	options.Apply(opts...)
+	return e.reentrant.NewLock(key, options, func() lock.Lock {
+		return NewEtcdLock(
+			e.lg,
+			e.client,
+			e.prefix,
+			key,
+			options,
+		)
+	})
+}
+
+// RWLocks share the key of the exclusive lock returned by NewLock, with the same session semantics.
+func (e *EtcdLockManager) NewRWLock(key string, opts ...lock.LockOption) lock.RWLock {
 	options := lock.DefaultLockOptions()
 	options.Apply(opts...)
 	return NewEtcdLock(
@@ -99,3 +121,57 @@ func (e *EtcdLockManager) NewLock(key string, opts ...lock.LockOption) lock.Lock
 		options,
 	)
 }
+
+func (e *EtcdLockManager) NewMultiLock(keys []string, opts ...lock.LockOption) lock.Lock {
+	return lock.NewMultiLock(keys, func(key string) lock.Lock {
+		return e.NewLock(key, opts...)
+	})
+}
+
+// Semaphores follow the same session semantics as locks, under a prefix separate from the keys of locks.
+func (e *EtcdLockManager) NewSemaphore(key string, capacity int64, opts ...lock.LockOption) lock.Semaphore {
+	options := lock.DefaultLockOptions()
+	options.Apply(opts...)
+	return NewEtcdSemaphore(
+		e.lg,
+		e.client,
+		e.prefix+semaphoreSuffix,
+		key,
+		capacity,
+		options,
+	)
+}
+
+func (e *EtcdLockManager) DescribeLock(ctx context.Context, key string) (lock.LockInfo, error) {
+	return describe(ctx, e.client, e.prefix, key)
+}
+
+// ForceRelease revokes the leases of the lock's holders, evicted holders notice it when their session closes
+func (e *EtcdLockManager) ForceRelease(ctx context.Context, key, reason string) (lock.LockInfo, error) {
+	info, err := forceRelease(ctx, e.client, e.prefix, key)
+	if err != nil {
+		return lock.LockInfo{}, err
+	}
+	e.lg.With("key", key, "reason", reason, "holders", len(info.Holders)).Warn("force released lock")
+	return info, nil
+}
+
+func (e *EtcdLockManager) ListLocks(ctx context.Context, prefix string) ([]lock.LockInfo, error) {
+	names, err := keys(ctx, e.client, e.prefix, prefix)
+	if err != nil {
+		return nil, err
+	}
+	infos := []lock.LockInfo{}
+	for _, key := range names {
+		info, err := describe(ctx, e.client, e.prefix, key)
+		if err != nil {
+			return nil, err
+		}
+		// the keys of the lock may have been released since they were listed
+		if len(info.Holders) == 0 && info.Waiters == 0 {
+			continue
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
@@ -9,6 +9,7 @@ import (
 
 	"github.com/alexandreLamarre/dlock/pkg/lock"
 	"github.com/samber/lo"
+	clientv3 "go.etcd.io/etcd/client/v3"
 	"go.etcd.io/etcd/client/v3/concurrency"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
@@ -20,10 +21,19 @@ type etcdMutex struct {
 
 	prefix string
 	key    string
+	// shared mutexes hold the read side of a RWLock
+	shared bool
+	// semaphore mutexes, with a non-zero capacity, hold weight units of a semaphore
+	weight   int64
+	capacity int64
 
 	session *concurrency.Session
 
-	mutex *concurrency.Mutex
+	mutex locker
+	// lease named by the key of the holder, which is the lease of the session unless the lock was transferred to it
+	holderLease clientv3.LeaseID
+	// revision of the etcd store when the mutex was acquired, used as a fencing token
+	fencingToken uint64
 
 	internalDone chan struct{}
 	*lock.LockOptions
@@ -32,6 +42,7 @@ type etcdMutex struct {
 func NewEtcdMutex(
 	lg *slog.Logger,
 	prefix, key string,
+	shared bool,
 	session *concurrency.Session,
 	opts *lock.LockOptions,
 ) etcdMutex {
@@ -41,33 +52,66 @@ func NewEtcdMutex(
 
 		key:    key,
 		prefix: prefix,
+		shared: shared,
 		// mu:           sync.Mutex{},
 		internalDone: make(chan struct{}),
 		LockOptions:  opts,
 	}
 }
 
+func newEtcdSemaphore(
+	lg *slog.Logger,
+	prefix, key string,
+	weight, capacity int64,
+	session *concurrency.Session,
+	opts *lock.LockOptions,
+) etcdMutex {
+	m := NewEtcdMutex(lg, prefix, key, false, session, opts)
+	m.weight = weight
+	m.capacity = capacity
+	return m
+}
+
+func (e *etcdMutex) newLocker() locker {
+	if e.capacity > 0 {
+		return newSemaphoreMutex(e.session, path.Join(e.prefix, e.key), e.weight, e.capacity)
+	}
+	if e.shared {
+		return newReadMutex(e.session, path.Join(e.prefix, e.key))
+	}
+	return concurrency.NewMutex(e.session, path.Join(e.prefix, e.key))
+}
+
 func (e *etcdMutex) lock(ctx context.Context) (<-chan struct{}, error) {
-	mutex := concurrency.NewMutex(
-		e.session,
-		path.Join(e.prefix, e.key),
-	)
-	if err := mutex.Lock(ctx); err != nil {
+	var mutex locker = e.newLocker()
+	var err error
+	if _, ok := mutex.(owner); ok {
+		// exclusive locks can be transferred to their waiters
+		mutex, err = e.lockOrClaim(ctx, mutex)
+	} else {
+		err = mutex.Lock(ctx)
+	}
+	if err != nil {
 		return nil, err
 	}
 	e.mutex = mutex
+	if !e.shared && e.capacity == 0 {
+		e.fencingToken = uint64(mutex.Header().Revision)
+	}
+	e.putInfo(ctx)
 	return lo.Async(e.keepalive), nil
 }
 
 func (e *etcdMutex) tryLock(ctx context.Context) (<-chan struct{}, error) {
-	mutex := concurrency.NewMutex(
-		e.session,
-		path.Join(e.prefix, e.key),
-	)
+	mutex := e.newLocker()
 	if err := mutex.TryLock(ctx); err != nil {
 		return nil, err
 	}
 	e.mutex = mutex
+	if !e.shared && e.capacity == 0 {
+		e.fencingToken = uint64(mutex.Header().Revision)
+	}
+	e.putInfo(ctx)
 
 	return lo.Async(e.keepalive), nil
 }
@@ -119,7 +163,7 @@ func (e *etcdMutex) unlock() error {
 	}
 	defer e.teardown()
 
-	mutex := *e.mutex
+	mutex := e.mutex
 	e.mutex = nil
 	go func() {
 		ctxca, ca := context.WithTimeout(ctx, 60*time.Second)
@@ -128,6 +172,11 @@ func (e *etcdMutex) unlock() error {
 			e.lg.Warn("failed to unlock mutex", "err", err.Error())
 			span.RecordError(err)
 		}
+		if e.capacity == 0 {
+			if _, err := e.session.Client().Delete(ctxca, e.infoKey()); err != nil {
+				e.lg.Warn("failed to delete holder info", "err", err.Error())
+			}
+		}
 	}()
 	return nil
 }
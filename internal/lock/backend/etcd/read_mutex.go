@@ -0,0 +1,166 @@
+package etcd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/client/v3/concurrency"
+)
+
+// readerMarker prefixes the keys of readers under the lock prefix.
+// Writer keys created by concurrency.Mutex are hex encoded lease IDs, so they can never start with it.
+const readerMarker = "r"
+
+// locker is the subset of *concurrency.Mutex used to hold either side of a lock
+type locker interface {
+	Lock(ctx context.Context) error
+	TryLock(ctx context.Context) error
+	Unlock(ctx context.Context) error
+	Header() *pb.ResponseHeader
+}
+
+var _ locker = (*concurrency.Mutex)(nil)
+var _ locker = (*readMutex)(nil)
+
+// readMutex is the read side of a lock, sharing its key prefix with concurrency.Mutex.
+//
+// Like concurrency.Mutex, waiters are ordered by the create revision of their key : a reader holds the lock
+// once no writer key with a lower create revision remains, while writers already wait for every key, including
+// readers, with a lower create revision to be deleted.
+type readMutex struct {
+	s *concurrency.Session
+
+	pfx   string
+	myKey string
+	myRev int64
+	hdr   *pb.ResponseHeader
+}
+
+func newReadMutex(s *concurrency.Session, pfx string) *readMutex {
+	return &readMutex{s: s, pfx: pfx + "/", myRev: -1}
+}
+
+func (m *readMutex) tryAcquire(ctx context.Context) error {
+	client := m.s.Client()
+	m.myKey = fmt.Sprintf("%s%s%x", m.pfx, readerMarker, m.s.Lease())
+	cmp := clientv3.Compare(clientv3.CreateRevision(m.myKey), "=", 0)
+	put := clientv3.OpPut(m.myKey, "", clientv3.WithLease(m.s.Lease()))
+	get := clientv3.OpGet(m.myKey)
+	resp, err := client.Txn(ctx).If(cmp).Then(put).Else(get).Commit()
+	if err != nil {
+		return err
+	}
+	m.myRev = resp.Header.Revision
+	if !resp.Succeeded {
+		m.myRev = resp.Responses[0].GetResponseRange().Kvs[0].CreateRevision
+	}
+	return nil
+}
+
+// lastWriter returns the most recent writer key created before our own key, if any
+func (m *readMutex) lastWriter(ctx context.Context) (*mvccpb.KeyValue, *pb.ResponseHeader, error) {
+	resp, err := m.s.Client().Get(
+		ctx,
+		m.pfx,
+		clientv3.WithPrefix(),
+		clientv3.WithMaxCreateRev(m.myRev-1),
+		clientv3.WithSort(clientv3.SortByCreateRevision, clientv3.SortDescend),
+		clientv3.WithKeysOnly(),
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, kv := range resp.Kvs {
+		if !strings.HasPrefix(strings.TrimPrefix(string(kv.Key), m.pfx), readerMarker) {
+			return kv, resp.Header, nil
+		}
+	}
+	return nil, resp.Header, nil
+}
+
+func (m *readMutex) TryLock(ctx context.Context) error {
+	if err := m.tryAcquire(ctx); err != nil {
+		return err
+	}
+	writer, hdr, err := m.lastWriter(ctx)
+	if err != nil {
+		return errors.Join(err, m.Unlock(m.s.Client().Ctx()))
+	}
+	if writer != nil {
+		if err := m.Unlock(ctx); err != nil {
+			return err
+		}
+		return concurrency.ErrLocked
+	}
+	m.hdr = hdr
+	return nil
+}
+
+func (m *readMutex) Lock(ctx context.Context) error {
+	if err := m.tryAcquire(ctx); err != nil {
+		return err
+	}
+	client := m.s.Client()
+	for {
+		writer, hdr, err := m.lastWriter(ctx)
+		if err != nil {
+			return errors.Join(err, m.Unlock(client.Ctx()))
+		}
+		if writer == nil {
+			// make sure the session is not expired, and our key still exists.
+			gresp, err := client.Get(ctx, m.myKey)
+			if err != nil {
+				return errors.Join(err, m.Unlock(client.Ctx()))
+			}
+			if len(gresp.Kvs) == 0 {
+				return concurrency.ErrSessionExpired
+			}
+			m.hdr = hdr
+			return nil
+		}
+		if err := waitDelete(ctx, client, string(writer.Key), hdr.Revision); err != nil {
+			return errors.Join(err, m.Unlock(client.Ctx()))
+		}
+	}
+}
+
+func (m *readMutex) Unlock(ctx context.Context) error {
+	if m.myKey == "" || m.myRev <= 0 {
+		return concurrency.ErrLockReleased
+	}
+	if _, err := m.s.Client().Delete(ctx, m.myKey); err != nil {
+		return err
+	}
+	m.myKey = ""
+	m.myRev = -1
+	return nil
+}
+
+func (m *readMutex) Header() *pb.ResponseHeader { return m.hdr }
+
+func waitDelete(ctx context.Context, client *clientv3.Client, key string, rev int64) error {
+	ctxca, ca := context.WithCancel(ctx)
+	defer ca()
+
+	var wr clientv3.WatchResponse
+	wch := client.Watch(ctxca, key, clientv3.WithRev(rev))
+	for wr = range wch {
+		for _, ev := range wr.Events {
+			if ev.Type == mvccpb.Event_DELETE {
+				return nil
+			}
+		}
+	}
+	if err := wr.Err(); err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return errors.New("lost watcher waiting for delete")
+}
@@ -0,0 +1,126 @@
+package etcd
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/alexandreLamarre/dlock/pkg/lock"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/client/v3/concurrency"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// semaphoreSuffix is appended to the lock manager's prefix, so that the keys of semaphores
+// never collide with the keys of locks nested under the prefix
+const semaphoreSuffix = ".semaphore"
+
+// EtcdSemaphore holds its units with a key bound to a session per acquisition, like EtcdLock
+type EtcdSemaphore struct {
+	lg *slog.Logger
+
+	prefix   string
+	key      string
+	capacity int64
+
+	options *lock.LockOptions
+
+	scheduler *lock.LockScheduler
+
+	client *clientv3.Client
+	mutex  *etcdMutex
+}
+
+var _ lock.Semaphore = (*EtcdSemaphore)(nil)
+
+func NewEtcdSemaphore(
+	lg *slog.Logger,
+	client *clientv3.Client,
+	prefix, key string,
+	capacity int64,
+	options *lock.LockOptions,
+) *EtcdSemaphore {
+	return &EtcdSemaphore{
+		lg:        lg.With("key", key, "capacity", capacity),
+		client:    client,
+		prefix:    prefix,
+		key:       key,
+		capacity:  capacity,
+		options:   options,
+		scheduler: lock.NewLockScheduler(),
+	}
+}
+
+func (e *EtcdSemaphore) Capacity() int64 {
+	return e.capacity
+}
+
+func (e *EtcdSemaphore) Acquire(ctx context.Context, n int64) (<-chan struct{}, error) {
+	return e.acquire(ctx, n, false)
+}
+
+func (e *EtcdSemaphore) TryAcquire(ctx context.Context, n int64) (acquired bool, done <-chan struct{}, err error) {
+	done, err = e.acquire(ctx, n, true)
+	if err != nil {
+		if errors.Is(err, concurrency.ErrLocked) {
+			return false, nil, nil
+		}
+		return false, nil, err
+	}
+	return true, done, nil
+}
+
+func (e *EtcdSemaphore) acquire(ctx context.Context, n int64, try bool) (<-chan struct{}, error) {
+	if err := lock.ValidateWeight(n, e.capacity); err != nil {
+		return nil, err
+	}
+	e.lg.Debug("trying to acquire semaphore", "weight", n, "try", try)
+	if e.options.Tracer != nil {
+		ctxSpan, span := e.options.Tracer.Start(ctx, "Acquire/etcd-semaphore", trace.WithAttributes())
+		defer span.End()
+		ctx = ctxSpan
+	}
+	var closureDone <-chan struct{}
+	if err := e.scheduler.Schedule(func() error {
+		session, err := concurrency.NewSession(e.client, sessionOptions(e.options)...)
+		if err != nil {
+			return err
+		}
+		mutex := newEtcdSemaphore(e.lg, e.prefix, e.key, n, e.capacity, session, e.options)
+		var done <-chan struct{}
+		if try {
+			done, err = mutex.tryLock(ctx)
+		} else {
+			done, err = mutex.lock(ctx)
+		}
+		if err != nil {
+			// revoking the session's lease deletes any key left behind by the failed acquisition
+			if closeErr := session.Close(); closeErr != nil {
+				e.lg.Warn("failed to close etcd session", "err", closeErr.Error())
+			}
+			return err
+		}
+		e.mutex = &mutex
+		closureDone = done
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return closureDone, nil
+}
+
+func (e *EtcdSemaphore) Release() error {
+	return e.scheduler.Done(func() error {
+		if e.mutex == nil {
+			panic("never acquired")
+		}
+		mutex := *e.mutex
+		go func() {
+			if err := mutex.unlock(); err != nil {
+				e.lg.Error(err.Error())
+			}
+		}()
+		e.mutex = nil
+		return nil
+	})
+}
@@ -0,0 +1,157 @@
+package etcd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/client/v3/concurrency"
+)
+
+var _ locker = (*semaphoreMutex)(nil)
+
+// semaphoreMutex holds weight units of a semaphore, storing its weight as the value of its key.
+//
+// Like concurrency.Mutex, waiters are ordered by the create revision of their key : a holder acquires its units
+// once the weights of every key with a lower create revision, held or waiting, leave room for its own.
+type semaphoreMutex struct {
+	s *concurrency.Session
+
+	pfx      string
+	weight   int64
+	capacity int64
+
+	myKey string
+	myRev int64
+	hdr   *pb.ResponseHeader
+}
+
+func newSemaphoreMutex(s *concurrency.Session, pfx string, weight, capacity int64) *semaphoreMutex {
+	return &semaphoreMutex{s: s, pfx: pfx + "/", weight: weight, capacity: capacity, myRev: -1}
+}
+
+func (m *semaphoreMutex) tryAcquire(ctx context.Context) error {
+	client := m.s.Client()
+	m.myKey = fmt.Sprintf("%s%x", m.pfx, m.s.Lease())
+	cmp := clientv3.Compare(clientv3.CreateRevision(m.myKey), "=", 0)
+	put := clientv3.OpPut(m.myKey, strconv.FormatInt(m.weight, 10), clientv3.WithLease(m.s.Lease()))
+	get := clientv3.OpGet(m.myKey)
+	resp, err := client.Txn(ctx).If(cmp).Then(put).Else(get).Commit()
+	if err != nil {
+		return err
+	}
+	m.myRev = resp.Header.Revision
+	if !resp.Succeeded {
+		m.myRev = resp.Responses[0].GetResponseRange().Kvs[0].CreateRevision
+	}
+	return nil
+}
+
+// fits reports whether the units ahead of our key leave room for our own weight
+func (m *semaphoreMutex) fits(ctx context.Context) (bool, *pb.ResponseHeader, error) {
+	resp, err := m.s.Client().Get(
+		ctx,
+		m.pfx,
+		clientv3.WithPrefix(),
+		clientv3.WithMaxCreateRev(m.myRev-1),
+	)
+	if err != nil {
+		return false, nil, err
+	}
+	held := m.weight
+	for _, kv := range resp.Kvs {
+		weight, err := strconv.ParseInt(string(kv.Value), 10, 64)
+		if err != nil {
+			return false, nil, fmt.Errorf("invalid semaphore weight at %s : %w", kv.Key, err)
+		}
+		held += weight
+	}
+	return held <= m.capacity, resp.Header, nil
+}
+
+func (m *semaphoreMutex) TryLock(ctx context.Context) error {
+	if err := m.tryAcquire(ctx); err != nil {
+		return err
+	}
+	ok, hdr, err := m.fits(ctx)
+	if err != nil {
+		return errors.Join(err, m.Unlock(m.s.Client().Ctx()))
+	}
+	if !ok {
+		if err := m.Unlock(ctx); err != nil {
+			return err
+		}
+		return concurrency.ErrLocked
+	}
+	m.hdr = hdr
+	return nil
+}
+
+func (m *semaphoreMutex) Lock(ctx context.Context) error {
+	if err := m.tryAcquire(ctx); err != nil {
+		return err
+	}
+	client := m.s.Client()
+	for {
+		ok, hdr, err := m.fits(ctx)
+		if err != nil {
+			return errors.Join(err, m.Unlock(client.Ctx()))
+		}
+		if ok {
+			// make sure the session is not expired, and our key still exists.
+			gresp, err := client.Get(ctx, m.myKey)
+			if err != nil {
+				return errors.Join(err, m.Unlock(client.Ctx()))
+			}
+			if len(gresp.Kvs) == 0 {
+				return concurrency.ErrSessionExpired
+			}
+			m.hdr = hdr
+			return nil
+		}
+		if err := waitAnyDelete(ctx, client, m.pfx, hdr.Revision+1); err != nil {
+			return errors.Join(err, m.Unlock(client.Ctx()))
+		}
+	}
+}
+
+func (m *semaphoreMutex) Unlock(ctx context.Context) error {
+	if m.myKey == "" || m.myRev <= 0 {
+		return concurrency.ErrLockReleased
+	}
+	if _, err := m.s.Client().Delete(ctx, m.myKey); err != nil {
+		return err
+	}
+	m.myKey = ""
+	m.myRev = -1
+	return nil
+}
+
+func (m *semaphoreMutex) Header() *pb.ResponseHeader { return m.hdr }
+
+// waitAnyDelete waits for any key under the prefix to be deleted, starting at the given revision
+func waitAnyDelete(ctx context.Context, client *clientv3.Client, pfx string, rev int64) error {
+	ctxca, ca := context.WithCancel(ctx)
+	defer ca()
+
+	var wr clientv3.WatchResponse
+	wch := client.Watch(ctxca, pfx, clientv3.WithPrefix(), clientv3.WithRev(rev))
+	for wr = range wch {
+		for _, ev := range wr.Events {
+			if ev.Type == mvccpb.Event_DELETE {
+				return nil
+			}
+		}
+	}
+	if err := wr.Err(); err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return errors.New("lost watcher waiting for delete")
+}
@@ -0,0 +1,215 @@
+package etcd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alexandreLamarre/dlock/pkg/lock"
+	"github.com/alexandreLamarre/dlock/pkg/logger"
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/client/v3/concurrency"
+)
+
+// transferSuffix is appended to the lock manager's prefix, the key of the holder a lock was transferred to
+// is stored under it for the waiter, with the lease of the waiter's session
+const transferSuffix = ".transfer"
+
+func transferKey(prefix, key string, lease clientv3.LeaseID) string {
+	return fmt.Sprintf("%s%s/%s/%x", prefix, transferSuffix, key, int64(lease))
+}
+
+// owner is a held exclusive lock that can be transferred
+type owner interface {
+	locker
+	Key() string
+	IsOwner() clientv3.Cmp
+}
+
+var _ owner = (*concurrency.Mutex)(nil)
+var _ owner = (*transferredMutex)(nil)
+
+// transferredMutex holds the key of the holder a lock was transferred to, which is attached to the lease of our
+// session. The key keeps its create revision, so the waiters of the lock keep waiting for it to be deleted.
+type transferredMutex struct {
+	s     *concurrency.Session
+	myKey string
+	hdr   *pb.ResponseHeader
+}
+
+func (m *transferredMutex) Lock(context.Context) error {
+	return errors.New("transferred mutexes can't be locked")
+}
+
+func (m *transferredMutex) TryLock(context.Context) error {
+	return errors.New("transferred mutexes can't be locked")
+}
+
+// Unlock deletes the key, unless it was transferred again to another session
+func (m *transferredMutex) Unlock(ctx context.Context) error {
+	_, err := m.s.Client().Txn(ctx).If(m.IsOwner()).Then(clientv3.OpDelete(m.myKey)).Commit()
+	return err
+}
+
+func (m *transferredMutex) Header() *pb.ResponseHeader { return m.hdr }
+
+func (m *transferredMutex) Key() string { return m.myKey }
+
+func (m *transferredMutex) IsOwner() clientv3.Cmp {
+	return clientv3.Compare(clientv3.LeaseValue(m.myKey), "=", m.s.Lease())
+}
+
+// transfer attaches the key of the holder to the lease of the earliest exclusive waiter of the owner,
+// and notifies the waiter through its transfer key. The revision of the transfer is the waiter's fencing token.
+func (e *etcdMutex) transfer(ctx context.Context, toOwner string) error {
+	held, ok := e.mutex.(owner)
+	if !ok {
+		return lock.ErrLockMode
+	}
+	client := e.session.Client()
+	lease, info, err := transferee(ctx, client, e.prefix, e.key, toOwner)
+	if err != nil {
+		return err
+	}
+	info.AcquiredAt = time.Now()
+	data, err := json.Marshal(info)
+	if err != nil {
+		return err
+	}
+	waiterKey := fmt.Sprintf("%s/%s/%x", e.prefix, e.key, int64(lease))
+	resp, err := client.Txn(ctx).If(held.IsOwner()).Then(clientv3.OpTxn(
+		[]clientv3.Cmp{clientv3.Compare(clientv3.CreateRevision(waiterKey), "!=", 0)},
+		[]clientv3.Op{
+			clientv3.OpPut(held.Key(), "", clientv3.WithLease(lease)),
+			clientv3.OpPut(e.infoKey(), string(data), clientv3.WithLease(lease)),
+			clientv3.OpPut(transferKey(e.prefix, e.key, lease), held.Key(), clientv3.WithLease(lease)),
+		},
+		nil,
+	)).Commit()
+	if err != nil {
+		return err
+	}
+	if !resp.Succeeded {
+		return lock.ErrNotHeld
+	}
+	if !resp.Responses[0].GetResponseTxn().Succeeded {
+		// the waiter gave up in the meantime
+		return lock.ErrNoTransferee
+	}
+	e.mutex = nil
+	e.teardown()
+	return nil
+}
+
+// transferee returns the lease & HolderInfo of the earliest exclusive waiter of the owner, see queue
+func transferee(ctx context.Context, client *clientv3.Client, prefix, key, toOwner string) (clientv3.LeaseID, lock.HolderInfo, error) {
+	_, waiters, err := queue(ctx, client, prefix, key)
+	if err != nil {
+		return 0, lock.HolderInfo{}, err
+	}
+	for _, name := range waiters {
+		if strings.HasPrefix(name, readerMarker) {
+			continue
+		}
+		lease, err := strconv.ParseInt(name, 16, 64)
+		if err != nil {
+			continue
+		}
+		resp, err := client.Get(ctx, infoKey(prefix, key, clientv3.LeaseID(lease)))
+		if err != nil {
+			return 0, lock.HolderInfo{}, err
+		}
+		if len(resp.Kvs) == 0 {
+			continue
+		}
+		var info lock.HolderInfo
+		if err := json.Unmarshal(resp.Kvs[0].Value, &info); err != nil {
+			continue
+		}
+		if !info.Shared && info.Owner == toOwner {
+			return clientv3.LeaseID(lease), info, nil
+		}
+	}
+	return 0, lock.HolderInfo{}, lock.ErrNoTransferee
+}
+
+// lockOrClaim waits for the lock like lock, storing the HolderInfo of the waiter first so that locks can be
+// transferred to it. It stops waiting once a lock is transferred to it, and holds the key of the previous holder.
+func (e *etcdMutex) lockOrClaim(ctx context.Context, mutex locker) (locker, error) {
+	client := e.session.Client()
+	data, err := json.Marshal(e.Holder(false))
+	if err != nil {
+		return nil, err
+	}
+	put, err := client.Put(ctx, e.infoKey(), string(data), clientv3.WithLease(e.session.Lease()))
+	if err != nil {
+		return nil, err
+	}
+	ctxca, ca := context.WithCancel(ctx)
+	defer ca()
+	transferred := make(chan *mvccpb.KeyValue, 1)
+	wch := client.Watch(ctxca, transferKey(e.prefix, e.key, e.session.Lease()), clientv3.WithRev(put.Header.Revision))
+	go func() {
+		for wr := range wch {
+			for _, ev := range wr.Events {
+				if ev.Type == mvccpb.PUT {
+					transferred <- ev.Kv
+					ca()
+					return
+				}
+			}
+		}
+	}()
+	lockErr := mutex.Lock(ctxca)
+	var kv *mvccpb.KeyValue
+	select {
+	case kv = <-transferred:
+	default:
+	}
+	if kv == nil {
+		if lockErr != nil {
+			return nil, errors.Join(lockErr, e.cleanupTransfer(client.Ctx()))
+		}
+		return mutex, nil
+	}
+	held := &transferredMutex{
+		s:     e.session,
+		myKey: string(kv.Value),
+		hdr:   &pb.ResponseHeader{Revision: kv.ModRevision},
+	}
+	if lockErr == nil || ctx.Err() != nil {
+		// the lock was acquired before the transfer was noticed, or the acquisition was abandoned meanwhile
+		if err := errors.Join(held.Unlock(client.Ctx()), e.cleanupTransfer(client.Ctx())); err != nil {
+			e.lg.With(logger.Err(err)).Warn("failed to release lock transfer")
+		}
+		if lockErr == nil {
+			return mutex, nil
+		}
+		return nil, errors.Join(ctx.Err(), lockErr)
+	}
+	name, err := strconv.ParseInt(path.Base(held.myKey), 16, 64)
+	if err != nil {
+		return nil, errors.Join(fmt.Errorf("invalid lock key %s : %w", held.myKey, err), held.Unlock(client.Ctx()))
+	}
+	if err := e.cleanupTransfer(ctx); err != nil {
+		e.lg.With(logger.Err(err)).Warn("failed to clean up lock transfer")
+	}
+	e.holderLease = clientv3.LeaseID(name)
+	return held, nil
+}
+
+// cleanupTransfer deletes the HolderInfo of the waiter & its transfer key
+func (e *etcdMutex) cleanupTransfer(ctx context.Context) error {
+	_, err := e.session.Client().Txn(ctx).Then(
+		clientv3.OpDelete(infoKey(e.prefix, e.key, e.session.Lease())),
+		clientv3.OpDelete(transferKey(e.prefix, e.key, e.session.Lease())),
+	).Commit()
+	return err
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"crypto/tls"
 	"fmt"
+	"time"
 
 	"github.com/alexandreLamarre/dlock/pkg/config/v1alpha1"
 	"github.com/alexandreLamarre/dlock/pkg/util"
@@ -12,26 +13,56 @@ import (
 	"google.golang.org/grpc"
 )
 
-func NewEtcdClient(
+// EtcdClientConfig returns the config of the client of the etcd cluster of the spec
+func EtcdClientConfig(
 	ctx context.Context,
 	conf *v1alpha1.EtcdClientSpec,
-) (*clientv3.Client, error) {
+) (clientv3.Config, error) {
 	var tlsConfig *tls.Config
 	if conf.Certs != nil {
 		var err error
 		tlsConfig, err = util.LoadClientMTLSConfig(*conf.Certs)
 		if err != nil {
-			return nil, fmt.Errorf("failed to load client TLS config: %w", err)
+			return clientv3.Config{}, fmt.Errorf("failed to load client TLS config: %w", err)
 		}
 	}
 	clientConfig := clientv3.Config{
 		Endpoints: conf.Endpoints,
 		TLS:       tlsConfig,
+		Username:  conf.Username,
+		Password:  conf.Password,
 		Context:   context.WithoutCancel(ctx),
 		DialOptions: []grpc.DialOption{
 			grpc.WithStatsHandler(otelgrpc.NewClientHandler()),
 		},
 	}
+	for _, timing := range []struct {
+		name  string
+		value string
+		field *time.Duration
+	}{
+		{"dialTimeout", conf.DialTimeout, &clientConfig.DialTimeout},
+		{"keepAliveTime", conf.KeepAliveTime, &clientConfig.DialKeepAliveTime},
+		{"keepAliveTimeout", conf.KeepAliveTimeout, &clientConfig.DialKeepAliveTimeout},
+		{"autoSyncInterval", conf.AutoSyncInterval, &clientConfig.AutoSyncInterval},
+	} {
+		d, err := util.ParseOptionalDuration(timing.name, timing.value)
+		if err != nil {
+			return clientv3.Config{}, err
+		}
+		*timing.field = d
+	}
+	return clientConfig, nil
+}
+
+func NewEtcdClient(
+	ctx context.Context,
+	conf *v1alpha1.EtcdClientSpec,
+) (*clientv3.Client, error) {
+	clientConfig, err := EtcdClientConfig(ctx, conf)
+	if err != nil {
+		return nil, err
+	}
 	cli, err := clientv3.New(clientConfig)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create etcd client: %w", err)
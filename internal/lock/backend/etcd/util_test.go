@@ -0,0 +1,45 @@
+package etcd_test
+
+import (
+	"context"
+	"time"
+
+	"github.com/alexandreLamarre/dlock/internal/lock/backend/etcd"
+	"github.com/alexandreLamarre/dlock/pkg/config/v1alpha1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Etcd client config", Label("unit"), func() {
+	It("should configure the client with the spec", func() {
+		conf, err := etcd.EtcdClientConfig(context.Background(), &v1alpha1.EtcdClientSpec{
+			Endpoints:        []string{"localhost:2379"},
+			Username:         "dlock",
+			Password:         "secret",
+			DialTimeout:      "5s",
+			KeepAliveTime:    "30s",
+			KeepAliveTimeout: "10s",
+			AutoSyncInterval: "1m",
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(conf.Endpoints).To(Equal([]string{"localhost:2379"}))
+		Expect(conf.Username).To(Equal("dlock"))
+		Expect(conf.Password).To(Equal("secret"))
+		Expect(conf.DialTimeout).To(Equal(5 * time.Second))
+		Expect(conf.DialKeepAliveTime).To(Equal(30 * time.Second))
+		Expect(conf.DialKeepAliveTimeout).To(Equal(10 * time.Second))
+		Expect(conf.AutoSyncInterval).To(Equal(time.Minute))
+		Expect(conf.TLS).To(BeNil())
+	})
+
+	It("should reject invalid specs", func() {
+		for _, spec := range []*v1alpha1.EtcdClientSpec{
+			{DialTimeout: "5"},
+			{KeepAliveTime: "-1s"},
+			{Certs: &v1alpha1.MTLSSpec{ClientCert: "missing.crt", ClientKey: "missing.key"}},
+		} {
+			_, err := etcd.EtcdClientConfig(context.Background(), spec)
+			Expect(err).To(HaveOccurred())
+		}
+	})
+})
@@ -0,0 +1,64 @@
+package etcd
+
+import (
+	"context"
+	"strings"
+
+	"github.com/alexandreLamarre/dlock/pkg/lock"
+	"github.com/alexandreLamarre/dlock/pkg/logger"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// Watch watches the keys of the holders & waiters of the locks under the prefix, describing their lock again
+// on every change. Deleted keys whose lease is gone were expired or force released, since unlocking deletes
+// the key of the holder before its session is orphaned.
+//
+// When the etcd watch fails, for instance when its revision is compacted, locks are only described every
+// lock.WatchInterval.
+func (e *EtcdLockManager) Watch(ctx context.Context, prefix string) (<-chan lock.WatchEvent, error) {
+	ctxca, ca := context.WithCancel(ctx)
+	// the watch starts before the locks are first described, so that no change is missed in between
+	wch := e.client.Watch(ctxca, e.prefix+"/"+prefix, clientv3.WithPrefix(), clientv3.WithPrevKV())
+	changes := make(chan lock.WatchChange)
+	events, err := lock.WatchDescriptions(ctx, e, prefix, changes)
+	if err != nil {
+		ca()
+		return nil, err
+	}
+	go func() {
+		defer ca()
+		defer close(changes)
+		for wr := range wch {
+			if err := wr.Err(); err != nil {
+				e.lg.With(logger.Err(err)).Warn("etcd watch failed, polling locks instead")
+				return
+			}
+			for _, ev := range wr.Events {
+				name := strings.TrimPrefix(string(ev.Kv.Key), e.prefix+"/")
+				idx := strings.LastIndex(name, "/")
+				if idx < 0 {
+					continue
+				}
+				change := lock.WatchChange{Key: name[:idx]}
+				if ev.Type == mvccpb.DELETE && ev.PrevKv != nil {
+					change.Expired = e.leaseExpired(ctx, clientv3.LeaseID(ev.PrevKv.Lease))
+				}
+				select {
+				case changes <- change:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return events, nil
+}
+
+func (e *EtcdLockManager) leaseExpired(ctx context.Context, lease clientv3.LeaseID) bool {
+	if lease == clientv3.NoLease {
+		return false
+	}
+	resp, err := e.client.TimeToLive(ctx, lease)
+	return err != nil || resp.TTL <= 0
+}
@@ -0,0 +1,85 @@
+package file_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alexandreLamarre/dlock/internal/lock/backend/file"
+	"github.com/alexandreLamarre/dlock/pkg/constants"
+	"github.com/alexandreLamarre/dlock/pkg/lock"
+	"github.com/alexandreLamarre/dlock/pkg/lock/broker"
+	"github.com/alexandreLamarre/dlock/pkg/logger"
+	"github.com/alexandreLamarre/dlock/pkg/test/conformance/integration"
+	"github.com/alexandreLamarre/dlock/pkg/util/future"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/samber/lo"
+)
+
+func TestFile(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "File Suite")
+}
+
+var lmF = future.New[lock.LockManager]()
+var lmSetF = future.New[lo.Tuple3[
+	lock.LockManager, lock.LockManager, lock.LockManager,
+]]()
+
+var _ = BeforeSuite(func() {
+	if Label("integration").MatchesLabelFilter(GinkgoLabelFilter()) {
+		ctx := context.Background()
+		dir := GinkgoT().TempDir()
+
+		lm, err := file.NewLockManager(ctx, dir, "test", nil, logger.NewNop())
+		Expect(err).NotTo(HaveOccurred())
+		lmF.Set(lm)
+
+		// every lock manager opens its own files, so they lock each other out like separate processes would
+		x, err := file.NewLockManager(ctx, dir, "test", nil, logger.NewNop())
+		Expect(err).NotTo(HaveOccurred())
+		y, err := file.NewLockManager(ctx, dir, "test", nil, logger.NewNop())
+		Expect(err).NotTo(HaveOccurred())
+		z, err := file.NewLockManager(ctx, dir, "test", nil, logger.NewNop())
+		Expect(err).NotTo(HaveOccurred())
+
+		lmSetF.Set(lo.Tuple3[lock.LockManager, lock.LockManager, lock.LockManager]{
+			A: x, B: y, C: z,
+		})
+	}
+})
+
+var _ = Describe("File Lock Manager", Ordered, Label("integration"), integration.LockManagerTestSuite(lmF, lmSetF))
+var _ = Describe("File Broker", Label("unit"), func() {
+	When("we register the lock broker", func() {
+		It("should register the file lock manager as a broker", func() {
+			fBroker, ok := broker.GetLockBroker(constants.FileLockManager)
+			Expect(ok).To(BeTrue())
+			Expect(fBroker).NotTo(BeNil())
+		})
+	})
+})
+
+var _ = Describe("File Fencing", Label("unit"), func() {
+	It("should keep issuing greater fencing tokens once locks are force released", func(ctx SpecContext) {
+		dir := GinkgoT().TempDir()
+		evicter, err := file.NewLockManager(ctx, dir, "test", nil, logger.NewNop())
+		Expect(err).NotTo(HaveOccurred())
+		var last uint64
+		for range 5 {
+			// every holder opens the files of its own lock manager, like separate processes would
+			lm, err := file.NewLockManager(ctx, dir, "test", nil, logger.NewNop())
+			Expect(err).NotTo(HaveOccurred())
+			l := lm.NewLock("fenced")
+			acquired, _, err := l.TryLock(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(acquired).To(BeTrue())
+			Expect(l.FencingToken()).To(BeNumerically(">", last))
+			last = l.FencingToken()
+
+			_, err = evicter.ForceRelease(ctx, "fenced", "test")
+			Expect(err).NotTo(HaveOccurred())
+			DeferCleanup(l.Unlock)
+		}
+	})
+})
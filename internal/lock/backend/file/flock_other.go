@@ -0,0 +1,22 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly)
+
+package file
+
+import (
+	"errors"
+	"os"
+)
+
+var errUnsupported = errors.New("flock is not supported on this platform")
+
+func tryFlock(_ *os.File, _ bool) error {
+	return errUnsupported
+}
+
+func funlock(_ *os.File) error {
+	return errUnsupported
+}
+
+func processAlive(_ int) bool {
+	return true
+}
@@ -0,0 +1,235 @@
+package file
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/alexandreLamarre/dlock/pkg/lock"
+	"github.com/google/uuid"
+)
+
+const (
+	holderExt = ".holder"
+	waiterExt = ".waiter"
+	// tickets are the records of fair waiters, named after the time they started waiting
+	ticketExt = ".ticket"
+)
+
+// record is stored in the info directory of a lock by each of its holders & waiters,
+// since flock(2) does not tell who holds a file
+type record struct {
+	lock.HolderInfo
+	// pid of the process holding the flock, which is not necessarily the pid of the metadata
+	ProcessPid int `json:"processPid"`
+}
+
+// infoDir holds the records of a lock, next to its lock file.
+// Like lock files, info directories are never removed.
+func infoDir(lockPath string) string {
+	return strings.TrimSuffix(lockPath, ".lock") + ".info"
+}
+
+// writeRecord returns the path of the record, to be removed once the holder or waiter is done
+func writeRecord(dir, ext string, info lock.HolderInfo) (string, error) {
+	return writeNamedRecord(dir, uuid.New().String()+ext, info)
+}
+
+// writeWaiter records a waiter, or the ticket of a fair waiter, waiters sorting by the time they were written
+func writeWaiter(dir, ext string, info lock.HolderInfo) (string, error) {
+	return writeNamedRecord(dir, fmt.Sprintf("%020d-%s%s", time.Now().UnixNano(), uuid.New().String(), ext), info)
+}
+
+func writeNamedRecord(dir, name string, info lock.HolderInfo) (string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(record{HolderInfo: info, ProcessPid: os.Getpid()})
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, name)
+	// records are written to a temporary file first, so that readers never observe partial records
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return "", err
+	}
+	return path, os.Rename(tmp, path)
+}
+
+func removeRecord(path string) error {
+	if path == "" {
+		return nil
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// describe reads the records of a lock, records of processes that exited without removing them are removed
+func describe(key, dir string) (lock.LockInfo, error) {
+	info := lock.LockInfo{
+		Key:     key,
+		Holders: []lock.HolderInfo{},
+	}
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return info, nil
+	}
+	if err != nil {
+		return info, err
+	}
+	for _, entry := range entries {
+		ext := filepath.Ext(entry.Name())
+		if ext != holderExt && ext != waiterExt && ext != ticketExt {
+			continue
+		}
+		rec, ok, err := readRecord(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return info, err
+		}
+		if !ok {
+			continue
+		}
+		if ext != holderExt {
+			info.Waiters++
+			continue
+		}
+		info.Holders = append(info.Holders, rec.HolderInfo)
+	}
+	slices.SortFunc(info.Holders, func(a, b lock.HolderInfo) int {
+		return a.AcquiredAt.Compare(b.AcquiredAt)
+	})
+	return info, nil
+}
+
+// readRecord reads the record at the path, records of processes that exited without removing them are removed
+func readRecord(path string) (record, bool, error) {
+	var rec record
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return rec, false, nil
+	}
+	if err != nil {
+		return rec, false, err
+	}
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return rec, false, err
+	}
+	if !processAlive(rec.ProcessPid) {
+		_ = removeRecord(path)
+		return rec, false, nil
+	}
+	return rec, true, nil
+}
+
+// queued reports whether a fair waiter wrote its ticket before the given ticket,
+// or whether any fair waiter is queued if ticket is empty
+func queued(dir, ticket string) (bool, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	// entries are sorted by name, so by the time their tickets were written
+	for _, entry := range entries {
+		if filepath.Ext(entry.Name()) != ticketExt {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		if path == ticket {
+			return false, nil
+		}
+		_, ok, err := readRecord(path)
+		if err != nil {
+			return false, err
+		}
+		if ok {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// position returns the number of waiters that were recorded before the waiter
+func position(dir, waiter string) (int, error) {
+	if waiter == "" {
+		return 0, lock.ErrNotQueued
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return 0, err
+	}
+	ahead := 0
+	// entries are sorted by name, so by the time waiters were recorded
+	for _, entry := range entries {
+		ext := filepath.Ext(entry.Name())
+		if ext != waiterExt && ext != ticketExt {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		if path == waiter {
+			return ahead, nil
+		}
+		_, ok, err := readRecord(path)
+		if err != nil {
+			return 0, err
+		}
+		if ok {
+			ahead++
+		}
+	}
+	return 0, lock.ErrNotQueued
+}
+
+// transferee returns the name of the record of the earliest exclusive waiter of the owner
+func transferee(dir, owner string) (string, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", lock.ErrNoTransferee
+	}
+	if err != nil {
+		return "", err
+	}
+	// entries are sorted by name, so by the time waiters were recorded
+	for _, entry := range entries {
+		ext := filepath.Ext(entry.Name())
+		if ext != waiterExt && ext != ticketExt {
+			continue
+		}
+		rec, ok, err := readRecord(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return "", err
+		}
+		if ok && !rec.Shared && rec.Owner == owner {
+			return entry.Name(), nil
+		}
+	}
+	return "", lock.ErrNoTransferee
+}
+
+// removeHolders removes the records of every holder of a lock
+func removeHolders(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var errs error
+	for _, entry := range entries {
+		if filepath.Ext(entry.Name()) == holderExt {
+			errs = errors.Join(errs, removeRecord(filepath.Join(dir, entry.Name())))
+		}
+	}
+	return errs
+}
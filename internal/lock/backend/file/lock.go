@@ -0,0 +1,223 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"github.com/alexandreLamarre/dlock/pkg/lock"
+	"github.com/alexandreLamarre/dlock/pkg/logger"
+	backoffv2 "github.com/lestrrat-go/backoff/v2"
+	"github.com/samber/lo"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Lock holds an flock on its lock file, readers holding a shared flock on the same file
+type Lock struct {
+	path string
+
+	*lock.LockOptions
+
+	scheduler *lock.LockScheduler
+	mutex     *fileMutex
+	token     atomic.Uint64
+
+	lg *slog.Logger
+}
+
+var _ lock.RWLock = (*Lock)(nil)
+var _ lock.Transferer = (*Lock)(nil)
+
+func NewLock(path string, lg *slog.Logger, options *lock.LockOptions) *Lock {
+	return &Lock{
+		path:        path,
+		lg:          lg,
+		LockOptions: options,
+		scheduler:   lock.NewLockScheduler(),
+	}
+}
+
+func retryPolicy(delay time.Duration) *backoffv2.Policy {
+	return lo.ToPtr(backoffv2.Constant(
+		backoffv2.WithMaxRetries(0),
+		backoffv2.WithInterval(delay),
+		backoffv2.WithJitterFactor(0.1),
+	))
+}
+
+func (l *Lock) acquire(ctx context.Context, retrier *backoffv2.Policy, shared bool) (<-chan struct{}, error) {
+	mutex := newFileMutex(l.lg, l.path, shared, l.LockOptions)
+	if retrier != nil && l.Fair {
+		// fair waiters are queued before their first attempt, so that they never overtake earlier waiters
+		return l.wait(ctx, retrier, &mutex, nil)
+	}
+	done, err := l.tryAcquire(&mutex, "")
+	if err == nil {
+		return done, nil
+	}
+	if retrier != nil {
+		return l.wait(ctx, retrier, &mutex, err)
+	}
+	return nil, err
+}
+
+// tryAcquire locks the mutex, unless fair waiters are queued before the given ticket
+func (l *Lock) tryAcquire(mutex *fileMutex, ticket string) (<-chan struct{}, error) {
+	// the lock may have been transferred to a waiter that is not at the head of the queue
+	if l.Fair && !isTransferee(l.path, ticket) {
+		ahead, err := queued(infoDir(l.path), ticket)
+		if err != nil {
+			return nil, err
+		}
+		if ahead {
+			return nil, errLocked
+		}
+	}
+	done, err := mutex.tryLock()
+	if err != nil {
+		return nil, err
+	}
+	l.mutex = mutex
+	l.token.Store(mutex.fencingToken)
+	return done, nil
+}
+
+// wait records the mutex as a waiter of the lock until it is acquired or the context is done
+func (l *Lock) wait(ctx context.Context, retrier *backoffv2.Policy, mutex *fileMutex, curErr error) (<-chan struct{}, error) {
+	var waiter string
+	var err error
+	if l.Fair {
+		waiter, err = writeWaiter(infoDir(l.path), ticketExt, l.Holder(mutex.shared))
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		waiter, err = writeWaiter(infoDir(l.path), waiterExt, l.Holder(mutex.shared))
+		if err != nil {
+			l.lg.With(logger.Err(err)).Warn("failed to record lock waiter")
+		}
+	}
+	mutex.waiter = waiter
+	defer func() {
+		if err := removeRecord(waiter); err != nil {
+			l.lg.With(logger.Err(err)).Warn("failed to remove lock waiter record")
+		}
+	}()
+	defer l.NotifyPosition(ctx, func(context.Context) (int, error) {
+		return position(infoDir(l.path), waiter)
+	})()
+	ret := *retrier
+	acq := ret.Start(ctx)
+	for backoffv2.Continue(acq) {
+		done, err := l.tryAcquire(mutex, waiter)
+		curErr = err
+		if err == nil {
+			return done, nil
+		}
+		if !errors.Is(err, errLocked) {
+			return nil, err
+		}
+	}
+	return nil, errors.Join(ctx.Err(), curErr)
+}
+
+func (l *Lock) lock(ctx context.Context, retrier *backoffv2.Policy, shared bool) (<-chan struct{}, error) {
+	if l.Tracer != nil {
+		ctxSpan, span := l.Tracer.Start(ctx, "Lock/file-lock", trace.WithAttributes())
+		defer span.End()
+		ctx = ctxSpan
+	}
+	// https://github.com/lestrrat-go/backoff/issues/31
+	ctxca, ca := context.WithCancel(ctx)
+	defer ca()
+
+	var closureDone <-chan struct{}
+	if err := l.scheduler.Schedule(func() error {
+		done, err := l.acquire(ctxca, retrier, shared)
+		if err != nil {
+			return err
+		}
+		closureDone = done
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return closureDone, nil
+}
+
+func (l *Lock) tryLock(ctx context.Context, shared bool) (acquired bool, done <-chan struct{}, err error) {
+	closureDone, err := l.lock(ctx, nil, shared)
+	if err != nil {
+		if errors.Is(err, errLocked) {
+			return false, nil, nil
+		}
+		return false, nil, err
+	}
+	return true, closureDone, nil
+}
+
+func (l *Lock) Lock(ctx context.Context) (<-chan struct{}, error) {
+	return l.lock(ctx, retryPolicy(l.RetryDelayOr(LockRetryDelay)), false)
+}
+
+func (l *Lock) TryLock(ctx context.Context) (acquired bool, done <-chan struct{}, err error) {
+	return l.tryLock(ctx, false)
+}
+
+func (l *Lock) Unlock() error {
+	return l.unlock(false)
+}
+
+func (l *Lock) RLock(ctx context.Context) (<-chan struct{}, error) {
+	return l.lock(ctx, retryPolicy(l.RetryDelayOr(LockRetryDelay)), true)
+}
+
+func (l *Lock) TryRLock(ctx context.Context) (acquired bool, done <-chan struct{}, err error) {
+	return l.tryLock(ctx, true)
+}
+
+func (l *Lock) RUnlock() error {
+	return l.unlock(true)
+}
+
+func (l *Lock) unlock(shared bool) error {
+	return l.scheduler.Done(func() error {
+		if l.mutex == nil {
+			panic("never acquired")
+		}
+		if l.mutex.shared != shared {
+			return lock.ErrLockMode
+		}
+		mutex := *l.mutex
+		if err := mutex.unlock(); err != nil {
+			l.lg.Error(err.Error())
+		}
+		l.mutex = nil
+		l.token.Store(0)
+		return nil
+	})
+}
+
+// Transfer does not need the context, since lock files are handed over locally
+func (l *Lock) Transfer(_ context.Context, toOwner string) error {
+	return l.scheduler.Done(func() error {
+		if l.mutex == nil {
+			panic("never acquired")
+		}
+		if l.mutex.shared {
+			return lock.ErrLockMode
+		}
+		if err := l.mutex.transfer(toOwner); err != nil {
+			return err
+		}
+		l.mutex = nil
+		l.token.Store(0)
+		return nil
+	})
+}
+
+func (l *Lock) FencingToken() uint64 {
+	return l.token.Load()
+}
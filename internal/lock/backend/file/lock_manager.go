@@ -0,0 +1,178 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/alexandreLamarre/dlock/pkg/constants"
+	"github.com/alexandreLamarre/dlock/pkg/lock"
+	"github.com/alexandreLamarre/dlock/pkg/lock/broker"
+	"github.com/alexandreLamarre/dlock/pkg/logger"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func init() {
+	broker.RegisterLockBroker(
+		constants.FileLockManager,
+		func(ctx context.Context, l broker.LockBroker) (broker.LockManagers, error) {
+			l.Lg.With("dir", l.Config.FileClientSpec.Dir).Info("acquiring lock directory...")
+			if _, err := NewLockManager(ctx, l.Config.FileClientSpec.Dir, broker.DefaultPrefix, l.Tracer, l.Lg); err != nil {
+				l.Lg.With(logger.Err(err)).Warn("failed to acquire lock directory")
+				return nil, err
+			}
+			l.Lg.Info("acquired lock directory")
+			return func(prefix string) (lock.LockManager, error) {
+				return NewLockManager(ctx, l.Config.FileClientSpec.Dir, prefix, l.Tracer, l.Lg)
+			}, nil
+		},
+	)
+}
+
+// LockManager implements locks with flock(2) on files of a local directory, so locks are only
+// shared between processes of the same host.
+// The kernel releases the locks of a process when it exits, whether it crashes or not.
+type LockManager struct {
+	ctx    context.Context
+	dir    string
+	prefix string
+	tracer trace.Tracer
+
+	lg *slog.Logger
+
+	reentrant *lock.ReentrantLocks
+}
+
+var _ lock.LockManager = (*LockManager)(nil)
+
+func NewLockManager(
+	ctx context.Context,
+	dir string,
+	prefix string,
+	tracer trace.Tracer,
+	lg *slog.Logger,
+) (*LockManager, error) {
+	if dir == "" {
+		return nil, errors.New("lock directory is required")
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create lock directory : %w", err)
+	}
+	return &LockManager{
+		ctx:       ctx,
+		dir:       dir,
+		prefix:    prefix,
+		tracer:    tracer,
+		lg:        lg,
+		reentrant: lock.NewReentrantLocks(),
+	}, nil
+}
+
+func (l *LockManager) Health(_ context.Context) (conditions []string, err error) {
+	f, err := os.CreateTemp(l.dir, ".health-*")
+	if err != nil {
+		return nil, err
+	}
+	return []string{}, errors.Join(f.Close(), os.Remove(f.Name()))
+}
+
+// lock files are never removed, since removing a file while it is locked would let
+// another process lock a new file at the same path. They are only replaced by ForceRelease.
+func (l *LockManager) lockPath(key string) string {
+	return filepath.Join(l.dir, l.prefix+"-"+url.PathEscape(key)+".lock")
+}
+
+func (l *LockManager) ListLocks(_ context.Context, prefix string) ([]lock.LockInfo, error) {
+	entries, err := os.ReadDir(l.dir)
+	if err != nil {
+		return nil, err
+	}
+	infos := []lock.LockInfo{}
+	for _, entry := range entries {
+		name, ok := strings.CutPrefix(entry.Name(), l.prefix+"-")
+		if !ok || !entry.IsDir() {
+			continue
+		}
+		name, ok = strings.CutSuffix(name, ".info")
+		if !ok {
+			continue
+		}
+		key, err := url.PathUnescape(name)
+		if err != nil || !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		info, err := describe(key, filepath.Join(l.dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		if len(info.Holders) > 0 || info.Waiters > 0 {
+			infos = append(infos, info)
+		}
+	}
+	return infos, nil
+}
+
+func (l *LockManager) DescribeLock(_ context.Context, key string) (lock.LockInfo, error) {
+	return describe(key, infoDir(l.lockPath(key)))
+}
+
+// ForceRelease replaces the lock file, since the flocks of other processes can't be released.
+// Evicted holders notice it within LockCheckInterval, and their flocks on the replaced file are harmless.
+func (l *LockManager) ForceRelease(_ context.Context, key, reason string) (lock.LockInfo, error) {
+	path := l.lockPath(key)
+	var info lock.LockInfo
+	if err := replaceLockFile(path, func() error {
+		var err error
+		info, err = describe(key, infoDir(path))
+		if err != nil {
+			return err
+		}
+		return removeHolders(infoDir(path))
+	}); err != nil {
+		return lock.LockInfo{}, err
+	}
+	l.lg.With("key", key, "reason", reason, "holders", len(info.Holders)).Warn("force released lock")
+	return info, nil
+}
+
+// Watch polls the holders of the locks every lock.WatchInterval, since other processes
+// never notify changes to the lock files
+func (l *LockManager) Watch(ctx context.Context, prefix string) (<-chan lock.WatchEvent, error) {
+	return lock.WatchDescriptions(ctx, l, prefix, nil)
+}
+
+func (l *LockManager) semaphoreDir(key string) string {
+	return filepath.Join(l.dir, l.prefix+".semaphore-"+url.PathEscape(key))
+}
+
+func (l *LockManager) NewLock(key string, opts ...lock.LockOption) lock.Lock {
+	options := lock.DefaultLockOptions()
+	options.Apply(opts...)
+	return l.reentrant.NewLock(key, options, func() lock.Lock {
+		return NewLock(l.lockPath(key), l.lg.With("key", key), options)
+	})
+}
+
+// RWLocks share the lock file of the exclusive lock returned by NewLock
+func (l *LockManager) NewRWLock(key string, opts ...lock.LockOption) lock.RWLock {
+	options := lock.DefaultLockOptions()
+	options.Apply(opts...)
+	return NewLock(l.lockPath(key), l.lg.With("key", key), options)
+}
+
+func (l *LockManager) NewMultiLock(keys []string, opts ...lock.LockOption) lock.Lock {
+	return lock.NewMultiLock(keys, func(key string) lock.Lock {
+		return l.NewLock(key, opts...)
+	})
+}
+
+func (l *LockManager) NewSemaphore(key string, capacity int64, opts ...lock.LockOption) lock.Semaphore {
+	options := lock.DefaultLockOptions()
+	options.Apply(opts...)
+	return NewSemaphore(l.semaphoreDir(key), capacity, l.lg.With("key", key), options)
+}
@@ -0,0 +1,297 @@
+package file
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/alexandreLamarre/dlock/pkg/lock"
+	"github.com/alexandreLamarre/dlock/pkg/logger"
+	"github.com/samber/lo"
+)
+
+var (
+	LockRetryDelay = 50 * time.Millisecond
+	// LockCheckInterval is the interval at which holders check that their lock file was not replaced by ForceRelease,
+	// overridden by the KeepaliveInterval of locks. Flocks never expire, so the TTL of locks does not apply.
+	LockCheckInterval = 500 * time.Millisecond
+)
+
+var errLocked = errors.New("file is locked by someone else")
+
+// encapsulates stateful information and tasks required for holding a lock on a file
+type fileMutex struct {
+	lg *slog.Logger
+
+	path string
+	// shared mutexes hold the read side of a RWLock
+	shared bool
+
+	f *os.File
+	// counter stored in the lock file, incremented by every exclusive holder
+	fencingToken uint64
+	// path of the record describing this holder
+	record string
+	// path of the record of the waiter acquiring the mutex, that locks can be transferred to
+	waiter string
+
+	internalDone chan struct{}
+	*lock.LockOptions
+}
+
+func newFileMutex(
+	lg *slog.Logger,
+	path string,
+	shared bool,
+	opts *lock.LockOptions,
+) fileMutex {
+	return fileMutex{
+		lg:           lg.With("path", path, "shared", shared),
+		path:         path,
+		shared:       shared,
+		internalDone: make(chan struct{}),
+		LockOptions:  opts,
+	}
+}
+
+func (m *fileMutex) tryLock() (<-chan struct{}, error) {
+	f, err := m.open()
+	if err != nil {
+		return nil, err
+	}
+	m.f = f
+	record, err := writeRecord(infoDir(m.path), holderExt, m.Holder(m.shared))
+	if err != nil {
+		m.lg.With(logger.Err(err)).Warn("failed to record lock holder")
+	}
+	m.record = record
+	return lo.Async(m.keepalive), nil
+}
+
+// open locks the lock file, retrying when the file was replaced by ForceRelease after it was opened
+func (m *fileMutex) open() (*os.File, error) {
+	for {
+		f, err := os.OpenFile(m.path, os.O_RDWR|os.O_CREATE, 0o644)
+		if err != nil {
+			return nil, err
+		}
+		if err := tryFlock(f, m.shared); err != nil {
+			return nil, errors.Join(err, f.Close())
+		}
+		if err := m.claimTransfer(f); err != nil {
+			return nil, errors.Join(err, funlock(f), f.Close())
+		}
+		// the counter is raised before checking that the file is current : ForceRelease copies the counter of the
+		// file once it replaced it, so the tokens raised before the file is found current are carried over to its
+		// replacement, while the tokens raised on a replaced file are never issued
+		var token uint64
+		if !m.shared {
+			if token, err = nextFencingToken(f); err != nil {
+				return nil, errors.Join(err, funlock(f), f.Close())
+			}
+		}
+		replaced, err := isReplaced(f, m.path)
+		if err != nil {
+			return nil, errors.Join(err, funlock(f), f.Close())
+		}
+		if !replaced {
+			m.fencingToken = token
+			return f, nil
+		}
+		if err := errors.Join(funlock(f), f.Close()); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// isReplaced reports whether the path no longer refers to the open file
+func isReplaced(f *os.File, path string) (bool, error) {
+	current, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	held, err := f.Stat()
+	if err != nil {
+		return false, err
+	}
+	return !os.SameFile(current, held), nil
+}
+
+// nextFencingToken increments the counter stored at the start of the lock file,
+// exclusive holders being serialized by the lock itself
+func nextFencingToken(f *os.File) (uint64, error) {
+	var buf [8]byte
+	if _, err := f.ReadAt(buf[:], 0); err != nil && !errors.Is(err, io.EOF) {
+		return 0, fmt.Errorf("failed to read fencing token : %w", err)
+	}
+	token := binary.BigEndian.Uint64(buf[:]) + 1
+	binary.BigEndian.PutUint64(buf[:], token)
+	if _, err := f.WriteAt(buf[:], 0); err != nil {
+		return 0, fmt.Errorf("failed to write fencing token : %w", err)
+	}
+	return token, nil
+}
+
+// transferredTo returns the name of the waiter record the lock file was transferred to, stored after its fencing
+// token. The transfer is pending until the waiter acquires the lock file, or until its record is gone.
+func transferredTo(f *os.File) (string, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+	if info.Size() <= 8 {
+		return "", nil
+	}
+	buf := make([]byte, info.Size()-8)
+	if _, err := f.ReadAt(buf, 8); err != nil && !errors.Is(err, io.EOF) {
+		return "", fmt.Errorf("failed to read transfer : %w", err)
+	}
+	return string(buf), nil
+}
+
+// isTransferee reports whether the lock file was transferred to the waiter, without locking it
+func isTransferee(path, waiter string) bool {
+	if waiter == "" {
+		return false
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	to, err := transferredTo(f)
+	return err == nil && to == filepath.Base(waiter)
+}
+
+// claimTransfer fails with errLocked while the lock file is transferred to another live waiter,
+// and clears the transfer once the lock file is exclusively locked by its waiter or the transfer is stale
+func (m *fileMutex) claimTransfer(f *os.File) error {
+	to, err := transferredTo(f)
+	if err != nil || to == "" {
+		return err
+	}
+	if m.waiter == "" || to != filepath.Base(m.waiter) {
+		_, live, err := readRecord(filepath.Join(infoDir(m.path), to))
+		if err != nil {
+			return err
+		}
+		if live {
+			return errLocked
+		}
+	}
+	if m.shared {
+		return nil
+	}
+	return f.Truncate(8)
+}
+
+// transfer hands the lock file over to the earliest exclusive waiter of the owner, and unlocks it
+func (m *fileMutex) transfer(toOwner string) error {
+	replaced, err := isReplaced(m.f, m.path)
+	if err != nil {
+		return err
+	}
+	if replaced {
+		return lock.ErrNotHeld
+	}
+	to, err := transferee(infoDir(m.path), toOwner)
+	if err != nil {
+		return err
+	}
+	if err := m.f.Truncate(8); err != nil {
+		return err
+	}
+	if _, err := m.f.WriteAt([]byte(to), 8); err != nil {
+		return fmt.Errorf("failed to write transfer : %w", err)
+	}
+	return m.unlock()
+}
+
+// locks are held until they are unlocked or the process exits, so the expired chan
+// only fires on unlock, or once the lock file is replaced by ForceRelease
+func (m *fileMutex) keepalive() struct{} {
+	t := time.NewTicker(m.KeepaliveIntervalOr(LockCheckInterval))
+	defer t.Stop()
+	for {
+		select {
+		case <-m.internalDone:
+			return struct{}{}
+		case <-t.C:
+			replaced, err := isReplaced(m.f, m.path)
+			if err != nil {
+				m.lg.With(logger.Err(err)).Warn("failed to check lock file")
+				continue
+			}
+			if replaced {
+				m.lg.Warn("lock file was replaced, the lock was force released")
+				return struct{}{}
+			}
+		}
+	}
+}
+
+// replaceLockFile evicts the holders of the lock file by atomically replacing it, while keeping its fencing
+// token. The new file stays locked until evict returns, so that evict only observes the evicted holders.
+func replaceLockFile(path string, evict func() error) error {
+	old, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return evict()
+	}
+	if err != nil {
+		return err
+	}
+	defer old.Close()
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := tryFlock(f, false); err != nil {
+		return errors.Join(err, os.Remove(f.Name()))
+	}
+	defer funlock(f) //nolint:errcheck
+	if err := os.Rename(f.Name(), path); err != nil {
+		return errors.Join(err, os.Remove(f.Name()))
+	}
+	// evicted holders can only have issued tokens before the file was replaced
+	var buf [8]byte
+	if _, err := old.ReadAt(buf[:], 0); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("failed to read fencing token : %w", err)
+	}
+	if _, err := f.WriteAt(buf[:], 0); err != nil {
+		return fmt.Errorf("failed to write fencing token : %w", err)
+	}
+	return evict()
+}
+
+func (m *fileMutex) teardown() {
+	defer close(m.internalDone)
+	select {
+	case m.internalDone <- struct{}{}:
+	default:
+	}
+}
+
+// closing the file always releases the lock, even if the explicit unlock fails
+func (m *fileMutex) unlock() error {
+	defer m.teardown()
+	if m.f == nil {
+		return errors.New("mutex not acquired")
+	}
+	if err := removeRecord(m.record); err != nil {
+		m.lg.With(logger.Err(err)).Warn("failed to remove lock holder record")
+	}
+	unlockErr := funlock(m.f)
+	if unlockErr != nil {
+		m.lg.With(logger.Err(unlockErr)).Warn("failed to unlock file, releasing it by closing it")
+	}
+	return m.f.Close()
+}
@@ -0,0 +1,203 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/alexandreLamarre/dlock/pkg/lock"
+	"github.com/alexandreLamarre/dlock/pkg/logger"
+	backoffv2 "github.com/lestrrat-go/backoff/v2"
+	"github.com/samber/lo"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// semaphoreMutex holds one exclusive flock per unit, on the slot files of the semaphore's directory
+type semaphoreMutex struct {
+	lg *slog.Logger
+
+	dir      string
+	weight   int64
+	capacity int64
+
+	held         []*os.File
+	internalDone chan struct{}
+}
+
+func newSemaphoreMutex(lg *slog.Logger, dir string, weight, capacity int64) semaphoreMutex {
+	return semaphoreMutex{
+		lg:           lg.With("weight", weight),
+		dir:          dir,
+		weight:       weight,
+		capacity:     capacity,
+		internalDone: make(chan struct{}),
+	}
+}
+
+// tryLock holds the first available slots, releasing the slots it already holds if not enough of them are available
+func (s *semaphoreMutex) tryLock() (<-chan struct{}, error) {
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return nil, err
+	}
+	for slot := range s.capacity {
+		f, err := os.OpenFile(filepath.Join(s.dir, strconv.FormatInt(slot, 10)), os.O_RDWR|os.O_CREATE, 0o644)
+		if err != nil {
+			return nil, errors.Join(err, s.release())
+		}
+		if err := tryFlock(f, false); err != nil {
+			if closeErr := f.Close(); closeErr != nil {
+				s.lg.With(logger.Err(closeErr)).Warn("failed to close slot file")
+			}
+			if errors.Is(err, errLocked) {
+				continue
+			}
+			return nil, errors.Join(err, s.release())
+		}
+		s.held = append(s.held, f)
+		if int64(len(s.held)) == s.weight {
+			return lo.Async(s.keepalive), nil
+		}
+	}
+	if err := s.release(); err != nil {
+		return nil, err
+	}
+	return nil, errLocked
+}
+
+func (s *semaphoreMutex) keepalive() struct{} {
+	<-s.internalDone
+	return struct{}{}
+}
+
+func (s *semaphoreMutex) teardown() {
+	defer close(s.internalDone)
+	select {
+	case s.internalDone <- struct{}{}:
+	default:
+	}
+}
+
+// release closes every slot file held, which always releases their locks
+func (s *semaphoreMutex) release() error {
+	var errs []error
+	for _, f := range s.held {
+		if err := f.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	s.held = nil
+	return errors.Join(errs...)
+}
+
+func (s *semaphoreMutex) unlock() error {
+	defer s.teardown()
+	return s.release()
+}
+
+// Semaphore's units are slot files in a directory per key, each held with an exclusive flock
+type Semaphore struct {
+	dir      string
+	capacity int64
+
+	*lock.LockOptions
+
+	scheduler *lock.LockScheduler
+	mutex     *semaphoreMutex
+
+	lg *slog.Logger
+}
+
+var _ lock.Semaphore = (*Semaphore)(nil)
+
+func NewSemaphore(dir string, capacity int64, lg *slog.Logger, options *lock.LockOptions) *Semaphore {
+	return &Semaphore{
+		dir:         dir,
+		capacity:    capacity,
+		lg:          lg.With("capacity", capacity),
+		LockOptions: options,
+		scheduler:   lock.NewLockScheduler(),
+	}
+}
+
+func (s *Semaphore) Capacity() int64 {
+	return s.capacity
+}
+
+func (s *Semaphore) Acquire(ctx context.Context, n int64) (<-chan struct{}, error) {
+	return s.acquire(ctx, n, retryPolicy(s.RetryDelayOr(LockRetryDelay)))
+}
+
+func (s *Semaphore) TryAcquire(ctx context.Context, n int64) (acquired bool, done <-chan struct{}, err error) {
+	done, err = s.acquire(ctx, n, nil)
+	if err != nil {
+		if errors.Is(err, errLocked) {
+			return false, nil, nil
+		}
+		return false, nil, err
+	}
+	return true, done, nil
+}
+
+func (s *Semaphore) acquire(ctx context.Context, n int64, retrier *backoffv2.Policy) (<-chan struct{}, error) {
+	if err := lock.ValidateWeight(n, s.capacity); err != nil {
+		return nil, err
+	}
+	if s.Tracer != nil {
+		ctxSpan, span := s.Tracer.Start(ctx, "Acquire/file-semaphore", trace.WithAttributes())
+		defer span.End()
+		ctx = ctxSpan
+	}
+	// https://github.com/lestrrat-go/backoff/issues/31
+	ctxca, ca := context.WithCancel(ctx)
+	defer ca()
+
+	var closureDone <-chan struct{}
+	if err := s.scheduler.Schedule(func() error {
+		mutex := newSemaphoreMutex(s.lg, s.dir, n, s.capacity)
+		done, err := mutex.tryLock()
+		curErr := err
+		if err == nil {
+			s.mutex = &mutex
+			closureDone = done
+			return nil
+		}
+		if retrier == nil {
+			return curErr
+		}
+		ret := *retrier
+		acq := ret.Start(ctxca)
+		for backoffv2.Continue(acq) {
+			done, err := mutex.tryLock()
+			curErr = err
+			if err == nil {
+				s.mutex = &mutex
+				closureDone = done
+				return nil
+			}
+			if !errors.Is(err, errLocked) {
+				return err
+			}
+		}
+		return errors.Join(ctxca.Err(), curErr)
+	}); err != nil {
+		return nil, err
+	}
+	return closureDone, nil
+}
+
+func (s *Semaphore) Release() error {
+	return s.scheduler.Done(func() error {
+		if s.mutex == nil {
+			panic("never acquired")
+		}
+		mutex := *s.mutex
+		if err := mutex.unlock(); err != nil {
+			s.lg.Error(err.Error())
+		}
+		s.mutex = nil
+		return nil
+	})
+}
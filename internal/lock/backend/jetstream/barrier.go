@@ -0,0 +1,296 @@
+package jetstream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/alexandreLamarre/dlock/pkg/lock"
+	"github.com/alexandreLamarre/dlock/pkg/logger"
+	"github.com/google/uuid"
+	"github.com/nats-io/nats.go"
+)
+
+// barrierKey is the name of the stream each waiting participant of the barrier holds a consumer on
+func barrierKey(prefix, key string) string {
+	return prefix + "_barrier-" + key
+}
+
+// latchKey is the name of the stream holding the count of the latch, as its only message
+func latchKey(prefix, key string) string {
+	return prefix + "_latch-" + key
+}
+
+// Barrier registers each waiting participant as a consumer of the barrier's stream, kept alive by its subscription
+// like the consumers of lock holders. The participant completing the barrier publishes a message that is delivered
+// to the consumers of every registered participant.
+type Barrier struct {
+	js     nats.JetStreamContext
+	prefix string
+	key    string
+	n      int64
+	lg     *slog.Logger
+
+	*lock.LockOptions
+}
+
+var _ lock.Barrier = (*Barrier)(nil)
+
+func NewBarrier(
+	js nats.JetStreamContext,
+	prefix, key string,
+	n int64,
+	lg *slog.Logger,
+	opts *lock.LockOptions,
+) *Barrier {
+	return &Barrier{
+		js:          js,
+		prefix:      prefix,
+		key:         key,
+		n:           n,
+		lg:          lg.With("barrier", key, "participants", n),
+		LockOptions: opts,
+	}
+}
+
+func (b *Barrier) stream() string {
+	return barrierKey(b.prefix, b.key)
+}
+
+func (b *Barrier) Wait(ctx context.Context) error {
+	if err := lock.ValidateParticipants(b.n); err != nil {
+		return err
+	}
+	if b.Tracer != nil {
+		ctxSpan, span := b.Tracer.Start(ctx, "Wait/jetstream-barrier")
+		defer span.End()
+		ctx = ctxSpan
+	}
+	if _, err := b.js.AddStream(&nats.StreamConfig{
+		Name:      b.stream(),
+		Retention: nats.InterestPolicy,
+		Subjects:  []string{fmt.Sprintf("%s.reached", b.stream())},
+	}); err != nil {
+		return err
+	}
+	participant := uuid.New().String()
+	// participants only receive the messages published once they registered, i.e. the barrier they reach
+	if _, err := b.js.AddConsumer(b.stream(), &nats.ConsumerConfig{
+		Durable:           participant,
+		AckPolicy:         nats.AckExplicitPolicy,
+		DeliverPolicy:     nats.DeliverNewPolicy,
+		InactiveThreshold: validity(b.LockOptions),
+		DeliverSubject:    participant,
+		Heartbeat:         heartbeat(b.LockOptions),
+	}); err != nil {
+		return err
+	}
+	defer b.leave(participant)
+	msgQ := make(chan *nats.Msg, 1)
+	sub, err := b.js.ChanSubscribe(participant, msgQ, nats.Bind(b.stream(), participant))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := sub.Unsubscribe(); err != nil {
+			b.lg.With(logger.Err(err)).Debug("failed to unsubscribe from consumer")
+		}
+	}()
+	t := time.NewTicker(b.RetryDelayOr(LockRetryDelay))
+	defer t.Stop()
+	for {
+		// participants that registered concurrently may not see each other, so they check again until reached
+		if err := b.reach(ctx); err != nil {
+			b.lg.With(logger.Err(err)).Debug("failed to check the participants of the barrier")
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case msg := <-msgQ:
+			if err := msg.Ack(); err != nil {
+				b.lg.Warn(fmt.Sprintf("failed to ack : %s", err.Error()))
+			}
+			return nil
+		case <-t.C:
+		}
+	}
+}
+
+// reach publishes the message reaching the barrier once enough participants wait on it. Participants wait while
+// their consumer is bound to their subscription and was not delivered a message, so participants that crashed
+// are not counted even before jetstream removes their consumer.
+func (b *Barrier) reach(ctx context.Context) error {
+	info, err := b.js.StreamInfo(b.stream(), nats.Context(ctx))
+	if err != nil {
+		return err
+	}
+	waiting := int64(0)
+	for _, consumer := range consumers(ctx, b.js, b.stream()) {
+		if consumer.PushBound && consumer.NumPending == 0 && consumer.NumAckPending == 0 {
+			waiting++
+		}
+	}
+	if waiting < b.n {
+		return nil
+	}
+	// participants reaching the barrier concurrently publish a single message
+	_, err = b.js.Publish(
+		fmt.Sprintf("%s.reached", b.stream()),
+		nil,
+		nats.Context(ctx),
+		nats.ExpectLastSequence(info.State.LastSeq),
+	)
+	var apiErr *nats.APIError
+	if errors.As(err, &apiErr) && apiErr.ErrorCode == nats.JSErrCodeStreamWrongLastSequence {
+		return nil
+	}
+	return err
+}
+
+func (b *Barrier) leave(participant string) {
+	if err := b.js.DeleteConsumer(b.stream(), participant); err != nil && !errors.Is(err, nats.ErrConsumerNotFound) {
+		b.lg.With(logger.Err(err)).Warn("failed to unregister barrier participant, it will expire")
+	}
+}
+
+// CountDownLatch holds the count of the latch as the only message of its stream, messages expiring after
+// LatchRetention. Counting down publishes the next count only if no other count was published since it was read.
+type CountDownLatch struct {
+	js     nats.JetStreamContext
+	prefix string
+	key    string
+	n      int64
+	lg     *slog.Logger
+
+	*lock.LockOptions
+}
+
+var _ lock.CountDownLatch = (*CountDownLatch)(nil)
+
+func NewCountDownLatch(
+	js nats.JetStreamContext,
+	prefix, key string,
+	n int64,
+	lg *slog.Logger,
+	opts *lock.LockOptions,
+) *CountDownLatch {
+	return &CountDownLatch{
+		js:          js,
+		prefix:      prefix,
+		key:         key,
+		n:           n,
+		lg:          lg.With("latch", key, "count", n),
+		LockOptions: opts,
+	}
+}
+
+func (l *CountDownLatch) stream() string {
+	return latchKey(l.prefix, l.key)
+}
+
+func (l *CountDownLatch) subject() string {
+	return fmt.Sprintf("%s.count", l.stream())
+}
+
+func (l *CountDownLatch) init() error {
+	_, err := l.js.AddStream(&nats.StreamConfig{
+		Name:     l.stream(),
+		Subjects: []string{l.subject()},
+		MaxMsgs:  1,
+		MaxAge:   lock.LatchRetention,
+	})
+	return err
+}
+
+// count returns the count of the latch along with the sequence & time of its message,
+// latches without a message being at their initial count
+func (l *CountDownLatch) count(ctx context.Context) (int64, uint64, time.Time, error) {
+	msg, err := l.js.GetLastMsg(l.stream(), l.subject(), nats.Context(ctx))
+	if errors.Is(err, nats.ErrMsgNotFound) {
+		return l.n, 0, time.Time{}, nil
+	}
+	if err != nil {
+		return 0, 0, time.Time{}, err
+	}
+	count, err := strconv.ParseInt(string(msg.Data), 10, 64)
+	if err != nil {
+		return 0, 0, time.Time{}, fmt.Errorf("invalid count for latch %s : %w", l.key, err)
+	}
+	return count, msg.Sequence, msg.Time, nil
+}
+
+func (l *CountDownLatch) publish(ctx context.Context, count int64, seq uint64) error {
+	_, err := l.js.Publish(
+		l.subject(),
+		[]byte(strconv.FormatInt(count, 10)),
+		nats.Context(ctx),
+		nats.ExpectLastSequencePerSubject(seq),
+	)
+	return err
+}
+
+func (l *CountDownLatch) CountDown(ctx context.Context) error {
+	if err := lock.ValidateParticipants(l.n); err != nil {
+		return err
+	}
+	if l.Tracer != nil {
+		ctxSpan, span := l.Tracer.Start(ctx, "CountDown/jetstream-latch")
+		defer span.End()
+		ctx = ctxSpan
+	}
+	if err := l.init(); err != nil {
+		return err
+	}
+	for {
+		count, seq, _, err := l.count(ctx)
+		if err != nil || count == 0 {
+			return err
+		}
+		err = l.publish(ctx, count-1, seq)
+		var apiErr *nats.APIError
+		if errors.As(err, &apiErr) && apiErr.ErrorCode == nats.JSErrCodeStreamWrongLastSequence {
+			// another process counted the latch down since we read its count
+			continue
+		}
+		return err
+	}
+}
+
+// Await polls the count of the latch, publishing it again when it is about to expire
+func (l *CountDownLatch) Await(ctx context.Context) error {
+	if err := lock.ValidateParticipants(l.n); err != nil {
+		return err
+	}
+	if l.Tracer != nil {
+		ctxSpan, span := l.Tracer.Start(ctx, "Await/jetstream-latch")
+		defer span.End()
+		ctx = ctxSpan
+	}
+	if err := l.init(); err != nil {
+		return err
+	}
+	t := time.NewTicker(l.RetryDelayOr(LockRetryDelay))
+	defer t.Stop()
+	for {
+		count, seq, published, err := l.count(ctx)
+		if err != nil {
+			l.lg.With(logger.Err(err)).Debug("failed to check the count of the latch")
+		}
+		if err == nil && count == 0 {
+			return nil
+		}
+		if err == nil && seq > 0 && time.Since(published) > lock.LatchRetention/2 {
+			if err := l.publish(ctx, count, seq); err != nil {
+				l.lg.With(logger.Err(err)).Debug("failed to keep the latch alive")
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
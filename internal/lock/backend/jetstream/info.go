@@ -0,0 +1,247 @@
+package jetstream
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alexandreLamarre/dlock/pkg/lock"
+	"github.com/alexandreLamarre/dlock/pkg/logger"
+	"github.com/nats-io/nats.go"
+)
+
+// holderMetadataKey is the consumer metadata entry holding the HolderInfo of the consumer's holder
+const holderMetadataKey = "dlock.holder"
+
+// waitersKey is the name of the stream blocking acquisitions hold a consumer on while they wait for the lock
+func waitersKey(prefix, key string) string {
+	return prefix + "_waiters-" + key
+}
+
+func (j *jetstreamMutex) waitersKey() string {
+	return waitersKey(j.prefix, j.key)
+}
+
+func (j *jetstreamMutex) metadata() map[string]string {
+	data, err := json.Marshal(j.Holder(j.shared))
+	if err != nil {
+		j.lg.With(logger.Err(err)).Warn("failed to encode holder info")
+		return nil
+	}
+	return map[string]string{holderMetadataKey: string(data)}
+}
+
+// ticketMetadataKey is the waiter consumer metadata entry holding the ticket of fair waiters
+const ticketMetadataKey = "dlock.ticket"
+
+// queueKey is the name of the stream issuing the tickets of fair waiters, as the sequences of its messages.
+// The locks of the prefix share the stream, so that it does not outlive the queue of any of them.
+func (j *jetstreamMutex) queueKey() string {
+	return j.prefix + "_queue"
+}
+
+// enqueue issues the ticket of a fair waiter. Tickets are only ever compared between the waiters of a lock, and the
+// sequences of the stream always grow, so the stream keeps a single message.
+func (j *jetstreamMutex) enqueue() error {
+	if _, err := j.js.AddStream(&nats.StreamConfig{
+		Name:     j.queueKey(),
+		Subjects: []string{fmt.Sprintf("%s.ticket", j.queueKey())},
+		MaxMsgs:  1,
+	}); err != nil {
+		return err
+	}
+	ack, err := j.js.Publish(fmt.Sprintf("%s.ticket", j.queueKey()), nil)
+	if err != nil {
+		return err
+	}
+	j.ticket = ack.Sequence
+	return nil
+}
+
+// wait registers the mutex as a waiter of the lock, waiters that crash are removed after their validity
+func (j *jetstreamMutex) wait() {
+	if _, err := j.js.AddStream(&nats.StreamConfig{
+		Name:      j.waitersKey(),
+		Retention: nats.InterestPolicy,
+		Subjects:  []string{fmt.Sprintf("%s.wait", j.waitersKey())},
+	}); err != nil {
+		j.lg.With(logger.Err(err)).Debug("failed to register waiter")
+		return
+	}
+	// waiters have not acquired the lock yet, and their config must not change when they register again
+	data, err := json.Marshal(lock.HolderInfo{Metadata: j.Metadata, Shared: j.shared})
+	if err != nil {
+		j.lg.With(logger.Err(err)).Debug("failed to encode waiter info")
+	}
+	cfg := &nats.ConsumerConfig{
+		Durable:           j.uuid,
+		AckPolicy:         nats.AckExplicitPolicy,
+		InactiveThreshold: validity(j.LockOptions),
+		Metadata:          map[string]string{holderMetadataKey: string(data)},
+	}
+	if j.ticket > 0 {
+		cfg.Metadata[ticketMetadataKey] = strconv.FormatUint(j.ticket, 10)
+	}
+	if _, err := j.js.AddConsumer(j.waitersKey(), cfg); err != nil {
+		j.lg.With(logger.Err(err)).Debug("failed to register waiter")
+	}
+}
+
+// queued reports whether a fair waiter was issued a ticket before ours,
+// or whether any fair waiter is queued if we have no ticket
+func (j *jetstreamMutex) queued() (bool, error) {
+	for info := range j.js.ConsumersInfo(j.waitersKey()) {
+		data, ok := info.Config.Metadata[ticketMetadataKey]
+		if !ok || info.Name == j.uuid {
+			continue
+		}
+		ticket, err := strconv.ParseUint(data, 10, 64)
+		if err != nil {
+			return false, fmt.Errorf("invalid ticket for %s : %w", j.key, err)
+		}
+		if j.ticket == 0 || ticket < j.ticket {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// position returns the number of waiter consumers created before ours, adding a consumer that already exists
+// keeps its creation time
+func (j *jetstreamMutex) position(ctx context.Context) (int, error) {
+	waiters := consumers(ctx, j.js, j.waitersKey())
+	i := slices.IndexFunc(waiters, func(info *nats.ConsumerInfo) bool {
+		return info.Name == j.uuid
+	})
+	if i < 0 {
+		return 0, lock.ErrNotQueued
+	}
+	ahead := 0
+	for _, info := range waiters {
+		if info.Created.Before(waiters[i].Created) {
+			ahead++
+		}
+	}
+	return ahead, nil
+}
+
+// unwait unregisters the waiter, and releases the lock if it was transferred to the waiter but not claimed
+func (j *jetstreamMutex) unwait() {
+	if err := j.js.DeleteConsumer(j.waitersKey(), j.uuid); !j.isReleased(err) {
+		j.lg.With(logger.Err(err)).Warn("failed to unregister waiter")
+	}
+	j.abandonTransfer()
+}
+
+func consumers(ctx context.Context, js nats.JetStreamContext, stream string) []*nats.ConsumerInfo {
+	infos := []*nats.ConsumerInfo{}
+	for info := range js.ConsumersInfo(stream, nats.Context(ctx)) {
+		infos = append(infos, info)
+	}
+	return infos
+}
+
+// describe reports the consumers of the writer & readers streams of the lock as its holders.
+// Jetstream expires holders on inactivity rather than on a deadline, so their TTL is left unset.
+func describe(ctx context.Context, js nats.JetStreamContext, prefix, key string) (lock.LockInfo, error) {
+	info := lock.LockInfo{
+		Key:     key,
+		Holders: []lock.HolderInfo{},
+	}
+	mutex := jetstreamMutex{prefix: prefix, key: key}
+	for _, stream := range []string{mutex.Key(), mutex.readersKey()} {
+		for _, consumer := range consumers(ctx, js, stream) {
+			holder := lock.HolderInfo{
+				Shared:     stream == mutex.readersKey(),
+				AcquiredAt: consumer.Created,
+			}
+			if data, ok := consumer.Config.Metadata[holderMetadataKey]; ok {
+				if err := json.Unmarshal([]byte(data), &holder); err != nil {
+					return info, fmt.Errorf("invalid holder info for %s : %w", key, err)
+				}
+			}
+			info.Holders = append(info.Holders, holder)
+		}
+	}
+	slices.SortFunc(info.Holders, func(a, b lock.HolderInfo) int {
+		return a.AcquiredAt.Compare(b.AcquiredAt)
+	})
+	info.Waiters = len(consumers(ctx, js, waitersKey(prefix, key)))
+	return info, nil
+}
+
+// evictedHeader is set on the messages telling holders they were evicted by ForceRelease
+const evictedHeader = "Dlock-Evicted"
+
+// forceRelease deletes the consumers of the lock's holders. Each holder is first sent an eviction message on its
+// lease subject, so that its expired channel fires even though jetstream does not notify deleted consumers.
+func forceRelease(ctx context.Context, js nats.JetStreamContext, prefix, key, reason string) (lock.LockInfo, error) {
+	info, err := describe(ctx, js, prefix, key)
+	if err != nil {
+		return info, err
+	}
+	mutex := jetstreamMutex{prefix: prefix, key: key}
+	var errs error
+	for _, stream := range []string{mutex.Key(), mutex.readersKey()} {
+		for _, consumer := range consumers(ctx, js, stream) {
+			evict(ctx, js, stream, consumer.Name, reason)
+			if err := js.DeleteConsumer(stream, consumer.Name, nats.Context(ctx)); err != nil &&
+				!errors.Is(err, nats.ErrConsumerNotFound) {
+				errs = errors.Join(errs, err)
+			}
+		}
+	}
+	return info, errs
+}
+
+// evict waits for a bounded time for the eviction message to be delivered to the holder,
+// holders that are not connected anymore never receive it
+func evict(ctx context.Context, js nats.JetStreamContext, stream, consumer, reason string) {
+	msg := nats.NewMsg(fmt.Sprintf("%s.lease.%s", stream, consumer))
+	msg.Header.Set(evictedHeader, reason)
+	ack, err := js.PublishMsg(msg, nats.Context(ctx))
+	if err != nil {
+		return
+	}
+	ctxT, ca := context.WithTimeout(ctx, 10*LockRetryDelay)
+	defer ca()
+	t := time.NewTicker(LockRetryDelay)
+	defer t.Stop()
+	for {
+		info, err := js.ConsumerInfo(stream, consumer, nats.Context(ctxT))
+		if err != nil || info.Delivered.Stream >= ack.Sequence {
+			return
+		}
+		select {
+		case <-ctxT.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// keys returns the keys of the locks under the prefix that have a stream, streams are never deleted
+// so their keys may not be held anymore
+func keys(ctx context.Context, js nats.JetStreamContext, prefix, keyPrefix string) []string {
+	prefixes := []string{
+		prefix + "-",
+		prefix + "_readers-",
+		waitersKey(prefix, ""),
+	}
+	ret := []string{}
+	for name := range js.StreamNames(nats.Context(ctx)) {
+		for _, p := range prefixes {
+			if key, ok := strings.CutPrefix(name, p); ok && strings.HasPrefix(key, keyPrefix) {
+				ret = append(ret, key)
+				break
+			}
+		}
+	}
+	slices.Sort(ret)
+	return slices.Compact(ret)
+}
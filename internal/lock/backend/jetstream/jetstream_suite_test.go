@@ -14,6 +14,7 @@ import (
 	"github.com/alexandreLamarre/dlock/pkg/test/conformance/integration"
 	"github.com/alexandreLamarre/dlock/pkg/test/container"
 	"github.com/alexandreLamarre/dlock/pkg/util/future"
+	"github.com/nats-io/nats.go"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	"github.com/samber/lo"
@@ -28,6 +29,7 @@ var lmF = future.New[lock.LockManager]()
 var lmSetF = future.New[lo.Tuple3[
 	lock.LockManager, lock.LockManager, lock.LockManager,
 ]]()
+var crashF = future.New[integration.Crash]()
 
 var _ = BeforeSuite(func() {
 	if Label("integration").MatchesLabelFilter(GinkgoLabelFilter()) {
@@ -92,10 +94,19 @@ var _ = BeforeSuite(func() {
 		lmSetF.Set(lo.Tuple3[lock.LockManager, lock.LockManager, lock.LockManager]{
 			A: x, B: y, C: z,
 		})
+		crashF.Set(func() (lock.LockManager, func()) {
+			nc, err := nats.Connect(conf.Endpoint)
+			Expect(err).NotTo(HaveOccurred())
+			js, err := nc.JetStream()
+			Expect(err).NotTo(HaveOccurred())
+			// closing the connection leaves the consumers of the lock manager to expire on their own
+			return jetstream.NewLockManager(context.Background(), js, "test", nil, logger.NewNop()), nc.Close
+		})
 	}
 })
 
 var _ = Describe("Jetstream Lock Manager", Ordered, Label("integration", "slow"), integration.LockManagerTestSuite(lmF, lmSetF))
+var _ = Describe("Jetstream Crashes", Ordered, Label("integration", "slow"), integration.CrashTestSuite(lmSetF, crashF))
 var _ = Describe("Jetstream Broker", Label("unit"), func() {
 	When("we register the lock broker", func() {
 		It("should register the jetstream lock manager as a broker", func() {
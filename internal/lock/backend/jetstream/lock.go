@@ -4,9 +4,11 @@ import (
 	"context"
 	"errors"
 	"log/slog"
-	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/alexandreLamarre/dlock/pkg/lock"
+	"github.com/alexandreLamarre/dlock/pkg/logger"
 	backoffv2 "github.com/lestrrat-go/backoff/v2"
 	"github.com/nats-io/nats.go"
 	"github.com/samber/lo"
@@ -22,11 +24,13 @@ type Lock struct {
 
 	scheduler *lock.LockScheduler
 	mutex     *jetstreamMutex
+	token     atomic.Uint64
 
 	lg *slog.Logger
 }
 
-var _ lock.Lock = (*Lock)(nil)
+var _ lock.RWLock = (*Lock)(nil)
+var _ lock.Transferer = (*Lock)(nil)
 
 func NewLock(js nats.JetStreamContext, prefix, key string, lg *slog.Logger, options *lock.LockOptions) *Lock {
 	return &Lock{
@@ -43,32 +47,63 @@ func (l *Lock) Key() string {
 	return l.key
 }
 
-func (l *Lock) acquire(ctx context.Context, retrier *backoffv2.Policy) (<-chan struct{}, error) {
-	var curErr error
-	mutex := newJetstreamMutex(l.lg, l.js, l.prefix, l.key, l.LockOptions)
+func (l *Lock) acquire(ctx context.Context, retrier *backoffv2.Policy, shared bool) (<-chan struct{}, error) {
+	mutex := newJetstreamMutex(l.lg, l.js, l.prefix, l.key, shared, l.LockOptions)
+	if retrier != nil && l.Fair {
+		// fair waiters are queued before their first attempt, so that they never overtake earlier waiters
+		if err := mutex.enqueue(); err != nil {
+			return nil, err
+		}
+		return l.wait(ctx, retrier, &mutex, nil)
+	}
 	done, err := mutex.tryLock()
-	curErr = err
 	if err == nil {
 		l.mutex = &mutex
+		l.token.Store(mutex.fencingToken)
 		return done, nil
 	}
 	if retrier != nil {
-		ret := *retrier
-		acq := ret.Start(ctx)
-		for backoffv2.Continue(acq) {
-			done, err := mutex.tryLock()
-			curErr = err
-			if err == nil {
-				l.mutex = &mutex
-				return done, nil
-			}
+		return l.wait(ctx, retrier, &mutex, err)
+	}
+	return nil, err
+}
+
+// wait registers the mutex as a waiter of the lock until it acquires the lock or gives up, and registers it again
+// in case jetstream removed it for inactivity
+func (l *Lock) wait(ctx context.Context, retrier *backoffv2.Policy, mutex *jetstreamMutex, curErr error) (<-chan struct{}, error) {
+	mutex.wait()
+	registered := time.Now()
+	defer mutex.unwait()
+	defer l.NotifyPosition(ctx, mutex.position)()
+	ret := *retrier
+	acq := ret.Start(ctx)
+	for backoffv2.Continue(acq) {
+		if time.Since(registered) > validity(l.LockOptions)/2 {
+			mutex.wait()
+			registered = time.Now()
+		}
+		// the lock may have been transferred to the waiter
+		done, err := mutex.claim(ctx)
+		if err == nil {
+			l.mutex = mutex
+			l.token.Store(mutex.fencingToken)
+			return done, nil
+		}
+		if !errors.Is(err, errNotTransferred) {
+			l.lg.With(logger.Err(err)).Warn("failed to claim transferred lock")
+		}
+		done, err = mutex.tryLock()
+		curErr = err
+		if err == nil {
+			l.mutex = mutex
+			l.token.Store(mutex.fencingToken)
+			return done, nil
 		}
-		return nil, errors.Join(ctx.Err(), curErr)
 	}
-	return nil, curErr
+	return nil, errors.Join(ctx.Err(), curErr)
 }
 
-func (l *Lock) lock(ctx context.Context, retrier *backoffv2.Policy) (<-chan struct{}, error) {
+func (l *Lock) lock(ctx context.Context, retrier *backoffv2.Policy, shared bool) (<-chan struct{}, error) {
 	if l.Tracer != nil {
 		ctxSpan, span := l.Tracer.Start(ctx, "Lock/jetstream-lock", trace.WithAttributes())
 		defer span.End()
@@ -80,7 +115,7 @@ func (l *Lock) lock(ctx context.Context, retrier *backoffv2.Policy) (<-chan stru
 
 	var closureDone <-chan struct{}
 	if err := l.scheduler.Schedule(func() error {
-		done, err := l.acquire(ctxca, retrier)
+		done, err := l.acquire(ctxca, retrier, shared)
 		if err != nil {
 			return err
 		}
@@ -92,20 +127,40 @@ func (l *Lock) lock(ctx context.Context, retrier *backoffv2.Policy) (<-chan stru
 	return closureDone, nil
 }
 
-func (l *Lock) Lock(ctx context.Context) (<-chan struct{}, error) {
-	retry := lo.ToPtr(backoffv2.Constant(
+func retryPolicy(delay time.Duration) *backoffv2.Policy {
+	return lo.ToPtr(backoffv2.Constant(
 		backoffv2.WithMaxRetries(0),
-		backoffv2.WithInterval(LockRetryDelay),
+		backoffv2.WithInterval(delay),
 		backoffv2.WithJitterFactor(0.1),
 	))
-	return l.lock(ctx, retry)
+}
+
+func (l *Lock) Lock(ctx context.Context) (<-chan struct{}, error) {
+	return l.lock(ctx, retryPolicy(l.RetryDelayOr(LockRetryDelay)), false)
+}
+
+// RLock acquires the read side of the lock, readers each hold a consumer
+// on a stream separate from the writer's
+func (l *Lock) RLock(ctx context.Context) (<-chan struct{}, error) {
+	return l.lock(ctx, retryPolicy(l.RetryDelayOr(LockRetryDelay)), true)
 }
 
 func (l *Lock) Unlock() error {
+	return l.unlock(false)
+}
+
+func (l *Lock) RUnlock() error {
+	return l.unlock(true)
+}
+
+func (l *Lock) unlock(shared bool) error {
 	if err := l.scheduler.Done(func() error {
 		if l.mutex == nil {
 			panic("never acquired")
 		}
+		if l.mutex.shared != shared {
+			return lock.ErrLockMode
+		}
 		mutex := *l.mutex
 		go func() {
 			if err := mutex.unlock(); err != nil {
@@ -113,6 +168,7 @@ func (l *Lock) Unlock() error {
 			}
 		}()
 		l.mutex = nil
+		l.token.Store(0)
 		return nil
 	}); err != nil {
 		return err
@@ -120,11 +176,42 @@ func (l *Lock) Unlock() error {
 	return nil
 }
 
+func (l *Lock) Transfer(ctx context.Context, toOwner string) error {
+	return l.scheduler.Done(func() error {
+		if l.mutex == nil {
+			panic("never acquired")
+		}
+		if l.mutex.shared {
+			return lock.ErrLockMode
+		}
+		if err := l.mutex.transfer(ctx, toOwner); err != nil {
+			return err
+		}
+		l.mutex = nil
+		l.token.Store(0)
+		return nil
+	})
+}
+
+func (l *Lock) FencingToken() uint64 {
+	return l.token.Load()
+}
+
 func (l *Lock) TryLock(ctx context.Context) (acquired bool, done <-chan struct{}, err error) {
-	closureDone, err := l.lock(ctx, nil)
+	return l.tryLock(ctx, false)
+}
+
+func (l *Lock) TryRLock(ctx context.Context) (acquired bool, done <-chan struct{}, err error) {
+	return l.tryLock(ctx, true)
+}
+
+func (l *Lock) tryLock(ctx context.Context, shared bool) (acquired bool, done <-chan struct{}, err error) {
+	closureDone, err := l.lock(ctx, nil, shared)
 	if err != nil {
-		// hack : jetstream client does not have a stronly typed error for : maxium consumers limit reached
-		if strings.Contains(err.Error(), "maximum consumers limit reached") {
+		if errors.Is(err, errConflict) {
+			return false, nil, nil
+		}
+		if isMaxConsumers(err) {
 			// the request has gone through but someone else has the lock
 			return false, nil, nil
 		}
@@ -3,6 +3,7 @@ package jetstream
 import (
 	"context"
 	"log/slog"
+	"time"
 
 	"github.com/alexandreLamarre/dlock/pkg/constants"
 	"github.com/alexandreLamarre/dlock/pkg/lock"
@@ -15,7 +16,7 @@ import (
 func init() {
 	broker.RegisterLockBroker(
 		constants.JetstreamLockManager,
-		func(ctx context.Context, l broker.LockBroker) (lock.LockManager, error) {
+		func(ctx context.Context, l broker.LockBroker) (broker.LockManagers, error) {
 			l.Lg.Info("acquiring jetstream client...")
 			cli, err := AcquireJetstreamConn(ctx, l.Config.JetstreamClientSpec, l.Lg)
 			if err != nil {
@@ -23,7 +24,9 @@ func init() {
 				return nil, err
 			}
 			l.Lg.Info("acquired jetstream client")
-			return NewLockManager(ctx, cli, "lock", l.Tracer, l.Lg), nil
+			return func(prefix string) (lock.LockManager, error) {
+				return NewLockManager(ctx, cli, prefix, l.Tracer, l.Lg), nil
+			}, nil
 		},
 	)
 }
@@ -37,9 +40,13 @@ type LockManager struct {
 	lg *slog.Logger
 
 	prefix string
+
+	reentrant *lock.ReentrantLocks
 }
 
 var _ lock.LockManager = (*LockManager)(nil)
+var _ lock.TTLDefaulter = (*LockManager)(nil)
+var _ lock.BarrierManager = (*LockManager)(nil)
 
 func NewLockManager(
 	ctx context.Context,
@@ -50,14 +57,19 @@ func NewLockManager(
 ) *LockManager {
 	prefix = sanitizePrefix(prefix)
 	return &LockManager{
-		ctx:    ctx,
-		js:     js,
-		lg:     lg,
-		prefix: prefix,
-		tracer: tracer,
+		ctx:       ctx,
+		js:        js,
+		lg:        lg,
+		prefix:    prefix,
+		tracer:    tracer,
+		reentrant: lock.NewReentrantLocks(),
 	}
 }
 
+func (l *LockManager) DefaultTTL() time.Duration {
+	return LockValidity
+}
+
 func (l *LockManager) Health(ctx context.Context) (conditions []string, err error) {
 	// We could be using jsm.go here: https://github.com/nats-io/jsm.go
 	// for now, we'll count account info as a health check
@@ -69,7 +81,70 @@ func (l *LockManager) Health(ctx context.Context) (conditions []string, err erro
 }
 
 func (l *LockManager) NewLock(key string, opts ...lock.LockOption) lock.Lock {
+	options := lock.DefaultLockOptions()
+	options.Apply(opts...)
+	return l.reentrant.NewLock(key, options, func() lock.Lock {
+		return NewLock(l.js, l.prefix, key, l.lg, options)
+	})
+}
+
+// RWLocks share the key of the exclusive lock returned by NewLock
+func (l *LockManager) NewRWLock(key string, opts ...lock.LockOption) lock.RWLock {
 	options := lock.DefaultLockOptions()
 	options.Apply(opts...)
 	return NewLock(l.js, l.prefix, key, l.lg, options)
 }
+
+func (l *LockManager) NewMultiLock(keys []string, opts ...lock.LockOption) lock.Lock {
+	return lock.NewMultiLock(keys, func(key string) lock.Lock {
+		return l.NewLock(key, opts...)
+	})
+}
+
+// Semaphores are backed by a stream whose consumer limit is their capacity
+func (l *LockManager) NewSemaphore(key string, capacity int64, opts ...lock.LockOption) lock.Semaphore {
+	options := lock.DefaultLockOptions()
+	options.Apply(opts...)
+	return NewSemaphore(l.js, l.prefix, key, capacity, l.lg, options)
+}
+
+// Barriers & latches are backed by streams of their own, see Barrier & CountDownLatch
+func (l *LockManager) NewBarrier(key string, n int64, opts ...lock.LockOption) lock.Barrier {
+	options := lock.DefaultLockOptions()
+	options.Apply(opts...)
+	return NewBarrier(l.js, l.prefix, key, n, l.lg, options)
+}
+
+func (l *LockManager) NewCountDownLatch(key string, n int64, opts ...lock.LockOption) lock.CountDownLatch {
+	options := lock.DefaultLockOptions()
+	options.Apply(opts...)
+	return NewCountDownLatch(l.js, l.prefix, key, n, l.lg, options)
+}
+
+func (l *LockManager) DescribeLock(ctx context.Context, key string) (lock.LockInfo, error) {
+	return describe(ctx, l.js, l.prefix, key)
+}
+
+func (l *LockManager) ForceRelease(ctx context.Context, key, reason string) (lock.LockInfo, error) {
+	info, err := forceRelease(ctx, l.js, l.prefix, key, reason)
+	if err != nil {
+		return lock.LockInfo{}, err
+	}
+	l.lg.With("key", key, "reason", reason, "holders", len(info.Holders)).Warn("force released lock")
+	return info, nil
+}
+
+func (l *LockManager) ListLocks(ctx context.Context, prefix string) ([]lock.LockInfo, error) {
+	infos := []lock.LockInfo{}
+	for _, key := range keys(ctx, l.js, l.prefix, prefix) {
+		info, err := describe(ctx, l.js, l.prefix, key)
+		if err != nil {
+			return nil, err
+		}
+		if len(info.Holders) == 0 && info.Waiters == 0 {
+			continue
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"strings"
 	"time"
 
 	"github.com/alexandreLamarre/dlock/pkg/lock"
@@ -25,11 +26,33 @@ func newLease(key string) *nats.StreamConfig {
 	}
 }
 
+// readers hold the read side of a lock with one consumer each on a separate stream
+func newReadLease(key string) *nats.StreamConfig {
+	return &nats.StreamConfig{
+		Name:      key,
+		Retention: nats.InterestPolicy,
+		Subjects:  []string{fmt.Sprintf("%s.lease.*", key)},
+	}
+}
+
+// errConflict is returned when the lock is held in the other mode
+var errConflict = errors.New("lock is held in a conflicting mode")
+
 var (
 	LockValidity   = 60 * time.Second
 	LockRetryDelay = 100 * time.Millisecond
 )
 
+// validity is the duration after which jetstream removes the consumers of holders that stopped keeping them alive
+func validity(opts *lock.LockOptions) time.Duration {
+	return opts.TTLOr(LockValidity)
+}
+
+// heartbeat is the interval at which jetstream checks in with the subscriptions of holders
+func heartbeat(opts *lock.LockOptions) time.Duration {
+	return max(opts.KeepaliveIntervalOr(LockRetryDelay), 100*time.Millisecond)
+}
+
 // encapsulates stateful information and tasks required for holding a lock
 type jetstreamMutex struct {
 	lg *slog.Logger
@@ -37,6 +60,9 @@ type jetstreamMutex struct {
 	prefix string
 	key    string
 	uuid   string
+	shared bool
+	// name of the consumer holding the lock, the uuid unless the lock was transferred to the mutex
+	consumer string
 
 	js   nats.JetStreamContext
 	msgQ chan *nats.Msg
@@ -45,6 +71,11 @@ type jetstreamMutex struct {
 	internalDone chan struct{}
 	retDone      chan struct{}
 
+	// stream sequence of the lease message published on acquisition, used as a fencing token
+	fencingToken uint64
+	// ticket of fair waiters, ordering them by the time they started waiting
+	ticket uint64
+
 	*lock.LockOptions
 }
 
@@ -52,6 +83,7 @@ func newJetstreamMutex(
 	lg *slog.Logger,
 	js nats.JetStreamContext,
 	prefix, key string,
+	shared bool,
 	opts *lock.LockOptions,
 ) jetstreamMutex {
 	uuid := uuid.New().String()
@@ -61,6 +93,8 @@ func newJetstreamMutex(
 		prefix:       prefix,
 		key:          key,
 		uuid:         uuid,
+		shared:       shared,
+		consumer:     uuid,
 		msgQ:         make(chan *nats.Msg, 16),
 		internalDone: make(chan struct{}),
 		retDone:      make(chan struct{}),
@@ -72,27 +106,97 @@ func (j *jetstreamMutex) Key() string {
 	return j.prefix + "-" + j.key
 }
 
+// readersKey is the name of the stream tracking readers of the key,
+// it never collides with Key() since prefixes are sanitized
+func (j *jetstreamMutex) readersKey() string {
+	return j.prefix + "_readers-" + j.key
+}
+
+// streamKey is the name of the stream this mutex holds a consumer on
+func (j *jetstreamMutex) streamKey() string {
+	if j.shared {
+		return j.readersKey()
+	}
+	return j.Key()
+}
+
+// held reports whether any consumer, other than our own, holds the given stream
+func (j *jetstreamMutex) held(stream string) (bool, error) {
+	info, err := j.js.StreamInfo(stream)
+	if errors.Is(err, nats.ErrStreamNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return info.State.Consumers > 0, nil
+}
+
 func (j *jetstreamMutex) tryLock() (<-chan struct{}, error) {
+	if j.Fair {
+		ahead, err := j.queued()
+		if err != nil {
+			return nil, err
+		}
+		if ahead {
+			return nil, errConflict
+		}
+	}
 	var err error
-	if _, err := j.js.AddStream(newLease(j.Key())); err != nil {
+	streamCfg := newLease(j.Key())
+	if j.shared {
+		streamCfg = newReadLease(j.readersKey())
+	}
+	if _, err := j.js.AddStream(streamCfg); err != nil {
 		return nil, err
 	}
 	cfg := &nats.ConsumerConfig{
 		Durable:           j.uuid,
 		AckPolicy:         nats.AckExplicitPolicy,
-		InactiveThreshold: LockValidity,
+		InactiveThreshold: validity(j.LockOptions),
 		DeliverSubject:    j.uuid,
-		Heartbeat:         max(LockRetryDelay, 100*time.Millisecond),
+		Heartbeat:         heartbeat(j.LockOptions),
+		Metadata:          j.metadata(),
 	}
-	if _, err := j.js.AddConsumer(j.Key(), cfg); err != nil {
+	if _, err := j.js.AddConsumer(j.streamKey(), cfg); err != nil {
 		j.lg.Warn(err.Error())
 		return nil, err
 	}
-	j.sub, err = j.js.ChanSubscribe(j.uuid, j.msgQ, nats.Bind(j.Key(), j.uuid))
+	j.sub, err = j.js.ChanSubscribe(j.uuid, j.msgQ, nats.Bind(j.streamKey(), j.uuid))
 	if err != nil {
 		j.lg.Warn(err.Error())
 		return nil, err
 	}
+	// both sides register their consumer before checking the other side, so at worst
+	// concurrent readers and writers both back off and retry
+	conflict := j.readersKey()
+	if j.shared {
+		conflict = j.Key()
+	}
+	held, err := j.held(conflict)
+	if err == nil && held {
+		err = errConflict
+	}
+	if err != nil {
+		if unlockErr := j.tryUnlock(); unlockErr != nil {
+			j.lg.With(logger.Err(unlockErr)).Warn("failed to release lock after conflict check")
+		}
+		return nil, err
+	}
+	if j.shared {
+		return lo.Async(j.keepaliveC), nil
+	}
+	// stream sequences are never reused, so the sequence of our lease message is a monotonically increasing
+	// fencing token for the key
+	ack, err := j.js.Publish(fmt.Sprintf("%s.lease.%s", j.Key(), j.uuid), nil)
+	if err != nil {
+		j.lg.Warn(err.Error())
+		if unlockErr := j.tryUnlock(); unlockErr != nil {
+			j.lg.With(logger.Err(unlockErr)).Warn("failed to release lock after failing to issue a fencing token")
+		}
+		return nil, err
+	}
+	j.fencingToken = ack.Sequence
 	return lo.Async(j.keepaliveC), nil
 }
 
@@ -108,6 +212,10 @@ func (j *jetstreamMutex) keepaliveC() struct{} {
 			if err := msg.Ack(); err != nil {
 				j.lg.Warn(fmt.Sprintf("failed to ack : %s", err.Error()))
 			}
+			if reason := msg.Header.Get(evictedHeader); reason != "" && strings.HasSuffix(msg.Subject, "."+j.consumer) {
+				j.lg.With("reason", reason).Warn("lock was force released")
+				return struct{}{}
+			}
 		}
 	}
 }
@@ -134,7 +242,7 @@ func (j *jetstreamMutex) tryUnlock() error {
 	if drainErr != nil {
 		j.lg.With(logger.Err(drainErr)).Warn("failed to drain subscriber")
 	}
-	consumerErr := j.js.DeleteConsumer(j.Key(), j.uuid)
+	consumerErr := j.js.DeleteConsumer(j.streamKey(), j.consumer)
 	if j.isReleased(consumerErr) {
 		consumerErr = nil
 	} else {
@@ -163,7 +271,7 @@ func (j *jetstreamMutex) unlock() error {
 
 	ctx, ca := context.WithTimeout(ctx, 60*time.Second)
 	defer ca()
-	tTicker := time.NewTicker(LockRetryDelay)
+	tTicker := time.NewTicker(j.RetryDelayOr(LockRetryDelay))
 	defer tTicker.Stop()
 
 	// always try at least one unlock operation before ctx is done
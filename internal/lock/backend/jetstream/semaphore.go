@@ -0,0 +1,304 @@
+package jetstream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/alexandreLamarre/dlock/pkg/lock"
+	"github.com/alexandreLamarre/dlock/pkg/logger"
+	"github.com/google/uuid"
+	backoffv2 "github.com/lestrrat-go/backoff/v2"
+	"github.com/nats-io/nats.go"
+	"github.com/samber/lo"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// the stream's consumer limit is the capacity of the semaphore, every unit held being a consumer
+func newSemaphoreLease(key string, capacity int64) *nats.StreamConfig {
+	return &nats.StreamConfig{
+		Name:         key,
+		Retention:    nats.InterestPolicy,
+		Subjects:     []string{fmt.Sprintf("%s.lease.*", key)},
+		MaxConsumers: int(capacity),
+	}
+}
+
+// isMaxConsumers reports whether the error is returned because the consumer limit of the stream is reached
+//
+// hack : jetstream client does not have a stronly typed error for : maxium consumers limit reached
+func isMaxConsumers(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "maximum consumers limit reached")
+}
+
+// encapsulates stateful information and tasks required for holding units of a semaphore
+type semaphoreMutex struct {
+	lg *slog.Logger
+
+	prefix   string
+	key      string
+	uuid     string
+	weight   int64
+	capacity int64
+
+	js   nats.JetStreamContext
+	msgQ chan *nats.Msg
+
+	held         []heldConsumer
+	internalDone chan struct{}
+
+	*lock.LockOptions
+}
+
+// heldConsumer is a single unit of a semaphore
+type heldConsumer struct {
+	name string
+	sub  *nats.Subscription
+}
+
+func newSemaphoreMutex(
+	lg *slog.Logger,
+	js nats.JetStreamContext,
+	prefix, key string,
+	weight, capacity int64,
+	opts *lock.LockOptions,
+) semaphoreMutex {
+	uuid := uuid.New().String()
+	return semaphoreMutex{
+		js:           js,
+		lg:           lg.With("uuid", uuid, "weight", weight),
+		prefix:       prefix,
+		key:          key,
+		uuid:         uuid,
+		weight:       weight,
+		capacity:     capacity,
+		msgQ:         make(chan *nats.Msg, 16),
+		internalDone: make(chan struct{}),
+		LockOptions:  opts,
+	}
+}
+
+func (s *semaphoreMutex) Key() string {
+	return s.prefix + "_semaphore-" + s.key
+}
+
+func (s *semaphoreMutex) consumer(i int) string {
+	return fmt.Sprintf("%s-%d", s.uuid, i)
+}
+
+// tryLock holds one consumer per unit, releasing the units it already holds if any of them is not available
+func (s *semaphoreMutex) tryLock() (<-chan struct{}, error) {
+	if _, err := s.js.AddStream(newSemaphoreLease(s.Key(), s.capacity)); err != nil {
+		return nil, err
+	}
+	for i := range int(s.weight) {
+		if err := s.hold(s.consumer(i)); err != nil {
+			if unlockErr := s.tryUnlock(); unlockErr != nil {
+				s.lg.With(logger.Err(unlockErr)).Warn("failed to release partially acquired semaphore")
+			}
+			return nil, err
+		}
+	}
+	return lo.Async(s.keepaliveC), nil
+}
+
+func (s *semaphoreMutex) hold(name string) error {
+	cfg := &nats.ConsumerConfig{
+		Durable:           name,
+		AckPolicy:         nats.AckExplicitPolicy,
+		InactiveThreshold: validity(s.LockOptions),
+		DeliverSubject:    name,
+		Heartbeat:         heartbeat(s.LockOptions),
+	}
+	if _, err := s.js.AddConsumer(s.Key(), cfg); err != nil {
+		s.lg.Debug(err.Error())
+		return err
+	}
+	sub, err := s.js.ChanSubscribe(name, s.msgQ, nats.Bind(s.Key(), name))
+	if err != nil {
+		s.lg.Warn(err.Error())
+		if delErr := s.js.DeleteConsumer(s.Key(), name); delErr != nil && !errors.Is(delErr, nats.ErrConsumerNotFound) {
+			return errors.Join(err, delErr)
+		}
+		return err
+	}
+	s.held = append(s.held, heldConsumer{name: name, sub: sub})
+	return nil
+}
+
+func (s *semaphoreMutex) keepaliveC() struct{} {
+	for {
+		select {
+		case <-s.internalDone:
+			return struct{}{}
+		case msg, ok := <-s.msgQ:
+			if !ok {
+				return struct{}{}
+			}
+			if err := msg.Ack(); err != nil {
+				s.lg.Warn(fmt.Sprintf("failed to ack : %s", err.Error()))
+			}
+		}
+	}
+}
+
+func (s *semaphoreMutex) teardown() {
+	defer close(s.internalDone)
+	select {
+	case s.internalDone <- struct{}{}:
+	default:
+	}
+}
+
+// !!Important : never treat nats closed connections as successful unlocks, this could lead to inconsistent states
+func (s *semaphoreMutex) tryUnlock() error {
+	var errs []error
+	var remaining []heldConsumer
+	for _, c := range s.held {
+		if err := c.sub.Unsubscribe(); err != nil && !errors.Is(err, nats.ErrBadSubscription) {
+			s.lg.With(logger.Err(err)).Warn("failed to unsubscribe to consumer")
+		}
+		err := s.js.DeleteConsumer(s.Key(), c.name)
+		if err != nil && !errors.Is(err, nats.ErrConsumerNotFound) {
+			s.lg.With(logger.Err(err)).Warn("failed to delete consumer")
+			errs = append(errs, err)
+			remaining = append(remaining, c)
+		}
+	}
+	s.held = remaining
+	return errors.Join(errs...)
+}
+
+// best effort unlock, retrying until the consumers are deleted or they expire server-side
+func (s *semaphoreMutex) unlock() error {
+	defer s.teardown()
+	deadline := time.Now().Add(validity(s.LockOptions))
+	for {
+		err := s.tryUnlock()
+		if err == nil {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return err
+		}
+		s.lg.Warn(fmt.Sprintf("failed to release semaphore : %s, retrying...", err.Error()))
+		time.Sleep(s.RetryDelayOr(LockRetryDelay))
+	}
+}
+
+type Semaphore struct {
+	prefix   string
+	key      string
+	capacity int64
+
+	js nats.JetStreamContext
+	*lock.LockOptions
+
+	scheduler *lock.LockScheduler
+	mutex     *semaphoreMutex
+
+	lg *slog.Logger
+}
+
+var _ lock.Semaphore = (*Semaphore)(nil)
+
+func NewSemaphore(
+	js nats.JetStreamContext,
+	prefix, key string,
+	capacity int64,
+	lg *slog.Logger,
+	options *lock.LockOptions,
+) *Semaphore {
+	return &Semaphore{
+		prefix:      prefix,
+		key:         key,
+		capacity:    capacity,
+		js:          js,
+		lg:          lg.With("key", key, "capacity", capacity),
+		LockOptions: options,
+		scheduler:   lock.NewLockScheduler(),
+	}
+}
+
+func (s *Semaphore) Capacity() int64 {
+	return s.capacity
+}
+
+func (s *Semaphore) Acquire(ctx context.Context, n int64) (<-chan struct{}, error) {
+	return s.acquire(ctx, n, retryPolicy(s.RetryDelayOr(LockRetryDelay)))
+}
+
+func (s *Semaphore) TryAcquire(ctx context.Context, n int64) (acquired bool, done <-chan struct{}, err error) {
+	done, err = s.acquire(ctx, n, nil)
+	if err != nil {
+		if isMaxConsumers(err) {
+			// the request has gone through but the units are held by others
+			return false, nil, nil
+		}
+		return false, nil, err
+	}
+	return true, done, nil
+}
+
+func (s *Semaphore) acquire(ctx context.Context, n int64, retrier *backoffv2.Policy) (<-chan struct{}, error) {
+	if err := lock.ValidateWeight(n, s.capacity); err != nil {
+		return nil, err
+	}
+	if s.Tracer != nil {
+		ctxSpan, span := s.Tracer.Start(ctx, "Acquire/jetstream-semaphore", trace.WithAttributes())
+		defer span.End()
+		ctx = ctxSpan
+	}
+	// https://github.com/lestrrat-go/backoff/issues/31
+	ctxca, ca := context.WithCancel(ctx)
+	defer ca()
+
+	var closureDone <-chan struct{}
+	if err := s.scheduler.Schedule(func() error {
+		mutex := newSemaphoreMutex(s.lg, s.js, s.prefix, s.key, n, s.capacity, s.LockOptions)
+		done, err := mutex.tryLock()
+		curErr := err
+		if err == nil {
+			s.mutex = &mutex
+			closureDone = done
+			return nil
+		}
+		if retrier == nil {
+			return curErr
+		}
+		ret := *retrier
+		acq := ret.Start(ctxca)
+		for backoffv2.Continue(acq) {
+			done, err := mutex.tryLock()
+			curErr = err
+			if err == nil {
+				s.mutex = &mutex
+				closureDone = done
+				return nil
+			}
+		}
+		return errors.Join(ctxca.Err(), curErr)
+	}); err != nil {
+		return nil, err
+	}
+	return closureDone, nil
+}
+
+func (s *Semaphore) Release() error {
+	return s.scheduler.Done(func() error {
+		if s.mutex == nil {
+			panic("never acquired")
+		}
+		mutex := *s.mutex
+		go func() {
+			if err := mutex.unlock(); err != nil {
+				s.lg.Error(err.Error())
+			}
+		}()
+		s.mutex = nil
+		return nil
+	})
+}
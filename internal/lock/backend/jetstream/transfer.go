@@ -0,0 +1,112 @@
+package jetstream
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"time"
+
+	"github.com/alexandreLamarre/dlock/pkg/lock"
+	"github.com/alexandreLamarre/dlock/pkg/logger"
+	"github.com/nats-io/nats.go"
+	"github.com/samber/lo"
+)
+
+// transferMetadataKey is the holder consumer metadata entry naming the waiter the lock was transferred to
+const transferMetadataKey = "dlock.transfer"
+
+var errNotTransferred = errors.New("lock was not transferred to the waiter")
+
+// transfer marks the consumer of the holder for the earliest exclusive waiter of the owner, and stops consuming it.
+// The consumer is kept, so that no other acquisition can add a consumer to the lock stream until the waiter binds to
+// it, or until jetstream removes it for inactivity.
+func (j *jetstreamMutex) transfer(ctx context.Context, toOwner string) error {
+	to := ""
+	var created time.Time
+	for _, info := range consumers(ctx, j.js, j.waitersKey()) {
+		data, ok := info.Config.Metadata[holderMetadataKey]
+		if !ok {
+			continue
+		}
+		var holder lock.HolderInfo
+		if err := json.Unmarshal([]byte(data), &holder); err != nil {
+			continue
+		}
+		if holder.Shared || holder.Owner != toOwner {
+			continue
+		}
+		if to == "" || info.Created.Before(created) {
+			to, created = info.Name, info.Created
+		}
+	}
+	if to == "" {
+		return lock.ErrNoTransferee
+	}
+	info, err := j.js.ConsumerInfo(j.Key(), j.consumer, nats.Context(ctx))
+	if errors.Is(err, nats.ErrConsumerNotFound) {
+		return lock.ErrNotHeld
+	}
+	if err != nil {
+		return err
+	}
+	cfg := info.Config
+	cfg.Metadata = maps.Clone(cfg.Metadata)
+	if cfg.Metadata == nil {
+		cfg.Metadata = map[string]string{}
+	}
+	cfg.Metadata[transferMetadataKey] = to
+	if _, err := j.js.UpdateConsumer(j.Key(), &cfg, nats.Context(ctx)); err != nil {
+		if errors.Is(err, nats.ErrConsumerNotFound) {
+			return lock.ErrNotHeld
+		}
+		return err
+	}
+	if err := j.sub.Unsubscribe(); err != nil {
+		j.lg.With(logger.Err(err)).Warn("failed to unsubscribe from transferred consumer")
+	}
+	j.teardown()
+	return nil
+}
+
+// claim binds to the consumer of the holder that transferred the lock to the waiter, and issues its fencing token
+func (j *jetstreamMutex) claim(ctx context.Context) (<-chan struct{}, error) {
+	for _, info := range consumers(ctx, j.js, j.Key()) {
+		if info.Config.Metadata[transferMetadataKey] != j.uuid {
+			continue
+		}
+		cfg := info.Config
+		cfg.Metadata = j.metadata()
+		if _, err := j.js.UpdateConsumer(j.Key(), &cfg, nats.Context(ctx)); err != nil {
+			return nil, err
+		}
+		sub, err := j.js.ChanSubscribe(info.Name, j.msgQ, nats.Bind(j.Key(), info.Name))
+		if err != nil {
+			return nil, err
+		}
+		j.sub = sub
+		j.consumer = info.Name
+		ack, err := j.js.Publish(fmt.Sprintf("%s.lease.%s", j.Key(), j.consumer), nil)
+		if err != nil {
+			return nil, errors.Join(err, j.tryUnlock())
+		}
+		j.fencingToken = ack.Sequence
+		return lo.Async(j.keepaliveC), nil
+	}
+	return nil, errNotTransferred
+}
+
+// abandonTransfer deletes the consumer of a holder that transferred the lock to the waiter after it gave up
+func (j *jetstreamMutex) abandonTransfer() {
+	ctx, ca := context.WithTimeout(context.Background(), validity(j.LockOptions))
+	defer ca()
+	for _, info := range consumers(ctx, j.js, j.Key()) {
+		if info.Config.Metadata[transferMetadataKey] != j.uuid {
+			continue
+		}
+		if err := j.js.DeleteConsumer(j.Key(), info.Name, nats.Context(ctx)); !j.isReleased(err) {
+			j.lg.With(logger.Err(err)).Warn("failed to release abandoned lock transfer")
+		}
+	}
+}
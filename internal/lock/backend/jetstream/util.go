@@ -2,12 +2,14 @@ package jetstream
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
 	"strings"
 	"time"
 
 	"github.com/alexandreLamarre/dlock/pkg/config/v1alpha1"
 	"github.com/alexandreLamarre/dlock/pkg/logger"
+	"github.com/alexandreLamarre/dlock/pkg/util"
 	"github.com/lestrrat-go/backoff/v2"
 	"github.com/nats-io/nats.go"
 )
@@ -17,6 +19,49 @@ func sanitizePrefix(prefix string) string {
 	return strings.ReplaceAll(strings.ReplaceAll(prefix, "/", "-"), ".", "_")
 }
 
+// JetstreamClientOptions returns the credentials, TLS & timeouts of the connection to the NATS server of the spec
+func JetstreamClientOptions(conf *v1alpha1.JetstreamClientSpec) ([]nats.Option, error) {
+	options := []nats.Option{}
+	if conf.NkeySeedPath != "" {
+		nkeyOpt, err := nats.NkeyOptionFromSeed(conf.NkeySeedPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load nkey seed : %w", err)
+		}
+		options = append(options, nkeyOpt)
+	}
+	if conf.CredsFile != "" {
+		options = append(options, nats.UserCredentials(conf.CredsFile))
+	}
+	if conf.Username != "" || conf.Password != "" {
+		options = append(options, nats.UserInfo(conf.Username, conf.Password))
+	}
+	if conf.Token != "" {
+		options = append(options, nats.Token(conf.Token))
+	}
+	if conf.TLS != nil {
+		tlsConfig, err := util.LoadClientMTLSConfig(*conf.TLS)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client TLS config: %w", err)
+		}
+		options = append(options, nats.Secure(tlsConfig))
+	}
+	connectTimeout, err := util.ParseOptionalDuration("connectTimeout", conf.ConnectTimeout)
+	if err != nil {
+		return nil, err
+	}
+	if connectTimeout > 0 {
+		options = append(options, nats.Timeout(connectTimeout))
+	}
+	pingInterval, err := util.ParseOptionalDuration("pingInterval", conf.PingInterval)
+	if err != nil {
+		return nil, err
+	}
+	if pingInterval > 0 {
+		options = append(options, nats.PingInterval(pingInterval))
+	}
+	return options, nil
+}
+
 func AcquireJetstreamConn(ctx context.Context, conf *v1alpha1.JetstreamClientSpec, lg *slog.Logger) (nats.JetStreamContext, error) {
 	options := []nats.Option{
 		nats.MaxReconnects(-1),
@@ -39,10 +84,11 @@ func AcquireJetstreamConn(ctx context.Context, conf *v1alpha1.JetstreamClientSpe
 			).Info("reconnected to jetstream")
 		}),
 	}
-	nkeyOpt, err := nats.NkeyOptionFromSeed(conf.NkeySeedPath)
-	if err == nil {
-		options = append(options, nkeyOpt)
+	clientOptions, err := JetstreamClientOptions(conf)
+	if err != nil {
+		return nil, err
 	}
+	options = append(options, clientOptions...)
 	nc, err := nats.Connect(conf.Endpoint,
 		options...,
 	)
@@ -0,0 +1,52 @@
+package jetstream_test
+
+import (
+	"time"
+
+	"github.com/alexandreLamarre/dlock/internal/lock/backend/jetstream"
+	"github.com/alexandreLamarre/dlock/pkg/config/v1alpha1"
+	"github.com/nats-io/nats.go"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Jetstream client config", Label("unit"), func() {
+	It("should configure the connection with the spec", func() {
+		options, err := jetstream.JetstreamClientOptions(&v1alpha1.JetstreamClientSpec{
+			Username:       "dlock",
+			Password:       "secret",
+			Token:          "token",
+			ConnectTimeout: "5s",
+			PingInterval:   "10s",
+			TLS: &v1alpha1.MTLSSpec{
+				ServerCA:   "../../../../pkg/test/testdata/testdata/root_ca.crt",
+				ClientCert: "../../../../pkg/test/testdata/testdata/client.crt",
+				ClientKey:  "../../../../pkg/test/testdata/testdata/client.key",
+			},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		opts := nats.GetDefaultOptions()
+		for _, option := range options {
+			Expect(option(&opts)).To(Succeed())
+		}
+		Expect(opts.User).To(Equal("dlock"))
+		Expect(opts.Password).To(Equal("secret"))
+		Expect(opts.Token).To(Equal("token"))
+		Expect(opts.Timeout).To(Equal(5 * time.Second))
+		Expect(opts.PingInterval).To(Equal(10 * time.Second))
+		Expect(opts.Secure).To(BeTrue())
+		Expect(opts.TLSConfig.Certificates).To(HaveLen(1))
+	})
+
+	It("should reject invalid specs", func() {
+		for _, spec := range []*v1alpha1.JetstreamClientSpec{
+			{NkeySeedPath: "missing.nk"},
+			{ConnectTimeout: "5"},
+			{PingInterval: "-1s"},
+			{TLS: &v1alpha1.MTLSSpec{ServerCA: "missing.crt"}},
+		} {
+			_, err := jetstream.JetstreamClientOptions(spec)
+			Expect(err).To(HaveOccurred())
+		}
+	})
+})
@@ -0,0 +1,95 @@
+package jetstream
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/alexandreLamarre/dlock/pkg/lock"
+	"github.com/alexandreLamarre/dlock/pkg/logger"
+	"github.com/nats-io/nats.go"
+)
+
+const (
+	consumerCreatedAdvisory = "$JS.EVENT.ADVISORY.CONSUMER.CREATED"
+	consumerDeletedAdvisory = "$JS.EVENT.ADVISORY.CONSUMER.DELETED"
+)
+
+// advisoriesKey is the name of the stream capturing the consumer advisories of every stream, so that watches
+// can consume them. It only keeps advisories until every watch consumed them.
+func advisoriesKey(prefix string) string {
+	return prefix + "_advisories"
+}
+
+// Watch consumes the advisories of the consumers created & deleted on the streams of holders & readers,
+// describing their lock again on every advisory. Consumers of expired holders are deleted like released ones,
+// so expiries are reported as releases.
+//
+// Locks are only described every lock.WatchInterval when advisories can't be consumed.
+func (l *LockManager) Watch(ctx context.Context, prefix string) (<-chan lock.WatchEvent, error) {
+	prefixes := []string{
+		l.prefix + "-",
+		l.prefix + "_readers-",
+	}
+	changes := make(chan lock.WatchChange, 16)
+	// the subscription starts before the locks are first described, so that no change is missed
+	sub, err := l.subscribeAdvisories(func(msg *nats.Msg) {
+		// advisory subjects end with the name of the stream and of the consumer
+		tokens := strings.Split(msg.Subject, ".")
+		if len(tokens) < 2 {
+			return
+		}
+		stream := tokens[len(tokens)-2]
+		for _, p := range prefixes {
+			if key, ok := strings.CutPrefix(stream, p); ok && strings.HasPrefix(key, prefix) {
+				select {
+				case changes <- lock.WatchChange{Key: key}:
+				case <-ctx.Done():
+				}
+				return
+			}
+		}
+	})
+	if err != nil {
+		l.lg.With(logger.Err(err)).Warn("failed to consume consumer advisories, polling locks instead")
+		changes = nil
+	}
+	events, err := lock.WatchDescriptions(ctx, l, prefix, changes)
+	if err != nil {
+		if sub != nil {
+			_ = sub.Unsubscribe()
+		}
+		return nil, err
+	}
+	if sub != nil {
+		go func() {
+			<-ctx.Done()
+			if err := sub.Unsubscribe(); err != nil {
+				l.lg.With(logger.Err(err)).Warn("failed to unsubscribe from consumer advisories")
+			}
+		}()
+	}
+	return events, nil
+}
+
+func (l *LockManager) subscribeAdvisories(handler nats.MsgHandler) (*nats.Subscription, error) {
+	if _, err := l.js.AddStream(&nats.StreamConfig{
+		Name:      advisoriesKey(l.prefix),
+		Retention: nats.InterestPolicy,
+		Storage:   nats.MemoryStorage,
+		MaxAge:    time.Minute,
+		Subjects: []string{
+			consumerCreatedAdvisory + ".*.*",
+			consumerDeletedAdvisory + ".*.*",
+		},
+	}); err != nil {
+		return nil, err
+	}
+	return l.js.Subscribe(
+		"",
+		handler,
+		nats.BindStream(advisoriesKey(l.prefix)),
+		nats.DeliverNew(),
+		nats.OrderedConsumer(),
+	)
+}
@@ -0,0 +1,189 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"github.com/alexandreLamarre/dlock/pkg/lock"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var errLocked = errors.New("key is locked by someone else")
+
+// Lock is held in the memory of the process, readers sharing the key of the exclusive lock
+type Lock struct {
+	store *store
+	key   string
+	ttl   time.Duration
+
+	*lock.LockOptions
+
+	scheduler *lock.LockScheduler
+	held      *holder
+	shared    bool
+	token     atomic.Uint64
+
+	lg *slog.Logger
+}
+
+var _ lock.RWLock = (*Lock)(nil)
+var _ lock.Transferer = (*Lock)(nil)
+
+func newLock(store *store, key string, ttl time.Duration, lg *slog.Logger, options *lock.LockOptions) *Lock {
+	return &Lock{
+		store:       store,
+		key:         key,
+		ttl:         ttl,
+		lg:          lg,
+		LockOptions: options,
+		scheduler:   lock.NewLockScheduler(),
+	}
+}
+
+// acquire blocks until the lock is acquired when block is set, without polling the store
+func (l *Lock) acquire(ctx context.Context, block, shared bool) (<-chan struct{}, error) {
+	h := newHolder(l.Holder(shared), l.ttl)
+	waiting := false
+	if block && l.Fair {
+		// fair acquisitions are queued before their first attempt, so that they never overtake earlier waiters
+		waiting = true
+		defer l.wait(ctx, h)()
+		l.store.enqueue(l.key, h)
+		defer l.store.dequeue(l.key, h)
+	}
+	for {
+		ok, token, changed := l.store.tryLock(l.key, h, shared, l.Fair)
+		if ok {
+			h.expireAfterTTL(func() {
+				l.lg.Warn("lock expired")
+				l.store.unlock(l.key, h, true)
+			})
+			l.held = h
+			l.shared = shared
+			l.token.Store(token)
+			return h.expired, nil
+		}
+		if !block {
+			return nil, errLocked
+		}
+		if !waiting {
+			waiting = true
+			defer l.wait(ctx, h)()
+		}
+		select {
+		case <-ctx.Done():
+			// the lock may have been transferred to h right before the context was done
+			l.store.unlock(l.key, h, false)
+			return nil, errors.Join(ctx.Err(), errLocked)
+		case <-changed:
+		}
+	}
+}
+
+// wait registers h as a waiter of the lock until the returned func is called, notifying its position meanwhile
+func (l *Lock) wait(ctx context.Context, h *holder) (done func()) {
+	unwait := l.store.wait(l.key, h)
+	stop := l.NotifyPosition(ctx, func(context.Context) (int, error) {
+		return l.store.position(l.key, h)
+	})
+	return func() {
+		stop()
+		unwait()
+	}
+}
+
+func (l *Lock) lock(ctx context.Context, block, shared bool) (<-chan struct{}, error) {
+	if l.Tracer != nil {
+		ctxSpan, span := l.Tracer.Start(ctx, "Lock/memory-lock", trace.WithAttributes())
+		defer span.End()
+		ctx = ctxSpan
+	}
+	var closureDone <-chan struct{}
+	if err := l.scheduler.Schedule(func() error {
+		done, err := l.acquire(ctx, block, shared)
+		if err != nil {
+			return err
+		}
+		closureDone = done
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return closureDone, nil
+}
+
+func (l *Lock) tryLock(ctx context.Context, shared bool) (acquired bool, done <-chan struct{}, err error) {
+	closureDone, err := l.lock(ctx, false, shared)
+	if err != nil {
+		if errors.Is(err, errLocked) {
+			return false, nil, nil
+		}
+		return false, nil, err
+	}
+	return true, closureDone, nil
+}
+
+func (l *Lock) Lock(ctx context.Context) (<-chan struct{}, error) {
+	return l.lock(ctx, true, false)
+}
+
+func (l *Lock) TryLock(ctx context.Context) (acquired bool, done <-chan struct{}, err error) {
+	return l.tryLock(ctx, false)
+}
+
+func (l *Lock) Unlock() error {
+	return l.unlock(false)
+}
+
+func (l *Lock) RLock(ctx context.Context) (<-chan struct{}, error) {
+	return l.lock(ctx, true, true)
+}
+
+func (l *Lock) TryRLock(ctx context.Context) (acquired bool, done <-chan struct{}, err error) {
+	return l.tryLock(ctx, true)
+}
+
+func (l *Lock) RUnlock() error {
+	return l.unlock(true)
+}
+
+func (l *Lock) unlock(shared bool) error {
+	return l.scheduler.Done(func() error {
+		if l.held == nil {
+			panic("never acquired")
+		}
+		if l.shared != shared {
+			return lock.ErrLockMode
+		}
+		// releasing an expired acquisition is a no-op, since the store only releases the holder it knows of
+		l.store.unlock(l.key, l.held, false)
+		l.held.close()
+		l.held = nil
+		l.token.Store(0)
+		return nil
+	})
+}
+
+func (l *Lock) Transfer(_ context.Context, toOwner string) error {
+	return l.scheduler.Done(func() error {
+		if l.held == nil {
+			panic("never acquired")
+		}
+		if l.shared {
+			return lock.ErrLockMode
+		}
+		if err := l.store.transfer(l.key, l.held, toOwner); err != nil {
+			return err
+		}
+		l.held = nil
+		l.token.Store(0)
+		return nil
+	})
+}
+
+func (l *Lock) FencingToken() uint64 {
+	return l.token.Load()
+}
@@ -0,0 +1,118 @@
+package memory
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/alexandreLamarre/dlock/pkg/lock"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type LockManagerOptions struct {
+	// TTL after which every acquisition expires, simulating a holder that stopped keeping its lock alive.
+	// Acquisitions never expire on their own when it is zero.
+	// The timings of lock.LockOptions do not apply, since holders of the same process never stop keeping their
+	// locks alive, and blocked acquisitions are woken up by releases.
+	TTL time.Duration
+}
+
+type LockManagerOption func(o *LockManagerOptions)
+
+func (o *LockManagerOptions) Apply(opts ...LockManagerOption) {
+	for _, op := range opts {
+		op(o)
+	}
+}
+
+func WithTTL(ttl time.Duration) LockManagerOption {
+	return func(o *LockManagerOptions) {
+		o.TTL = ttl
+	}
+}
+
+// LockManager holds locks in the memory of the process, it is meant as a stand-in for
+// the distributed backends in unit tests, or to synchronize goroutines of a single process.
+// Locks of the same LockManager exclude each other like locks of separate processes would.
+type LockManager struct {
+	store  *store
+	ttl    time.Duration
+	tracer trace.Tracer
+
+	lg *slog.Logger
+
+	reentrant *lock.ReentrantLocks
+}
+
+var _ lock.LockManager = (*LockManager)(nil)
+var _ lock.TTLDefaulter = (*LockManager)(nil)
+
+func NewLockManager(tracer trace.Tracer, lg *slog.Logger, opts ...LockManagerOption) *LockManager {
+	options := &LockManagerOptions{}
+	options.Apply(opts...)
+	return &LockManager{
+		store:     newStore(),
+		ttl:       options.TTL,
+		tracer:    tracer,
+		lg:        lg,
+		reentrant: lock.NewReentrantLocks(),
+	}
+}
+
+func (l *LockManager) DefaultTTL() time.Duration {
+	return l.ttl
+}
+
+func (l *LockManager) Health(_ context.Context) (conditions []string, err error) {
+	return []string{}, nil
+}
+
+func (l *LockManager) NewLock(key string, opts ...lock.LockOption) lock.Lock {
+	options := lock.DefaultLockOptions()
+	options.Apply(opts...)
+	return l.reentrant.NewLock(key, options, func() lock.Lock {
+		return newLock(l.store, key, l.ttl, l.lg.With("key", key), options)
+	})
+}
+
+func (l *LockManager) NewRWLock(key string, opts ...lock.LockOption) lock.RWLock {
+	options := lock.DefaultLockOptions()
+	options.Apply(opts...)
+	return newLock(l.store, key, l.ttl, l.lg.With("key", key), options)
+}
+
+func (l *LockManager) NewMultiLock(keys []string, opts ...lock.LockOption) lock.Lock {
+	return lock.NewMultiLock(keys, func(key string) lock.Lock {
+		return l.NewLock(key, opts...)
+	})
+}
+
+func (l *LockManager) NewSemaphore(key string, capacity int64, opts ...lock.LockOption) lock.Semaphore {
+	options := lock.DefaultLockOptions()
+	options.Apply(opts...)
+	return newSemaphore(l.store, key, capacity, l.ttl, l.lg.With("key", key), options)
+}
+
+func (l *LockManager) ListLocks(_ context.Context, prefix string) ([]lock.LockInfo, error) {
+	return l.store.list(prefix), nil
+}
+
+func (l *LockManager) DescribeLock(_ context.Context, key string) (lock.LockInfo, error) {
+	return l.store.describe(key), nil
+}
+
+func (l *LockManager) ForceRelease(_ context.Context, key, reason string) (lock.LockInfo, error) {
+	info := l.store.forceRelease(key)
+	l.lg.With("key", key, "reason", reason, "holders", len(info.Holders)).Warn("force released lock")
+	return info, nil
+}
+
+func (l *LockManager) Watch(ctx context.Context, prefix string) (<-chan lock.WatchEvent, error) {
+	return l.store.watchers.Watch(ctx, "", prefix), nil
+}
+
+// Expire simulates the expiry of every lock & semaphore acquisition held on the key, as if their holders
+// had crashed : they are released and their expired channels are signaled.
+func (l *LockManager) Expire(key string) {
+	l.store.expire(key)
+}
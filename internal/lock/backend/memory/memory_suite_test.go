@@ -0,0 +1,117 @@
+package memory_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alexandreLamarre/dlock/internal/lock/backend/memory"
+	"github.com/alexandreLamarre/dlock/pkg/lock"
+	"github.com/alexandreLamarre/dlock/pkg/logger"
+	"github.com/alexandreLamarre/dlock/pkg/test/conformance/integration"
+	"github.com/alexandreLamarre/dlock/pkg/util/future"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/samber/lo"
+)
+
+func TestMemory(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Memory Suite")
+}
+
+var lmF = future.New[lock.LockManager]()
+var lmSetF = future.New[lo.Tuple3[
+	lock.LockManager, lock.LockManager, lock.LockManager,
+]]()
+
+var _ = BeforeSuite(func() {
+	lm := memory.NewLockManager(nil, logger.NewNop())
+	lmF.Set(lm)
+	// there is no client connection to separate, locks of the same lock manager exclude each other
+	lmSetF.Set(lo.Tuple3[lock.LockManager, lock.LockManager, lock.LockManager]{
+		A: lm, B: lm, C: lm,
+	})
+})
+
+// the memory lock manager has no external dependencies, so it runs the conformance suite as a unit test
+var _ = Describe("Memory Lock Manager", Ordered, Label("unit"), integration.LockManagerTestSuite(lmF, lmSetF))
+
+var _ = Describe("Memory Lock Manager expiry", Label("unit"), func() {
+	var ctx context.Context
+	BeforeEach(func() {
+		ctxca, ca := context.WithCancel(context.Background())
+		DeferCleanup(ca)
+		ctx = ctxca
+	})
+
+	When("an expiry is injected", func() {
+		It("should release the locks & semaphores held on the key", func() {
+			lm := memory.NewLockManager(nil, logger.NewNop())
+			l := lm.NewLock("foo")
+			expired, err := l.Lock(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			sem := lm.NewSemaphore("foo", 2)
+			semExpired, err := sem.Acquire(ctx, 2)
+			Expect(err).NotTo(HaveOccurred())
+
+			lm.Expire("foo")
+			Eventually(expired).Should(Receive())
+			Eventually(semExpired).Should(Receive())
+
+			other := lm.NewLock("foo")
+			acquired, _, err := other.TryLock(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(acquired).To(BeTrue())
+			Expect(other.FencingToken()).To(BeNumerically(">", 1))
+
+			// releasing an expired acquisition must not release the new holder
+			Expect(l.Unlock()).To(Succeed())
+			acquired, _, err = lm.NewLock("foo").TryLock(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(acquired).To(BeFalse())
+			Expect(other.Unlock()).To(Succeed())
+			Expect(sem.Release()).To(Succeed())
+		})
+	})
+
+	When("locks are watched", func() {
+		It("should stream the expiry of their holders", func() {
+			lm := memory.NewLockManager(nil, logger.NewNop())
+			events, err := lm.Watch(ctx, "")
+			Expect(err).NotTo(HaveOccurred())
+			l := lm.NewRWLock("foo")
+			_, err = l.RLock(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			var ev lock.WatchEvent
+			Eventually(events).Should(Receive(&ev))
+			Expect(ev.Type).To(Equal(lock.WatchAcquired))
+			Expect(ev.Holder.Shared).To(BeTrue())
+
+			lm.Expire("foo")
+			Eventually(events).Should(Receive(&ev))
+			Expect(ev.Type).To(Equal(lock.WatchExpired))
+			Expect(ev.Key).To(Equal("foo"))
+
+			// releasing an expired acquisition is not an event
+			Expect(l.RUnlock()).To(Succeed())
+			Consistently(events).ShouldNot(Receive())
+		})
+	})
+
+	When("a TTL is configured", func() {
+		It("should expire acquisitions after the TTL", func() {
+			lm := memory.NewLockManager(nil, logger.NewNop(), memory.WithTTL(100*time.Millisecond))
+			l := lm.NewLock("bar")
+			expired, err := l.Lock(ctx)
+			Expect(err).NotTo(HaveOccurred())
+
+			waiter := lm.NewLock("bar")
+			_, err = waiter.Lock(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(expired).To(Receive())
+			Expect(waiter.Unlock()).To(Succeed())
+			Expect(l.Unlock()).To(Succeed())
+		})
+	})
+})
@@ -0,0 +1,111 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/alexandreLamarre/dlock/pkg/lock"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Semaphore's units are held in the memory of the process
+type Semaphore struct {
+	store    *store
+	key      string
+	capacity int64
+	ttl      time.Duration
+
+	*lock.LockOptions
+
+	scheduler *lock.LockScheduler
+	held      *holder
+
+	lg *slog.Logger
+}
+
+var _ lock.Semaphore = (*Semaphore)(nil)
+
+func newSemaphore(store *store, key string, capacity int64, ttl time.Duration, lg *slog.Logger, options *lock.LockOptions) *Semaphore {
+	return &Semaphore{
+		store:       store,
+		key:         key,
+		capacity:    capacity,
+		ttl:         ttl,
+		lg:          lg.With("capacity", capacity),
+		LockOptions: options,
+		scheduler:   lock.NewLockScheduler(),
+	}
+}
+
+func (s *Semaphore) Capacity() int64 {
+	return s.capacity
+}
+
+func (s *Semaphore) Acquire(ctx context.Context, n int64) (<-chan struct{}, error) {
+	return s.acquire(ctx, n, true)
+}
+
+func (s *Semaphore) TryAcquire(ctx context.Context, n int64) (acquired bool, done <-chan struct{}, err error) {
+	done, err = s.acquire(ctx, n, false)
+	if err != nil {
+		if errors.Is(err, errLocked) {
+			return false, nil, nil
+		}
+		return false, nil, err
+	}
+	return true, done, nil
+}
+
+func (s *Semaphore) acquire(ctx context.Context, n int64, block bool) (<-chan struct{}, error) {
+	if err := lock.ValidateWeight(n, s.capacity); err != nil {
+		return nil, err
+	}
+	if s.Tracer != nil {
+		ctxSpan, span := s.Tracer.Start(ctx, "Acquire/memory-semaphore", trace.WithAttributes())
+		defer span.End()
+		ctx = ctxSpan
+	}
+
+	var closureDone <-chan struct{}
+	if err := s.scheduler.Schedule(func() error {
+		h := newHolder(s.Holder(false), s.ttl)
+		for {
+			ok, changed := s.store.tryAcquire(s.key, h, n, s.capacity)
+			if ok {
+				break
+			}
+			if !block {
+				return errLocked
+			}
+			select {
+			case <-ctx.Done():
+				return errors.Join(ctx.Err(), errLocked)
+			case <-changed:
+			}
+		}
+		h.expireAfterTTL(func() {
+			s.lg.Warn("semaphore expired")
+			s.store.release(s.key, h)
+		})
+		s.held = h
+		closureDone = h.expired
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return closureDone, nil
+}
+
+func (s *Semaphore) Release() error {
+	return s.scheduler.Done(func() error {
+		if s.held == nil {
+			panic("never acquired")
+		}
+		s.store.release(s.key, s.held)
+		s.held.close()
+		s.held = nil
+		return nil
+	})
+}
@@ -0,0 +1,387 @@
+package memory
+
+import (
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/alexandreLamarre/dlock/pkg/lock"
+)
+
+// holder is a single acquisition of a lock or semaphore,
+// its expired channel receives a single value once it is released or expired
+type holder struct {
+	info lock.HolderInfo
+	// simulated TTL, and its deadline set by the store on acquisition
+	ttl      time.Duration
+	deadline time.Time
+
+	expired chan struct{}
+	once    sync.Once
+	// simulated TTL of the acquisition, if any
+	timer atomic.Pointer[time.Timer]
+}
+
+func newHolder(info lock.HolderInfo, ttl time.Duration) *holder {
+	return &holder{
+		info:    info,
+		ttl:     ttl,
+		expired: make(chan struct{}, 1),
+	}
+}
+
+// acquired must be called by the store, when it records the acquisition
+func (h *holder) acquired() {
+	h.info.AcquiredAt = time.Now()
+	if h.ttl > 0 {
+		h.deadline = h.info.AcquiredAt.Add(h.ttl)
+	}
+}
+
+// expireAfterTTL simulates the expiry of the acquisition, by calling release
+func (h *holder) expireAfterTTL(release func()) {
+	if h.ttl <= 0 {
+		return
+	}
+	h.timer.Store(time.AfterFunc(h.ttl, func() {
+		release()
+		h.close()
+	}))
+}
+
+func (h *holder) close() {
+	h.once.Do(func() {
+		if t := h.timer.Load(); t != nil {
+			t.Stop()
+		}
+		h.expired <- struct{}{}
+	})
+}
+
+type lockState struct {
+	owner   *holder
+	readers map[*holder]struct{}
+}
+
+// store is the lock state shared by every lock of a LockManager
+type store struct {
+	mu sync.Mutex
+
+	locks      map[string]*lockState
+	semaphores map[string]map[*holder]int64
+	// fencing tokens outlive the locks they were issued for
+	tokens map[string]uint64
+	// blocking acquisitions waiting for each lock, in arrival order
+	waiters map[string][]*holder
+	// fair blocking acquisitions waiting for each lock, in arrival order
+	queues map[string][]*holder
+
+	// closed and replaced every time something is released, to wake up blocked acquisitions
+	changed chan struct{}
+
+	watchers lock.Watchers
+}
+
+func newStore() *store {
+	return &store{
+		locks:      map[string]*lockState{},
+		semaphores: map[string]map[*holder]int64{},
+		tokens:     map[string]uint64{},
+		waiters:    map[string][]*holder{},
+		queues:     map[string][]*holder{},
+		changed:    make(chan struct{}),
+	}
+}
+
+func (s *store) broadcast() {
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+// tryLock acquires the lock for h, returning the fencing token of exclusive acquisitions.
+// Fair acquisitions are only granted to the head of the queue of the lock, or when nobody is queued
+// if h is not queued.
+// The returned channel is closed on the next release when the lock is not acquired.
+func (s *store) tryLock(key string, h *holder, shared, fair bool) (ok bool, token uint64, changed <-chan struct{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st := s.locks[key]
+	if st != nil && st.owner == h {
+		// the lock was transferred to h while it was waiting
+		return true, s.tokens[key], nil
+	}
+	if q := s.queues[key]; fair && len(q) > 0 && q[0] != h {
+		return false, 0, s.changed
+	}
+	if st == nil {
+		st = &lockState{readers: map[*holder]struct{}{}}
+		s.locks[key] = st
+	}
+	if st.owner != nil || (!shared && len(st.readers) > 0) {
+		return false, 0, s.changed
+	}
+	h.acquired()
+	s.dequeueLocked(key, h)
+	s.watchers.Publish(lock.WatchEvent{Type: lock.WatchAcquired, Key: key, Holder: h.info})
+	if shared {
+		st.readers[h] = struct{}{}
+		return true, 0, nil
+	}
+	st.owner = h
+	s.tokens[key]++
+	return true, s.tokens[key], nil
+}
+
+// wait registers a blocking acquisition of the lock until the returned func is called
+func (s *store) wait(key string, h *holder) (done func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.waiters[key] = append(s.waiters[key], h)
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		w := slices.DeleteFunc(s.waiters[key], func(waiter *holder) bool {
+			return waiter == h
+		})
+		if len(w) == 0 {
+			delete(s.waiters, key)
+		} else {
+			s.waiters[key] = w
+		}
+	}
+}
+
+// position returns the number of blocking acquisitions that started waiting for the lock before h
+func (s *store) position(key string, h *holder) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := slices.Index(s.waiters[key], h)
+	if i < 0 {
+		return 0, lock.ErrNotQueued
+	}
+	return i, nil
+}
+
+// enqueue queues a fair blocking acquisition of the lock until it is dequeued
+func (s *store) enqueue(key string, h *holder) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.queues[key] = append(s.queues[key], h)
+}
+
+func (s *store) dequeue(key string, h *holder) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dequeueLocked(key, h)
+}
+
+// dequeueLocked wakes up blocked acquisitions when h was queued, since the next waiter may now be at the head
+func (s *store) dequeueLocked(key string, h *holder) {
+	q := s.queues[key]
+	i := slices.Index(q, h)
+	if i < 0 {
+		return
+	}
+	q = slices.Delete(q, i, i+1)
+	if len(q) == 0 {
+		delete(s.queues, key)
+	} else {
+		s.queues[key] = q
+	}
+	s.broadcast()
+}
+
+func (s *store) describe(key string) lock.LockInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.describeLocked(key)
+}
+
+func (s *store) describeLocked(key string) lock.LockInfo {
+	info := lock.LockInfo{
+		Key:     key,
+		Holders: []lock.HolderInfo{},
+		Waiters: len(s.waiters[key]),
+	}
+	st := s.locks[key]
+	if st == nil {
+		return info
+	}
+	holders := []*holder{}
+	if st.owner != nil {
+		holders = append(holders, st.owner)
+	}
+	for h := range st.readers {
+		holders = append(holders, h)
+	}
+	for _, h := range holders {
+		hi := h.info
+		if !h.deadline.IsZero() {
+			hi.TTL = max(time.Until(h.deadline), 0)
+		}
+		info.Holders = append(info.Holders, hi)
+	}
+	slices.SortFunc(info.Holders, func(a, b lock.HolderInfo) int {
+		return a.AcquiredAt.Compare(b.AcquiredAt)
+	})
+	return info
+}
+
+func (s *store) list(prefix string) []lock.LockInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	keys := []string{}
+	for key := range s.locks {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	for key := range s.waiters {
+		if _, ok := s.locks[key]; !ok && strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+	infos := []lock.LockInfo{}
+	for _, key := range keys {
+		infos = append(infos, s.describeLocked(key))
+	}
+	return infos
+}
+
+// unlock releases the acquisition of h if it still holds the lock, expired being set when its TTL elapsed
+func (s *store) unlock(key string, h *holder, expired bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st := s.locks[key]
+	if st == nil {
+		return
+	}
+	_, reader := st.readers[h]
+	if st.owner != h && !reader {
+		return
+	}
+	if st.owner == h {
+		st.owner = nil
+	}
+	delete(st.readers, h)
+	ev := lock.WatchEvent{Type: lock.WatchReleased, Key: key, Holder: h.info}
+	if expired {
+		ev.Type = lock.WatchExpired
+	}
+	s.watchers.Publish(ev)
+	if st.owner == nil && len(st.readers) == 0 {
+		delete(s.locks, key)
+	}
+	s.broadcast()
+}
+
+// transfer makes the earliest exclusive waiter of the owner hold the lock in place of h, which is closed
+func (s *store) transfer(key string, h *holder, toOwner string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st := s.locks[key]
+	if st == nil || st.owner != h {
+		return lock.ErrNotHeld
+	}
+	i := slices.IndexFunc(s.waiters[key], func(w *holder) bool {
+		return !w.info.Shared && w.info.Owner == toOwner
+	})
+	if i < 0 {
+		return lock.ErrNoTransferee
+	}
+	to := s.waiters[key][i]
+	to.acquired()
+	st.owner = to
+	s.tokens[key]++
+	s.dequeueLocked(key, to)
+	s.watchers.Publish(lock.WatchEvent{Type: lock.WatchReleased, Key: key, Holder: h.info})
+	s.watchers.Publish(lock.WatchEvent{Type: lock.WatchAcquired, Key: key, Holder: to.info})
+	s.broadcast()
+	h.close()
+	return nil
+}
+
+func (s *store) tryAcquire(key string, h *holder, n, capacity int64) (ok bool, changed <-chan struct{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	holders := s.semaphores[key]
+	held := n
+	for _, w := range holders {
+		held += w
+	}
+	if held > capacity {
+		return false, s.changed
+	}
+	if holders == nil {
+		holders = map[*holder]int64{}
+		s.semaphores[key] = holders
+	}
+	h.acquired()
+	holders[h] = n
+	return true, nil
+}
+
+func (s *store) release(key string, h *holder) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.releaseLocked(key, h)
+}
+
+func (s *store) releaseLocked(key string, h *holder) {
+	delete(s.semaphores[key], h)
+	if len(s.semaphores[key]) == 0 {
+		delete(s.semaphores, key)
+	}
+	s.broadcast()
+}
+
+// forceRelease releases every lock acquisition held on the key, and signals their expired channels
+func (s *store) forceRelease(key string) lock.LockInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	info := s.describeLocked(key)
+	if st := s.locks[key]; st != nil {
+		if st.owner != nil {
+			st.owner.close()
+		}
+		for h := range st.readers {
+			h.close()
+		}
+		delete(s.locks, key)
+	}
+	for _, h := range info.Holders {
+		s.watchers.Publish(lock.WatchEvent{Type: lock.WatchExpired, Key: key, Holder: h})
+	}
+	s.broadcast()
+	return info
+}
+
+// expire releases every lock & semaphore acquisition held on the key, and signals their expired channels
+func (s *store) expire(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	holders := []*holder{}
+	if st := s.locks[key]; st != nil {
+		if st.owner != nil {
+			holders = append(holders, st.owner)
+		}
+		for h := range st.readers {
+			holders = append(holders, h)
+		}
+		delete(s.locks, key)
+		for _, h := range holders {
+			s.watchers.Publish(lock.WatchEvent{Type: lock.WatchExpired, Key: key, Holder: h.info})
+		}
+	}
+	for h := range s.semaphores[key] {
+		holders = append(holders, h)
+	}
+	delete(s.semaphores, key)
+	for _, h := range holders {
+		h.close()
+	}
+	s.broadcast()
+}
@@ -0,0 +1,484 @@
+package raft
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alexandreLamarre/dlock/pkg/lock"
+	hraft "github.com/hashicorp/raft"
+)
+
+type Op int
+
+const (
+	OpAcquire Op = iota
+	OpKeepalive
+	OpRelease
+	// OpExpire releases every session whose deadline has passed, it is periodically applied by the leader
+	OpExpire
+	// OpForceRelease releases every session holding the lock on the key, on behalf of an operator
+	OpForceRelease
+	// OpTransfer hands the exclusive lock held by the session over to the earliest exclusive waiter of the owner
+	OpTransfer
+)
+
+type Kind int
+
+const (
+	KindExclusive Kind = iota
+	KindShared
+	KindSemaphore
+)
+
+// Command is a replicated operation on the lock state.
+// Now is set by the leader when the command is applied, so that every node expires sessions identically.
+type Command struct {
+	Op      Op
+	Session string
+	Key     string
+	Kind    Kind
+	// weight & capacity of semaphore acquisitions
+	Weight   int64
+	Capacity int64
+	// TTL of the session, in nanoseconds
+	TTL int64
+	Now int64
+	// Wait registers the session as a waiter of the key when it can't be acquired
+	Wait bool
+	// Fair acquisitions never overtake the fair waiters of the key that registered before them
+	Fair   bool
+	Holder lock.HolderInfo
+	// To is the owner of the waiter OpTransfer hands the lock over to
+	To string
+}
+
+type Result struct {
+	Ok bool
+	// index of the log entry that acquired an exclusive lock, raft indices are monotonically increasing
+	FencingToken uint64
+	// Evicted describes the holders released by OpForceRelease
+	Evicted lock.LockInfo
+	// NotHeld is set when OpTransfer is applied by a session that no longer holds the lock
+	NotHeld bool
+}
+
+// session holds exactly one acquisition, it is released when its deadline passes without a keepalive
+type session struct {
+	Key      string          `json:"key"`
+	Kind     Kind            `json:"kind"`
+	Weight   int64           `json:"weight"`
+	Deadline int64           `json:"deadline"`
+	Holder   lock.HolderInfo `json:"holder"`
+	// index of the log entry that acquired an exclusive lock, returned to retried acquisitions
+	FencingToken uint64 `json:"fencingToken"`
+}
+
+// waiter is a session blocked on acquiring a key, it is refreshed by every attempt
+type waiter struct {
+	Key      string `json:"key"`
+	Deadline int64  `json:"deadline"`
+	Fair     bool   `json:"fair"`
+	// Ticket is the index of the log entry that registered the waiter, ordering fair waiters
+	Ticket uint64 `json:"ticket"`
+	// kind, TTL & holder of the acquisition, so that locks can be transferred to the waiter
+	Kind   Kind            `json:"kind"`
+	TTL    int64           `json:"ttl"`
+	Holder lock.HolderInfo `json:"holder"`
+}
+
+type fsmState struct {
+	Sessions map[string]*session `json:"sessions"`
+	Waiters  map[string]*waiter  `json:"waiters"`
+}
+
+type lockState struct {
+	owner   string
+	readers map[string]struct{}
+}
+
+// fsm is the replicated lock state, indexed by key from the sessions it holds
+type fsm struct {
+	mu sync.Mutex
+
+	sessions   map[string]*session
+	waiters    map[string]*waiter
+	locks      map[string]*lockState
+	semaphores map[string]map[string]int64
+
+	// every node applies the log, so the watches of each node are notified locally
+	watchers lock.Watchers
+}
+
+var _ hraft.FSM = (*fsm)(nil)
+
+func newFSM() *fsm {
+	return &fsm{
+		sessions:   map[string]*session{},
+		waiters:    map[string]*waiter{},
+		locks:      map[string]*lockState{},
+		semaphores: map[string]map[string]int64{},
+	}
+}
+
+func (f *fsm) Apply(l *hraft.Log) interface{} {
+	var cmd Command
+	if err := json.Unmarshal(l.Data, &cmd); err != nil {
+		return fmt.Errorf("failed to decode command : %w", err)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch cmd.Op {
+	case OpAcquire:
+		return f.acquire(cmd, l.Index)
+	case OpKeepalive:
+		s, ok := f.sessions[cmd.Session]
+		if ok {
+			s.Deadline = cmd.Now + cmd.TTL
+		}
+		return Result{Ok: ok}
+	case OpRelease:
+		_, ok := f.sessions[cmd.Session]
+		f.release(cmd.Session, lock.WatchReleased)
+		return Result{Ok: ok}
+	case OpExpire:
+		for id, s := range f.sessions {
+			if s.Deadline < cmd.Now {
+				f.release(id, lock.WatchExpired)
+			}
+		}
+		for id, w := range f.waiters {
+			if w.Deadline < cmd.Now {
+				delete(f.waiters, id)
+			}
+		}
+		return Result{Ok: true}
+	case OpForceRelease:
+		evicted := f.describeLocked(cmd.Key, time.Unix(0, cmd.Now))
+		if st := f.locks[cmd.Key]; st != nil {
+			if st.owner != "" {
+				f.release(st.owner, lock.WatchExpired)
+			}
+			for id := range st.readers {
+				f.release(id, lock.WatchExpired)
+			}
+		}
+		return Result{Ok: true, Evicted: evicted}
+	case OpTransfer:
+		return f.transfer(cmd, l.Index)
+	default:
+		return fmt.Errorf("unknown op %d", cmd.Op)
+	}
+}
+
+func (f *fsm) acquire(cmd Command, index uint64) Result {
+	ticket := index
+	if w, ok := f.waiters[cmd.Session]; ok {
+		ticket = w.Ticket
+	}
+	res := Result{}
+	if _, held := f.sessions[cmd.Session]; held || !cmd.Fair || !f.overtakes(cmd, ticket) {
+		res = f.tryAcquire(cmd, index)
+	}
+	if res.Ok || !cmd.Wait {
+		delete(f.waiters, cmd.Session)
+		return res
+	}
+	f.waiters[cmd.Session] = &waiter{
+		Key:      cmd.Key,
+		Deadline: cmd.Now + cmd.TTL,
+		Fair:     cmd.Fair,
+		Ticket:   ticket,
+		Kind:     cmd.Kind,
+		TTL:      cmd.TTL,
+		Holder:   cmd.Holder,
+	}
+	return res
+}
+
+// transfer releases the exclusive lock of the session and acquires it for the earliest exclusive waiter of the
+// owner, which finds its session held on its next attempt
+func (f *fsm) transfer(cmd Command, index uint64) Result {
+	st := f.locks[cmd.Key]
+	if _, ok := f.sessions[cmd.Session]; !ok || st == nil || st.owner != cmd.Session {
+		return Result{NotHeld: true}
+	}
+	to := ""
+	for id, w := range f.waiters {
+		if w.Key != cmd.Key || w.Kind != KindExclusive || w.Holder.Owner != cmd.To || w.Deadline < cmd.Now {
+			continue
+		}
+		if to == "" || w.Ticket < f.waiters[to].Ticket {
+			to = id
+		}
+	}
+	if to == "" {
+		return Result{}
+	}
+	w := f.waiters[to]
+	delete(f.waiters, to)
+	f.release(cmd.Session, lock.WatchReleased)
+	return f.tryAcquire(Command{
+		Session: to,
+		Key:     cmd.Key,
+		Kind:    KindExclusive,
+		TTL:     w.TTL,
+		Now:     cmd.Now,
+		Holder:  w.Holder,
+	}, index)
+}
+
+// overtakes reports whether the acquisition would overtake a fair waiter of the key that registered before it,
+// acquisitions that do not wait being behind every waiter
+func (f *fsm) overtakes(cmd Command, ticket uint64) bool {
+	for id, w := range f.waiters {
+		if id == cmd.Session || !w.Fair || w.Key != cmd.Key || w.Deadline < cmd.Now {
+			continue
+		}
+		if !cmd.Wait || w.Ticket < ticket {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *fsm) tryAcquire(cmd Command, index uint64) Result {
+	if s, ok := f.sessions[cmd.Session]; ok {
+		// the command was retried after being applied, or the lock was transferred to the session
+		return Result{Ok: true, FencingToken: s.FencingToken}
+	}
+	res := Result{}
+	switch cmd.Kind {
+	case KindSemaphore:
+		holders := f.semaphores[cmd.Key]
+		held := cmd.Weight
+		for _, w := range holders {
+			held += w
+		}
+		if held > cmd.Capacity {
+			return res
+		}
+		if holders == nil {
+			holders = map[string]int64{}
+			f.semaphores[cmd.Key] = holders
+		}
+		holders[cmd.Session] = cmd.Weight
+	default:
+		st := f.locks[cmd.Key]
+		if st == nil {
+			st = &lockState{readers: map[string]struct{}{}}
+			f.locks[cmd.Key] = st
+		}
+		if st.owner != "" {
+			return res
+		}
+		if cmd.Kind == KindShared {
+			st.readers[cmd.Session] = struct{}{}
+		} else {
+			if len(st.readers) > 0 {
+				return res
+			}
+			st.owner = cmd.Session
+			res.FencingToken = index
+		}
+	}
+	holder := cmd.Holder
+	holder.AcquiredAt = time.Unix(0, cmd.Now)
+	f.sessions[cmd.Session] = &session{
+		Key:          cmd.Key,
+		Kind:         cmd.Kind,
+		Weight:       cmd.Weight,
+		Deadline:     cmd.Now + cmd.TTL,
+		Holder:       holder,
+		FencingToken: res.FencingToken,
+	}
+	if cmd.Kind != KindSemaphore {
+		holder.Shared = cmd.Kind == KindShared
+		f.watchers.Publish(lock.WatchEvent{Type: lock.WatchAcquired, Key: cmd.Key, Holder: holder})
+	}
+	res.Ok = true
+	return res
+}
+
+// release releases the session, notifying watches of the lock it held with an event of the type
+func (f *fsm) release(id string, typ lock.WatchEventType) {
+	delete(f.waiters, id)
+	s, ok := f.sessions[id]
+	if !ok {
+		return
+	}
+	delete(f.sessions, id)
+	switch s.Kind {
+	case KindSemaphore:
+		delete(f.semaphores[s.Key], id)
+		if len(f.semaphores[s.Key]) == 0 {
+			delete(f.semaphores, s.Key)
+		}
+	default:
+		st := f.locks[s.Key]
+		if st == nil {
+			return
+		}
+		holder := s.Holder
+		holder.Shared = s.Kind == KindShared
+		f.watchers.Publish(lock.WatchEvent{Type: typ, Key: s.Key, Holder: holder})
+		if st.owner == id {
+			st.owner = ""
+		}
+		delete(st.readers, id)
+		if st.owner == "" && len(st.readers) == 0 {
+			delete(f.locks, s.Key)
+		}
+	}
+}
+
+func (f *fsm) Snapshot() (hraft.FSMSnapshot, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	data, err := json.Marshal(fsmState{
+		Sessions: f.sessions,
+		Waiters:  f.waiters,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &fsmSnapshot{data: data}, nil
+}
+
+// Restore rebuilds the key indices from the sessions of the snapshot
+func (f *fsm) Restore(r io.ReadCloser) error {
+	defer r.Close()
+	state := fsmState{
+		Sessions: map[string]*session{},
+		Waiters:  map[string]*waiter{},
+	}
+	if err := json.NewDecoder(r).Decode(&state); err != nil {
+		return err
+	}
+	sessions := state.Sessions
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sessions = sessions
+	f.waiters = state.Waiters
+	f.locks = map[string]*lockState{}
+	f.semaphores = map[string]map[string]int64{}
+	for id, s := range sessions {
+		switch s.Kind {
+		case KindSemaphore:
+			if f.semaphores[s.Key] == nil {
+				f.semaphores[s.Key] = map[string]int64{}
+			}
+			f.semaphores[s.Key][id] = s.Weight
+		default:
+			st := f.locks[s.Key]
+			if st == nil {
+				st = &lockState{readers: map[string]struct{}{}}
+				f.locks[s.Key] = st
+			}
+			if s.Kind == KindShared {
+				st.readers[id] = struct{}{}
+			} else {
+				st.owner = id
+			}
+		}
+	}
+	return nil
+}
+
+// describe reports the holders of the lock on the key, the TTL of holders being relative to now
+func (f *fsm) describe(key string, now time.Time) lock.LockInfo {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.describeLocked(key, now)
+}
+
+func (f *fsm) describeLocked(key string, now time.Time) lock.LockInfo {
+	info := lock.LockInfo{
+		Key:     key,
+		Holders: []lock.HolderInfo{},
+	}
+	for _, w := range f.waiters {
+		if w.Key == key {
+			info.Waiters++
+		}
+	}
+	st := f.locks[key]
+	if st == nil {
+		return info
+	}
+	ids := []string{}
+	if st.owner != "" {
+		ids = append(ids, st.owner)
+	}
+	for id := range st.readers {
+		ids = append(ids, id)
+	}
+	for _, id := range ids {
+		s := f.sessions[id]
+		holder := s.Holder
+		holder.Shared = s.Kind == KindShared
+		holder.TTL = max(time.Duration(s.Deadline-now.UnixNano()), 0)
+		info.Holders = append(info.Holders, holder)
+	}
+	slices.SortFunc(info.Holders, func(a, b lock.HolderInfo) int {
+		return a.AcquiredAt.Compare(b.AcquiredAt)
+	})
+	return info
+}
+
+// position returns the number of waiters of the key that registered before the session
+func (f *fsm) position(session string, now time.Time) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	w, ok := f.waiters[session]
+	if !ok || w.Deadline < now.UnixNano() {
+		return 0, lock.ErrNotQueued
+	}
+	ahead := 0
+	for id, other := range f.waiters {
+		if id != session && other.Key == w.Key && other.Deadline >= now.UnixNano() && other.Ticket < w.Ticket {
+			ahead++
+		}
+	}
+	return ahead, nil
+}
+
+func (f *fsm) list(prefix string, now time.Time) []lock.LockInfo {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	keys := map[string]struct{}{}
+	for key := range f.locks {
+		keys[key] = struct{}{}
+	}
+	for _, w := range f.waiters {
+		keys[w.Key] = struct{}{}
+	}
+	infos := []lock.LockInfo{}
+	for key := range keys {
+		if strings.HasPrefix(key, prefix) {
+			infos = append(infos, f.describeLocked(key, now))
+		}
+	}
+	slices.SortFunc(infos, func(a, b lock.LockInfo) int {
+		return strings.Compare(a.Key, b.Key)
+	})
+	return infos
+}
+
+type fsmSnapshot struct {
+	data []byte
+}
+
+func (s *fsmSnapshot) Persist(sink hraft.SnapshotSink) error {
+	if _, err := sink.Write(s.data); err != nil {
+		return errors.Join(fmt.Errorf("failed to persist snapshot : %w", err), sink.Cancel())
+	}
+	return sink.Close()
+}
+
+func (s *fsmSnapshot) Release() {}
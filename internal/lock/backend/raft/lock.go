@@ -0,0 +1,183 @@
+package raft
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"github.com/alexandreLamarre/dlock/pkg/lock"
+	backoffv2 "github.com/lestrrat-go/backoff/v2"
+	"github.com/samber/lo"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Lock holds a session on a key of the replicated lock state, readers holding shared sessions on the same key
+type Lock struct {
+	node *Node
+	key  string
+
+	*lock.LockOptions
+
+	scheduler *lock.LockScheduler
+	mutex     *raftMutex
+	token     atomic.Uint64
+
+	lg *slog.Logger
+}
+
+var _ lock.RWLock = (*Lock)(nil)
+var _ lock.Transferer = (*Lock)(nil)
+
+func NewLock(node *Node, key string, lg *slog.Logger, options *lock.LockOptions) *Lock {
+	return &Lock{
+		node:        node,
+		key:         key,
+		lg:          lg,
+		LockOptions: options,
+		scheduler:   lock.NewLockScheduler(),
+	}
+}
+
+func retryPolicy(delay time.Duration) *backoffv2.Policy {
+	return lo.ToPtr(backoffv2.Constant(
+		backoffv2.WithMaxRetries(0),
+		backoffv2.WithInterval(delay),
+		backoffv2.WithJitterFactor(0.1),
+	))
+}
+
+func (l *Lock) acquire(ctx context.Context, retrier *backoffv2.Policy, kind Kind) (<-chan struct{}, error) {
+	var curErr error
+	mutex := newRaftMutex(l.lg, l.node, l.key, kind, 0, 0, l.LockOptions)
+	done, err := mutex.tryLock(ctx, retrier != nil)
+	curErr = err
+	if err == nil {
+		l.mutex = &mutex
+		l.token.Store(mutex.fencingToken)
+		return done, nil
+	}
+	if retrier != nil {
+		// the first attempt registered the session as a waiter
+		defer l.NotifyPosition(ctx, func(ctx context.Context) (int, error) {
+			return l.node.position(ctx, mutex.session)
+		})()
+		ret := *retrier
+		acq := ret.Start(ctx)
+		// unlike local backends, operations are retried on any error since leader elections are transient
+		for backoffv2.Continue(acq) {
+			done, err := mutex.tryLock(ctx, true)
+			curErr = err
+			if err == nil {
+				l.mutex = &mutex
+				l.token.Store(mutex.fencingToken)
+				return done, nil
+			}
+		}
+		mutex.abandon()
+		return nil, errors.Join(ctx.Err(), curErr)
+	}
+	if !errors.Is(curErr, errLocked) {
+		mutex.abandon()
+	}
+	return nil, curErr
+}
+
+func (l *Lock) lock(ctx context.Context, retrier *backoffv2.Policy, kind Kind) (<-chan struct{}, error) {
+	if l.Tracer != nil {
+		ctxSpan, span := l.Tracer.Start(ctx, "Lock/raft-lock", trace.WithAttributes())
+		defer span.End()
+		ctx = ctxSpan
+	}
+	// https://github.com/lestrrat-go/backoff/issues/31
+	ctxca, ca := context.WithCancel(ctx)
+	defer ca()
+
+	var closureDone <-chan struct{}
+	if err := l.scheduler.Schedule(func() error {
+		done, err := l.acquire(ctxca, retrier, kind)
+		if err != nil {
+			return err
+		}
+		closureDone = done
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return closureDone, nil
+}
+
+func (l *Lock) tryLock(ctx context.Context, kind Kind) (acquired bool, done <-chan struct{}, err error) {
+	closureDone, err := l.lock(ctx, nil, kind)
+	if err != nil {
+		if errors.Is(err, errLocked) {
+			return false, nil, nil
+		}
+		return false, nil, err
+	}
+	return true, closureDone, nil
+}
+
+func (l *Lock) Lock(ctx context.Context) (<-chan struct{}, error) {
+	return l.lock(ctx, retryPolicy(l.RetryDelayOr(LockRetryDelay)), KindExclusive)
+}
+
+func (l *Lock) TryLock(ctx context.Context) (acquired bool, done <-chan struct{}, err error) {
+	return l.tryLock(ctx, KindExclusive)
+}
+
+func (l *Lock) Unlock() error {
+	return l.unlock(KindExclusive)
+}
+
+func (l *Lock) RLock(ctx context.Context) (<-chan struct{}, error) {
+	return l.lock(ctx, retryPolicy(l.RetryDelayOr(LockRetryDelay)), KindShared)
+}
+
+func (l *Lock) TryRLock(ctx context.Context) (acquired bool, done <-chan struct{}, err error) {
+	return l.tryLock(ctx, KindShared)
+}
+
+func (l *Lock) RUnlock() error {
+	return l.unlock(KindShared)
+}
+
+func (l *Lock) unlock(kind Kind) error {
+	return l.scheduler.Done(func() error {
+		if l.mutex == nil {
+			panic("never acquired")
+		}
+		if l.mutex.kind != kind {
+			return lock.ErrLockMode
+		}
+		mutex := *l.mutex
+		if err := mutex.unlock(); err != nil {
+			l.lg.Error(err.Error())
+		}
+		l.mutex = nil
+		l.token.Store(0)
+		return nil
+	})
+}
+
+func (l *Lock) Transfer(ctx context.Context, toOwner string) error {
+	return l.scheduler.Done(func() error {
+		if l.mutex == nil {
+			panic("never acquired")
+		}
+		if l.mutex.kind != KindExclusive {
+			return lock.ErrLockMode
+		}
+		if err := l.mutex.transfer(ctx, toOwner); err != nil {
+			return err
+		}
+		l.mutex = nil
+		l.token.Store(0)
+		return nil
+	})
+}
+
+func (l *Lock) FencingToken() uint64 {
+	return l.token.Load()
+}
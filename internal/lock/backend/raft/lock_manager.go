@@ -0,0 +1,149 @@
+package raft
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/alexandreLamarre/dlock/pkg/constants"
+	"github.com/alexandreLamarre/dlock/pkg/lock"
+	"github.com/alexandreLamarre/dlock/pkg/lock/broker"
+	"github.com/alexandreLamarre/dlock/pkg/logger"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func init() {
+	broker.RegisterLockBroker(
+		constants.RaftLockManager,
+		func(ctx context.Context, l broker.LockBroker) (broker.LockManagers, error) {
+			spec := l.Config.RaftClientSpec
+			l.Lg.With("id", spec.NodeID, "addr", spec.BindAddr).Info("starting raft node...")
+			node, err := NewNode(spec, l.Lg)
+			if err != nil {
+				l.Lg.With(logger.Err(err)).Warn("failed to start raft node")
+				return nil, err
+			}
+			go func() {
+				<-ctx.Done()
+				if err := node.Shutdown(); err != nil {
+					l.Lg.With(logger.Err(err)).Warn("failed to shutdown raft node")
+				}
+			}()
+			l.Lg.Info("started raft node")
+			return func(prefix string) (lock.LockManager, error) {
+				return NewLockManager(node, prefix, l.Tracer, l.Lg), nil
+			}, nil
+		},
+	)
+}
+
+// LockManager replicates locks between the dlock servers themselves, using an embedded raft cluster.
+// Locks are held by sessions that are released by the leader when their keepalives stop.
+type LockManager struct {
+	node   *Node
+	prefix string
+	tracer trace.Tracer
+
+	lg *slog.Logger
+
+	reentrant *lock.ReentrantLocks
+}
+
+var _ lock.LockManager = (*LockManager)(nil)
+var _ lock.TTLDefaulter = (*LockManager)(nil)
+
+func NewLockManager(
+	node *Node,
+	prefix string,
+	tracer trace.Tracer,
+	lg *slog.Logger,
+) *LockManager {
+	return &LockManager{
+		node:      node,
+		prefix:    prefix,
+		tracer:    tracer,
+		lg:        lg,
+		reentrant: lock.NewReentrantLocks(),
+	}
+}
+
+func (l *LockManager) DefaultTTL() time.Duration {
+	return l.node.TTL()
+}
+
+func (l *LockManager) Health(_ context.Context) (conditions []string, err error) {
+	conditions = []string{}
+	if l.node.Leader() == "" {
+		conditions = append(conditions, errNoLeader.Error())
+	}
+	return conditions, nil
+}
+
+func (l *LockManager) key(key string) string {
+	return l.prefix + "/" + key
+}
+
+func (l *LockManager) ListLocks(ctx context.Context, prefix string) ([]lock.LockInfo, error) {
+	infos, err := l.node.list(ctx, l.key(prefix))
+	if err != nil {
+		return nil, err
+	}
+	for i := range infos {
+		infos[i].Key = strings.TrimPrefix(infos[i].Key, l.key(""))
+	}
+	return infos, nil
+}
+
+func (l *LockManager) DescribeLock(ctx context.Context, key string) (lock.LockInfo, error) {
+	info, err := l.node.describe(ctx, l.key(key))
+	if err != nil {
+		return lock.LockInfo{}, err
+	}
+	info.Key = key
+	return info, nil
+}
+
+// ForceRelease evicts the holders of the lock through the leader, evicted holders notice it
+// when their next keepalive fails, within a third of the session TTL
+func (l *LockManager) ForceRelease(ctx context.Context, key, reason string) (lock.LockInfo, error) {
+	info, err := l.node.forceRelease(ctx, l.key(key))
+	if err != nil {
+		return lock.LockInfo{}, err
+	}
+	info.Key = key
+	l.lg.With("key", key, "reason", reason, "holders", len(info.Holders)).Warn("force released lock")
+	return info, nil
+}
+
+// Watch streams the events applied by the local node, which lag behind the leader by its replication delay
+func (l *LockManager) Watch(ctx context.Context, prefix string) (<-chan lock.WatchEvent, error) {
+	return l.node.fsm.watchers.Watch(ctx, l.key(""), prefix), nil
+}
+
+func (l *LockManager) NewLock(key string, opts ...lock.LockOption) lock.Lock {
+	options := lock.DefaultLockOptions()
+	options.Apply(opts...)
+	return l.reentrant.NewLock(key, options, func() lock.Lock {
+		return NewLock(l.node, l.key(key), l.lg.With("key", key), options)
+	})
+}
+
+func (l *LockManager) NewRWLock(key string, opts ...lock.LockOption) lock.RWLock {
+	options := lock.DefaultLockOptions()
+	options.Apply(opts...)
+	return NewLock(l.node, l.key(key), l.lg.With("key", key), options)
+}
+
+func (l *LockManager) NewMultiLock(keys []string, opts ...lock.LockOption) lock.Lock {
+	return lock.NewMultiLock(keys, func(key string) lock.Lock {
+		return l.NewLock(key, opts...)
+	})
+}
+
+// semaphores use their own keys, so that they never conflict with locks on the same key
+func (l *LockManager) NewSemaphore(key string, capacity int64, opts ...lock.LockOption) lock.Semaphore {
+	options := lock.DefaultLockOptions()
+	options.Apply(opts...)
+	return NewSemaphore(l.node, l.prefix+".semaphore/"+key, capacity, l.lg.With("key", key), options)
+}
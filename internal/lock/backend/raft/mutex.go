@@ -0,0 +1,191 @@
+package raft
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/alexandreLamarre/dlock/pkg/lock"
+	"github.com/alexandreLamarre/dlock/pkg/logger"
+	"github.com/google/uuid"
+	backoffv2 "github.com/lestrrat-go/backoff/v2"
+	"github.com/samber/lo"
+)
+
+var (
+	LockRetryDelay = 50 * time.Millisecond
+)
+
+var errLocked = errors.New("key is locked by someone else")
+
+// encapsulates stateful information and tasks required for holding a session on the replicated lock state
+type raftMutex struct {
+	lg *slog.Logger
+
+	node *Node
+	key  string
+	kind Kind
+	// weight & capacity of semaphore acquisitions
+	weight   int64
+	capacity int64
+
+	// every attempt of an acquisition uses the same session, acquiring it twice is a no-op
+	session      string
+	holder       lock.HolderInfo
+	fencingToken uint64
+	fair         bool
+
+	// TTL of the session, and the interval at which it is kept alive. The leader expires sessions every
+	// quarter of the node's session TTL, so sessions with a shorter TTL may outlive it by that much
+	ttl               time.Duration
+	keepaliveInterval time.Duration
+	retryDelay        time.Duration
+
+	internalDone chan struct{}
+}
+
+func newRaftMutex(
+	lg *slog.Logger,
+	node *Node,
+	key string,
+	kind Kind,
+	weight, capacity int64,
+	opts *lock.LockOptions,
+) raftMutex {
+	ttl := opts.TTLOr(node.TTL())
+	return raftMutex{
+		lg:                lg.With("kind", kind),
+		node:              node,
+		key:               key,
+		kind:              kind,
+		weight:            weight,
+		capacity:          capacity,
+		session:           uuid.New().String(),
+		holder:            opts.Holder(kind == KindShared),
+		ttl:               ttl,
+		keepaliveInterval: opts.KeepaliveIntervalOr(ttl / 3),
+		retryDelay:        opts.RetryDelayOr(LockRetryDelay),
+		fair:              opts.Fair && kind != KindSemaphore,
+		internalDone:      make(chan struct{}),
+	}
+}
+
+func (m *raftMutex) command(op Op) Command {
+	return Command{
+		Op:       op,
+		Session:  m.session,
+		Key:      m.key,
+		Kind:     m.kind,
+		Weight:   m.weight,
+		Capacity: m.capacity,
+		TTL:      int64(m.ttl),
+		Fair:     m.fair,
+		Holder:   m.holder,
+	}
+}
+
+// tryLock registers the session as a waiter of the key when wait is set and the key is held
+func (m *raftMutex) tryLock(ctx context.Context, wait bool) (<-chan struct{}, error) {
+	cmd := m.command(OpAcquire)
+	cmd.Wait = wait
+	res, err := m.node.apply(ctx, cmd)
+	if err != nil {
+		return nil, err
+	}
+	if !res.Ok {
+		return nil, errLocked
+	}
+	m.fencingToken = res.FencingToken
+	return lo.Async(m.keepalive), nil
+}
+
+// keepalive extends the session until the lock is released, or until the session can't be extended
+// before its TTL passes, in which case the leader may already have expired it
+func (m *raftMutex) keepalive() struct{} {
+	ttl := m.ttl
+	t := time.NewTicker(m.keepaliveInterval)
+	defer t.Stop()
+	renewed := time.Now()
+	for {
+		select {
+		case <-m.internalDone:
+			return struct{}{}
+		case <-t.C:
+			start := time.Now()
+			ctx, ca := context.WithTimeout(context.Background(), m.keepaliveInterval)
+			res, err := m.node.apply(ctx, m.command(OpKeepalive))
+			ca()
+			if err != nil {
+				m.lg.With(logger.Err(err)).Warn("failed to extend session")
+				if time.Since(renewed) > ttl {
+					m.lg.Warn("session expired")
+					return struct{}{}
+				}
+				continue
+			}
+			if !res.Ok {
+				m.lg.Warn("session expired")
+				return struct{}{}
+			}
+			renewed = start
+		}
+	}
+}
+
+// abandon releases the session of an acquisition that failed, since it may have been acquired without us
+// knowing about it, or may still be registered as a waiter
+func (m *raftMutex) abandon() {
+	go func(cmd Command) {
+		if err := m.release(cmd); err != nil {
+			m.lg.With(logger.Err(err)).Warn("failed to release session")
+		}
+	}(m.command(OpRelease))
+}
+
+func (m *raftMutex) teardown() {
+	defer close(m.internalDone)
+	select {
+	case m.internalDone <- struct{}{}:
+	default:
+	}
+}
+
+// release retries until the session would have expired anyway
+func (m *raftMutex) release(cmd Command) error {
+	ctx, ca := context.WithTimeout(context.Background(), m.ttl)
+	defer ca()
+	var curErr error
+	acq := (*retryPolicy(m.retryDelay)).Start(ctx)
+	for backoffv2.Continue(acq) {
+		_, err := m.node.apply(ctx, cmd)
+		if err == nil {
+			return nil
+		}
+		curErr = err
+	}
+	return errors.Join(ctx.Err(), curErr)
+}
+
+func (m *raftMutex) unlock() error {
+	defer m.teardown()
+	return m.release(m.command(OpRelease))
+}
+
+// transfer is not retried, since a retried transfer finds its session released and can't tell it succeeded
+func (m *raftMutex) transfer(ctx context.Context, toOwner string) error {
+	cmd := m.command(OpTransfer)
+	cmd.To = toOwner
+	res, err := m.node.apply(ctx, cmd)
+	if err != nil {
+		return err
+	}
+	if res.NotHeld {
+		return lock.ErrNotHeld
+	}
+	if !res.Ok {
+		return lock.ErrNoTransferee
+	}
+	m.teardown()
+	return nil
+}
@@ -0,0 +1,416 @@
+package raft
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/rpc"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alexandreLamarre/dlock/pkg/config/v1alpha1"
+	"github.com/alexandreLamarre/dlock/pkg/lock"
+	"github.com/alexandreLamarre/dlock/pkg/logger"
+	"github.com/alexandreLamarre/dlock/pkg/util"
+	"github.com/hashicorp/go-hclog"
+	hraft "github.com/hashicorp/raft"
+	raftboltdb "github.com/hashicorp/raft-boltdb/v2"
+)
+
+var (
+	DefaultSessionTTL = 10 * time.Second
+	ApplyTimeout      = 5 * time.Second
+	DialTimeout       = 5 * time.Second
+)
+
+var errNoLeader = errors.New("raft cluster has no leader")
+
+// Node is a member of an embedded raft cluster replicating the lock state.
+// Lock operations are applied on the leader, followers forward them to the leader over the raft listener.
+type Node struct {
+	raft  *hraft.Raft
+	fsm   *fsm
+	layer *muxLayer
+	store *raftboltdb.BoltStore
+
+	ttl time.Duration
+
+	clientsMu sync.Mutex
+	clients   map[string]*rpc.Client
+
+	stopC     chan struct{}
+	closeOnce sync.Once
+
+	lg *slog.Logger
+}
+
+func NewNode(spec *v1alpha1.RaftClientSpec, lg *slog.Logger) (*Node, error) {
+	if spec == nil {
+		return nil, errors.New("raft client spec is required")
+	}
+	if spec.NodeID == "" {
+		return nil, errors.New("raft node ID is required")
+	}
+	if spec.BindAddr == "" {
+		return nil, errors.New("raft bind address is required")
+	}
+	if spec.DataDir == "" {
+		return nil, errors.New("raft data directory is required")
+	}
+	var tlsConfig *tls.Config
+	if spec.TLS != nil {
+		config, err := util.LoadPeerTLSConfig(*spec.TLS)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load raft TLS config : %w", err)
+		}
+		tlsConfig = config
+	} else {
+		private, err := isPrivateAddr(spec.BindAddr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid raft bind address : %w", err)
+		}
+		if !private {
+			return nil, fmt.Errorf("raft bind address %s is not private, serving the plaintext raft transport on it requires raft TLS", spec.BindAddr)
+		}
+	}
+	ttl := DefaultSessionTTL
+	if spec.SessionTTL != "" {
+		d, err := time.ParseDuration(spec.SessionTTL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid session TTL : %w", err)
+		}
+		ttl = d
+	}
+	if ttl <= 0 {
+		return nil, errors.New("session TTL must be positive")
+	}
+	if err := os.MkdirAll(spec.DataDir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create raft data directory : %w", err)
+	}
+
+	lg = lg.With("node", spec.NodeID)
+	hlg := hclog.New(&hclog.LoggerOptions{
+		Name:   "raft",
+		Level:  hclog.Info,
+		Output: &slogWriter{lg: lg},
+	})
+
+	n := &Node{
+		fsm:     newFSM(),
+		ttl:     ttl,
+		clients: map[string]*rpc.Client{},
+		stopC:   make(chan struct{}),
+		lg:      lg,
+	}
+
+	forward := rpc.NewServer()
+	if err := forward.RegisterName("Dlock", &forwarder{n: n}); err != nil {
+		return nil, err
+	}
+	layer, err := newMuxLayer(spec.BindAddr, forward, tlsConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s : %w", spec.BindAddr, err)
+	}
+	n.layer = layer
+
+	store, err := raftboltdb.NewBoltStore(filepath.Join(spec.DataDir, "raft.db"))
+	if err != nil {
+		return nil, errors.Join(fmt.Errorf("failed to open raft store : %w", err), layer.Close())
+	}
+	n.store = store
+	snapshots, err := hraft.NewFileSnapshotStoreWithLogger(spec.DataDir, 2, hlg)
+	if err != nil {
+		return nil, errors.Join(fmt.Errorf("failed to open raft snapshot store : %w", err), store.Close(), layer.Close())
+	}
+	transport := hraft.NewNetworkTransportWithConfig(&hraft.NetworkTransportConfig{
+		Stream:  layer,
+		MaxPool: 3,
+		Timeout: 10 * time.Second,
+		Logger:  hlg,
+	})
+
+	conf := hraft.DefaultConfig()
+	conf.LocalID = hraft.ServerID(spec.NodeID)
+	conf.Logger = hlg
+
+	hasState, err := hraft.HasExistingState(store, store, snapshots)
+	if err != nil {
+		return nil, errors.Join(err, transport.Close(), store.Close())
+	}
+	if !hasState {
+		servers := []hraft.Server{}
+		for _, peer := range spec.Peers {
+			servers = append(servers, hraft.Server{
+				ID:      hraft.ServerID(peer.ID),
+				Address: hraft.ServerAddress(peer.Addr),
+			})
+		}
+		if len(servers) == 0 {
+			servers = append(servers, hraft.Server{
+				ID:      conf.LocalID,
+				Address: hraft.ServerAddress(spec.BindAddr),
+			})
+		}
+		if err := hraft.BootstrapCluster(
+			conf, store, store, snapshots, transport, hraft.Configuration{Servers: servers},
+		); err != nil && !errors.Is(err, hraft.ErrCantBootstrap) {
+			return nil, errors.Join(fmt.Errorf("failed to bootstrap raft cluster : %w", err), transport.Close(), store.Close())
+		}
+	}
+
+	r, err := hraft.NewRaft(conf, n.fsm, store, store, snapshots, transport)
+	if err != nil {
+		return nil, errors.Join(fmt.Errorf("failed to start raft : %w", err), transport.Close(), store.Close())
+	}
+	n.raft = r
+	go n.expireSessions()
+	return n, nil
+}
+
+func (n *Node) TTL() time.Duration {
+	return n.ttl
+}
+
+// Leader returns the address of the current leader, empty if there is none
+func (n *Node) Leader() string {
+	addr, _ := n.raft.LeaderWithID()
+	return string(addr)
+}
+
+func (n *Node) Shutdown() error {
+	var err error
+	n.closeOnce.Do(func() {
+		close(n.stopC)
+		err = n.raft.Shutdown().Error()
+		n.clientsMu.Lock()
+		for addr, client := range n.clients {
+			err = errors.Join(err, client.Close())
+			delete(n.clients, addr)
+		}
+		n.clientsMu.Unlock()
+		err = errors.Join(err, n.layer.Close(), n.store.Close())
+	})
+	return err
+}
+
+// expireSessions periodically releases the sessions that stopped sending keepalives, while this node is the leader
+func (n *Node) expireSessions() {
+	t := time.NewTicker(n.ttl / 4)
+	defer t.Stop()
+	for {
+		select {
+		case <-n.stopC:
+			return
+		case <-t.C:
+			if n.raft.State() != hraft.Leader {
+				continue
+			}
+			if _, err := n.applyLocal(Command{Op: OpExpire}); err != nil {
+				n.lg.With(logger.Err(err)).Warn("failed to expire sessions")
+			}
+		}
+	}
+}
+
+func (n *Node) apply(ctx context.Context, cmd Command) (Result, error) {
+	if n.raft.State() == hraft.Leader {
+		return n.applyLocal(cmd)
+	}
+	addr := n.Leader()
+	if addr == "" {
+		return Result{}, errNoLeader
+	}
+	client, err := n.client(addr)
+	if err != nil {
+		return Result{}, err
+	}
+	var res Result
+	call := client.Go("Dlock.Apply", cmd, &res, make(chan *rpc.Call, 1))
+	select {
+	case <-ctx.Done():
+		return Result{}, ctx.Err()
+	case <-call.Done:
+	}
+	if call.Error != nil {
+		var serverErr rpc.ServerError
+		if !errors.As(call.Error, &serverErr) {
+			n.dropClient(addr, client)
+		}
+		return Result{}, call.Error
+	}
+	return res, nil
+}
+
+// applyLocal replicates the command through the raft log, it only succeeds on the leader
+func (n *Node) applyLocal(cmd Command) (Result, error) {
+	cmd.Now = time.Now().UnixNano()
+	data, err := json.Marshal(cmd)
+	if err != nil {
+		return Result{}, err
+	}
+	f := n.raft.Apply(data, ApplyTimeout)
+	if err := f.Error(); err != nil {
+		return Result{}, err
+	}
+	switch resp := f.Response().(type) {
+	case Result:
+		return resp, nil
+	case error:
+		return Result{}, resp
+	default:
+		return Result{}, fmt.Errorf("unexpected raft response %T", resp)
+	}
+}
+
+// read runs a linearizable read of the lock state on the leader, forwarding it if this node is a follower
+func (n *Node) read(ctx context.Context, method string, args string, res any, local func()) error {
+	if n.raft.State() == hraft.Leader {
+		if err := n.raft.VerifyLeader().Error(); err != nil {
+			return err
+		}
+		local()
+		return nil
+	}
+	addr := n.Leader()
+	if addr == "" {
+		return errNoLeader
+	}
+	client, err := n.client(addr)
+	if err != nil {
+		return err
+	}
+	call := client.Go(method, args, res, make(chan *rpc.Call, 1))
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-call.Done:
+	}
+	if call.Error != nil {
+		var serverErr rpc.ServerError
+		if !errors.As(call.Error, &serverErr) {
+			n.dropClient(addr, client)
+		}
+		return call.Error
+	}
+	return nil
+}
+
+func (n *Node) describe(ctx context.Context, key string) (lock.LockInfo, error) {
+	var info lock.LockInfo
+	err := n.read(ctx, "Dlock.Describe", key, &info, func() {
+		info = n.fsm.describe(key, time.Now())
+	})
+	return info, err
+}
+
+func (n *Node) list(ctx context.Context, prefix string) ([]lock.LockInfo, error) {
+	var infos []lock.LockInfo
+	err := n.read(ctx, "Dlock.List", prefix, &infos, func() {
+		infos = n.fsm.list(prefix, time.Now())
+	})
+	return infos, err
+}
+
+// position returns the number of waiters of the key that registered before the session
+func (n *Node) position(ctx context.Context, session string) (int, error) {
+	var position int
+	var posErr error
+	err := n.read(ctx, "Dlock.Position", session, &position, func() {
+		position, posErr = n.fsm.position(session, time.Now())
+	})
+	return position, errors.Join(err, posErr)
+}
+
+// forceRelease evicts the holders of the lock on the key, their keepalives fail on their next renewal
+func (n *Node) forceRelease(ctx context.Context, key string) (lock.LockInfo, error) {
+	res, err := n.apply(ctx, Command{Op: OpForceRelease, Key: key})
+	if err != nil {
+		return lock.LockInfo{}, err
+	}
+	return res.Evicted, nil
+}
+
+func (n *Node) client(addr string) (*rpc.Client, error) {
+	n.clientsMu.Lock()
+	defer n.clientsMu.Unlock()
+	if client, ok := n.clients[addr]; ok {
+		return client, nil
+	}
+	conn, err := n.layer.dial(addr, connForward, DialTimeout)
+	if err != nil {
+		return nil, err
+	}
+	client := rpc.NewClient(conn)
+	n.clients[addr] = client
+	return client, nil
+}
+
+func (n *Node) dropClient(addr string, client *rpc.Client) {
+	n.clientsMu.Lock()
+	defer n.clientsMu.Unlock()
+	if n.clients[addr] == client {
+		delete(n.clients, addr)
+		_ = client.Close()
+	}
+}
+
+// forwarder applies the lock operations forwarded by followers
+type forwarder struct {
+	n *Node
+}
+
+func (f *forwarder) Apply(cmd Command, res *Result) error {
+	if f.n.raft.State() != hraft.Leader {
+		return errors.New("not the raft leader")
+	}
+	r, err := f.n.applyLocal(cmd)
+	if err != nil {
+		return err
+	}
+	*res = r
+	return nil
+}
+
+func (f *forwarder) Describe(key string, res *lock.LockInfo) error {
+	if err := f.n.raft.VerifyLeader().Error(); err != nil {
+		return err
+	}
+	*res = f.n.fsm.describe(key, time.Now())
+	return nil
+}
+
+func (f *forwarder) Position(session string, res *int) error {
+	if err := f.n.raft.VerifyLeader().Error(); err != nil {
+		return err
+	}
+	position, err := f.n.fsm.position(session, time.Now())
+	if err != nil {
+		return err
+	}
+	*res = position
+	return nil
+}
+
+func (f *forwarder) List(prefix string, res *[]lock.LockInfo) error {
+	if err := f.n.raft.VerifyLeader().Error(); err != nil {
+		return err
+	}
+	*res = f.n.fsm.list(prefix, time.Now())
+	return nil
+}
+
+// slogWriter bridges the raft library's logs to the server's logger
+type slogWriter struct {
+	lg *slog.Logger
+}
+
+func (w *slogWriter) Write(p []byte) (int, error) {
+	w.lg.Debug(strings.TrimSpace(string(p)))
+	return len(p), nil
+}
@@ -0,0 +1,143 @@
+package raft_test
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alexandreLamarre/dlock/internal/lock/backend/raft"
+	"github.com/alexandreLamarre/dlock/pkg/config/v1alpha1"
+	"github.com/alexandreLamarre/dlock/pkg/constants"
+	"github.com/alexandreLamarre/dlock/pkg/lock"
+	"github.com/alexandreLamarre/dlock/pkg/lock/broker"
+	"github.com/alexandreLamarre/dlock/pkg/logger"
+	"github.com/alexandreLamarre/dlock/pkg/test/conformance/integration"
+	"github.com/alexandreLamarre/dlock/pkg/test/freeport"
+	"github.com/alexandreLamarre/dlock/pkg/test/testdata"
+	"github.com/alexandreLamarre/dlock/pkg/util/future"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/samber/lo"
+)
+
+func TestRaft(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Raft Suite")
+}
+
+var lmF = future.New[lock.LockManager]()
+var lmSetF = future.New[lo.Tuple3[
+	lock.LockManager, lock.LockManager, lock.LockManager,
+]]()
+
+var _ = BeforeSuite(func() {
+	if Label("integration").MatchesLabelFilter(GinkgoLabelFilter()) {
+		dir := GinkgoT().TempDir()
+		ports := freeport.GetFreePorts(3)
+		peers := []v1alpha1.RaftPeerSpec{}
+		for i, port := range ports {
+			peers = append(peers, v1alpha1.RaftPeerSpec{
+				ID:   fmt.Sprintf("node%d", i),
+				Addr: fmt.Sprintf("127.0.0.1:%d", port),
+			})
+		}
+
+		nodes := []*raft.Node{}
+		for _, peer := range peers {
+			node, err := raft.NewNode(&v1alpha1.RaftClientSpec{
+				NodeID:   peer.ID,
+				BindAddr: peer.Addr,
+				DataDir:  filepath.Join(dir, peer.ID),
+				Peers:    peers,
+			}, logger.NewNop())
+			Expect(err).NotTo(HaveOccurred())
+			nodes = append(nodes, node)
+			DeferCleanup(node.Shutdown)
+		}
+		Eventually(func() string {
+			return nodes[0].Leader()
+		}).WithTimeout(30 * time.Second).WithPolling(100 * time.Millisecond).ShouldNot(BeEmpty())
+
+		lmF.Set(raft.NewLockManager(nodes[0], "test", nil, logger.NewNop()))
+
+		// every node either applies or forwards operations to the leader, so they lock each other out
+		lmSetF.Set(lo.Tuple3[lock.LockManager, lock.LockManager, lock.LockManager]{
+			A: raft.NewLockManager(nodes[0], "test", nil, logger.NewNop()),
+			B: raft.NewLockManager(nodes[1], "test", nil, logger.NewNop()),
+			C: raft.NewLockManager(nodes[2], "test", nil, logger.NewNop()),
+		})
+	}
+})
+
+var _ = Describe("Raft Lock Manager", Ordered, Label("integration"), integration.LockManagerTestSuite(lmF, lmSetF))
+var _ = Describe("Raft Broker", Label("unit"), func() {
+	When("we register the lock broker", func() {
+		It("should register the raft lock manager as a broker", func() {
+			rBroker, ok := broker.GetLockBroker(constants.RaftLockManager)
+			Expect(ok).To(BeTrue())
+			Expect(rBroker).NotTo(BeNil())
+		})
+	})
+})
+
+var _ = Describe("Raft Transport", func() {
+	It("should require TLS to serve on a public bind address", Label("unit"), func() {
+		for _, addr := range []string{"0.0.0.0:7000", ":7000", "8.8.8.8:7000"} {
+			_, err := raft.NewNode(&v1alpha1.RaftClientSpec{
+				NodeID:   "node0",
+				BindAddr: addr,
+				DataDir:  GinkgoT().TempDir(),
+			}, logger.NewNop())
+			Expect(err).To(MatchError(ContainSubstring("is not private")), addr)
+		}
+	})
+
+	It("should forward lock operations to the leader over mutual TLS", Label("integration"), func() {
+		dir := GinkgoT().TempDir()
+		ports := freeport.GetFreePorts(3)
+		peers := []v1alpha1.RaftPeerSpec{}
+		for i, port := range ports {
+			peers = append(peers, v1alpha1.RaftPeerSpec{
+				ID:   fmt.Sprintf("tls%d", i),
+				Addr: fmt.Sprintf("127.0.0.1:%d", port),
+			})
+		}
+		nodes := []*raft.Node{}
+		for _, peer := range peers {
+			node, err := raft.NewNode(&v1alpha1.RaftClientSpec{
+				NodeID:   peer.ID,
+				BindAddr: peer.Addr,
+				DataDir:  filepath.Join(dir, peer.ID),
+				Peers:    peers,
+				TLS: &v1alpha1.CertsSpec{
+					CACertData:      testdata.TestData("root_ca.crt"),
+					ServingCertData: testdata.TestData("localhost.crt"),
+					ServingKeyData:  testdata.TestData("localhost.key"),
+				},
+			}, logger.NewNop())
+			Expect(err).NotTo(HaveOccurred())
+			nodes = append(nodes, node)
+			DeferCleanup(node.Shutdown)
+		}
+		var follower *raft.Node
+		Eventually(func() *raft.Node {
+			leader := nodes[0].Leader()
+			for i, node := range nodes {
+				if leader != "" && peers[i].Addr != leader {
+					follower = node
+				}
+			}
+			return follower
+		}).WithTimeout(30 * time.Second).WithPolling(100 * time.Millisecond).ShouldNot(BeNil())
+
+		lm := raft.NewLockManager(follower, "tls", nil, logger.NewNop())
+		ctx, ca := context.WithTimeout(context.Background(), 10*time.Second)
+		defer ca()
+		l := lm.NewLock("forwarded")
+		_, err := l.Lock(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(l.Unlock()).To(Succeed())
+	})
+})
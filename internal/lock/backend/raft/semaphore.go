@@ -0,0 +1,120 @@
+package raft
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/alexandreLamarre/dlock/pkg/lock"
+	backoffv2 "github.com/lestrrat-go/backoff/v2"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Semaphore holds a weighted session on a key of the replicated lock state,
+// the leader only accepts it when the weights held on the key fit the capacity
+type Semaphore struct {
+	node     *Node
+	key      string
+	capacity int64
+
+	*lock.LockOptions
+
+	scheduler *lock.LockScheduler
+	mutex     *raftMutex
+
+	lg *slog.Logger
+}
+
+var _ lock.Semaphore = (*Semaphore)(nil)
+
+func NewSemaphore(node *Node, key string, capacity int64, lg *slog.Logger, options *lock.LockOptions) *Semaphore {
+	return &Semaphore{
+		node:        node,
+		key:         key,
+		capacity:    capacity,
+		lg:          lg.With("capacity", capacity),
+		LockOptions: options,
+		scheduler:   lock.NewLockScheduler(),
+	}
+}
+
+func (s *Semaphore) Capacity() int64 {
+	return s.capacity
+}
+
+func (s *Semaphore) Acquire(ctx context.Context, n int64) (<-chan struct{}, error) {
+	return s.acquire(ctx, n, retryPolicy(s.RetryDelayOr(LockRetryDelay)))
+}
+
+func (s *Semaphore) TryAcquire(ctx context.Context, n int64) (acquired bool, done <-chan struct{}, err error) {
+	done, err = s.acquire(ctx, n, nil)
+	if err != nil {
+		if errors.Is(err, errLocked) {
+			return false, nil, nil
+		}
+		return false, nil, err
+	}
+	return true, done, nil
+}
+
+func (s *Semaphore) acquire(ctx context.Context, n int64, retrier *backoffv2.Policy) (<-chan struct{}, error) {
+	if err := lock.ValidateWeight(n, s.capacity); err != nil {
+		return nil, err
+	}
+	if s.Tracer != nil {
+		ctxSpan, span := s.Tracer.Start(ctx, "Acquire/raft-semaphore", trace.WithAttributes())
+		defer span.End()
+		ctx = ctxSpan
+	}
+	// https://github.com/lestrrat-go/backoff/issues/31
+	ctxca, ca := context.WithCancel(ctx)
+	defer ca()
+
+	var closureDone <-chan struct{}
+	if err := s.scheduler.Schedule(func() error {
+		mutex := newRaftMutex(s.lg.With("weight", n), s.node, s.key, KindSemaphore, n, s.capacity, s.LockOptions)
+		done, err := mutex.tryLock(ctxca, false)
+		curErr := err
+		if err == nil {
+			s.mutex = &mutex
+			closureDone = done
+			return nil
+		}
+		if retrier == nil {
+			if !errors.Is(curErr, errLocked) {
+				mutex.abandon()
+			}
+			return curErr
+		}
+		ret := *retrier
+		acq := ret.Start(ctxca)
+		for backoffv2.Continue(acq) {
+			done, err := mutex.tryLock(ctxca, false)
+			curErr = err
+			if err == nil {
+				s.mutex = &mutex
+				closureDone = done
+				return nil
+			}
+		}
+		mutex.abandon()
+		return errors.Join(ctxca.Err(), curErr)
+	}); err != nil {
+		return nil, err
+	}
+	return closureDone, nil
+}
+
+func (s *Semaphore) Release() error {
+	return s.scheduler.Done(func() error {
+		if s.mutex == nil {
+			panic("never acquired")
+		}
+		mutex := *s.mutex
+		if err := mutex.unlock(); err != nil {
+			s.lg.Error(err.Error())
+		}
+		s.mutex = nil
+		return nil
+	})
+}
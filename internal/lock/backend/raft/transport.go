@@ -0,0 +1,171 @@
+package raft
+
+import (
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/rpc"
+	"sync"
+	"time"
+
+	hraft "github.com/hashicorp/raft"
+)
+
+// first byte written on every connection, to multiplex the raft transport
+// and forwarded lock operations on a single listener
+const (
+	connRaft byte = iota + 1
+	connForward
+)
+
+var errLayerClosed = errors.New("raft stream layer closed")
+
+// isPrivateAddr reports whether every IP the host of the address listens on is loopback, private or
+// link-local, the plaintext transport authenticating neither peers nor forwarded lock operations
+func isPrivateAddr(addr string) (bool, error) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false, err
+	}
+	if host == "" {
+		return false, nil
+	}
+	ips := []net.IP{net.ParseIP(host)}
+	if ips[0] == nil {
+		ips, err = net.LookupIP(host)
+		if err != nil {
+			return false, err
+		}
+	}
+	for _, ip := range ips {
+		if !ip.IsLoopback() && !ip.IsPrivate() && !ip.IsLinkLocalUnicast() {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// muxLayer is a raft.StreamLayer that also serves forwarded lock operations, over mutual TLS between the
+// peers when its TLS config is set
+type muxLayer struct {
+	ln      net.Listener
+	forward *rpc.Server
+	config  *tls.Config
+
+	raftC     chan net.Conn
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+var _ hraft.StreamLayer = (*muxLayer)(nil)
+
+func newMuxLayer(addr string, forward *rpc.Server, config *tls.Config) (*muxLayer, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	if config != nil {
+		ln = tls.NewListener(ln, config)
+	}
+	m := &muxLayer{
+		ln:      ln,
+		forward: forward,
+		config:  config,
+		raftC:   make(chan net.Conn),
+		closed:  make(chan struct{}),
+	}
+	go m.serve()
+	return m, nil
+}
+
+func (m *muxLayer) serve() {
+	for {
+		conn, err := m.ln.Accept()
+		if err != nil {
+			select {
+			case <-m.closed:
+				return
+			default:
+			}
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				continue
+			}
+			return
+		}
+		go m.handle(conn)
+	}
+}
+
+func (m *muxLayer) handle(conn net.Conn) {
+	var kind [1]byte
+	if err := conn.SetReadDeadline(time.Now().Add(10 * time.Second)); err != nil {
+		_ = conn.Close()
+		return
+	}
+	if _, err := conn.Read(kind[:]); err != nil {
+		_ = conn.Close()
+		return
+	}
+	if err := conn.SetReadDeadline(time.Time{}); err != nil {
+		_ = conn.Close()
+		return
+	}
+	switch kind[0] {
+	case connRaft:
+		select {
+		case m.raftC <- conn:
+		case <-m.closed:
+			_ = conn.Close()
+		}
+	case connForward:
+		m.forward.ServeConn(conn)
+	default:
+		_ = conn.Close()
+	}
+}
+
+func (m *muxLayer) Accept() (net.Conn, error) {
+	select {
+	case conn := <-m.raftC:
+		return conn, nil
+	case <-m.closed:
+		return nil, errLayerClosed
+	}
+}
+
+func (m *muxLayer) Close() error {
+	var err error
+	m.closeOnce.Do(func() {
+		close(m.closed)
+		err = m.ln.Close()
+	})
+	return err
+}
+
+func (m *muxLayer) Addr() net.Addr {
+	return m.ln.Addr()
+}
+
+func (m *muxLayer) Dial(address hraft.ServerAddress, timeout time.Duration) (net.Conn, error) {
+	return m.dial(string(address), connRaft, timeout)
+}
+
+// dial connects to the layer of a peer, verifying its certificate against the address when TLS is set
+func (m *muxLayer) dial(address string, kind byte, timeout time.Duration) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: timeout}
+	var conn net.Conn
+	var err error
+	if m.config != nil {
+		conn, err = tls.DialWithDialer(dialer, "tcp", address, m.config)
+	} else {
+		conn, err = dialer.Dial("tcp", address)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if _, err := conn.Write([]byte{kind}); err != nil {
+		return nil, errors.Join(err, conn.Close())
+	}
+	return conn, nil
+}
@@ -0,0 +1,314 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/alexandreLamarre/dlock/pkg/lock"
+	"github.com/alexandreLamarre/dlock/pkg/logger"
+	"github.com/go-redsync/redsync/v4/redis"
+	"github.com/google/uuid"
+	"github.com/samber/lo"
+)
+
+// arriveScript registers a participant of the barrier, participants whose registration expired being removed.
+// Once the barrier has enough participants they are all marked as tripped and the barrier is reset,
+// it returns 2 when the participant reached the barrier and 1 otherwise.
+var arriveScript = redis.NewScript(3, `
+	local now = redis.call("TIME")
+	local ms = now[1] * 1000 + math.floor(now[2] / 1000)
+	redis.call("ZREMRANGEBYSCORE", KEYS[1], "-inf", ms)
+	redis.call("ZADD", KEYS[1], ms + tonumber(ARGV[2]), ARGV[1])
+	if redis.call("PTTL", KEYS[1]) < tonumber(ARGV[2]) then
+		redis.call("PEXPIRE", KEYS[1], ARGV[2])
+	end
+	redis.call("HSET", KEYS[3], ARGV[1], ARGV[4])
+	if redis.call("PTTL", KEYS[3]) < tonumber(ARGV[2]) then
+		redis.call("PEXPIRE", KEYS[3], ARGV[2])
+	end
+	if redis.call("ZCARD", KEYS[1]) < tonumber(ARGV[3]) then
+		return 1
+	end
+	for _, participant in ipairs(redis.call("ZRANGE", KEYS[1], 0, -1)) do
+		redis.call("HSET", KEYS[2], participant, 1)
+		redis.call("HDEL", KEYS[3], participant)
+	end
+	redis.call("DEL", KEYS[1])
+	if redis.call("PTTL", KEYS[2]) < tonumber(ARGV[2]) then
+		redis.call("PEXPIRE", KEYS[2], ARGV[2])
+	end
+	return 2
+`, "")
+
+var trippedScript = redis.NewScript(1, `
+	return redis.call("HEXISTS", KEYS[1], ARGV[1])
+`, "")
+
+var leaveScript = redis.NewScript(3, `
+	redis.call("HDEL", KEYS[2], ARGV[1])
+	redis.call("HDEL", KEYS[3], ARGV[1])
+	return redis.call("ZREM", KEYS[1], ARGV[1])
+`, "")
+
+// Barrier registers each waiting participant in a sorted set of the barrier scored by the time at which
+// it expires, participants being kept alive like the readers of a lock
+type Barrier struct {
+	pools  []redis.Pool
+	quorum int
+
+	prefix string
+	key    string
+	n      int64
+	lg     *slog.Logger
+
+	*lock.LockOptions
+}
+
+var _ lock.Barrier = (*Barrier)(nil)
+
+func NewBarrier(
+	pools []redis.Pool,
+	quorum int,
+	prefix, key string,
+	n int64,
+	lg *slog.Logger,
+	opts *lock.LockOptions,
+) *Barrier {
+	return &Barrier{
+		pools:       pools,
+		quorum:      quorum,
+		prefix:      prefix,
+		key:         key,
+		n:           n,
+		lg:          lg,
+		LockOptions: opts,
+	}
+}
+
+func (b *Barrier) Wait(ctx context.Context) error {
+	if err := lock.ValidateParticipants(b.n); err != nil {
+		return err
+	}
+	if b.Tracer != nil {
+		ctxSpan, span := b.Tracer.Start(ctx, "Wait/redis-barrier")
+		defer span.End()
+		ctx = ctxSpan
+	}
+	mutex := newRedisBarrier(b.prefix, b.key, b.n, b.quorum, b.pools, b.lg, b.LockOptions)
+	defer mutex.leave()
+	reached, err := mutex.arrive(ctx)
+	if err != nil || reached {
+		return err
+	}
+	expired := lo.Async(mutex.keepalive)
+	t := time.NewTicker(b.RetryDelayOr(LockRetryDelay))
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-expired:
+			// the registration of participants is deleted once they reach the barrier
+			if mutex.reached(ctx) {
+				return nil
+			}
+			return lock.ErrBarrierExpired
+		case <-t.C:
+			if mutex.reached(ctx) {
+				return nil
+			}
+		}
+	}
+}
+
+// arrive registers the participant on every node, and reports whether a quorum of nodes reached the barrier
+func (m *redisMutex) arrive(ctx context.Context) (bool, error) {
+	m.uuid = uuid.New().String()
+	info, err := json.Marshal(m.Holder(false))
+	if err != nil {
+		return false, err
+	}
+	start := time.Now()
+	var reached atomic.Int64
+	n, err := func() (int, error) {
+		ctx, ca := context.WithTimeout(ctx, ackTimeoutFactor(m.expiry()))
+		defer ca()
+		return m.actOnPoolsAsync(func(pool redis.Pool) (bool, error) {
+			status, err := eval(ctx, pool, m.lg, arriveScript,
+				m.barrierKey(), m.trippedKey(), m.infoKey(),
+				m.uuid, int(m.expiry()/time.Millisecond), m.participants, string(info),
+			)
+			if err != nil {
+				return false, err
+			}
+			if status == int64(2) {
+				reached.Add(1)
+			}
+			return true, nil
+		})
+	}()
+	if n < m.quorum {
+		return false, err
+	}
+	now := time.Now()
+	m.until = now.Add(m.expiry() - now.Sub(start) - expiryDriftFactor(m.expiry()))
+	return reached.Load() >= int64(m.quorum), nil
+}
+
+// reached reports whether a quorum of nodes reached the barrier since the participant registered
+func (m *redisMutex) reached(ctx context.Context) bool {
+	ctx, ca := context.WithTimeout(ctx, ackTimeoutFactor(m.expiry()))
+	defer ca()
+	n, err := m.actOnPoolsAsync(func(pool redis.Pool) (bool, error) {
+		status, err := eval(ctx, pool, m.lg, trippedScript, m.trippedKey(), m.uuid)
+		return status == int64(1), err
+	})
+	if err != nil && !errors.Is(err, ErrTaken) {
+		m.lg.With(logger.Err(err)).Debug("failed to check whether the barrier was reached")
+	}
+	return n >= m.quorum
+}
+
+// leave stops keeping the participant alive and unregisters it from every node
+func (m *redisMutex) leave() {
+	m.teardown()
+	if m.uuid == "" {
+		return
+	}
+	ctx, ca := context.WithTimeout(context.Background(), ackTimeoutFactor(m.expiry()))
+	defer ca()
+	if _, err := m.actOnPoolsAsync(func(pool redis.Pool) (bool, error) {
+		_, err := eval(ctx, pool, m.lg, leaveScript, m.barrierKey(), m.trippedKey(), m.infoKey(), m.uuid)
+		return err == nil, err
+	}); err != nil {
+		m.lg.With(logger.Err(err)).Debug("failed to unregister barrier participant, it will expire")
+	}
+}
+
+// latchKey holds the count of the latch
+func (m *redisMutex) latchKey() string {
+	return m.taggedKey(".latch-")
+}
+
+// countDownScript decrements the count of the latch, a latch without a count starting at its initial count
+var countDownScript = redis.NewScript(1, `
+	local count = tonumber(redis.call("GET", KEYS[1]) or ARGV[1])
+	if count > 0 then
+		count = count - 1
+	end
+	redis.call("SET", KEYS[1], count, "PX", ARGV[2])
+	return count
+`, "")
+
+// awaitScript returns the count of the latch, keeping it from expiring while it is awaited
+var awaitScript = redis.NewScript(1, `
+	local count = redis.call("GET", KEYS[1])
+	if not count then
+		return tonumber(ARGV[1])
+	end
+	redis.call("PEXPIRE", KEYS[1], ARGV[2])
+	return tonumber(count)
+`, "")
+
+// CountDownLatch holds the count of the latch in a key of every node, awaiting processes poll it
+type CountDownLatch struct {
+	pools  []redis.Pool
+	quorum int
+
+	prefix string
+	key    string
+	n      int64
+	lg     *slog.Logger
+
+	*lock.LockOptions
+}
+
+var _ lock.CountDownLatch = (*CountDownLatch)(nil)
+
+func NewCountDownLatch(
+	pools []redis.Pool,
+	quorum int,
+	prefix, key string,
+	n int64,
+	lg *slog.Logger,
+	opts *lock.LockOptions,
+) *CountDownLatch {
+	return &CountDownLatch{
+		pools:       pools,
+		quorum:      quorum,
+		prefix:      prefix,
+		key:         key,
+		n:           n,
+		lg:          lg,
+		LockOptions: opts,
+	}
+}
+
+func (c *CountDownLatch) retention() string {
+	return strconv.FormatInt(lock.LatchRetention.Milliseconds(), 10)
+}
+
+func (c *CountDownLatch) CountDown(ctx context.Context) error {
+	if err := lock.ValidateParticipants(c.n); err != nil {
+		return err
+	}
+	if c.Tracer != nil {
+		ctxSpan, span := c.Tracer.Start(ctx, "CountDown/redis-latch")
+		defer span.End()
+		ctx = ctxSpan
+	}
+	mutex := newRedisMutex(c.prefix, c.key, false, c.quorum, c.pools, c.lg, c.LockOptions)
+	ctx, ca := context.WithTimeout(ctx, ackTimeoutFactor(mutex.expiry()))
+	defer ca()
+	n, err := mutex.actOnPoolsAsync(func(pool redis.Pool) (bool, error) {
+		_, err := eval(ctx, pool, c.lg, countDownScript, mutex.latchKey(), c.n, c.retention())
+		return err == nil, err
+	})
+	if n < c.quorum {
+		return err
+	}
+	return nil
+}
+
+func (c *CountDownLatch) Await(ctx context.Context) error {
+	if err := lock.ValidateParticipants(c.n); err != nil {
+		return err
+	}
+	if c.Tracer != nil {
+		ctxSpan, span := c.Tracer.Start(ctx, "Await/redis-latch")
+		defer span.End()
+		ctx = ctxSpan
+	}
+	mutex := newRedisMutex(c.prefix, c.key, false, c.quorum, c.pools, c.lg, c.LockOptions)
+	t := time.NewTicker(c.RetryDelayOr(LockRetryDelay))
+	defer t.Stop()
+	for {
+		if c.released(ctx, &mutex) {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// released reports whether the latch was counted down to zero on a quorum of nodes
+func (c *CountDownLatch) released(ctx context.Context, mutex *redisMutex) bool {
+	ctx, ca := context.WithTimeout(ctx, ackTimeoutFactor(mutex.expiry()))
+	defer ca()
+	n, err := mutex.actOnPoolsAsync(func(pool redis.Pool) (bool, error) {
+		count, err := eval(ctx, pool, c.lg, awaitScript, mutex.latchKey(), c.n, c.retention())
+		return count == int64(0), err
+	})
+	if err != nil && !errors.Is(err, ErrTaken) {
+		c.lg.With(logger.Err(err)).Debug("failed to check the count of the latch")
+	}
+	return n >= c.quorum
+}
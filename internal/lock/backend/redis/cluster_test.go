@@ -0,0 +1,67 @@
+package redis_test
+
+import (
+	"context"
+
+	"github.com/alexandreLamarre/dlock/internal/lock/backend/redis"
+	"github.com/alexandreLamarre/dlock/pkg/config/v1alpha1"
+	"github.com/alexandreLamarre/dlock/pkg/lock"
+	"github.com/alexandreLamarre/dlock/pkg/logger"
+	"github.com/alexandreLamarre/dlock/pkg/test/conformance/integration"
+	"github.com/alexandreLamarre/dlock/pkg/util/future"
+	"github.com/alicebob/miniredis/v2"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/samber/lo"
+)
+
+// The cluster suite runs against an in-process redis node serving every slot of the cluster
+var _ = Describe("Redis Cluster", Ordered, Label("unit", "slow"), func() {
+	lmF := future.New[lock.LockManager]()
+	lmSetF := future.New[lo.Tuple3[lock.LockManager, lock.LockManager, lock.LockManager]]()
+
+	BeforeAll(func() {
+		node := miniredis.NewMiniRedis()
+		Expect(node.Start()).To(Succeed())
+		DeferCleanup(node.Close)
+		opts, err := redis.RedisClientOptions(&v1alpha1.RedisClientSpec{RedisNodeSpec: v1alpha1.RedisNodeSpec{
+			Cluster: &v1alpha1.RedisClusterSpec{Addrs: []string{node.Addr()}},
+		}})
+		Expect(err).NotTo(HaveOccurred())
+		newLockManager := func() lock.LockManager {
+			return redis.NewLockManager(context.Background(), "test", redis.AcquireRedisNodePool(opts), logger.NewNop())
+		}
+		lmF.Set(newLockManager())
+		lmSetF.Set(lo.Tuple3[lock.LockManager, lock.LockManager, lock.LockManager]{
+			A: newLockManager(), B: newLockManager(), C: newLockManager(),
+		})
+	})
+
+	Context("with a cluster client", integration.LockManagerTestSuite(lmF, lmSetF))
+})
+
+var _ = Describe("Redis lock keys", Label("unit"), func() {
+	lockKeys := func(ctx context.Context, spec v1alpha1.RedisNodeSpec, node *miniredis.Miniredis) []string {
+		opts, err := redis.RedisClientOptions(&v1alpha1.RedisClientSpec{RedisNodeSpec: spec})
+		Expect(err).NotTo(HaveOccurred())
+		lm := redis.NewLockManager(context.Background(), "test", redis.AcquireRedisNodePool(opts), logger.NewNop())
+		l := lm.NewLock("a")
+		_, err = l.Lock(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(l.Unlock)
+		return node.Keys()
+	}
+
+	It("should keep the names of the keys of locks on standalone nodes", func(ctx SpecContext) {
+		node := miniredis.RunT(GinkgoT())
+		Expect(lockKeys(ctx, v1alpha1.RedisNodeSpec{Addr: node.Addr()}, node)).
+			To(ContainElements("test-a", "test.fence-a", "test.info-a"))
+	})
+
+	It("should hash tag the keys of locks on clusters", func(ctx SpecContext) {
+		node := miniredis.RunT(GinkgoT())
+		Expect(lockKeys(ctx, v1alpha1.RedisNodeSpec{
+			Cluster: &v1alpha1.RedisClusterSpec{Addrs: []string{node.Addr()}},
+		}, node)).To(ContainElements("test-{a}", "test.fence-{a}", "test.info-{a}"))
+	})
+})
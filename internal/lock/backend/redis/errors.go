@@ -28,3 +28,11 @@ func (err ErrNodeTaken) Error() string {
 }
 
 var ErrTaken = errors.New("lock already taken")
+
+// ErrFenceFailed is the error of acquisitions whose fencing token could not be recorded by a quorum of nodes,
+// so that the next quorum could issue a token that is not greater
+var ErrFenceFailed = errors.New("failed to record the fencing token on a quorum of nodes")
+
+// ErrTicketFailed is the error of fair blocking acquisitions that could not take a ticket from a quorum of nodes,
+// without which they can't be queued in the order waiters arrived
+var ErrTicketFailed = errors.New("failed to take a ticket of the queue from a quorum of nodes")
@@ -0,0 +1,393 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alexandreLamarre/dlock/pkg/lock"
+	"github.com/alexandreLamarre/dlock/pkg/logger"
+	"github.com/go-redsync/redsync/v4/redis"
+	"github.com/google/uuid"
+)
+
+// waitScript registers a waiter & its HolderInfo, fair waiters being queued once with their ticket.
+// The sequence of tickets is raised to the ticket of the waiter, so that later waiters take greater tickets.
+var waitScript = redis.NewScript(4, `
+	local now = redis.call("TIME")
+	local ms = now[1] * 1000 + math.floor(now[2] / 1000)
+	redis.call("ZREMRANGEBYSCORE", KEYS[1], "-inf", ms)
+	redis.call("ZADD", KEYS[1], ms + tonumber(ARGV[2]), ARGV[1])
+	if redis.call("PTTL", KEYS[1]) < tonumber(ARGV[2]) then
+		redis.call("PEXPIRE", KEYS[1], ARGV[2])
+	end
+	if ARGV[3] ~= "" then
+		redis.call("ZADD", KEYS[2], "NX", ARGV[3], ARGV[1])
+		if tonumber(redis.call("GET", KEYS[4]) or "0") < tonumber(ARGV[3]) then
+			redis.call("SET", KEYS[4], ARGV[3])
+		end
+		for _, key in ipairs({KEYS[2], KEYS[4]}) do
+			if redis.call("PTTL", key) < tonumber(ARGV[2]) then
+				redis.call("PEXPIRE", key, ARGV[2])
+			end
+		end
+	end
+	redis.call("HSET", KEYS[3], ARGV[1], ARGV[4])
+	if redis.call("PTTL", KEYS[3]) < tonumber(ARGV[2]) then
+		redis.call("PEXPIRE", KEYS[3], ARGV[2])
+	end
+	return 1
+`, "")
+
+// ticketScript issues the next ticket of the fair waiters of the lock
+var ticketScript = redis.NewScript(1, `
+	return redis.call("INCR", KEYS[1])
+`, "")
+
+// unwaitScript unregisters a waiter, releasing the lock when it was transferred to the waiter and not claimed
+var unwaitScript = redis.NewScript(5, `
+	redis.call("ZREM", KEYS[2], ARGV[1])
+	redis.call("HDEL", KEYS[3], ARGV[1])
+	if ARGV[2] == "1" and redis.call("GET", KEYS[4]) == ARGV[1] then
+		redis.call("DEL", KEYS[4])
+		redis.call("HDEL", KEYS[5], ARGV[1])
+	end
+	return redis.call("ZREM", KEYS[1], ARGV[1])
+`, "")
+
+// positionScript returns the number of live waiters ordered before the waiter, waiters being prefixed
+// by the time they started waiting
+var positionScript = redis.NewScript(1, `
+	local now = redis.call("TIME")
+	local ms = now[1] * 1000 + math.floor(now[2] / 1000)
+	local score = redis.call("ZSCORE", KEYS[1], ARGV[1])
+	if not score or tonumber(score) <= ms then
+		return -1
+	end
+	local ahead = 0
+	for _, waiter in ipairs(redis.call("ZRANGEBYSCORE", KEYS[1], "(" .. ms, "+inf")) do
+		if waiter < ARGV[1] then
+			ahead = ahead + 1
+		end
+	end
+	return ahead
+`, "")
+
+// describeScript returns the holders of the lock as (value, ttl in ms, info, shared) tuples
+// followed by the number of waiters
+var describeScript = redis.NewScript(4, `
+	local now = redis.call("TIME")
+	local ms = now[1] * 1000 + math.floor(now[2] / 1000)
+	local holders = {}
+	local holder = redis.call("GET", KEYS[1])
+	if holder then
+		table.insert(holders, {holder, redis.call("PTTL", KEYS[1]), redis.call("HGET", KEYS[3], holder) or "", 0})
+	end
+	local readers = redis.call("ZRANGEBYSCORE", KEYS[2], "(" .. ms, "+inf", "WITHSCORES")
+	for i = 1, #readers, 2 do
+		local ttl = tonumber(readers[i + 1]) - ms
+		table.insert(holders, {readers[i], ttl, redis.call("HGET", KEYS[3], readers[i]) or "", 1})
+	end
+	return {holders, redis.call("ZCOUNT", KEYS[4], "(" .. ms, "+inf")}
+`, "")
+
+// scanScript runs a single iteration of SCAN, the cursor being iterated by the client so that scanning the keyspace
+// never blocks the node for longer than one round trip
+var scanScript = redis.NewScript(0, `
+	return redis.call("SCAN", ARGV[1], "MATCH", ARGV[2], "COUNT", ARGV[3])
+`, "")
+
+// ScanCount is the number of keys SCAN visits on each round trip
+var ScanCount = 1000
+
+func eval(ctx context.Context, pool redis.Pool, lg *slog.Logger, script *redis.Script, keysAndArgs ...interface{}) (interface{}, error) {
+	conn, err := pool.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := conn.Close(); err != nil {
+			lg.With("err", err).Error("failed to close redis connection, potential connection leak")
+		}
+	}()
+	return conn.Eval(script, keysAndArgs...)
+}
+
+// wait registers a blocking acquisition as a waiter of the lock, until it is unregistered or expires.
+// Fair waiters are queued by their ticket, which is taken on their first registration,
+// and it returns ErrTicketFailed when no ticket could be taken.
+func (m *redisMutex) wait(ctx context.Context, waiter string) error {
+	ctx, ca := context.WithTimeout(ctx, ackTimeoutFactor(m.expiry()))
+	defer ca()
+	ticket := ""
+	if m.fair() {
+		if m.ticket == 0 {
+			if err := m.takeTicket(ctx); err != nil {
+				return err
+			}
+		}
+		ticket = strconv.FormatInt(m.ticket, 10)
+	}
+	info, err := json.Marshal(m.Holder(m.shared))
+	if err != nil {
+		m.lg.With(logger.Err(err)).Debug("failed to encode waiter info")
+	}
+	if _, err := m.actOnPoolsAsync(func(pool redis.Pool) (bool, error) {
+		_, err := eval(ctx, pool, m.lg, waitScript, m.waitersKey(), m.queueKey(), m.waitingKey(), m.ticketKey(),
+			waiter, int(m.expiry()/time.Millisecond), ticket, string(info))
+		return err == nil, err
+	}); err != nil {
+		m.lg.With(logger.Err(err)).Debug("failed to register waiter")
+	}
+	return nil
+}
+
+// takeTicket takes the greatest of the tickets issued by a quorum of nodes, the ticket being tied to the order in
+// which waiters arrived rather than to the clocks of their processes. Since registering the waiter raises the sequence
+// of a quorum of nodes to its ticket, waiters arriving once it is registered take greater tickets.
+func (m *redisMutex) takeTicket(ctx context.Context) error {
+	var mu sync.Mutex
+	var ticket int64
+	n, err := m.actOnPoolsAsync(func(pool redis.Pool) (bool, error) {
+		reply, err := eval(ctx, pool, m.lg, ticketScript, m.ticketKey())
+		if err != nil {
+			return false, err
+		}
+		issued, ok := reply.(int64)
+		if !ok {
+			return false, fmt.Errorf("unexpected ticket %v", reply)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		ticket = max(ticket, issued)
+		return true, nil
+	})
+	if n < m.quorum {
+		return errors.Join(ErrTicketFailed, err)
+	}
+	m.ticket = ticket
+	return nil
+}
+
+// unwait unregisters the waiter, releasing the lock if it was transferred to the waiter unless it was claimed
+func (m *redisMutex) unwait(waiter string, claimed bool) {
+	ctx, ca := context.WithTimeout(context.Background(), m.expiry())
+	defer ca()
+	release := "1"
+	if claimed {
+		release = "0"
+	}
+	if _, err := m.actOnPoolsAsync(func(pool redis.Pool) (bool, error) {
+		_, err := eval(ctx, pool, m.lg, unwaitScript, m.waitersKey(), m.queueKey(), m.waitingKey(), m.key(), m.infoKey(),
+			waiter, release)
+		return err == nil, err
+	}); err != nil {
+		m.lg.With(logger.Err(err)).Warn("failed to unregister waiter")
+	}
+}
+
+// waiterID orders the waiters of a lock by the time they started waiting
+func waiterID(since time.Time) string {
+	return fmt.Sprintf("%020d-%s", since.UnixMicro(), uuid.New().String())
+}
+
+// position returns the largest number of waiters ahead of the waiter seen by a quorum of nodes
+func (m *redisMutex) position(ctx context.Context, waiter string) (int, error) {
+	var mu sync.Mutex
+	position := -1
+	n, err := m.actOnPoolsAsync(func(pool redis.Pool) (bool, error) {
+		reply, err := eval(ctx, pool, m.lg, positionScript, m.waitersKey(), waiter)
+		if err != nil {
+			return false, err
+		}
+		ahead, _ := reply.(int64)
+		if ahead < 0 {
+			return false, nil
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		position = max(position, int(ahead))
+		return true, nil
+	})
+	if n < m.quorum {
+		return 0, errors.Join(lock.ErrNotQueued, err)
+	}
+	return position, nil
+}
+
+type nodeHolder struct {
+	info  lock.HolderInfo
+	nodes int
+}
+
+// describe reports the holders acknowledged by a quorum of nodes, and the largest number of waiters seen by a node
+func (m *redisMutex) describe(ctx context.Context) (lock.LockInfo, error) {
+	var mu sync.Mutex
+	holders := map[string]*nodeHolder{}
+	waiters := 0
+	n, err := m.actOnPoolsAsync(func(pool redis.Pool) (bool, error) {
+		reply, err := eval(ctx, pool, m.lg, describeScript, m.key(), m.readersKey(), m.infoKey(), m.waitersKey())
+		if err != nil {
+			return false, err
+		}
+		res, ok := reply.([]interface{})
+		if !ok || len(res) != 2 {
+			return false, fmt.Errorf("unexpected describe reply : %v", reply)
+		}
+		tuples, _ := res[0].([]interface{})
+		count, _ := res[1].(int64)
+		mu.Lock()
+		defer mu.Unlock()
+		waiters = max(waiters, int(count))
+		for _, t := range tuples {
+			tuple, ok := t.([]interface{})
+			if !ok || len(tuple) != 4 {
+				return false, fmt.Errorf("unexpected holder reply : %v", t)
+			}
+			value, _ := tuple[0].(string)
+			ttl, _ := tuple[1].(int64)
+			data, _ := tuple[2].(string)
+			shared, _ := tuple[3].(int64)
+			h, ok := holders[value]
+			if !ok {
+				h = &nodeHolder{}
+				if data != "" {
+					if err := json.Unmarshal([]byte(data), &h.info); err != nil {
+						m.lg.With(logger.Err(err)).Warn("failed to decode holder info")
+					}
+				}
+				h.info.Shared = shared == 1
+				h.info.TTL = time.Duration(ttl) * time.Millisecond
+				holders[value] = h
+			}
+			h.nodes++
+			h.info.TTL = min(h.info.TTL, time.Duration(ttl)*time.Millisecond)
+		}
+		return true, nil
+	})
+	if n < m.quorum {
+		return lock.LockInfo{}, errors.Join(errors.New("failed to describe lock : no consensus"), err)
+	}
+	info := lock.LockInfo{
+		Key:     m.mutexKey,
+		Holders: []lock.HolderInfo{},
+		Waiters: waiters,
+	}
+	for _, h := range holders {
+		if h.nodes >= m.quorum {
+			info.Holders = append(info.Holders, h.info)
+		}
+	}
+	slices.SortFunc(info.Holders, func(a, b lock.HolderInfo) int {
+		return a.AcquiredAt.Compare(b.AcquiredAt)
+	})
+	return info, nil
+}
+
+var forceReleaseScript = redis.NewScript(3, `
+	redis.call("DEL", KEYS[1], KEYS[2], KEYS[3])
+	return 1
+`, "")
+
+// forceRelease deletes the holders of the lock from every node, their next keepalive fails on a quorum of nodes
+func (m *redisMutex) forceRelease(ctx context.Context) (lock.LockInfo, error) {
+	info, err := m.describe(ctx)
+	if err != nil {
+		return lock.LockInfo{}, err
+	}
+	n, err := m.actOnPoolsAsync(func(pool redis.Pool) (bool, error) {
+		_, err := eval(ctx, pool, m.lg, forceReleaseScript, m.key(), m.readersKey(), m.infoKey())
+		return err == nil, err
+	})
+	if n < m.quorum {
+		return lock.LockInfo{}, errors.Join(errors.New("failed to force release lock : no consensus"), err)
+	}
+	return info, nil
+}
+
+var globReplacer = strings.NewReplacer(`\`, `\\`, `*`, `\*`, `?`, `\?`, `[`, `\[`, `]`, `\]`)
+
+// scanKeys returns the keys of the locks under the escaped prefix, whose keys of the given kinds are held by the
+// masters of the pool
+func (lm *LockManager) scanKeys(ctx context.Context, pool redis.Pool, kinds []string, escaped string) ([]string, error) {
+	masters, err := shards(ctx, pool)
+	if err != nil {
+		return nil, err
+	}
+	keys := []string{}
+	for _, master := range masters {
+		for _, kind := range kinds {
+			cursor := "0"
+			for {
+				if err := ctx.Err(); err != nil {
+					return nil, err
+				}
+				reply, err := eval(ctx, master, lm.lg, scanScript, cursor, keyPattern(lm.prefix, kind, escaped, lm.tagged), ScanCount)
+				if err != nil {
+					return nil, err
+				}
+				res, _ := reply.([]interface{})
+				if len(res) != 2 {
+					return nil, fmt.Errorf("unexpected reply to SCAN : %v", reply)
+				}
+				cursor, _ = res[0].(string)
+				names, _ := res[1].([]interface{})
+				for _, n := range names {
+					name, _ := n.(string)
+					if key, ok := lockKeyOf(strings.TrimPrefix(name, lm.prefix+kind), lm.tagged); ok {
+						keys = append(keys, key)
+					}
+				}
+				if cursor == "0" || cursor == "" {
+					break
+				}
+			}
+		}
+	}
+	return keys, nil
+}
+
+// keys returns the keys of the locks under the prefix that are held or waited on by any reachable node
+func (lm *LockManager) keys(ctx context.Context, prefix string) ([]string, error) {
+	escaped := globReplacer.Replace(prefix)
+	kinds := []string{".info-", ".waiters-"}
+	var mu sync.Mutex
+	keys := map[string]struct{}{}
+	var errs error
+	failed := 0
+	var wg sync.WaitGroup
+	for i, pool := range lm.pools {
+		wg.Add(1)
+		go func(i int, pool redis.Pool) {
+			defer wg.Done()
+			nodeKeys, err := lm.scanKeys(ctx, pool, kinds, escaped)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs = errors.Join(errs, &RedisError{Node: i, Err: err})
+				failed++
+				return
+			}
+			for _, k := range nodeKeys {
+				keys[k] = struct{}{}
+			}
+		}(i, pool)
+	}
+	wg.Wait()
+	if len(lm.pools)-failed < lm.quorum {
+		return nil, errs
+	}
+	ret := make([]string, 0, len(keys))
+	for k := range keys {
+		ret = append(ret, k)
+	}
+	slices.Sort(ret)
+	return ret, nil
+}
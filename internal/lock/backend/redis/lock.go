@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"sync/atomic"
 	"time"
 
 	"github.com/alexandreLamarre/dlock/pkg/lock"
@@ -32,6 +33,7 @@ type Lock struct {
 
 	scheduler *lock.LockScheduler
 	mutex     *redisMutex
+	token     atomic.Uint64
 
 	*lock.LockOptions
 }
@@ -54,21 +56,43 @@ func NewLock(
 	}
 }
 
-var _ lock.Lock = (*Lock)(nil)
+var _ lock.RWLock = (*Lock)(nil)
+var _ lock.Transferer = (*Lock)(nil)
 
 func (l *Lock) Lock(ctx context.Context) (expired <-chan struct{}, err error) {
-	retry := lo.ToPtr(
+	return l.lock(ctx, retryPolicy(l.RetryDelayOr(LockRetryDelay)), false)
+}
+
+func (l *Lock) TryLock(ctx context.Context) (acquired bool, expired <-chan struct{}, err error) {
+	return l.tryLock(ctx, false)
+}
+
+// RLock acquires the read side of the lock, readers are tracked in a sorted set
+// scored by their expiry so that crashed readers are eventually evicted
+func (l *Lock) RLock(ctx context.Context) (expired <-chan struct{}, err error) {
+	return l.lock(ctx, retryPolicy(l.RetryDelayOr(LockRetryDelay)), true)
+}
+
+func (l *Lock) TryRLock(ctx context.Context) (acquired bool, expired <-chan struct{}, err error) {
+	return l.tryLock(ctx, true)
+}
+
+func (l *Lock) RUnlock() error {
+	return l.unlock(true)
+}
+
+func retryPolicy(delay time.Duration) *backoffv2.Policy {
+	return lo.ToPtr(
 		backoffv2.Constant(
 			backoffv2.WithMaxRetries(0),
-			backoffv2.WithInterval(LockRetryDelay),
+			backoffv2.WithInterval(delay),
 			backoffv2.WithJitterFactor(0.1),
 		),
 	)
-	return l.lock(ctx, retry)
 }
 
-func (l *Lock) TryLock(ctx context.Context) (acquired bool, expired <-chan struct{}, err error) {
-	closureDone, err := l.lock(ctx, nil)
+func (l *Lock) tryLock(ctx context.Context, shared bool) (acquired bool, expired <-chan struct{}, err error) {
+	closureDone, err := l.lock(ctx, nil, shared)
 	if err != nil {
 		if errors.Is(err, ErrTaken) {
 			l.lg.Debug(
@@ -85,7 +109,7 @@ func (l *Lock) TryLock(ctx context.Context) (acquired bool, expired <-chan struc
 	return true, closureDone, nil
 }
 
-func (l *Lock) lock(ctx context.Context, retrier *backoffv2.Policy) (expired <-chan struct{}, err error) {
+func (l *Lock) lock(ctx context.Context, retrier *backoffv2.Policy, shared bool) (expired <-chan struct{}, err error) {
 	if l.Tracer != nil {
 		ctxSpan, span := l.Tracer.Start(ctx, "Lock/redis-lock")
 		defer span.End()
@@ -97,7 +121,7 @@ func (l *Lock) lock(ctx context.Context, retrier *backoffv2.Policy) (expired <-c
 
 	var closureDone <-chan struct{}
 	if err := l.scheduler.Schedule(func() error {
-		done, err := l.acquire(ctxca, retrier)
+		done, err := l.acquire(ctxca, retrier, shared)
 		if err != nil {
 			return err
 		}
@@ -110,36 +134,81 @@ func (l *Lock) lock(ctx context.Context, retrier *backoffv2.Policy) (expired <-c
 	return closureDone, nil
 }
 
-func (l *Lock) acquire(ctx context.Context, retrier *backoffv2.Policy) (<-chan struct{}, error) {
-	var curErr error
-	mutex := newRedisMutex(l.prefix, l.key, l.quorum, l.pools, l.lg, l.LockOptions)
+func (l *Lock) acquire(ctx context.Context, retrier *backoffv2.Policy, shared bool) (<-chan struct{}, error) {
+	mutex := newRedisMutex(l.prefix, l.key, shared, l.quorum, l.pools, l.lg, l.LockOptions)
+	if retrier != nil && mutex.fair() {
+		// fair waiters are queued before their first attempt, so that they never overtake earlier waiters
+		return l.wait(ctx, retrier, &mutex, nil)
+	}
 	done, err := mutex.lock(ctx)
-	curErr = err
 	if err == nil {
 		l.mutex = &mutex
+		l.token.Store(mutex.fencingToken)
 		return done, nil
 	}
 	if retrier != nil {
-		ret := *retrier
-		acq := ret.Start(ctx)
-		for backoffv2.Continue(acq) {
-			done, err := mutex.lock(ctx)
-			curErr = err
-			if err == nil {
-				l.mutex = &mutex
-				return done, nil
-			}
+		return l.wait(ctx, retrier, &mutex, err)
+	}
+	return nil, err
+}
+
+// wait registers the mutex as a waiter of the lock until it acquires the lock or gives up,
+// refreshing its registration before it expires
+func (l *Lock) wait(ctx context.Context, retrier *backoffv2.Policy, mutex *redisMutex, curErr error) (<-chan struct{}, error) {
+	since := time.Now()
+	waiter := waiterID(since)
+	if mutex.fair() {
+		mutex.waiter = waiter
+	}
+	if err := mutex.wait(ctx, waiter); err != nil {
+		return nil, errors.Join(err, curErr)
+	}
+	registered := time.Now()
+	claimed := false
+	defer func() {
+		go mutex.unwait(waiter, claimed)
+	}()
+	defer l.NotifyPosition(ctx, func(ctx context.Context) (int, error) {
+		return mutex.position(ctx, waiter)
+	})()
+	ret := *retrier
+	acq := ret.Start(ctx)
+	for backoffv2.Continue(acq) {
+		if time.Since(registered) > mutex.expiry()/2 {
+			// the ticket of fair waiters is only taken on their first registration
+			_ = mutex.wait(ctx, waiter)
+			registered = time.Now()
+		}
+		done, err := mutex.lock(ctx)
+		curErr = err
+		if err == nil {
+			l.mutex = mutex
+			l.token.Store(mutex.fencingToken)
+			return done, nil
+		}
+		// the lock may have been transferred to the waiter
+		if done, ok := mutex.claim(ctx, waiter); ok {
+			claimed = true
+			l.mutex = mutex
+			l.token.Store(mutex.fencingToken)
+			return done, nil
 		}
-		return nil, errors.Join(ctx.Err(), curErr)
 	}
-	return nil, curErr
+	return nil, errors.Join(ctx.Err(), curErr)
 }
 
 func (l *Lock) Unlock() error {
+	return l.unlock(false)
+}
+
+func (l *Lock) unlock(shared bool) error {
 	if err := l.scheduler.Done(func() error {
 		if l.mutex == nil {
 			return nil
 		}
+		if l.mutex.shared != shared {
+			return lock.ErrLockMode
+		}
 		mutex := *l.mutex
 		go func() {
 			if unlocked, err := mutex.unlock(); err != nil {
@@ -147,9 +216,32 @@ func (l *Lock) Unlock() error {
 			}
 		}()
 		l.mutex = nil
+		l.token.Store(0)
 		return nil
 	}); err != nil {
 		return err
 	}
 	return nil
 }
+
+func (l *Lock) Transfer(ctx context.Context, toOwner string) error {
+	return l.scheduler.Done(func() error {
+		if l.mutex == nil {
+			return lock.ErrNotHeld
+		}
+		if l.mutex.shared {
+			return lock.ErrLockMode
+		}
+		if err := l.mutex.transfer(ctx, toOwner); err != nil {
+			return err
+		}
+		l.mutex.teardown()
+		l.mutex = nil
+		l.token.Store(0)
+		return nil
+	})
+}
+
+func (l *Lock) FencingToken() uint64 {
+	return l.token.Load()
+}
@@ -4,12 +4,12 @@ import (
 	"context"
 	"errors"
 	"log/slog"
+	"time"
 
 	"github.com/alexandreLamarre/dlock/pkg/constants"
 	"github.com/alexandreLamarre/dlock/pkg/lock"
 	"github.com/alexandreLamarre/dlock/pkg/lock/broker"
 	"github.com/go-redsync/redsync/v4/redis"
-	goredislib "github.com/redis/go-redis/v9"
 )
 
 var pingScript = redis.NewScript(0, `
@@ -20,17 +20,21 @@ var pingScript = redis.NewScript(0, `
 func init() {
 	broker.RegisterLockBroker(
 		constants.RedisLockManager,
-		func(ctx context.Context, l broker.LockBroker) (lock.LockManager, error) {
+		func(ctx context.Context, l broker.LockBroker) (broker.LockManagers, error) {
 			l.Lg.Info("acquiring redis client...")
-			cli := AcquireRedisPool([]*goredislib.Options{
-				{
-					Addr:    l.Config.RedisClientSpec.Addr,
-					Network: l.Config.RedisClientSpec.Network,
-				},
-			})
+			opts, err := RedisClientOptions(l.Config.RedisClientSpec)
+			if err != nil {
+				return nil, err
+			}
+			if len(opts) > 1 {
+				l.Lg.With("nodes", len(opts)).Info("acquiring locks on a quorum of redis nodes")
+			}
+			cli := AcquireRedisNodePool(opts)
 			// TODO : ping redis pool for health before starting
 			l.Lg.Info("acquired redis client")
-			return NewLockManager(ctx, "lock", cli, l.Lg), nil
+			return func(prefix string) (lock.LockManager, error) {
+				return NewLockManager(ctx, prefix, cli, l.Lg), nil
+			}, nil
 		},
 	)
 }
@@ -39,13 +43,19 @@ type LockManager struct {
 	ctx    context.Context
 	pools  []redis.Pool
 	quorum int
+	// tagged lock managers hash tag the keys of locks, see lockKey
+	tagged bool
 
 	prefix string
 
 	lg *slog.Logger
+
+	reentrant *lock.ReentrantLocks
 }
 
 var _ lock.LockManager = (*LockManager)(nil)
+var _ lock.TTLDefaulter = (*LockManager)(nil)
+var _ lock.BarrierManager = (*LockManager)(nil)
 
 func NewLockManager(
 	ctx context.Context,
@@ -54,14 +64,20 @@ func NewLockManager(
 	lg *slog.Logger,
 ) *LockManager {
 	return &LockManager{
-		ctx:    ctx,
-		pools:  pools,
-		prefix: prefix,
-		quorum: len(pools)/2 + 1,
-		lg:     lg,
+		ctx:       ctx,
+		pools:     pools,
+		prefix:    prefix,
+		quorum:    len(pools)/2 + 1,
+		tagged:    clustered(pools),
+		lg:        lg,
+		reentrant: lock.NewReentrantLocks(),
 	}
 }
 
+func (lm *LockManager) DefaultTTL() time.Duration {
+	return LockExpiry
+}
+
 func (lm *LockManager) Health(ctx context.Context) (conditions []string, err error) {
 	for i, pool := range lm.pools {
 		conn, poolErr := pool.Get(ctx)
@@ -80,7 +96,76 @@ func (lm *LockManager) Health(ctx context.Context) (conditions []string, err err
 }
 
 func (lm *LockManager) NewLock(key string, opt ...lock.LockOption) lock.Lock {
+	options := lock.DefaultLockOptions()
+	options.Apply(opt...)
+	return lm.reentrant.NewLock(key, options, func() lock.Lock {
+		return NewLock(lm.pools, lm.quorum, lm.prefix, key, lm.lg, options)
+	})
+}
+
+func (lm *LockManager) NewRWLock(key string, opt ...lock.LockOption) lock.RWLock {
 	options := lock.DefaultLockOptions()
 	options.Apply(opt...)
 	return NewLock(lm.pools, lm.quorum, lm.prefix, key, lm.lg, options)
 }
+
+func (lm *LockManager) NewMultiLock(keys []string, opts ...lock.LockOption) lock.Lock {
+	return lock.NewMultiLock(keys, func(key string) lock.Lock {
+		return lm.NewLock(key, opts...)
+	})
+}
+
+func (lm *LockManager) NewSemaphore(key string, capacity int64, opt ...lock.LockOption) lock.Semaphore {
+	options := lock.DefaultLockOptions()
+	options.Apply(opt...)
+	return NewSemaphore(lm.pools, lm.quorum, lm.prefix, key, capacity, lm.lg, options)
+}
+
+func (lm *LockManager) NewBarrier(key string, n int64, opt ...lock.LockOption) lock.Barrier {
+	options := lock.DefaultLockOptions()
+	options.Apply(opt...)
+	return NewBarrier(lm.pools, lm.quorum, lm.prefix, key, n, lm.lg, options)
+}
+
+func (lm *LockManager) NewCountDownLatch(key string, n int64, opt ...lock.LockOption) lock.CountDownLatch {
+	options := lock.DefaultLockOptions()
+	options.Apply(opt...)
+	return NewCountDownLatch(lm.pools, lm.quorum, lm.prefix, key, n, lm.lg, options)
+}
+
+func (lm *LockManager) DescribeLock(ctx context.Context, key string) (lock.LockInfo, error) {
+	mutex := newRedisMutex(lm.prefix, key, false, lm.quorum, lm.pools, lm.lg, lock.DefaultLockOptions())
+	return mutex.describe(ctx)
+}
+
+// ForceRelease deletes the keys of the lock's holders on every node, evicted holders notice it
+// when their next keepalive fails
+func (lm *LockManager) ForceRelease(ctx context.Context, key, reason string) (lock.LockInfo, error) {
+	mutex := newRedisMutex(lm.prefix, key, false, lm.quorum, lm.pools, lm.lg, lock.DefaultLockOptions())
+	info, err := mutex.forceRelease(ctx)
+	if err != nil {
+		return lock.LockInfo{}, err
+	}
+	lm.lg.With("key", key, "reason", reason, "holders", len(info.Holders)).Warn("force released lock")
+	return info, nil
+}
+
+func (lm *LockManager) ListLocks(ctx context.Context, prefix string) ([]lock.LockInfo, error) {
+	keys, err := lm.keys(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+	infos := []lock.LockInfo{}
+	for _, key := range keys {
+		info, err := lm.DescribeLock(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		// info & waiters keys may outlive their holders & waiters until they expire
+		if len(info.Holders) == 0 && info.Waiters == 0 {
+			continue
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
@@ -2,9 +2,12 @@ package redis
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log/slog"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/alexandreLamarre/dlock/pkg/lock"
@@ -20,45 +23,106 @@ type redisMutex struct {
 	lg       *slog.Logger
 	prefix   string
 	mutexKey string
+	// shared mutexes hold the read side of a RWLock
+	shared bool
+	// semaphore mutexes, with a non-zero capacity, hold weight units of a semaphore
+	weight   int64
+	capacity int64
+	// barrier mutexes, with a non-zero number of participants, register a participant of a barrier
+	participants int64
 
 	internalDone chan struct{}
 	*lock.LockOptions
 
 	quorum int
 	pools  []redis.Pool
+	// tagged mutexes hash tag their key, see lockKey
+	tagged bool
 
 	uuid string
+	// waiter is the queued acquisition of fair mutexes, attempts that are not queued have none
+	waiter string
+	// ticket orders the waiter in the queue of fair mutexes, zero until it is issued
+	ticket int64
 
 	// TODO : make better
 	until time.Time
 
+	fencingToken uint64
+
 	// TODO : all the following are unused
 	// expiry time.Duration
 	// driftFactor   float64 // nolint:unused
 	// timeoutFactor float64
-	// fencingToken  string // this should add extra consistency, can be added to genValue instead
 	// parentCtx     context.Context
 }
 
 func newRedisMutex(
 	prefix, key string,
+	shared bool,
 	quorum int,
 	pools []redis.Pool,
 	lg *slog.Logger,
 	opts *lock.LockOptions,
 ) redisMutex {
 	return redisMutex{
-		lg:           lg.With("prefix", prefix, "key", key, "quorum", quorum),
+		lg:           lg.With("prefix", prefix, "key", key, "quorum", quorum, "shared", shared),
 		prefix:       prefix,
 		mutexKey:     key,
+		shared:       shared,
 		internalDone: make(chan struct{}),
 		LockOptions:  opts,
 		quorum:       quorum,
 		pools:        pools,
+		tagged:       clustered(pools),
 	}
 }
 
+func newRedisSemaphore(
+	prefix, key string,
+	weight, capacity int64,
+	quorum int,
+	pools []redis.Pool,
+	lg *slog.Logger,
+	opts *lock.LockOptions,
+) redisMutex {
+	m := newRedisMutex(prefix, key, false, quorum, pools, lg.With("weight", weight, "capacity", capacity), opts)
+	m.weight = weight
+	m.capacity = capacity
+	return m
+}
+
+func newRedisBarrier(
+	prefix, key string,
+	participants int64,
+	quorum int,
+	pools []redis.Pool,
+	lg *slog.Logger,
+	opts *lock.LockOptions,
+) redisMutex {
+	m := newRedisMutex(prefix, key, false, quorum, pools, lg.With("participants", participants), opts)
+	m.participants = participants
+	return m
+}
+
+func (m *redisMutex) isSemaphore() bool {
+	return m.capacity > 0
+}
+
+func (m *redisMutex) isBarrier() bool {
+	return m.participants > 0
+}
+
+// inSet reports whether the mutex is a member of the sorted set of its key rather than the holder of its key
+func (m *redisMutex) inSet() bool {
+	return m.shared || m.isSemaphore() || m.isBarrier()
+}
+
 func (m *redisMutex) scopedToken() string {
+	if m.isSemaphore() {
+		// holders of a semaphore carry their weight, so that acquisitions can sum the units in use
+		return fmt.Sprintf("%s:%d", uuid.New().String(), m.weight)
+	}
 	return uuid.New().String()
 }
 
@@ -99,27 +163,275 @@ func (m *redisMutex) actOnPoolsAsync(actFn func(redis.Pool) (bool, error)) (int,
 }
 
 func (m *redisMutex) key() string {
-	return m.prefix + "-" + m.mutexKey
+	return m.taggedKey("-")
+}
+
+func (m *redisMutex) taggedKey(kind string) string {
+	return lockKey(m.prefix, kind, m.mutexKey, m.tagged)
+}
+
+// lockKey returns the name of a key of the lock. The keys of locks on redis clusters hash tag the key of the lock,
+// e.g. `lock.info-{my-lock}`, so that every key of the lock is held by the same master & scripts can access them
+// together. Other deployments keep the names of the keys of locks acquired before clusters were supported.
+func lockKey(prefix, kind, key string, tagged bool) string {
+	if tagged {
+		key = "{" + key + "}"
+	}
+	return prefix + kind + key
+}
+
+// lockKeyOf returns the key of the lock from the name of one of its keys, trimmed of its prefix & kind
+func lockKeyOf(name string, tagged bool) (string, bool) {
+	if !tagged {
+		return name, name != ""
+	}
+	key, ok := strings.CutPrefix(name, "{")
+	if !ok {
+		return "", false
+	}
+	return strings.CutSuffix(key, "}")
 }
 
-func (m *redisMutex) acquire(ctx context.Context, pool redis.Pool, value string) (bool, error) {
+// keyPattern returns the glob pattern of the keys of the given kind of the locks under the escaped prefix
+func keyPattern(prefix, kind, escaped string, tagged bool) string {
+	if tagged {
+		escaped = "{" + escaped
+	}
+	return globReplacer.Replace(prefix+kind) + escaped + "*"
+}
+
+// fenceKey holds the fencing token counter for the lock, it never expires so
+// that tokens keep increasing across successive holders
+func (m *redisMutex) fenceKey() string {
+	return m.taggedKey(".fence-")
+}
+
+// readersKey holds the set of readers of the lock, scored by the time at which they expire
+func (m *redisMutex) readersKey() string {
+	return m.taggedKey(".readers-")
+}
+
+// semaphoreKey holds the set of holders of the semaphore, scored by the time at which they expire
+func (m *redisMutex) semaphoreKey() string {
+	return m.taggedKey(".semaphore-")
+}
+
+// barrierKey holds the set of participants waiting on the barrier, scored by the time at which they expire
+func (m *redisMutex) barrierKey() string {
+	return m.taggedKey(".barrier-")
+}
+
+// trippedKey holds the participants that reached the barrier and did not notice it yet
+func (m *redisMutex) trippedKey() string {
+	return m.taggedKey(".tripped-")
+}
+
+// infoKey holds the HolderInfo of the holders of the lock, by their fenced value.
+// Barriers hold the HolderInfo of their participants separately, so that their keys are never listed as locks.
+func (m *redisMutex) infoKey() string {
+	if m.isBarrier() {
+		return m.taggedKey(".participants-")
+	}
+	return m.taggedKey(".info-")
+}
+
+// waitersKey holds the set of blocking acquisitions waiting for the lock, scored by the time at which they expire
+func (m *redisMutex) waitersKey() string {
+	return m.taggedKey(".waiters-")
+}
+
+// waitingKey holds the HolderInfo of the blocking acquisitions waiting for the lock, by waiter
+func (m *redisMutex) waitingKey() string {
+	return m.taggedKey(".waiting-")
+}
+
+// queueKey holds the set of fair blocking acquisitions waiting for the lock, scored by their ticket.
+// Every node queues a waiter with the same ticket, so that every node orders the queue identically.
+func (m *redisMutex) queueKey() string {
+	return m.taggedKey(".queue-")
+}
+
+// ticketKey holds the sequence issuing the tickets of fair waiters, see takeTicket
+func (m *redisMutex) ticketKey() string {
+	return m.taggedKey(".ticket-")
+}
+
+// fair mutexes never overtake the fair waiters queued before them, semaphores are never fair
+func (m *redisMutex) fair() bool {
+	return m.Fair && !m.isSemaphore()
+}
+
+// setKey is the sorted set holding shared acquisitions, readers, semaphore holders or barrier participants
+func (m *redisMutex) setKey() string {
+	if m.isSemaphore() {
+		return m.semaphoreKey()
+	}
+	if m.isBarrier() {
+		return m.barrierKey()
+	}
+	return m.readersKey()
+}
+
+// fairPrelude fails the acquisitions of fair mutexes that are not at the head of the queue, waiters whose registration
+// expired being removed from the queue. Fair scripts take the queue & waiters keys after the keys of the acquisition,
+// and the waiter after its arguments.
+const fairPrelude = `
+	local waiter = ARGV[#ARGV]
+	local clock = redis.call("TIME")
+	local clockMs = clock[1] * 1000 + math.floor(clock[2] / 1000)
+	while true do
+		local head = redis.call("ZRANGE", KEYS[#KEYS - 1], 0, 0)[1]
+		if not head or head == waiter then
+			break
+		end
+		local deadline = redis.call("ZSCORE", KEYS[#KEYS], head)
+		if deadline and tonumber(deadline) > clockMs then
+			return 0
+		end
+		redis.call("ZREM", KEYS[#KEYS - 1], head)
+	end
+`
+
+const acquireSrc = `
+	local now = redis.call("TIME")
+	redis.call("ZREMRANGEBYSCORE", KEYS[3], "-inf", now[1] * 1000 + math.floor(now[2] / 1000))
+	if redis.call("ZCARD", KEYS[3]) > 0 then
+		return 0
+	end
+	if redis.call("SET", KEYS[1], ARGV[1], "NX", "PX", ARGV[2]) then
+		redis.call("DEL", KEYS[4])
+		redis.call("HSET", KEYS[4], ARGV[1], ARGV[3])
+		redis.call("PEXPIRE", KEYS[4], ARGV[2])
+		return redis.call("INCR", KEYS[2])
+	else
+		return 0
+	end
+`
+
+const readAcquireSrc = `
+	if redis.call("EXISTS", KEYS[1]) == 1 then
+		return 0
+	end
+	local now = redis.call("TIME")
+	local ms = now[1] * 1000 + math.floor(now[2] / 1000)
+	redis.call("ZREMRANGEBYSCORE", KEYS[2], "-inf", ms)
+	if redis.call("ZCARD", KEYS[2]) == 0 then
+		redis.call("DEL", KEYS[3])
+	end
+	redis.call("ZADD", KEYS[2], ms + tonumber(ARGV[2]), ARGV[1])
+	if redis.call("PTTL", KEYS[2]) < tonumber(ARGV[2]) then
+		redis.call("PEXPIRE", KEYS[2], ARGV[2])
+	end
+	redis.call("HSET", KEYS[3], ARGV[1], ARGV[3])
+	if redis.call("PTTL", KEYS[3]) < tonumber(ARGV[2]) then
+		redis.call("PEXPIRE", KEYS[3], ARGV[2])
+	end
+	return 1
+`
+
+var (
+	acquireScript         = redis.NewScript(4, acquireSrc, "")
+	readAcquireScript     = redis.NewScript(3, readAcquireSrc, "")
+	fairAcquireScript     = redis.NewScript(6, fairPrelude+acquireSrc, "")
+	fairReadAcquireScript = redis.NewScript(5, fairPrelude+readAcquireSrc, "")
+)
+
+var semaphoreAcquireScript = redis.NewScript(1, `
+	local now = redis.call("TIME")
+	local ms = now[1] * 1000 + math.floor(now[2] / 1000)
+	redis.call("ZREMRANGEBYSCORE", KEYS[1], "-inf", ms)
+	local held = 0
+	for _, holder in ipairs(redis.call("ZRANGE", KEYS[1], 0, -1)) do
+		held = held + tonumber(string.match(holder, ":(%d+)$"))
+	end
+	if held + tonumber(ARGV[3]) > tonumber(ARGV[4]) then
+		return 0
+	end
+	redis.call("ZADD", KEYS[1], ms + tonumber(ARGV[2]), ARGV[1])
+	if redis.call("PTTL", KEYS[1]) < tonumber(ARGV[2]) then
+		redis.call("PEXPIRE", KEYS[1], ARGV[2])
+	end
+	return 1
+`, "")
+
+func (m *redisMutex) acquire(ctx context.Context, pool redis.Pool, value, info string) (bool, uint64, error) {
 	m.lg.With("fenced", value).Debug("acquiring lock...")
 	conn, err := pool.Get(ctx)
 	if err != nil {
-		return false, err
+		return false, 0, err
 	}
 	defer func() {
 		if err := conn.Close(); err != nil {
 			m.lg.With("err", err).Error("failed to close redis connection, potential connection leak")
 		}
 	}()
-	reply, err := conn.SetNX(m.key(), value, LockExpiry)
+	var reply interface{}
+	switch {
+	case m.isSemaphore():
+		reply, err = conn.Eval(semaphoreAcquireScript, m.semaphoreKey(), value, int(m.expiry()/time.Millisecond), m.weight, m.capacity)
+	case m.fair() && m.shared:
+		reply, err = conn.Eval(fairReadAcquireScript, m.key(), m.readersKey(), m.infoKey(), m.queueKey(), m.waitersKey(),
+			value, int(m.expiry()/time.Millisecond), info, m.waiter)
+	case m.fair():
+		reply, err = conn.Eval(fairAcquireScript, m.key(), m.fenceKey(), m.readersKey(), m.infoKey(), m.queueKey(), m.waitersKey(),
+			value, int(m.expiry()/time.Millisecond), info, m.waiter)
+	case m.shared:
+		reply, err = conn.Eval(readAcquireScript, m.key(), m.readersKey(), m.infoKey(), value, int(m.expiry()/time.Millisecond), info)
+	default:
+		reply, err = conn.Eval(acquireScript, m.key(), m.fenceKey(), m.readersKey(), m.infoKey(), value, int(m.expiry()/time.Millisecond), info)
+	}
 	if err != nil {
 		m.lg.With("fenced", value).Error("failed to acquire lock", logger.Err(err))
+		return false, 0, err
+	}
+	status, _ := reply.(int64)
+	m.lg.With("fenced", value).Debug(fmt.Sprintf("acquired lock? %v", status > 0))
+	if m.shared || m.isSemaphore() {
+		return status > 0, 0, nil
+	}
+	return status > 0, uint64(status), nil
+}
+
+var fenceScript = redis.NewScript(1, `
+	local current = tonumber(redis.call("GET", KEYS[1]) or "0")
+	if current < tonumber(ARGV[1]) then
+		redis.call("SET", KEYS[1], ARGV[1])
+	end
+	return 1
+`, "")
+
+// fenceQuorum raises the fencing counters of the nodes to the token issued to this holder, so that the next quorum,
+// which overlaps with the quorum of raised counters, always issues a greater token. The token is only issued once
+// a quorum of counters is raised.
+func (m *redisMutex) fenceQuorum(ctx context.Context, token uint64) error {
+	if len(m.pools) == 1 {
+		return nil
+	}
+	n, err := m.actOnPoolsAsync(func(pool redis.Pool) (bool, error) {
+		return m.fence(ctx, pool, token)
+	})
+	if n < m.quorum {
+		return errors.Join(ErrFenceFailed, err)
+	}
+	return nil
+}
+
+// fence raises the fencing counter of the pool to the token, the GT-style script never lowering it
+func (m *redisMutex) fence(ctx context.Context, pool redis.Pool, token uint64) (bool, error) {
+	conn, err := pool.Get(ctx)
+	if err != nil {
 		return false, err
 	}
-	m.lg.With("fenced", value).Debug(fmt.Sprintf("acquired lock? %v", reply))
-	return reply, nil
+	defer func() {
+		if err := conn.Close(); err != nil {
+			m.lg.With("err", err).Error("failed to close redis connection, potential connection leak")
+		}
+	}()
+	status, err := conn.Eval(fenceScript, m.fenceKey(), token)
+	if err != nil {
+		return false, err
+	}
+	return status != int64(0), nil
 }
 
 func (m *redisMutex) lock(ctx context.Context) (<-chan struct{}, error) {
@@ -128,30 +440,55 @@ func (m *redisMutex) lock(ctx context.Context) (<-chan struct{}, error) {
 	m.uuid = uuid
 
 	start := time.Now()
+	info, err := json.Marshal(m.Holder(m.shared))
+	if err != nil {
+		return nil, err
+	}
 
+	var tokenMu sync.Mutex
+	var token uint64
 	n, lockErr := func() (int, error) {
-		ctx, ca := context.WithTimeout(ctx, ackTimeoutFactor())
+		ctx, ca := context.WithTimeout(ctx, ackTimeoutFactor(m.expiry()))
 		defer ca()
 		return m.actOnPoolsAsync(func(pool redis.Pool) (bool, error) {
-			return m.acquire(ctx, pool, uuid)
+			acquired, issued, err := m.acquire(ctx, pool, uuid, string(info))
+			if err != nil {
+				return false, err
+			}
+			tokenMu.Lock()
+			defer tokenMu.Unlock()
+			token = max(token, issued)
+			return acquired, nil
 		})
 	}()
 
+	if n >= m.quorum && !m.shared && !m.isSemaphore() {
+		if err := func() error {
+			ctx, ca := context.WithTimeout(ctx, ackTimeoutFactor(m.expiry()))
+			defer ca()
+			return m.fenceQuorum(ctx, token)
+		}(); err != nil {
+			m.lg.With(logger.Err(err)).Warn("failed to fence lock")
+			n, lockErr = 0, err
+		}
+	}
+
 	now := time.Now()
 	expiredC := lo.Async(m.keepalive)
 
-	until := now.Add(LockExpiry - now.Sub(start) - expiryDriftFactor())
+	until := now.Add(m.expiry() - now.Sub(start) - expiryDriftFactor(m.expiry()))
 	if n >= m.quorum && now.Before(until) {
 		m.lg.Debug("lock acquired and valid")
 		m.uuid = uuid
 		m.until = until
+		m.fencingToken = token
 		return expiredC, nil
 	}
 
 	m.lg.Debug("lock not acquired, or lock acquired but already timed out")
 	// otherwise, lock should already be expired, due to latency in the system
 	if _, err := func() (int, error) {
-		ctx, ca := context.WithTimeout(ctx, LockExpiry)
+		ctx, ca := context.WithTimeout(ctx, m.expiry())
 		defer ca()
 		return m.actOnPoolsAsync(func(pool redis.Pool) (bool, error) {
 			return m.release(ctx, pool, uuid)
@@ -186,7 +523,7 @@ func (m *redisMutex) unlock() (bool, error) {
 		defer span.End()
 	}
 
-	ctx, ca := context.WithTimeout(context.Background(), LockExpiry)
+	ctx, ca := context.WithTimeout(context.Background(), m.expiry())
 	defer ca()
 
 	n, err := m.actOnPoolsAsync(func(pool redis.Pool) (bool, error) {
@@ -202,14 +539,20 @@ func (m *redisMutex) unlock() (bool, error) {
 	return true, nil
 }
 
-var deleteScript = redis.NewScript(1, `
+var deleteScript = redis.NewScript(2, `
 	if redis.call("GET", KEYS[1]) == ARGV[1] then
+		redis.call("HDEL", KEYS[2], ARGV[1])
 		return redis.call("DEL", KEYS[1])
 	else
 		return 0
 	end
 `, "")
 
+var readDeleteScript = redis.NewScript(2, `
+	redis.call("HDEL", KEYS[2], ARGV[1])
+	return redis.call("ZREM", KEYS[1], ARGV[1])
+`, "")
+
 func (m *redisMutex) release(ctx context.Context, pool redis.Pool, value string) (bool, error) {
 	m.lg.With("fenced", m.uuid).Debug("release lock requested...")
 	conn, err := pool.Get(ctx)
@@ -221,7 +564,12 @@ func (m *redisMutex) release(ctx context.Context, pool redis.Pool, value string)
 			m.lg.With("err", err).Error("failed to close redis connection, potential connection leak")
 		}
 	}()
-	status, err := conn.Eval(deleteScript, m.key(), value)
+	var status interface{}
+	if m.inSet() {
+		status, err = conn.Eval(readDeleteScript, m.setKey(), m.infoKey(), value)
+	} else {
+		status, err = conn.Eval(deleteScript, m.key(), m.infoKey(), value)
+	}
 	if err != nil {
 		return false, err
 	}
@@ -229,15 +577,34 @@ func (m *redisMutex) release(ctx context.Context, pool redis.Pool, value string)
 	return status != int64(0), nil
 }
 
-var touchScript = redis.NewScript(1, `
+var touchScript = redis.NewScript(2, `
 	if redis.call("GET", KEYS[1]) == ARGV[1] then
+		if redis.call("PTTL", KEYS[2]) < tonumber(ARGV[2]) then
+			redis.call("PEXPIRE", KEYS[2], ARGV[2])
+		end
 		return redis.call("PEXPIRE", KEYS[1], ARGV[2])
 	else
 		return 0
 	end
 `, "")
 
-func (m *redisMutex) touch(ctx context.Context, pool redis.Pool, value string, _ /*expiry*/ int) (bool, error) {
+var readTouchScript = redis.NewScript(2, `
+	if redis.call("ZSCORE", KEYS[1], ARGV[1]) then
+		local now = redis.call("TIME")
+		redis.call("ZADD", KEYS[1], now[1] * 1000 + math.floor(now[2] / 1000) + tonumber(ARGV[2]), ARGV[1])
+		if redis.call("PTTL", KEYS[1]) < tonumber(ARGV[2]) then
+			redis.call("PEXPIRE", KEYS[1], ARGV[2])
+		end
+		if redis.call("PTTL", KEYS[2]) < tonumber(ARGV[2]) then
+			redis.call("PEXPIRE", KEYS[2], ARGV[2])
+		end
+		return 1
+	else
+		return 0
+	end
+`, "")
+
+func (m *redisMutex) touch(ctx context.Context, pool redis.Pool, value string, expiry int) (bool, error) {
 	conn, err := pool.Get(ctx)
 	if err != nil {
 		return false, nil
@@ -247,7 +614,12 @@ func (m *redisMutex) touch(ctx context.Context, pool redis.Pool, value string, _
 			m.lg.With("err", err).Error("failed to close redis connection, potential connection leak")
 		}
 	}()
-	status, err := conn.Eval(touchScript, m.key(), value)
+	var status interface{}
+	if m.inSet() {
+		status, err = conn.Eval(readTouchScript, m.setKey(), m.infoKey(), value, expiry)
+	} else {
+		status, err = conn.Eval(touchScript, m.key(), m.infoKey(), value, expiry)
+	}
 	if err != nil {
 		return false, err
 	}
@@ -255,12 +627,17 @@ func (m *redisMutex) touch(ctx context.Context, pool redis.Pool, value string, _
 	return status != int64(0), nil
 }
 
-func expiryDriftFactor() time.Duration {
-	return time.Duration(int64(float64(LockExpiry) * LockDriftFactor))
+// expiry is the TTL of the mutex's acquisitions, defaults to LockExpiry
+func (m *redisMutex) expiry() time.Duration {
+	return m.TTLOr(LockExpiry)
 }
 
-func ackTimeoutFactor() time.Duration {
-	return time.Duration(int64(float64(LockExpiry) * LockTimeoutFactor))
+func expiryDriftFactor(expiry time.Duration) time.Duration {
+	return time.Duration(int64(float64(expiry) * LockDriftFactor))
+}
+
+func ackTimeoutFactor(expiry time.Duration) time.Duration {
+	return time.Duration(int64(float64(expiry) * LockTimeoutFactor))
 }
 
 func (m *redisMutex) extend(ctx context.Context) (bool, error) {
@@ -268,14 +645,14 @@ func (m *redisMutex) extend(ctx context.Context) (bool, error) {
 	start := time.Now()
 	n, err := m.actOnPoolsAsync(func(pool redis.Pool) (bool, error) {
 		// cast to milliseconds
-		return m.touch(ctx, pool, m.uuid, int(LockExpiry/time.Millisecond))
+		return m.touch(ctx, pool, m.uuid, int(m.expiry()/time.Millisecond))
 	})
 	if n < m.quorum {
 		m.lg.With(logger.Err(err)).Warn("failed to extend lock expiry : ")
 		return false, err
 	}
 	now := time.Now()
-	until := now.Add(LockExpiry - now.Sub(start) - expiryDriftFactor())
+	until := now.Add(m.expiry() - now.Sub(start) - expiryDriftFactor(m.expiry()))
 	if now.Before(until) {
 		m.until = until
 		return true, nil
@@ -287,7 +664,7 @@ func (m *redisMutex) extend(ctx context.Context) (bool, error) {
 func (m *redisMutex) keepalive() struct{} {
 	// TODO : maybe replace with unused parentCtx
 	ctx := context.TODO()
-	t := time.NewTicker(LockExtendDelay)
+	t := time.NewTicker(m.KeepaliveIntervalOr(LockExtendDelay))
 	defer t.Stop()
 	for {
 		select {
@@ -297,6 +674,14 @@ func (m *redisMutex) keepalive() struct{} {
 			return struct{}{}
 		case <-t.C:
 			extended, err := m.extend(ctx)
+			if errors.Is(err, ErrTaken) {
+				// a quorum of nodes no longer knows of this holder, e.g. after ForceRelease,
+				// or of this participant once its barrier is reached
+				if !m.isBarrier() {
+					m.lg.Warn("lock was released from redis")
+				}
+				return struct{}{}
+			}
 			if err != nil {
 				m.lg.With(logger.Err(err), "extended", extended).Warn("failed to extend lock")
 			}
@@ -29,6 +29,7 @@ var lmF = future.New[lock.LockManager]()
 var lmSetF = future.New[lo.Tuple3[
 	lock.LockManager, lock.LockManager, lock.LockManager,
 ]]()
+var crashF = future.New[integration.Crash]()
 
 var _ = BeforeSuite(func() {
 	if Label("integration").MatchesLabelFilter(GinkgoLabelFilter()) {
@@ -91,11 +92,19 @@ var _ = BeforeSuite(func() {
 		lmSetF.Set(lo.Tuple3[lock.LockManager, lock.LockManager, lock.LockManager]{
 			A: x, B: y, C: z,
 		})
+		crashF.Set(func() (lock.LockManager, func()) {
+			client := goredislib.NewClient(conf[0])
+			lm := redis.NewLockManager(context.Background(), "test", redis.AcquireRedisClientPool([]goredislib.UniversalClient{client}), logger.NewNop())
+			return lm, func() {
+				_ = client.Close()
+			}
+		})
 
 	}
 })
 
 var _ = Describe("Redis Lock Manager", Ordered, Label("integration", "slow"), integration.LockManagerTestSuite(lmF, lmSetF))
+var _ = Describe("Redis Crashes", Ordered, Label("integration", "slow"), integration.CrashTestSuite(lmSetF, crashF))
 var _ = Describe("Redis Broker", Label("unit"), func() {
 	When("we register the lock broker", func() {
 		It("should register the redis lock manager as a broker", func() {
@@ -0,0 +1,185 @@
+package redis_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/alexandreLamarre/dlock/internal/lock/backend/redis"
+	"github.com/alexandreLamarre/dlock/pkg/config/v1alpha1"
+	"github.com/alexandreLamarre/dlock/pkg/lock"
+	"github.com/alexandreLamarre/dlock/pkg/logger"
+	"github.com/alexandreLamarre/dlock/pkg/test/conformance/integration"
+	"github.com/alexandreLamarre/dlock/pkg/util/future"
+	"github.com/alicebob/miniredis/v2"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	goredislib "github.com/redis/go-redis/v9"
+	"github.com/samber/lo"
+)
+
+// The Redlock suite runs against independent in-process redis nodes, so that one of them can be killed mid-test
+var _ = Describe("Redis Redlock", Ordered, Label("unit", "slow"), func() {
+	var nodes []*miniredis.Miniredis
+	lmF := future.New[lock.LockManager]()
+	lmSetF := future.New[lo.Tuple3[lock.LockManager, lock.LockManager, lock.LockManager]]()
+	// the lock managers of the suite run after the node is killed use their own prefix, so that they don't observe
+	// the keys left by the first run
+	killedLmF := future.New[lock.LockManager]()
+	killedLmSetF := future.New[lo.Tuple3[lock.LockManager, lock.LockManager, lock.LockManager]]()
+	crashF := future.New[integration.Crash]()
+
+	BeforeAll(func() {
+		spec := &v1alpha1.RedisClientSpec{}
+		for range 3 {
+			node := miniredis.NewMiniRedis()
+			Expect(node.Start()).To(Succeed())
+			DeferCleanup(node.Close)
+			nodes = append(nodes, node)
+			spec.Nodes = append(spec.Nodes, v1alpha1.RedisNodeSpec{Network: "tcp", Addr: node.Addr()})
+		}
+		opts, err := redis.RedisClientOptions(spec)
+		Expect(err).NotTo(HaveOccurred())
+		newLockManager := func(prefix string) lock.LockManager {
+			return redis.NewLockManager(context.Background(), prefix, redis.AcquireRedisNodePool(opts), logger.NewNop())
+		}
+		lmF.Set(newLockManager("test"))
+		lmSetF.Set(lo.Tuple3[lock.LockManager, lock.LockManager, lock.LockManager]{
+			A: newLockManager("test"), B: newLockManager("test"), C: newLockManager("test"),
+		})
+		crashF.Set(func() (lock.LockManager, func()) {
+			clients := lo.Map(opts, func(node redis.RedisNodeOptions, _ int) goredislib.UniversalClient {
+				return node.NewClient()
+			})
+			lm := redis.NewLockManager(context.Background(), "test", redis.AcquireRedisClientPool(clients), logger.NewNop())
+			return lm, func() {
+				for _, client := range clients {
+					_ = client.Close()
+				}
+			}
+		})
+		killedLmF.Set(newLockManager("test-killed"))
+		killedLmSetF.Set(lo.Tuple3[lock.LockManager, lock.LockManager, lock.LockManager]{
+			A: newLockManager("test-killed"), B: newLockManager("test-killed"), C: newLockManager("test-killed"),
+		})
+	})
+
+	Context("with every node", integration.LockManagerTestSuite(lmF, lmSetF))
+
+	Context("when a process crashes", integration.CrashTestSuite(lmSetF, crashF))
+
+	Context("when a node is killed", func() {
+		It("should keep the locks held on the remaining quorum of nodes", func(ctx SpecContext) {
+			lmSet := lmSetF.Get()
+			opts := []lock.LockOption{lock.WithTTL(time.Second), lock.WithKeepaliveInterval(100 * time.Millisecond)}
+			held := lmSet.A.NewLock("redlock", opts...)
+			expired, err := held.Lock(ctx)
+			Expect(err).NotTo(HaveOccurred())
+
+			By("killing a node while the lock is held")
+			nodes[0].Close()
+
+			By("verifying the holder keeps the lock alive on the other nodes")
+			Consistently(expired, 500*time.Millisecond).ShouldNot(Receive())
+			acquired, _, err := lmSet.B.NewLock("redlock", opts...).TryLock(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(acquired).To(BeFalse())
+
+			By("verifying the lock is acquired by others once released")
+			Expect(held.Unlock()).To(Succeed())
+			Eventually(func() bool {
+				acquired, _, err := lmSet.B.NewLock("redlock", opts...).TryLock(ctx)
+				return err == nil && acquired
+			}).Should(BeTrue())
+		})
+	})
+
+	Context("with a node killed", integration.LockManagerTestSuite(killedLmF, killedLmSetF))
+})
+
+// failScripts fails the scripts of a single key containing the key part, e.g. the scripts raising the fencing counters
+// of locks for ".fence-"
+type failScripts struct {
+	keyPart string
+}
+
+func (failScripts) DialHook(next goredislib.DialHook) goredislib.DialHook {
+	return next
+}
+
+func (f failScripts) ProcessHook(next goredislib.ProcessHook) goredislib.ProcessHook {
+	return func(ctx context.Context, cmd goredislib.Cmder) error {
+		args := cmd.Args()
+		if name := cmd.Name(); (name == "eval" || name == "evalsha") && len(args) > 3 &&
+			fmt.Sprint(args[2]) == "1" && strings.Contains(fmt.Sprint(args[3]), f.keyPart) {
+			err := fmt.Errorf("script on %s failed", f.keyPart)
+			cmd.SetErr(err)
+			return err
+		}
+		return next(ctx, cmd)
+	}
+}
+
+func (failScripts) ProcessPipelineHook(next goredislib.ProcessPipelineHook) goredislib.ProcessPipelineHook {
+	return next
+}
+
+var _ = Describe("Redis Redlock failures", Label("unit"), func() {
+	var opts []redis.RedisNodeOptions
+
+	BeforeEach(func() {
+		spec := &v1alpha1.RedisClientSpec{}
+		for range 3 {
+			node := miniredis.NewMiniRedis()
+			Expect(node.Start()).To(Succeed())
+			DeferCleanup(node.Close)
+			spec.Nodes = append(spec.Nodes, v1alpha1.RedisNodeSpec{Network: "tcp", Addr: node.Addr()})
+		}
+		var err error
+		opts, err = redis.RedisClientOptions(spec)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	// failing returns a lock manager whose scripts on the keys containing the key part fail on a majority of nodes
+	failing := func(keyPart string) *redis.LockManager {
+		clients := lo.Map(opts, func(node redis.RedisNodeOptions, i int) goredislib.UniversalClient {
+			client := node.NewClient()
+			DeferCleanup(client.Close)
+			if i > 0 {
+				client.AddHook(failScripts{keyPart: keyPart})
+			}
+			return client
+		})
+		return redis.NewLockManager(context.Background(), "test", redis.AcquireRedisClientPool(clients), logger.NewNop())
+	}
+
+	It("should not issue fencing tokens that a quorum of nodes did not record", func(ctx SpecContext) {
+		acquired, _, err := failing(".fence-").NewLock("fenced").TryLock(ctx)
+		Expect(err).To(MatchError(redis.ErrFenceFailed))
+		Expect(acquired).To(BeFalse())
+
+		By("releasing the lock that could not be fenced")
+		lm := redis.NewLockManager(context.Background(), "test", redis.AcquireRedisNodePool(opts), logger.NewNop())
+		l := lm.NewLock("fenced")
+		acquired, _, err = l.TryLock(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(acquired).To(BeTrue())
+		Expect(l.FencingToken()).To(BeNumerically(">", 1))
+		Expect(l.Unlock()).To(Succeed())
+	})
+
+	It("should not acquire fair locks without a ticket of their queue", func(ctx SpecContext) {
+		lockCtx, ca := context.WithTimeout(ctx, 5*time.Second)
+		defer ca()
+		_, err := failing(".ticket-").NewLock("queued", lock.WithFair(true)).Lock(lockCtx)
+		Expect(err).To(MatchError(redis.ErrTicketFailed))
+
+		By("queueing fair waiters once tickets are issued")
+		lm := redis.NewLockManager(context.Background(), "test", redis.AcquireRedisNodePool(opts), logger.NewNop())
+		l := lm.NewLock("queued", lock.WithFair(true))
+		_, err = l.Lock(lockCtx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(l.Unlock()).To(Succeed())
+	})
+})
@@ -0,0 +1,134 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/alexandreLamarre/dlock/pkg/lock"
+	"github.com/alexandreLamarre/dlock/pkg/logger"
+	"github.com/go-redsync/redsync/v4/redis"
+	backoffv2 "github.com/lestrrat-go/backoff/v2"
+)
+
+// Semaphore holders are tracked in a sorted set scored by their expiry, each holder
+// carrying its weight so that acquisitions can atomically sum the units in use.
+//
+// With multiple redis nodes, the capacity is enforced on each node of the acquiring quorum.
+type Semaphore struct {
+	pools  []redis.Pool
+	quorum int
+
+	prefix   string
+	key      string
+	capacity int64
+	lg       *slog.Logger
+
+	scheduler *lock.LockScheduler
+	mutex     *redisMutex
+
+	*lock.LockOptions
+}
+
+var _ lock.Semaphore = (*Semaphore)(nil)
+
+func NewSemaphore(
+	pools []redis.Pool,
+	quorum int,
+	prefix, key string,
+	capacity int64,
+	lg *slog.Logger,
+	opts *lock.LockOptions,
+) *Semaphore {
+	return &Semaphore{
+		pools:       pools,
+		quorum:      quorum,
+		prefix:      prefix,
+		key:         key,
+		capacity:    capacity,
+		lg:          lg,
+		scheduler:   lock.NewLockScheduler(),
+		LockOptions: opts,
+	}
+}
+
+func (s *Semaphore) Capacity() int64 {
+	return s.capacity
+}
+
+func (s *Semaphore) Acquire(ctx context.Context, n int64) (expired <-chan struct{}, err error) {
+	return s.acquire(ctx, n, retryPolicy(s.RetryDelayOr(LockRetryDelay)))
+}
+
+func (s *Semaphore) TryAcquire(ctx context.Context, n int64) (acquired bool, expired <-chan struct{}, err error) {
+	done, err := s.acquire(ctx, n, nil)
+	if err != nil {
+		if errors.Is(err, ErrTaken) {
+			s.lg.Debug("semaphore units already acquired by others")
+			return false, nil, nil
+		}
+		s.lg.With(logger.Err(err)).Error("failed to acquire semaphore")
+		return false, nil, err
+	}
+	return true, done, nil
+}
+
+func (s *Semaphore) acquire(ctx context.Context, n int64, retrier *backoffv2.Policy) (<-chan struct{}, error) {
+	if err := lock.ValidateWeight(n, s.capacity); err != nil {
+		return nil, err
+	}
+	if s.Tracer != nil {
+		ctxSpan, span := s.Tracer.Start(ctx, "Acquire/redis-semaphore")
+		defer span.End()
+		ctx = ctxSpan
+	}
+	// https://github.com/lestrrat-go/backoff/issues/31
+	ctxca, ca := context.WithCancel(ctx)
+	defer ca()
+
+	var closureDone <-chan struct{}
+	if err := s.scheduler.Schedule(func() error {
+		mutex := newRedisSemaphore(s.prefix, s.key, n, s.capacity, s.quorum, s.pools, s.lg, s.LockOptions)
+		done, err := mutex.lock(ctxca)
+		curErr := err
+		if err == nil {
+			s.mutex = &mutex
+			closureDone = done
+			return nil
+		}
+		if retrier == nil {
+			return curErr
+		}
+		ret := *retrier
+		acq := ret.Start(ctxca)
+		for backoffv2.Continue(acq) {
+			done, err := mutex.lock(ctxca)
+			curErr = err
+			if err == nil {
+				s.mutex = &mutex
+				closureDone = done
+				return nil
+			}
+		}
+		return errors.Join(ctxca.Err(), curErr)
+	}); err != nil {
+		return nil, err
+	}
+	return closureDone, nil
+}
+
+func (s *Semaphore) Release() error {
+	return s.scheduler.Done(func() error {
+		if s.mutex == nil {
+			return nil
+		}
+		mutex := *s.mutex
+		go func() {
+			if released, err := mutex.unlock(); err != nil {
+				s.lg.With(logger.Err(err), "released", released).Warn("failed to release semaphore")
+			}
+		}()
+		s.mutex = nil
+		return nil
+	})
+}
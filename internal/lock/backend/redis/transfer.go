@@ -0,0 +1,197 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/alexandreLamarre/dlock/pkg/lock"
+	"github.com/alexandreLamarre/dlock/pkg/logger"
+	"github.com/go-redsync/redsync/v4/redis"
+	"github.com/samber/lo"
+)
+
+// waitingScript returns the live waiters of the lock as (waiter, info) pairs
+var waitingScript = redis.NewScript(2, `
+	local now = redis.call("TIME")
+	local ms = now[1] * 1000 + math.floor(now[2] / 1000)
+	local waiting = {}
+	for _, waiter in ipairs(redis.call("ZRANGEBYSCORE", KEYS[1], "(" .. ms, "+inf")) do
+		local info = redis.call("HGET", KEYS[2], waiter)
+		if info then
+			table.insert(waiting, {waiter, info})
+		end
+	end
+	return waiting
+`, "")
+
+// transferScript swaps the value of the holder for the waiter when the holder still holds the lock and the waiter
+// is still waiting, keeping the TTL of the lock, and issues the fencing token of the waiter.
+// It returns -1 when the holder no longer holds the lock.
+var transferScript = redis.NewScript(6, `
+	if redis.call("GET", KEYS[1]) ~= ARGV[1] then
+		return -1
+	end
+	local now = redis.call("TIME")
+	local ms = now[1] * 1000 + math.floor(now[2] / 1000)
+	local deadline = redis.call("ZSCORE", KEYS[4], ARGV[2])
+	if not deadline or tonumber(deadline) <= ms then
+		return 0
+	end
+	redis.call("SET", KEYS[1], ARGV[2], "PX", redis.call("PTTL", KEYS[1]))
+	redis.call("HDEL", KEYS[3], ARGV[1])
+	redis.call("HSET", KEYS[3], ARGV[2], ARGV[3])
+	redis.call("ZREM", KEYS[4], ARGV[2])
+	redis.call("HDEL", KEYS[5], ARGV[2])
+	redis.call("ZREM", KEYS[6], ARGV[2])
+	return redis.call("INCR", KEYS[2])
+`, "")
+
+// claimScript extends the lock when it was transferred to the waiter, returning the current fencing token
+var claimScript = redis.NewScript(3, `
+	if redis.call("GET", KEYS[1]) ~= ARGV[1] then
+		return 0
+	end
+	redis.call("PEXPIRE", KEYS[1], ARGV[2])
+	if redis.call("PTTL", KEYS[3]) < tonumber(ARGV[2]) then
+		redis.call("PEXPIRE", KEYS[3], ARGV[2])
+	end
+	return tonumber(redis.call("GET", KEYS[2]) or "1")
+`, "")
+
+// transferee returns the earliest exclusive waiter of the owner seen by any node, with its HolderInfo.
+// Waiters are named after the time they started waiting, so they sort in arrival order.
+func (m *redisMutex) transferee(ctx context.Context, toOwner string) (string, lock.HolderInfo, error) {
+	var mu sync.Mutex
+	waiter := ""
+	info := lock.HolderInfo{}
+	n, err := m.actOnPoolsAsync(func(pool redis.Pool) (bool, error) {
+		reply, err := eval(ctx, pool, m.lg, waitingScript, m.waitersKey(), m.waitingKey())
+		if err != nil {
+			return false, err
+		}
+		pairs, _ := reply.([]interface{})
+		mu.Lock()
+		defer mu.Unlock()
+		for _, p := range pairs {
+			pair, ok := p.([]interface{})
+			if !ok || len(pair) != 2 {
+				continue
+			}
+			w, _ := pair[0].(string)
+			data, _ := pair[1].(string)
+			var hi lock.HolderInfo
+			if err := json.Unmarshal([]byte(data), &hi); err != nil {
+				m.lg.With(logger.Err(err)).Warn("failed to decode waiter info")
+				continue
+			}
+			if hi.Shared || hi.Owner != toOwner {
+				continue
+			}
+			if waiter == "" || w < waiter {
+				waiter, info = w, hi
+			}
+		}
+		return true, nil
+	})
+	if n < m.quorum {
+		return "", info, errors.Join(errors.New("failed to list waiters : no consensus"), err)
+	}
+	if waiter == "" {
+		return "", info, lock.ErrNoTransferee
+	}
+	return waiter, info, nil
+}
+
+// transfer hands the lock over to the earliest exclusive waiter of the owner, which claims it on its next attempt.
+// When the lock is only transferred on some nodes, the waiter's value is released from them, so that the keepalive
+// of the holder notices that the lock is lost.
+func (m *redisMutex) transfer(ctx context.Context, toOwner string) error {
+	ctx, ca := context.WithTimeout(ctx, ackTimeoutFactor(m.expiry()))
+	defer ca()
+	waiter, hi, err := m.transferee(ctx, toOwner)
+	if err != nil {
+		return err
+	}
+	hi.AcquiredAt = time.Now()
+	info, err := json.Marshal(hi)
+	if err != nil {
+		return err
+	}
+	var tokenMu sync.Mutex
+	var token uint64
+	notHeld := 0
+	n, err := m.actOnPoolsAsync(func(pool redis.Pool) (bool, error) {
+		reply, err := eval(ctx, pool, m.lg, transferScript,
+			m.key(), m.fenceKey(), m.infoKey(), m.waitersKey(), m.waitingKey(), m.queueKey(),
+			m.uuid, waiter, string(info))
+		if err != nil {
+			return false, err
+		}
+		issued, _ := reply.(int64)
+		tokenMu.Lock()
+		defer tokenMu.Unlock()
+		if issued < 0 {
+			notHeld++
+		}
+		token = max(token, uint64(max(issued, 0)))
+		return issued > 0, nil
+	})
+	if n >= m.quorum {
+		err = m.fenceQuorum(ctx, token)
+		if err == nil {
+			return nil
+		}
+		// the transferee can't claim the lock with a token that the next quorum may issue again
+		m.lg.With(logger.Err(err)).Warn("failed to fence transferred lock")
+	}
+	if n > 0 {
+		if _, err := m.actOnPoolsAsync(func(pool redis.Pool) (bool, error) {
+			return m.release(ctx, pool, waiter)
+		}); err != nil {
+			m.lg.With(logger.Err(err)).Warn("failed to release partial transfer")
+		}
+	}
+	if notHeld >= m.quorum {
+		return lock.ErrNotHeld
+	}
+	if errors.Is(err, ErrTaken) {
+		// a quorum of nodes no longer knows of the waiter
+		return lock.ErrNoTransferee
+	}
+	return errors.Join(errors.New("failed to transfer lock : no consensus"), err)
+}
+
+// claim holds the lock for the waiter when a quorum of nodes transferred it to the waiter
+func (m *redisMutex) claim(ctx context.Context, waiter string) (<-chan struct{}, bool) {
+	start := time.Now()
+	var tokenMu sync.Mutex
+	var token uint64
+	n, _ := func() (int, error) {
+		ctx, ca := context.WithTimeout(ctx, ackTimeoutFactor(m.expiry()))
+		defer ca()
+		return m.actOnPoolsAsync(func(pool redis.Pool) (bool, error) {
+			reply, err := eval(ctx, pool, m.lg, claimScript, m.key(), m.fenceKey(), m.infoKey(),
+				waiter, int(m.expiry()/time.Millisecond))
+			if err != nil {
+				return false, err
+			}
+			issued, _ := reply.(int64)
+			tokenMu.Lock()
+			defer tokenMu.Unlock()
+			token = max(token, uint64(max(issued, 0)))
+			return issued > 0, nil
+		})
+	}()
+	now := time.Now()
+	until := now.Add(m.expiry() - now.Sub(start) - expiryDriftFactor(m.expiry()))
+	if n < m.quorum || !now.Before(until) {
+		return nil, false
+	}
+	m.uuid = waiter
+	m.until = until
+	m.fencingToken = token
+	return lo.Async(m.keepalive), true
+}
@@ -2,26 +2,266 @@ package redis
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/alexandreLamarre/dlock/pkg/config/v1alpha1"
+	"github.com/alexandreLamarre/dlock/pkg/util"
 	"github.com/go-redsync/redsync/v4/redis"
 	redsyncgoredis "github.com/go-redsync/redsync/v4/redis/goredis/v9"
 	goredislib "github.com/redis/go-redis/v9"
+	"github.com/samber/lo"
 
 	"github.com/redis/rueidis"
 	"github.com/redis/rueidis/rueidiscompat"
 )
 
+// RedisNodeOptions are the options of the client of a redis node, only one of them being set : a standalone server,
+// a master monitored by sentinels or a cluster
+type RedisNodeOptions struct {
+	Client   *goredislib.Options
+	Failover *goredislib.FailoverOptions
+	Cluster  *goredislib.ClusterOptions
+}
+
+// NewClient returns the client of the node
+func (o RedisNodeOptions) NewClient() goredislib.UniversalClient {
+	switch {
+	case o.Failover != nil:
+		return goredislib.NewFailoverClient(o.Failover)
+	case o.Cluster != nil:
+		return goredislib.NewClusterClient(o.Cluster)
+	default:
+		return goredislib.NewClient(o.Client)
+	}
+}
+
+// RedisClientOptions returns the options of the client of each redis node of the spec
+func RedisClientOptions(conf *v1alpha1.RedisClientSpec) ([]RedisNodeOptions, error) {
+	nodes := conf.Nodes
+	if len(nodes) == 0 {
+		nodes = []v1alpha1.RedisNodeSpec{conf.RedisNodeSpec}
+	} else if conf.RedisNodeSpec != (v1alpha1.RedisNodeSpec{}) {
+		return nil, errors.New("the nodes of the redis spec are exclusive with the node of the spec itself")
+	}
+	opts := make([]RedisNodeOptions, len(nodes))
+	for i, node := range nodes {
+		nodeOpts, err := redisNodeOptions(&node)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redis node %d : %w", i, err)
+		}
+		opts[i] = nodeOpts
+	}
+	return opts, nil
+}
+
+func redisNodeOptions(conf *v1alpha1.RedisNodeSpec) (RedisNodeOptions, error) {
+	if conf.DB < 0 || conf.PoolSize < 0 || conf.MinIdleConns < 0 {
+		return RedisNodeOptions{}, errors.New("db, poolSize & minIdleConns must not be negative")
+	}
+	opts := &goredislib.Options{
+		Network:      conf.Network,
+		Addr:         conf.Addr,
+		Username:     conf.Username,
+		Password:     conf.Password,
+		DB:           conf.DB,
+		PoolSize:     conf.PoolSize,
+		MinIdleConns: conf.MinIdleConns,
+		MaxRetries:   conf.MaxRetries,
+	}
+	for _, timeout := range []struct {
+		name  string
+		value string
+		field *time.Duration
+	}{
+		{"dialTimeout", conf.DialTimeout, &opts.DialTimeout},
+		{"readTimeout", conf.ReadTimeout, &opts.ReadTimeout},
+		{"writeTimeout", conf.WriteTimeout, &opts.WriteTimeout},
+	} {
+		d, err := util.ParseOptionalDuration(timeout.name, timeout.value)
+		if err != nil {
+			return RedisNodeOptions{}, err
+		}
+		*timeout.field = d
+	}
+	if conf.TLS != nil {
+		tlsConfig, err := util.LoadClientMTLSConfig(*conf.TLS)
+		if err != nil {
+			return RedisNodeOptions{}, fmt.Errorf("failed to load client TLS config: %w", err)
+		}
+		opts.TLSConfig = tlsConfig
+	}
+	switch {
+	case conf.Sentinel != nil && conf.Cluster != nil:
+		return RedisNodeOptions{}, errors.New("sentinel & cluster are exclusive")
+	case conf.Sentinel != nil:
+		if conf.Addr != "" {
+			return RedisNodeOptions{}, errors.New("the addr of the master is discovered from the sentinels & must be unset")
+		}
+		if conf.Sentinel.MasterName == "" || len(conf.Sentinel.Addrs) == 0 {
+			return RedisNodeOptions{}, errors.New("sentinel requires the name of the master & the addrs of the sentinels")
+		}
+		return RedisNodeOptions{Failover: &goredislib.FailoverOptions{
+			MasterName:       conf.Sentinel.MasterName,
+			SentinelAddrs:    conf.Sentinel.Addrs,
+			SentinelUsername: conf.Sentinel.Username,
+			SentinelPassword: conf.Sentinel.Password,
+			Username:         opts.Username,
+			Password:         opts.Password,
+			DB:               opts.DB,
+			DialTimeout:      opts.DialTimeout,
+			ReadTimeout:      opts.ReadTimeout,
+			WriteTimeout:     opts.WriteTimeout,
+			PoolSize:         opts.PoolSize,
+			MinIdleConns:     opts.MinIdleConns,
+			MaxRetries:       opts.MaxRetries,
+			TLSConfig:        opts.TLSConfig,
+		}}, nil
+	case conf.Cluster != nil:
+		if conf.Addr != "" {
+			return RedisNodeOptions{}, errors.New("the addrs of the cluster are set by cluster.addrs & addr must be unset")
+		}
+		if len(conf.Cluster.Addrs) == 0 {
+			return RedisNodeOptions{}, errors.New("cluster requires the addrs of at least one node")
+		}
+		if conf.DB != 0 {
+			return RedisNodeOptions{}, errors.New("redis clusters only support db 0")
+		}
+		return RedisNodeOptions{Cluster: &goredislib.ClusterOptions{
+			Addrs:        conf.Cluster.Addrs,
+			Username:     opts.Username,
+			Password:     opts.Password,
+			DialTimeout:  opts.DialTimeout,
+			ReadTimeout:  opts.ReadTimeout,
+			WriteTimeout: opts.WriteTimeout,
+			PoolSize:     opts.PoolSize,
+			MinIdleConns: opts.MinIdleConns,
+			MaxRetries:   opts.MaxRetries,
+			TLSConfig:    opts.TLSConfig,
+		}}, nil
+	}
+	return RedisNodeOptions{Client: opts}, nil
+}
+
+// AcquireRedisPool returns the pools of standalone redis nodes
 func AcquireRedisPool(
 	clients []*goredislib.Options,
 ) []redis.Pool {
-	pools := make([]redis.Pool, len(clients))
-	for i, clientOps := range clients {
-		client := goredislib.NewClient(clientOps)
-		pools[i] = redsyncgoredis.NewPool(client)
+	return AcquireRedisNodePool(lo.Map(clients, func(opts *goredislib.Options, _ int) RedisNodeOptions {
+		return RedisNodeOptions{Client: opts}
+	}))
+}
+
+// AcquireRedisNodePool returns the pools of redis nodes, which are standalone servers, masters monitored by sentinels
+// or clusters
+func AcquireRedisNodePool(
+	nodes []RedisNodeOptions,
+) []redis.Pool {
+	return AcquireRedisClientPool(lo.Map(nodes, func(node RedisNodeOptions, _ int) goredislib.UniversalClient {
+		return node.NewClient()
+	}))
+}
+
+// AcquireRedisClientPool returns the pools of existing clients of redis nodes, which are closed by the caller
+func AcquireRedisClientPool(
+	clients []goredislib.UniversalClient,
+) []redis.Pool {
+	return lo.Map(clients, func(client goredislib.UniversalClient, _ int) redis.Pool {
+		return newClientPool(client)
+	})
+}
+
+// subscriber is implemented by the pools that can subscribe to channels, which redsync pools can't
+type subscriber interface {
+	PSubscribe(ctx context.Context, patterns ...string) *goredislib.PubSub
+	// keyspaceNotifications checks that the node publishes the keyspace notifications watches subscribe to
+	keyspaceNotifications(ctx context.Context) error
+}
+
+// keyspaceFlags are the flags of notify-keyspace-events required by watches : keyspace notifications of generic,
+// string, sorted set & expired events
+const keyspaceFlags = "Kg$zx"
+
+// sharded is implemented by the pools whose keys are spread over several masters, i.e. redis clusters.
+// Scripts without keys, scanning keys, and subscriptions to keyspace notifications, which are only published by the
+// master of the key, must run on each of them.
+type sharded interface {
+	shards(ctx context.Context) ([]redis.Pool, error)
+}
+
+// clustered reports whether any of the pools is a redis cluster
+func clustered(pools []redis.Pool) bool {
+	for _, pool := range pools {
+		if p, ok := pool.(*clientPool); ok {
+			if _, ok := p.client.(*goredislib.ClusterClient); ok {
+				return true
+			}
+		}
 	}
-	return pools
+	return false
+}
+
+// shards returns the pools of the masters holding the keys of the pool
+func shards(ctx context.Context, pool redis.Pool) ([]redis.Pool, error) {
+	if s, ok := pool.(sharded); ok {
+		return s.shards(ctx)
+	}
+	return []redis.Pool{pool}, nil
+}
+
+// clientPool keeps the client of a redsync pool, so that watches can subscribe to keyspace notifications
+type clientPool struct {
+	redis.Pool
+	client goredislib.UniversalClient
+}
+
+func newClientPool(client goredislib.UniversalClient) *clientPool {
+	return &clientPool{
+		Pool:   redsyncgoredis.NewPool(client),
+		client: client,
+	}
+}
+
+var _ subscriber = (*clientPool)(nil)
+var _ sharded = (*clientPool)(nil)
+
+func (p *clientPool) shards(ctx context.Context) ([]redis.Pool, error) {
+	cluster, ok := p.client.(*goredislib.ClusterClient)
+	if !ok {
+		return []redis.Pool{p}, nil
+	}
+	var mu sync.Mutex
+	masters := []redis.Pool{}
+	err := cluster.ForEachMaster(ctx, func(_ context.Context, master *goredislib.Client) error {
+		mu.Lock()
+		defer mu.Unlock()
+		masters = append(masters, newClientPool(master))
+		return nil
+	})
+	return masters, err
+}
+
+func (p *clientPool) PSubscribe(ctx context.Context, patterns ...string) *goredislib.PubSub {
+	return p.client.PSubscribe(ctx, patterns...)
+}
+
+func (p *clientPool) keyspaceNotifications(ctx context.Context) error {
+	config, err := p.client.ConfigGet(ctx, "notify-keyspace-events").Result()
+	if err != nil {
+		return fmt.Errorf("failed to read notify-keyspace-events : %w", err)
+	}
+	flags := config["notify-keyspace-events"]
+	// A is an alias of every class of events but keyspace & keyevent notifications
+	all := strings.ReplaceAll(flags, "A", "g$lshzxetd")
+	for _, flag := range keyspaceFlags {
+		if !strings.ContainsRune(all, flag) {
+			return fmt.Errorf("notify-keyspace-events '%s' must include the flags %s", flags, keyspaceFlags)
+		}
+	}
+	return nil
 }
 
 // The following code is copied from:
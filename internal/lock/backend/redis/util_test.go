@@ -0,0 +1,135 @@
+package redis_test
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/alexandreLamarre/dlock/internal/lock/backend/redis"
+	"github.com/alexandreLamarre/dlock/pkg/config/v1alpha1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Redis client config", Label("unit"), func() {
+	It("should configure the client with the spec", func() {
+		nodes, err := redis.RedisClientOptions(&v1alpha1.RedisClientSpec{RedisNodeSpec: v1alpha1.RedisNodeSpec{
+			Network:      "tcp",
+			Addr:         "localhost:6379",
+			Username:     "dlock",
+			Password:     "secret",
+			DB:           2,
+			DialTimeout:  "1s",
+			ReadTimeout:  "2s",
+			WriteTimeout: "3s",
+			PoolSize:     20,
+			MinIdleConns: 5,
+			MaxRetries:   -1,
+			TLS: &v1alpha1.MTLSSpec{
+				ServerCA: "../../../../pkg/test/testdata/testdata/root_ca.crt",
+			},
+		}})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(nodes).To(HaveLen(1))
+		Expect(nodes[0].Failover).To(BeNil())
+		Expect(nodes[0].Cluster).To(BeNil())
+		opts := nodes[0].Client
+		Expect(opts.Addr).To(Equal("localhost:6379"))
+		Expect(opts.Username).To(Equal("dlock"))
+		Expect(opts.Password).To(Equal("secret"))
+		Expect(opts.DB).To(Equal(2))
+		Expect(opts.DialTimeout).To(Equal(time.Second))
+		Expect(opts.ReadTimeout).To(Equal(2 * time.Second))
+		Expect(opts.WriteTimeout).To(Equal(3 * time.Second))
+		Expect(opts.PoolSize).To(Equal(20))
+		Expect(opts.MinIdleConns).To(Equal(5))
+		Expect(opts.MaxRetries).To(Equal(-1))
+		Expect(opts.TLSConfig).NotTo(BeNil())
+		Expect(opts.TLSConfig.RootCAs).NotTo(BeNil())
+		Expect(opts.TLSConfig.Certificates).To(BeEmpty())
+	})
+
+	It("should configure a client for each node", func() {
+		nodes, err := redis.RedisClientOptions(&v1alpha1.RedisClientSpec{Nodes: []v1alpha1.RedisNodeSpec{
+			{Addr: "redis-0:6379", Password: "secret-0"},
+			{Addr: "redis-1:6379", Password: "secret-1", DB: 1},
+			{Addr: "redis-2:6379", Password: "secret-2"},
+		}})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(nodes).To(HaveLen(3))
+		for i, opts := range nodes {
+			Expect(opts.Client.Addr).To(Equal(fmt.Sprintf("redis-%d:6379", i)))
+			Expect(opts.Client.Password).To(Equal(fmt.Sprintf("secret-%d", i)))
+		}
+		Expect(nodes[1].Client.DB).To(Equal(1))
+	})
+
+	It("should configure the clients of sentinels & clusters", func() {
+		nodes, err := redis.RedisClientOptions(&v1alpha1.RedisClientSpec{Nodes: []v1alpha1.RedisNodeSpec{
+			{
+				Password:    "secret",
+				DB:          1,
+				DialTimeout: "1s",
+				Sentinel: &v1alpha1.RedisSentinelSpec{
+					MasterName: "mymaster",
+					Addrs:      []string{"sentinel-0:26379", "sentinel-1:26379"},
+					Password:   "sentinel-secret",
+				},
+			},
+			{
+				Password:    "secret",
+				DialTimeout: "1s",
+				Cluster:     &v1alpha1.RedisClusterSpec{Addrs: []string{"redis-0:6379", "redis-1:6379"}},
+			},
+		}})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(nodes).To(HaveLen(2))
+
+		failover := nodes[0].Failover
+		Expect(nodes[0].Client).To(BeNil())
+		Expect(failover).NotTo(BeNil())
+		Expect(failover.MasterName).To(Equal("mymaster"))
+		Expect(failover.SentinelAddrs).To(Equal([]string{"sentinel-0:26379", "sentinel-1:26379"}))
+		Expect(failover.SentinelPassword).To(Equal("sentinel-secret"))
+		Expect(failover.Password).To(Equal("secret"))
+		Expect(failover.DB).To(Equal(1))
+		Expect(failover.DialTimeout).To(Equal(time.Second))
+
+		cluster := nodes[1].Cluster
+		Expect(nodes[1].Client).To(BeNil())
+		Expect(cluster).NotTo(BeNil())
+		Expect(cluster.Addrs).To(Equal([]string{"redis-0:6379", "redis-1:6379"}))
+		Expect(cluster.Password).To(Equal("secret"))
+		Expect(cluster.DialTimeout).To(Equal(time.Second))
+	})
+
+	It("should reject invalid specs", func() {
+		for _, node := range []v1alpha1.RedisNodeSpec{
+			{DialTimeout: "1"},
+			{ReadTimeout: "-1s"},
+			{DB: -1},
+			{PoolSize: -1},
+			{TLS: &v1alpha1.MTLSSpec{ClientCert: "missing.crt", ClientKey: "missing.key"}},
+			{Sentinel: &v1alpha1.RedisSentinelSpec{Addrs: []string{"sentinel-0:26379"}}},
+			{Sentinel: &v1alpha1.RedisSentinelSpec{MasterName: "mymaster"}},
+			{Addr: "redis-0:6379", Sentinel: &v1alpha1.RedisSentinelSpec{MasterName: "mymaster", Addrs: []string{"sentinel-0:26379"}}},
+			{Cluster: &v1alpha1.RedisClusterSpec{}},
+			{Cluster: &v1alpha1.RedisClusterSpec{Addrs: []string{"redis-0:6379"}}, DB: 1},
+			{
+				Sentinel: &v1alpha1.RedisSentinelSpec{MasterName: "mymaster", Addrs: []string{"sentinel-0:26379"}},
+				Cluster:  &v1alpha1.RedisClusterSpec{Addrs: []string{"redis-0:6379"}},
+			},
+		} {
+			_, err := redis.RedisClientOptions(&v1alpha1.RedisClientSpec{RedisNodeSpec: node})
+			Expect(err).To(HaveOccurred())
+			_, err = redis.RedisClientOptions(&v1alpha1.RedisClientSpec{Nodes: []v1alpha1.RedisNodeSpec{{Addr: "redis-0:6379"}, node}})
+			Expect(err).To(HaveOccurred())
+		}
+
+		By("rejecting specs setting both nodes and the node of the spec")
+		_, err := redis.RedisClientOptions(&v1alpha1.RedisClientSpec{
+			RedisNodeSpec: v1alpha1.RedisNodeSpec{Addr: "redis-0:6379"},
+			Nodes:         []v1alpha1.RedisNodeSpec{{Addr: "redis-1:6379"}},
+		})
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,119 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/alexandreLamarre/dlock/pkg/lock"
+	"github.com/alexandreLamarre/dlock/pkg/logger"
+	goredislib "github.com/redis/go-redis/v9"
+)
+
+// keyspaceExpiries are the keyspace notifications of holders that lost the lock without releasing it
+var keyspaceExpiries = map[string]struct{}{
+	"expired":          {},
+	"zremrangebyscore": {},
+}
+
+// Watch subscribes to the keyspace notifications of the keys of exclusive holders & readers on every node,
+// describing their lock again on every notification. Nodes must enable keyspace notifications of generic,
+// string, sorted set & expired events, for instance with `notify-keyspace-events Kg$zx`.
+//
+// Locks are only described every lock.WatchInterval when a node does not notify these events, or when its
+// configuration can't be read.
+func (lm *LockManager) Watch(ctx context.Context, prefix string) (<-chan lock.WatchEvent, error) {
+	escaped := globReplacer.Replace(prefix)
+	kinds := []string{"-", ".readers-"}
+	subscriptions := []string{}
+	for _, kind := range kinds {
+		subscriptions = append(subscriptions, "__keyspace@*__:"+keyPattern(lm.prefix, kind, escaped, lm.tagged))
+	}
+	pubsubs := []*goredislib.PubSub{}
+	closeAll := func() error {
+		var errs error
+		for _, pubsub := range pubsubs {
+			errs = errors.Join(errs, pubsub.Close())
+		}
+		return errs
+	}
+	notified := true
+	for i, pool := range lm.pools {
+		if !notified {
+			break
+		}
+		// keyspace notifications are only published by the master of the key
+		masters, err := shards(ctx, pool)
+		if err != nil {
+			lm.lg.With("pool", i, logger.Err(err)).Warn("failed to discover the masters of the pool, polling locks instead")
+			notified = false
+			break
+		}
+		for _, master := range masters {
+			sub, ok := master.(subscriber)
+			if !ok {
+				notified = false
+				break
+			}
+			if err := sub.keyspaceNotifications(ctx); err != nil {
+				lm.lg.With("pool", i, logger.Err(err)).Warn("keyspace notifications are not enabled, polling locks instead")
+				notified = false
+				break
+			}
+			pubsub := sub.PSubscribe(ctx, subscriptions...)
+			// the subscription is confirmed before the locks are first described, so that no change is missed
+			if _, err := pubsub.Receive(ctx); err != nil {
+				lm.lg.With("pool", i, logger.Err(err)).Warn("failed to subscribe to keyspace notifications, polling locks instead")
+				_ = pubsub.Close()
+				notified = false
+				break
+			}
+			pubsubs = append(pubsubs, pubsub)
+		}
+	}
+	if !notified {
+		if err := closeAll(); err != nil {
+			lm.lg.With(logger.Err(err)).Warn("failed to close keyspace subscriptions")
+		}
+		pubsubs = nil
+	}
+
+	var changes chan lock.WatchChange
+	if notified {
+		changes = make(chan lock.WatchChange)
+	}
+	events, err := lock.WatchDescriptions(ctx, lm, prefix, changes)
+	if err != nil {
+		return nil, errors.Join(err, closeAll())
+	}
+	for _, pubsub := range pubsubs {
+		go func() {
+			for msg := range pubsub.Channel() {
+				_, name, _ := strings.Cut(msg.Channel, ":")
+				change := lock.WatchChange{}
+				for _, kind := range kinds {
+					if rest, ok := strings.CutPrefix(name, lm.prefix+kind); ok {
+						change.Key, _ = lockKeyOf(rest, lm.tagged)
+						break
+					}
+				}
+				if change.Key == "" {
+					continue
+				}
+				_, change.Expired = keyspaceExpiries[msg.Payload]
+				select {
+				case changes <- change:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		<-ctx.Done()
+		if err := closeAll(); err != nil {
+			lm.lg.With(logger.Err(err)).Warn("failed to close keyspace subscriptions")
+		}
+	}()
+	return events, nil
+}
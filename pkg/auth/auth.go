@@ -0,0 +1,69 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"google.golang.org/grpc/metadata"
+)
+
+var (
+	// ErrNoCredentials is returned by authenticators when the request carries no credentials they verify,
+	// so that the next authenticator is tried
+	ErrNoCredentials = errors.New("no credentials")
+	ErrInvalidToken  = errors.New("invalid bearer token")
+)
+
+// Authenticator returns the identity of the client of a request
+type Authenticator interface {
+	Authenticate(ctx context.Context) (string, error)
+}
+
+// Authenticators authenticate requests with the first authenticator that verifies their credentials
+type Authenticators []Authenticator
+
+var _ Authenticator = Authenticators{}
+
+func (a Authenticators) Authenticate(ctx context.Context) (string, error) {
+	for _, authenticator := range a {
+		identity, err := authenticator.Authenticate(ctx)
+		if errors.Is(err, ErrNoCredentials) {
+			continue
+		}
+		return identity, err
+	}
+	// bearer tokens that none of the authenticators verify are invalid rather than missing
+	if _, ok := bearerToken(ctx); ok {
+		return "", ErrInvalidToken
+	}
+	return "", ErrNoCredentials
+}
+
+// bearerToken returns the token of the authorization header of the request
+func bearerToken(ctx context.Context) (string, bool) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return "", false
+	}
+	for _, value := range md.Get("authorization") {
+		scheme, token, found := strings.Cut(value, " ")
+		if found && strings.EqualFold(scheme, "bearer") && token != "" {
+			return strings.TrimSpace(token), true
+		}
+	}
+	return "", false
+}
+
+type identityKey struct{}
+
+// WithIdentity returns a context carrying the identity of the authenticated client
+func WithIdentity(ctx context.Context, identity string) context.Context {
+	return context.WithValue(ctx, identityKey{}, identity)
+}
+
+// IdentityFromContext returns the identity of the authenticated client, if the request was authenticated
+func IdentityFromContext(ctx context.Context) (string, bool) {
+	identity, ok := ctx.Value(identityKey{}).(string)
+	return identity, ok
+}
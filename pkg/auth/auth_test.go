@@ -0,0 +1,174 @@
+package auth_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"time"
+
+	"github.com/alexandreLamarre/dlock/pkg/auth"
+	"github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+func withBearer(token string) context.Context {
+	return metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+token))
+}
+
+func withClientCert(commonName string) context.Context {
+	info := credentials.TLSInfo{State: tls.ConnectionState{
+		VerifiedChains: [][]*x509.Certificate{{{Subject: pkix.Name{CommonName: commonName}}}},
+	}}
+	return peer.NewContext(context.Background(), &peer.Peer{AuthInfo: info})
+}
+
+// signer signs JWTs with a key of the JWKS it returns
+func signer(kid string) (jose.Signer, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	Expect(err).NotTo(HaveOccurred())
+	s, err := jose.NewSigner(
+		jose.SigningKey{Algorithm: jose.ES256, Key: key},
+		(&jose.SignerOptions{}).WithType("JWT").WithHeader(jose.HeaderKey("kid"), kid),
+	)
+	Expect(err).NotTo(HaveOccurred())
+	jwks, err := json.Marshal(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+		{Key: &key.PublicKey, KeyID: kid, Algorithm: string(jose.ES256), Use: "sig"},
+	}})
+	Expect(err).NotTo(HaveOccurred())
+	return s, jwks
+}
+
+func sign(s jose.Signer, claims ...any) string {
+	builder := jwt.Signed(s)
+	for _, c := range claims {
+		builder = builder.Claims(c)
+	}
+	token, err := builder.Serialize()
+	Expect(err).NotTo(HaveOccurred())
+	return token
+}
+
+var _ = Describe("Authentication", Label("unit"), func() {
+	It("should authenticate clients by the common name of their verified certificate", func() {
+		Expect(auth.MTLSAuthenticator{}.Authenticate(withClientCert("ci"))).To(Equal("ci"))
+
+		_, err := auth.MTLSAuthenticator{}.Authenticate(withClientCert(""))
+		Expect(err).To(HaveOccurred())
+		Expect(err).NotTo(MatchError(auth.ErrNoCredentials))
+
+		By("ignoring connections without verified client certificates")
+		_, err = auth.MTLSAuthenticator{}.Authenticate(context.Background())
+		Expect(err).To(MatchError(auth.ErrNoCredentials))
+		_, err = auth.MTLSAuthenticator{}.Authenticate(peer.NewContext(context.Background(), &peer.Peer{
+			AuthInfo: credentials.TLSInfo{},
+		}))
+		Expect(err).To(MatchError(auth.ErrNoCredentials))
+	})
+
+	It("should authenticate clients by static bearer tokens", func() {
+		authn, err := auth.NewTokenAuthenticator(map[string]string{"secret-1": "ci", "secret-2": "ops"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(authn.Authenticate(withBearer("secret-1"))).To(Equal("ci"))
+		Expect(authn.Authenticate(withBearer("secret-2"))).To(Equal("ops"))
+
+		_, err = authn.Authenticate(withBearer("secret-3"))
+		Expect(err).To(MatchError(auth.ErrNoCredentials))
+		_, err = authn.Authenticate(metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Basic secret-1")))
+		Expect(err).To(MatchError(auth.ErrNoCredentials))
+
+		_, err = auth.NewTokenAuthenticator(map[string]string{"": "ci"})
+		Expect(err).To(HaveOccurred())
+		_, err = auth.NewTokenAuthenticator(map[string]string{"secret": ""})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should authenticate clients by JWTs signed by a key of the JWKS", func() {
+		s, jwks := signer("key-1")
+		authn, err := auth.NewJWTAuthenticator(jwks, auth.JWTOptions{Issuer: "https://issuer", Audience: "dlock"})
+		Expect(err).NotTo(HaveOccurred())
+		valid := jwt.Claims{
+			Subject:  "ci",
+			Issuer:   "https://issuer",
+			Audience: jwt.Audience{"dlock", "other"},
+			Expiry:   jwt.NewNumericDate(time.Now().Add(time.Minute)),
+		}
+		Expect(authn.Authenticate(withBearer(sign(s, valid)))).To(Equal("ci"))
+
+		By("rejecting tokens with invalid claims")
+		for _, invalid := range []func(c *jwt.Claims){
+			func(c *jwt.Claims) { c.Issuer = "https://other" },
+			func(c *jwt.Claims) { c.Audience = jwt.Audience{"other"} },
+			func(c *jwt.Claims) { c.Expiry = jwt.NewNumericDate(time.Now().Add(-time.Second)) },
+			func(c *jwt.Claims) { c.Expiry = nil },
+			func(c *jwt.Claims) { c.Subject = "" },
+		} {
+			claims := valid
+			invalid(&claims)
+			_, err := authn.Authenticate(withBearer(sign(s, claims)))
+			Expect(err).To(HaveOccurred())
+			Expect(err).NotTo(MatchError(auth.ErrNoCredentials))
+		}
+
+		By("rejecting tokens signed by other keys")
+		other, _ := signer("key-1")
+		_, err = authn.Authenticate(withBearer(sign(other, valid)))
+		Expect(err).To(HaveOccurred())
+
+		By("ignoring bearer tokens that are not JWTs")
+		_, err = authn.Authenticate(withBearer("secret"))
+		Expect(err).To(MatchError(auth.ErrNoCredentials))
+	})
+
+	It("should read the identity of clients from the configured claim", func() {
+		s, jwks := signer("key-1")
+		authn, err := auth.NewJWTAuthenticator(jwks, auth.JWTOptions{IdentityClaim: "email"})
+		Expect(err).NotTo(HaveOccurred())
+		claims := jwt.Claims{Subject: "1234", Expiry: jwt.NewNumericDate(time.Now().Add(time.Minute))}
+		Expect(authn.Authenticate(withBearer(sign(s, claims, map[string]any{"email": "ci@example.com"})))).
+			To(Equal("ci@example.com"))
+		_, err = authn.Authenticate(withBearer(sign(s, claims)))
+		Expect(err).To(HaveOccurred())
+
+		_, err = auth.NewJWTAuthenticator([]byte(`{"keys":[]}`), auth.JWTOptions{})
+		Expect(err).To(HaveOccurred())
+		_, err = auth.NewJWTAuthenticator([]byte(`not json`), auth.JWTOptions{})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should authenticate clients with the first authenticator verifying their credentials", func() {
+		tokens, err := auth.NewTokenAuthenticator(map[string]string{"secret": "ops"})
+		Expect(err).NotTo(HaveOccurred())
+		s, jwks := signer("key-1")
+		jwts, err := auth.NewJWTAuthenticator(jwks, auth.JWTOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		authn := auth.Authenticators{auth.MTLSAuthenticator{}, tokens, jwts}
+
+		Expect(authn.Authenticate(withClientCert("ci"))).To(Equal("ci"))
+		Expect(authn.Authenticate(withBearer("secret"))).To(Equal("ops"))
+		token := sign(s, jwt.Claims{Subject: "ci", Expiry: jwt.NewNumericDate(time.Now().Add(time.Minute))})
+		Expect(authn.Authenticate(withBearer(token))).To(Equal("ci"))
+
+		_, err = authn.Authenticate(context.Background())
+		Expect(err).To(MatchError(auth.ErrNoCredentials))
+		_, err = authn.Authenticate(withBearer("unknown"))
+		Expect(err).To(MatchError(auth.ErrInvalidToken))
+	})
+
+	It("should carry the identity of clients in the context of their requests", func() {
+		_, ok := auth.IdentityFromContext(context.Background())
+		Expect(ok).To(BeFalse())
+		identity, ok := auth.IdentityFromContext(auth.WithIdentity(context.Background(), "ci"))
+		Expect(ok).To(BeTrue())
+		Expect(identity).To(Equal("ci"))
+	})
+})
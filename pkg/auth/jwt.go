@@ -0,0 +1,117 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+)
+
+// asymmetric signature algorithms accepted for keys of the JWKS that do not declare their algorithm
+var defaultSignatureAlgorithms = []jose.SignatureAlgorithm{
+	jose.RS256, jose.RS384, jose.RS512,
+	jose.PS256, jose.PS384, jose.PS512,
+	jose.ES256, jose.ES384, jose.ES512,
+	jose.EdDSA,
+}
+
+// JWTOptions are the claims verified by a JWTAuthenticator
+type JWTOptions struct {
+	// required "iss" claim, not verified when empty
+	Issuer string
+	// audience the "aud" claim must contain, not verified when empty
+	Audience string
+	// claim holding the identity of clients, defaults to "sub"
+	IdentityClaim string
+}
+
+// JWTAuthenticator authenticates clients by bearer JWTs signed by a key of a JWKS. Tokens must expire.
+type JWTAuthenticator struct {
+	jwks       jose.JSONWebKeySet
+	algorithms []jose.SignatureAlgorithm
+	opts       JWTOptions
+}
+
+var _ Authenticator = (*JWTAuthenticator)(nil)
+
+// NewJWTAuthenticator returns an authenticator of tokens signed by the keys of the JSON encoded JWKS
+func NewJWTAuthenticator(jwksData []byte, opts JWTOptions) (*JWTAuthenticator, error) {
+	var jwks jose.JSONWebKeySet
+	if err := json.Unmarshal(jwksData, &jwks); err != nil {
+		return nil, fmt.Errorf("failed to parse JWKS : %w", err)
+	}
+	if len(jwks.Keys) == 0 {
+		return nil, errors.New("JWKS has no keys")
+	}
+	algorithms := slices.Clone(defaultSignatureAlgorithms)
+	for _, key := range jwks.Keys {
+		if !key.Valid() {
+			return nil, fmt.Errorf("invalid key '%s' in JWKS", key.KeyID)
+		}
+		if key.Algorithm != "" {
+			algorithms = append(algorithms, jose.SignatureAlgorithm(key.Algorithm))
+		}
+	}
+	if opts.IdentityClaim == "" {
+		opts.IdentityClaim = "sub"
+	}
+	return &JWTAuthenticator{
+		jwks:       jwks,
+		algorithms: algorithms,
+		opts:       opts,
+	}, nil
+}
+
+func (a *JWTAuthenticator) Authenticate(ctx context.Context) (string, error) {
+	token, ok := bearerToken(ctx)
+	if !ok {
+		return "", ErrNoCredentials
+	}
+	parsed, err := jwt.ParseSigned(token, a.algorithms)
+	if err != nil {
+		// the token may be verified by another authenticator
+		return "", fmt.Errorf("%w : %w", ErrNoCredentials, err)
+	}
+	var claims jwt.Claims
+	custom := map[string]any{}
+	if err := parsed.Claims(a.verificationKey(parsed), &claims, &custom); err != nil {
+		return "", fmt.Errorf("failed to verify JWT : %w", err)
+	}
+	if claims.Expiry == nil {
+		return "", errors.New("JWT must expire")
+	}
+	expected := jwt.Expected{
+		Issuer: a.opts.Issuer,
+		Time:   time.Now(),
+	}
+	if a.opts.Audience != "" {
+		expected.AnyAudience = jwt.Audience{a.opts.Audience}
+	}
+	if err := claims.ValidateWithLeeway(expected, 0); err != nil {
+		return "", fmt.Errorf("invalid JWT : %w", err)
+	}
+	identity, ok := custom[a.opts.IdentityClaim].(string)
+	if !ok || identity == "" {
+		return "", fmt.Errorf("JWT has no '%s' claim", a.opts.IdentityClaim)
+	}
+	return identity, nil
+}
+
+// verificationKey returns the JWKS, which verifies tokens with the key of their key ID,
+// or its only key for tokens without a key ID
+func (a *JWTAuthenticator) verificationKey(token *jwt.JSONWebToken) any {
+	for _, header := range token.Headers {
+		if header.KeyID != "" {
+			return a.jwks
+		}
+	}
+	if len(a.jwks.Keys) == 1 {
+		return a.jwks.Keys[0]
+	}
+	return a.jwks
+}
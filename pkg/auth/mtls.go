@@ -0,0 +1,31 @@
+package auth
+
+import (
+	"context"
+	"errors"
+
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+)
+
+// MTLSAuthenticator authenticates clients by the common name of their client certificate,
+// once the TLS handshake verified it
+type MTLSAuthenticator struct{}
+
+var _ Authenticator = MTLSAuthenticator{}
+
+func (MTLSAuthenticator) Authenticate(ctx context.Context) (string, error) {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return "", ErrNoCredentials
+	}
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(info.State.VerifiedChains) == 0 || len(info.State.VerifiedChains[0]) == 0 {
+		return "", ErrNoCredentials
+	}
+	identity := info.State.VerifiedChains[0][0].Subject.CommonName
+	if identity == "" {
+		return "", errors.New("client certificate has no common name")
+	}
+	return identity, nil
+}
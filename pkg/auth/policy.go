@@ -0,0 +1,99 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Operation is the kind of access a request has to its keys
+type Operation string
+
+const (
+	// blocking acquisitions of locks, semaphores & leader elections, which also allow OpTryLock
+	OpLock Operation = "lock"
+	// acquisitions that fail rather than wait when the lock is held
+	OpTryLock Operation = "trylock"
+	// force releases of locks held by other clients
+	OpForceRelease Operation = "force-release"
+	// introspection & watches of locks and leader elections
+	OpRead Operation = "read"
+)
+
+var operations = []Operation{OpLock, OpTryLock, OpForceRelease, OpRead}
+
+// AnyIdentity matches the identity of every authenticated client
+const AnyIdentity = "*"
+
+// Policy allows identities to run operations on the keys under prefixes
+type Policy struct {
+	Identities []string
+	// every namespace when empty
+	Namespaces []string
+	// every key when empty
+	Prefixes   []string
+	Operations []Operation
+}
+
+// Validate checks that the policy allows operations to some identities
+func (p Policy) Validate() error {
+	if len(p.Identities) == 0 {
+		return errors.New("policies must set the identities they apply to")
+	}
+	if len(p.Operations) == 0 {
+		return errors.New("policies must set the operations they allow")
+	}
+	for _, op := range p.Operations {
+		if !slices.Contains(operations, op) {
+			return fmt.Errorf("invalid operation '%s', must be one of lock, trylock, force-release or read", op)
+		}
+	}
+	return nil
+}
+
+// Request is the access of a request to a key, or to every key under a prefix for requests listing keys
+type Request struct {
+	Identity  string
+	Namespace string
+	Key       string
+	Operation Operation
+}
+
+func (p Policy) allows(req Request) bool {
+	if !slices.Contains(p.Identities, req.Identity) && !slices.Contains(p.Identities, AnyIdentity) {
+		return false
+	}
+	if len(p.Namespaces) > 0 && !slices.Contains(p.Namespaces, req.Namespace) {
+		return false
+	}
+	if len(p.Prefixes) > 0 && !slices.ContainsFunc(p.Prefixes, func(prefix string) bool {
+		return strings.HasPrefix(req.Key, prefix)
+	}) {
+		return false
+	}
+	return slices.Contains(p.Operations, req.Operation) ||
+		(req.Operation == OpTryLock && slices.Contains(p.Operations, OpLock))
+}
+
+// Authorizer allows the requests allowed by any of its policies
+type Authorizer struct {
+	policies []Policy
+}
+
+func NewAuthorizer(policies []Policy) (*Authorizer, error) {
+	for i, policy := range policies {
+		if err := policy.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid policy %d : %w", i, err)
+		}
+	}
+	return &Authorizer{
+		policies: policies,
+	}, nil
+}
+
+func (a *Authorizer) Authorize(req Request) bool {
+	return slices.ContainsFunc(a.policies, func(p Policy) bool {
+		return p.allows(req)
+	})
+}
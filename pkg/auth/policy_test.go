@@ -0,0 +1,67 @@
+package auth_test
+
+import (
+	"github.com/alexandreLamarre/dlock/pkg/auth"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Authorization", Label("unit"), func() {
+	It("should allow the operations of policies on the keys under their prefixes", func() {
+		authz, err := auth.NewAuthorizer([]auth.Policy{
+			{
+				Identities: []string{"ci"},
+				Prefixes:   []string{"ci/", "builds/"},
+				Operations: []auth.Operation{auth.OpLock, auth.OpRead},
+			},
+			{
+				Identities: []string{"ops"},
+				Namespaces: []string{"billing"},
+				Operations: []auth.Operation{auth.OpForceRelease},
+			},
+			{
+				Identities: []string{auth.AnyIdentity},
+				Prefixes:   []string{"shared/"},
+				Operations: []auth.Operation{auth.OpTryLock},
+			},
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		for _, req := range []auth.Request{
+			{Identity: "ci", Namespace: "default", Key: "ci/deploy", Operation: auth.OpLock},
+			{Identity: "ci", Namespace: "billing", Key: "builds/1", Operation: auth.OpRead},
+			{Identity: "ci", Namespace: "default", Key: "ci/deploy", Operation: auth.OpTryLock},
+			{Identity: "ops", Namespace: "billing", Key: "invoices/1", Operation: auth.OpForceRelease},
+			{Identity: "anyone", Namespace: "default", Key: "shared/cache", Operation: auth.OpTryLock},
+		} {
+			Expect(authz.Authorize(req)).To(BeTrue(), "%+v", req)
+		}
+		for _, req := range []auth.Request{
+			{Identity: "ci", Namespace: "default", Key: "prod/deploy", Operation: auth.OpLock},
+			{Identity: "ci", Namespace: "default", Key: "ci/deploy", Operation: auth.OpForceRelease},
+			// listing every key is not allowed to identities restricted to prefixes
+			{Identity: "ci", Namespace: "default", Key: "", Operation: auth.OpRead},
+			{Identity: "ops", Namespace: "default", Key: "invoices/1", Operation: auth.OpForceRelease},
+			{Identity: "anyone", Namespace: "default", Key: "shared/cache", Operation: auth.OpLock},
+			{Identity: "", Namespace: "default", Key: "ci/deploy", Operation: auth.OpLock},
+		} {
+			Expect(authz.Authorize(req)).To(BeFalse(), "%+v", req)
+		}
+	})
+
+	It("should deny every request without policies", func() {
+		authz, err := auth.NewAuthorizer(nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(authz.Authorize(auth.Request{Identity: "ci", Namespace: "default", Key: "ci/deploy", Operation: auth.OpRead})).
+			To(BeFalse())
+	})
+
+	It("should validate policies", func() {
+		_, err := auth.NewAuthorizer([]auth.Policy{{Operations: []auth.Operation{auth.OpLock}}})
+		Expect(err).To(HaveOccurred())
+		_, err = auth.NewAuthorizer([]auth.Policy{{Identities: []string{"ci"}}})
+		Expect(err).To(HaveOccurred())
+		_, err = auth.NewAuthorizer([]auth.Policy{{Identities: []string{"ci"}, Operations: []auth.Operation{"unlock"}}})
+		Expect(err).To(HaveOccurred())
+	})
+})
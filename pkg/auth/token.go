@@ -0,0 +1,49 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+)
+
+// TokenAuthenticator authenticates clients by static bearer tokens
+type TokenAuthenticator struct {
+	// identities by the digest of their token, so that tokens are compared in constant time
+	identities map[[sha256.Size]byte]string
+}
+
+var _ Authenticator = (*TokenAuthenticator)(nil)
+
+// NewTokenAuthenticator returns an authenticator of the identities of the tokens
+func NewTokenAuthenticator(tokens map[string]string) (*TokenAuthenticator, error) {
+	a := &TokenAuthenticator{
+		identities: make(map[[sha256.Size]byte]string, len(tokens)),
+	}
+	for token, identity := range tokens {
+		if token == "" {
+			return nil, errors.New("tokens must not be empty")
+		}
+		if identity == "" {
+			return nil, errors.New("the identity of tokens must be set")
+		}
+		a.identities[sha256.Sum256([]byte(token))] = identity
+	}
+	return a, nil
+}
+
+func (a *TokenAuthenticator) Authenticate(ctx context.Context) (string, error) {
+	token, ok := bearerToken(ctx)
+	if !ok {
+		return "", ErrNoCredentials
+	}
+	digest := sha256.Sum256([]byte(token))
+	for known, identity := range a.identities {
+		if subtle.ConstantTimeCompare(digest[:], known[:]) == 1 {
+			return identity, nil
+		}
+	}
+	// the token may be verified by another authenticator
+	return "", fmt.Errorf("%w : unknown token", ErrNoCredentials)
+}
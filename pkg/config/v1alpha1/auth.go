@@ -0,0 +1,45 @@
+package v1alpha1
+
+// AuthSpec configures the authentication of the clients of the lock server & the policies authorizing their
+// requests. Every request, except health checks, must authenticate with one of the configured methods once it is set.
+type AuthSpec struct {
+	// Authenticates clients by the common name of their client certificate, which requires server.tls.clientAuth
+	// to verify client certificates.
+	MTLS bool `json:"mtls,omitempty" toml:"mtls"`
+	// Static bearer tokens, sent by clients in the authorization header of their requests.
+	Tokens []TokenSpec `json:"tokens,omitempty" toml:"tokens"`
+	// JWTs sent by clients as bearer tokens, verified against a local JWKS.
+	JWT *JWTSpec `json:"jwt,omitempty" toml:"jwt"`
+	// Policies authorizing the requests of authenticated clients, requests no policy allows are denied.
+	Policies []PolicySpec `json:"policies,omitempty" toml:"policies"`
+}
+
+type TokenSpec struct {
+	// Identity of the clients presenting the token, matched by the identities of policies.
+	Identity string `json:"identity,omitempty" toml:"identity"`
+	Token    string `json:"token,omitempty" toml:"token"`
+}
+
+type JWTSpec struct {
+	// Path to the JSON Web Key Set verifying the signatures of tokens, read once when the server starts.
+	JWKSFile string `json:"jwksFile,omitempty" toml:"jwksFile"`
+	// Required "iss" claim of tokens, not verified when unset.
+	Issuer string `json:"issuer,omitempty" toml:"issuer"`
+	// Audience the "aud" claim of tokens must contain, not verified when unset.
+	Audience string `json:"audience,omitempty" toml:"audience"`
+	// Claim holding the identity of clients, defaults to "sub".
+	IdentityClaim string `json:"identityClaim,omitempty" toml:"identityClaim"`
+}
+
+// PolicySpec allows identities to run operations on the keys under prefixes
+type PolicySpec struct {
+	// Identities the policy applies to, "*" matching every authenticated client.
+	Identities []string `json:"identities,omitempty" toml:"identities"`
+	// Namespaces the policy applies to, every namespace when unset. Semaphores & leader elections,
+	// which are not namespaced, are authorized as keys of the default namespace.
+	Namespaces []string `json:"namespaces,omitempty" toml:"namespaces"`
+	// Prefixes of the keys the policy applies to, every key when unset.
+	Prefixes []string `json:"prefixes,omitempty" toml:"prefixes"`
+	// Operations allowed : "lock", "trylock", "force-release" or "read". Lock also allows trylock.
+	Operations []string `json:"operations,omitempty" toml:"operations"`
+}
@@ -1,9 +1,18 @@
 package v1alpha1
 
 type LockServerConfig struct {
+	Server *ServerSpec `json:"server,omitempty" toml:"server"`
+
 	EtcdClientSpec      *EtcdClientSpec      `json:"etcd,omitempty" toml:"etcd"`
 	JetstreamClientSpec *JetstreamClientSpec `json:"jetstream,omitempty" toml:"jetstream"`
 	RedisClientSpec     *RedisClientSpec     `json:"redis,omitempty" toml:"redis"`
+	FileClientSpec      *FileClientSpec      `json:"file,omitempty" toml:"file"`
+	RaftClientSpec      *RaftClientSpec      `json:"raft,omitempty" toml:"raft"`
+
+	LockLimits *LockLimitsSpec `json:"limits,omitempty" toml:"limits"`
+	// Namespaces served in addition to the default namespace, which requests without a namespace use.
+	// The default namespace is configured by a namespace named "default", whose prefix is always "lock".
+	Namespaces []NamespaceSpec `json:"namespaces,omitempty" toml:"namespaces"`
 }
 
 type TracesConfig struct {
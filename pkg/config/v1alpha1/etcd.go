@@ -5,14 +5,27 @@ type EtcdClientSpec struct {
 	Endpoints []string `json:"endpoints,omitempty" toml:"endpoints"`
 	// Configuration for etcd client-cert auth.
 	Certs *MTLSSpec `json:"certs,omitempty" toml:"certs"`
+	// Credentials of the user, for clusters with authentication enabled.
+	Username string `json:"username,omitempty" toml:"username"`
+	Password string `json:"password,omitempty" toml:"password"`
+	// Timeout of the connection to the endpoints, e.g. "5s". The client connects without a timeout when unset.
+	DialTimeout string `json:"dialTimeout,omitempty" toml:"dialTimeout"`
+	// Interval at which the client pings the endpoints to check the connection is alive, e.g. "30s".
+	// The client does not ping the endpoints when unset.
+	KeepAliveTime string `json:"keepAliveTime,omitempty" toml:"keepAliveTime"`
+	// Time the client waits for the response of a ping before closing the connection, e.g. "10s".
+	KeepAliveTimeout string `json:"keepAliveTimeout,omitempty" toml:"keepAliveTimeout"`
+	// Interval at which the client updates its endpoints with the members of the cluster, e.g. "1m".
+	// The endpoints are not updated when unset.
+	AutoSyncInterval string `json:"autoSyncInterval,omitempty" toml:"autoSyncInterval"`
 }
 
 type MTLSSpec struct {
-	// Path to the server CA certificate.
+	// Path to the server CA certificate, the system's CA certificates are used when unset.
 	ServerCA string `json:"serverCA,omitempty" toml:"serverCA"`
 	// Path to the client CA certificate (not needed in all cases).
 	ClientCA string `json:"clientCA,omitempty" toml:"clientCA"`
-	// Path to the certificate used for client-cert auth.
+	// Path to the certificate used for client-cert auth, no client certificate is presented when unset.
 	ClientCert string `json:"clientCert,omitempty" toml:"clientCert"`
 	// Path to the private key used for client-cert auth.
 	ClientKey string `json:"clientKey,omitempty" toml:"clientKey"`
@@ -0,0 +1,7 @@
+package v1alpha1
+
+type FileClientSpec struct {
+	// Directory holding the lock files, created if it does not exist.
+	// Every process sharing locks must use the same directory on the same host.
+	Dir string `json:"dir,omitempty" toml:"dir"`
+}
@@ -3,4 +3,17 @@ package v1alpha1
 type JetstreamClientSpec struct {
 	Endpoint     string `json:"endpoint,omitempty" toml:"endpoint"`
 	NkeySeedPath string `json:"nkeySeedPath,omitempty" toml:"nkeySeedPath"`
+	// Path to a NATS credentials file, holding the user JWT & nkey seed of decentralized auth.
+	CredsFile string `json:"credsFile,omitempty" toml:"credsFile"`
+	// Credentials of the user, for servers authenticating users by password.
+	Username string `json:"username,omitempty" toml:"username"`
+	Password string `json:"password,omitempty" toml:"password"`
+	// Token of servers authenticating clients by token.
+	Token string `json:"token,omitempty" toml:"token"`
+	// Connects over TLS when set, the client certificate being optional.
+	TLS *MTLSSpec `json:"tls,omitempty" toml:"tls"`
+	// Timeout of the connection to the server, e.g. "2s". Defaults to 2s.
+	ConnectTimeout string `json:"connectTimeout,omitempty" toml:"connectTimeout"`
+	// Interval at which the client pings the server, e.g. "2m". Defaults to 2m.
+	PingInterval string `json:"pingInterval,omitempty" toml:"pingInterval"`
 }
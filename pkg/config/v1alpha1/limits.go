@@ -0,0 +1,15 @@
+package v1alpha1
+
+// LockLimitsSpec bounds the timings that clients can request for their locks, e.g. "1s".
+// Timings are not bounded on the sides that are unset, except for the min TTL which defaults to 1s.
+type LockLimitsSpec struct {
+	// Bounds of the TTL after which acquisitions expire once their holder stops keeping them alive.
+	MinTTL string `json:"minTTL,omitempty" toml:"minTTL"`
+	MaxTTL string `json:"maxTTL,omitempty" toml:"maxTTL"`
+	// Bounds of the interval at which holders keep their acquisitions alive.
+	MinKeepaliveInterval string `json:"minKeepaliveInterval,omitempty" toml:"minKeepaliveInterval"`
+	MaxKeepaliveInterval string `json:"maxKeepaliveInterval,omitempty" toml:"maxKeepaliveInterval"`
+	// Bounds of the delay between the attempts of blocking acquisitions.
+	MinRetryDelay string `json:"minRetryDelay,omitempty" toml:"minRetryDelay"`
+	MaxRetryDelay string `json:"maxRetryDelay,omitempty" toml:"maxRetryDelay"`
+}
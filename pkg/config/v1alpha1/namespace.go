@@ -0,0 +1,18 @@
+package v1alpha1
+
+// NamespaceSpec configures a namespace of locks, which maps to its own prefix in the backend
+// and carries the policy of the locks acquired in it.
+type NamespaceSpec struct {
+	// Name of the namespace, selected by the namespace field of requests.
+	Name string `json:"name,omitempty" toml:"name"`
+	// Prefix of the keys of the namespace in the backend, defaults to the name.
+	// The prefixes of namespaces must not start with one another, nor with the prefix of the default namespace, "lock".
+	Prefix string `json:"prefix,omitempty" toml:"prefix"`
+	// TTL of the locks & leases that do not request one, e.g. "30s". Defaults to the backend's & server's defaults.
+	DefaultTTL string `json:"defaultTTL,omitempty" toml:"defaultTTL"`
+	// Maximum number of locks & leases held at once through this server in the namespace, unbounded when unset.
+	MaxHolders int `json:"maxHolders,omitempty" toml:"maxHolders"`
+	// Identities of the authenticated clients allowed to acquire locks in the namespace, which requires server auth.
+	// Every client is allowed when unset.
+	AllowedClients []string `json:"allowedClients,omitempty" toml:"allowedClients"`
+}
@@ -0,0 +1,25 @@
+package v1alpha1
+
+type RaftClientSpec struct {
+	// Unique ID of this node in the raft cluster.
+	NodeID string `json:"nodeID,omitempty" toml:"nodeID"`
+	// Address the raft transport listens on, it must be reachable by every other peer since followers
+	// also forward lock operations to the leader through it.
+	BindAddr string `json:"bindAddr,omitempty" toml:"bindAddr"`
+	// Directory holding the raft log, stable store and snapshots of this node.
+	DataDir string `json:"dataDir,omitempty" toml:"dataDir"`
+	// Members of the cluster, including this node, used to bootstrap the cluster when no state exists on disk.
+	Peers []RaftPeerSpec `json:"peers,omitempty" toml:"peers"`
+	// Duration after which the locks of a session that stopped sending keepalives are released, e.g. "10s".
+	// Defaults to 10s.
+	SessionTTL string `json:"sessionTTL,omitempty" toml:"sessionTTL"`
+	// Mutual TLS of the raft transport : every peer serves its certificate, presents it when dialing the others
+	// and verifies theirs against the CA certificate. The transport is plaintext & unauthenticated when unset,
+	// in which case the bind address must be private.
+	TLS *CertsSpec `json:"tls,omitempty" toml:"tls"`
+}
+
+type RaftPeerSpec struct {
+	ID   string `json:"id,omitempty" toml:"id"`
+	Addr string `json:"addr,omitempty" toml:"addr"`
+}
@@ -1,6 +1,50 @@
 package v1alpha1
 
 type RedisClientSpec struct {
+	RedisNodeSpec
+	// Independent redis nodes, locks being acquired on a majority of them with the Redlock algorithm.
+	// Each node is configured on its own, and the node of the spec itself must be unset when nodes are set.
+	// An odd number of nodes, e.g. 3 or 5, tolerates the failure of a minority of them.
+	Nodes []RedisNodeSpec `json:"nodes,omitempty" toml:"nodes"`
+}
+
+// RedisNodeSpec configures the client of a redis node
+type RedisNodeSpec struct {
 	Network string `json:"network,omitempty" toml:"network"`
 	Addr    string `json:"addr,omitempty" toml:"addr"`
+	// Credentials of the ACL user, only the password is sent when the username is unset.
+	Username string `json:"username,omitempty" toml:"username"`
+	Password string `json:"password,omitempty" toml:"password"`
+	// Index of the database holding the locks, defaults to 0.
+	DB int `json:"db,omitempty" toml:"db"`
+	// Connects over TLS when set, the client certificate being optional.
+	TLS *MTLSSpec `json:"tls,omitempty" toml:"tls"`
+	// Timeouts of the client, e.g. "5s". The defaults of go-redis are used when they are unset.
+	DialTimeout  string `json:"dialTimeout,omitempty" toml:"dialTimeout"`
+	ReadTimeout  string `json:"readTimeout,omitempty" toml:"readTimeout"`
+	WriteTimeout string `json:"writeTimeout,omitempty" toml:"writeTimeout"`
+	// Maximum number of connections of the pool, defaults to 10 per CPU.
+	PoolSize int `json:"poolSize,omitempty" toml:"poolSize"`
+	// Minimum number of idle connections kept open by the pool.
+	MinIdleConns int `json:"minIdleConns,omitempty" toml:"minIdleConns"`
+	// Maximum number of retries of failed commands, defaults to 3. Set to -1 to disable retries.
+	MaxRetries int `json:"maxRetries,omitempty" toml:"maxRetries"`
+	// Connects to the master monitored by sentinels rather than to addr, following its failovers.
+	Sentinel *RedisSentinelSpec `json:"sentinel,omitempty" toml:"sentinel"`
+	// Connects to a redis cluster rather than to addr, the keys of each lock being held by the master of their slot.
+	Cluster *RedisClusterSpec `json:"cluster,omitempty" toml:"cluster"`
+}
+
+// RedisSentinelSpec configures the sentinels monitoring the master of a redis node
+type RedisSentinelSpec struct {
+	MasterName string   `json:"masterName,omitempty" toml:"masterName"`
+	Addrs      []string `json:"addrs,omitempty" toml:"addrs"`
+	// Credentials of the sentinels, which may differ from the credentials of the master.
+	Username string `json:"username,omitempty" toml:"username"`
+	Password string `json:"password,omitempty" toml:"password"`
+}
+
+// RedisClusterSpec configures the seed nodes of a redis cluster, the other nodes being discovered from them
+type RedisClusterSpec struct {
+	Addrs []string `json:"addrs,omitempty" toml:"addrs"`
 }
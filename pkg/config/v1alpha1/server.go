@@ -0,0 +1,17 @@
+package v1alpha1
+
+// ServerSpec configures the gRPC listener of the lock server
+type ServerSpec struct {
+	// TLS of the listener, which serves plaintext when unset
+	TLS *ServerTLSSpec `json:"tls,omitempty" toml:"tls"`
+	// Authentication & authorization of requests, every request is allowed when unset
+	Auth *AuthSpec `json:"auth,omitempty" toml:"auth"`
+}
+
+// ServerTLSSpec configures the certificate served by the listener, whose CA certificate also verifies
+// the certificates of clients
+type ServerTLSSpec struct {
+	CertsSpec
+	// Verification of client certificates : "none" (the default), "verifyIfGiven" or "require" for mutual TLS
+	ClientAuth string `json:"clientAuth,omitempty" toml:"clientAuth"`
+}
@@ -8,4 +8,6 @@ const (
 	EtcdLockManager      = "etcd"
 	RedisLockManager     = "redis"
 	JetstreamLockManager = "jetstream"
+	FileLockManager      = "file"
+	RaftLockManager      = "raft"
 )
@@ -0,0 +1,59 @@
+package election
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/alexandreLamarre/dlock/pkg/lock"
+)
+
+var (
+	ErrNoLeader    = errors.New("election has no leader")
+	ErrNotLeader   = errors.New("not the leader of the election")
+	ErrCampaigning = errors.New("already campaigning")
+)
+
+var (
+	// ObserveInterval is the interval at which elections poll their leader when they can't watch it,
+	// or retry reading it when it fails
+	ObserveInterval = 500 * time.Millisecond
+)
+
+// ValueLabel is the label of the lock metadata holding the value of the leader,
+// for elections implemented on top of locks
+const ValueLabel = "dlock.election.value"
+
+// Election elects a single leader among the candidates campaigning on the same name.
+// Leaders follow the liveliness guarantees of locks, a leader that crashes or can't reach the backend
+// eventually loses its leadership.
+//
+// A single Election instance campaigns for at most one candidate at a time, concurrent candidates
+// should each use their own instance.
+type Election interface {
+	// Campaign blocks until the candidate is elected leader with the value, or the context fails.
+	// The returned channel receives a value once the candidate is no longer the leader, including after Resign.
+	Campaign(ctx context.Context, value string) (lost <-chan struct{}, err error)
+	// Resign gives up the leadership, so that another candidate can be elected.
+	// It returns ErrNotLeader if the candidate was not elected.
+	Resign(ctx context.Context) error
+	// Leader returns the value of the current leader, or ErrNoLeader
+	Leader(ctx context.Context) (string, error)
+	// Observe returns a channel receiving the value of each new leader, starting with the current one.
+	// The channel is closed once the context is done.
+	Observe(ctx context.Context) <-chan string
+}
+
+// Provider is implemented by lock managers whose backend has native elections
+type Provider interface {
+	NewElection(name string, opts ...lock.LockOption) Election
+}
+
+// New returns the election on the given name, using the native elections of the lock manager's backend
+// if it has any. Otherwise the leader is the holder of the lock on the name.
+func New(lm lock.LockManager, name string, opts ...lock.LockOption) Election {
+	if p, ok := lm.(Provider); ok {
+		return p.NewElection(name, opts...)
+	}
+	return newLockElection(lm, name, opts...)
+}
@@ -0,0 +1,176 @@
+package election
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/alexandreLamarre/dlock/pkg/lock"
+)
+
+// electionSuffix is appended to the name of elections to get the key of their lock, so that elections never
+// share their lock with the locks of other keys
+const electionSuffix = ".election"
+
+// lockElection elects the holder of the lock on its name, the value of the leader being
+// stored in the metadata of its acquisition
+type lockElection struct {
+	lm   lock.LockManager
+	name string
+	opts []lock.LockOption
+
+	mu sync.Mutex
+	// set from the start of a campaign until the leadership is lost
+	campaigning bool
+	l           lock.Lock
+}
+
+var _ Election = (*lockElection)(nil)
+
+func newLockElection(lm lock.LockManager, name string, opts ...lock.LockOption) *lockElection {
+	return &lockElection{
+		lm:   lm,
+		name: name,
+		opts: opts,
+	}
+}
+
+func (e *lockElection) key() string {
+	return e.name + electionSuffix
+}
+
+func (e *lockElection) Campaign(ctx context.Context, value string) (<-chan struct{}, error) {
+	e.mu.Lock()
+	if e.campaigning {
+		e.mu.Unlock()
+		return nil, ErrCampaigning
+	}
+	e.campaigning = true
+	e.mu.Unlock()
+
+	options := lock.DefaultLockOptions()
+	options.Apply(e.opts...)
+	md := options.Metadata
+	md.Labels = maps.Clone(md.Labels)
+	if md.Labels == nil {
+		md.Labels = map[string]string{}
+	}
+	md.Labels[ValueLabel] = value
+
+	l := e.lm.NewLock(e.key(), append(slices.Clone(e.opts), lock.WithMetadata(md))...)
+	expired, err := l.Lock(ctx)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if err != nil {
+		e.campaigning = false
+		return nil, err
+	}
+	e.l = l
+	lost := make(chan struct{}, 1)
+	go func() {
+		<-expired
+		e.mu.Lock()
+		// the candidate can campaign again once its leadership expired, without resigning
+		if e.l == l {
+			e.l = nil
+			e.campaigning = false
+			_ = l.Unlock()
+		}
+		e.mu.Unlock()
+		lost <- struct{}{}
+	}()
+	return lost, nil
+}
+
+func (e *lockElection) Resign(_ context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.l == nil {
+		return ErrNotLeader
+	}
+	l := e.l
+	e.l = nil
+	e.campaigning = false
+	return l.Unlock()
+}
+
+func (e *lockElection) Leader(ctx context.Context) (string, error) {
+	info, err := e.lm.DescribeLock(ctx, e.key())
+	if err != nil {
+		return "", err
+	}
+	for _, h := range info.Holders {
+		if !h.Shared {
+			return h.Labels[ValueLabel], nil
+		}
+	}
+	return "", ErrNoLeader
+}
+
+// Observe reads the leader again on each change of the holders of the election's lock, as notified by watching
+// the lock. It polls the leader every ObserveInterval when the lock can't be watched, and retries reading it
+// after ObserveInterval when it fails. Watches dropped by the backend are started again.
+func (e *lockElection) Observe(ctx context.Context) <-chan string {
+	ch := make(chan string)
+	go func() {
+		defer close(ch)
+		var poll <-chan time.Time
+		var events <-chan lock.WatchEvent
+		last := ""
+		for {
+			if events == nil && poll == nil {
+				var err error
+				if events, err = e.lm.Watch(ctx, e.key()); err != nil {
+					t := time.NewTicker(ObserveInterval)
+					defer t.Stop()
+					poll = t.C
+				}
+			}
+			leader, err := e.Leader(ctx)
+			if err == nil && leader != last {
+				select {
+				case ch <- leader:
+				case <-ctx.Done():
+					return
+				}
+			}
+			var retry <-chan time.Time
+			if err == nil {
+				last = leader
+			} else {
+				last = ""
+				retry = time.After(ObserveInterval)
+			}
+			if !e.changed(ctx, &events, poll, retry) {
+				return
+			}
+		}
+	}()
+	return ch
+}
+
+// changed blocks until the leader may have changed, and reports whether the context is still alive.
+// The events are reset once the watch is closed, the leader having possibly changed since.
+func (e *lockElection) changed(ctx context.Context, events *<-chan lock.WatchEvent, poll, retry <-chan time.Time) bool {
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-poll:
+			return true
+		case <-retry:
+			return true
+		case event, ok := <-*events:
+			if !ok {
+				*events = nil
+				return ctx.Err() == nil
+			}
+			// the watch also streams the locks whose key starts with the key of the election
+			if event.Key == e.key() {
+				return true
+			}
+		}
+	}
+}
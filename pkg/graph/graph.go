@@ -0,0 +1,407 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alexandreLamarre/dlock/pkg/lock"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrCycle       = errors.New("lock graph has a cycle")
+	ErrUnknownNode = errors.New("unknown node")
+	ErrClosed      = errors.New("lock graph is closed")
+)
+
+var (
+	// PollInterval is the interval at which nodes waiting for their predecessors check whether
+	// the predecessors are held or done outside of the graph
+	PollInterval = 500 * time.Millisecond
+	// DoneCheckTimeout bounds every check of whether a node was marked done in the backend
+	DoneCheckTimeout = 100 * time.Millisecond
+)
+
+type Options struct {
+	// ID scopes the done markers of the nodes in the backend, the graphs of lock managers sharing a backend
+	// & their ID share the progress of their nodes. Defaults to a random ID, keeping the progress of the graph
+	// to itself.
+	ID string
+}
+
+type Option func(o *Options)
+
+func (o *Options) Apply(opts ...Option) {
+	for _, op := range opts {
+		op(o)
+	}
+}
+
+func WithID(id string) Option {
+	return func(o *Options) {
+		o.ID = id
+	}
+}
+
+// State is the progress of a node of a graph
+type State int
+
+const (
+	// Pending nodes were not locked & released through the graph yet
+	Pending State = iota
+	// Held nodes are locked through the graph
+	Held
+	// Done nodes were locked & released through the graph
+	Done
+)
+
+func (s State) String() string {
+	switch s {
+	case Pending:
+		return "pending"
+	case Held:
+		return "held"
+	case Done:
+		return "done"
+	}
+	return fmt.Sprintf("State(%d)", int(s))
+}
+
+// Node is the state of a node of a graph, whose key is locked once its predecessors are done or held
+type Node struct {
+	Key          string
+	Predecessors []string
+	State        State
+}
+
+// Graph is a directed acyclic graph of lock keys, for workflows whose stages must each run after the stages
+// they depend on. Locking a node waits until each of its predecessors is done, i.e. it was locked & released through
+// the graph, or is held, and then locks its key like a lock of the lock manager.
+//
+// Keys are locked in the backend, so stages exclude each other across processes. Nodes released through the graph
+// are marked done in the backend with a latch when the lock manager is a lock.BarrierManager, so predecessors
+// released through the graphs of other processes with the same ID are done, and predecessors held by other
+// processes count as held. The progress of graphs whose backend does not support latches is tracked in the memory
+// of the process.
+type Graph struct {
+	lm lock.LockManager
+	id string
+	// marks nodes done in the backend, it is nil when the backend does not support latches
+	markers lock.BarrierManager
+	preds   map[string][]string
+	// keys in a topological order
+	keys []string
+
+	mu     sync.Mutex
+	states map[string]State
+	// closed & replaced every time the state of a node changes
+	changed chan struct{}
+	closed  bool
+}
+
+// New returns the graph whose nodes are the keys of the map, each mapping to the keys of its predecessors.
+// It returns ErrCycle if a node is its own predecessor through any path, and ErrUnknownNode if a predecessor
+// is not a node of the graph.
+func New(lm lock.LockManager, predecessors map[string][]string, opts ...Option) (*Graph, error) {
+	options := &Options{}
+	options.Apply(opts...)
+	if options.ID == "" {
+		options.ID = uuid.NewString()
+	}
+	if len(predecessors) == 0 {
+		return nil, errors.New("lock graph has no nodes")
+	}
+	preds := make(map[string][]string, len(predecessors))
+	for key, p := range predecessors {
+		preds[key] = slices.Compact(slices.Sorted(slices.Values(p)))
+	}
+	keys, err := topologicalOrder(preds)
+	if err != nil {
+		return nil, err
+	}
+	markers, _ := lm.(lock.BarrierManager)
+	return &Graph{
+		lm:      lm,
+		id:      options.ID,
+		markers: markers,
+		preds:   preds,
+		keys:    keys,
+		states:  make(map[string]State, len(keys)),
+		changed: make(chan struct{}),
+	}, nil
+}
+
+// topologicalOrder returns the keys of the graph ordered so that every node comes after its predecessors,
+// or the first cycle found
+func topologicalOrder(preds map[string][]string) ([]string, error) {
+	keys := slices.Sorted(maps.Keys(preds))
+	for _, key := range keys {
+		if key == "" {
+			return nil, errors.New("node keys must not be empty")
+		}
+		for _, p := range preds[key] {
+			if _, ok := preds[p]; !ok {
+				return nil, fmt.Errorf("%w %s, predecessor of %s", ErrUnknownNode, p, key)
+			}
+		}
+	}
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	marks := make(map[string]int, len(keys))
+	order := make([]string, 0, len(keys))
+	// nodes being visited, each node being a predecessor of the previous one
+	path := []string{}
+	var visit func(key string) error
+	visit = func(key string) error {
+		switch marks[key] {
+		case visited:
+			return nil
+		case visiting:
+			cycle := append(slices.Clone(path[slices.Index(path, key):]), key)
+			slices.Reverse(cycle)
+			return fmt.Errorf("%w : %s", ErrCycle, strings.Join(cycle, " -> "))
+		}
+		marks[key] = visiting
+		path = append(path, key)
+		for _, p := range preds[key] {
+			if err := visit(p); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		marks[key] = visited
+		order = append(order, key)
+		return nil
+	}
+	for _, key := range keys {
+		if err := visit(key); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
+
+// NewLock returns the lock of the node on the key, whose Lock & TryLock first wait for the predecessors
+// of the node. The lock is created by the lock manager with the options.
+func (g *Graph) NewLock(key string, opts ...lock.LockOption) (lock.Lock, error) {
+	if _, ok := g.preds[key]; !ok {
+		return nil, fmt.Errorf("%w %s", ErrUnknownNode, key)
+	}
+	return &nodeLock{
+		g:   g,
+		key: key,
+		l:   g.lm.NewLock(key, opts...),
+	}, nil
+}
+
+// Nodes returns the nodes of the graph, every node coming after its predecessors
+func (g *Graph) Nodes() []Node {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.nodesLocked()
+}
+
+func (g *Graph) nodesLocked() []Node {
+	nodes := make([]Node, 0, len(g.keys))
+	for _, key := range g.keys {
+		nodes = append(nodes, Node{
+			Key:          key,
+			Predecessors: slices.Clone(g.preds[key]),
+			State:        g.states[key],
+		})
+	}
+	return nodes
+}
+
+// Observe returns a channel receiving every node of the graph, then each node whose state changed.
+// Intermediate states of a node are skipped when they change faster than the channel is consumed, and nodes
+// marked done in the backend are discovered every PollInterval.
+// The channel is closed once every node is done, the graph is closed or the context is done.
+func (g *Graph) Observe(ctx context.Context) <-chan Node {
+	ch := make(chan Node)
+	go func() {
+		defer close(ch)
+		t := time.NewTicker(PollInterval)
+		defer t.Stop()
+		sent := map[string]State{}
+		for {
+			g.mu.Lock()
+			nodes, changed, closed := g.nodesLocked(), g.changed, g.closed
+			g.mu.Unlock()
+			done := true
+			for _, n := range nodes {
+				done = done && n.State == Done
+				if s, ok := sent[n.Key]; ok && s == n.State {
+					continue
+				}
+				select {
+				case ch <- n:
+				case <-ctx.Done():
+					return
+				}
+				sent[n.Key] = n.State
+			}
+			if done || closed {
+				return
+			}
+			select {
+			case <-changed:
+			case <-t.C:
+				// nodes that could not be checked are checked again on the next tick
+				_ = g.refresh(ctx)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch
+}
+
+// Close stops the graph, pending acquisitions of its nodes fail with ErrClosed and its observers are done.
+// Nodes that are held stay held until they are unlocked.
+func (g *Graph) Close() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.closed {
+		return
+	}
+	g.closed = true
+	g.notifyLocked()
+}
+
+func (g *Graph) set(key string, state State) (prev State) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	prev = g.states[key]
+	g.states[key] = state
+	g.notifyLocked()
+	return prev
+}
+
+func (g *Graph) notifyLocked() {
+	close(g.changed)
+	g.changed = make(chan struct{})
+}
+
+// discover marks the node done when it is pending in the graph, once it was marked done in the backend
+func (g *Graph) discover(key string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.states[key] != Pending {
+		return
+	}
+	g.states[key] = Done
+	g.notifyLocked()
+}
+
+// doneMarker returns the latch marking the node done in the backend
+func (g *Graph) doneMarker(key string) lock.CountDownLatch {
+	return g.markers.NewCountDownLatch("graph/"+g.id+"/"+key, 1)
+}
+
+// markDone marks the node done in the backend
+func (g *Graph) markDone(ctx context.Context, key string) error {
+	if g.markers == nil {
+		return nil
+	}
+	return g.doneMarker(key).CountDown(ctx)
+}
+
+// markedDone reports whether the node was marked done in the backend, waiting at most DoneCheckTimeout
+func (g *Graph) markedDone(ctx context.Context, key string) (bool, error) {
+	if g.markers == nil {
+		return false, nil
+	}
+	ctxca, ca := context.WithTimeout(ctx, DoneCheckTimeout)
+	defer ca()
+	err := g.doneMarker(key).Await(ctxca)
+	if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// refresh discovers the pending nodes of the graph that were marked done in the backend
+func (g *Graph) refresh(ctx context.Context) error {
+	if g.markers == nil {
+		return nil
+	}
+	for _, n := range g.Nodes() {
+		if n.State != Pending {
+			continue
+		}
+		done, err := g.markedDone(ctx, n.Key)
+		if err != nil {
+			return err
+		}
+		if done {
+			g.discover(n.Key)
+		}
+	}
+	return nil
+}
+
+// ready reports whether each predecessor of the node is done or held, predecessors that are pending
+// in the graph may be held outside of it, or marked done in the backend
+func (g *Graph) ready(ctx context.Context, key string) (bool, error) {
+	g.mu.Lock()
+	if g.closed {
+		g.mu.Unlock()
+		return false, ErrClosed
+	}
+	pending := []string{}
+	for _, p := range g.preds[key] {
+		if g.states[p] == Pending {
+			pending = append(pending, p)
+		}
+	}
+	g.mu.Unlock()
+	for _, p := range pending {
+		info, err := g.lm.DescribeLock(ctx, p)
+		if err != nil {
+			return false, err
+		}
+		if len(info.Holders) > 0 {
+			continue
+		}
+		done, err := g.markedDone(ctx, p)
+		if err != nil || !done {
+			return false, err
+		}
+		g.discover(p)
+	}
+	return true, nil
+}
+
+// wait blocks until the node is ready, checking again every time a node changes or every PollInterval
+func (g *Graph) wait(ctx context.Context, key string) error {
+	t := time.NewTicker(PollInterval)
+	defer t.Stop()
+	for {
+		g.mu.Lock()
+		changed := g.changed
+		g.mu.Unlock()
+		ready, err := g.ready(ctx, key)
+		if err != nil {
+			return err
+		}
+		if ready {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-changed:
+		case <-t.C:
+		}
+	}
+}
@@ -0,0 +1,253 @@
+package graph_test
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/alexandreLamarre/dlock/internal/lock/backend/memory"
+	"github.com/alexandreLamarre/dlock/pkg/graph"
+	"github.com/alexandreLamarre/dlock/pkg/lock"
+	"github.com/alexandreLamarre/dlock/pkg/logger"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Graph", Label("unit"), func() {
+	var ctx context.Context
+	var lm lock.LockManager
+	BeforeEach(func() {
+		ctxca, ca := context.WithCancel(context.Background())
+		DeferCleanup(ca)
+		ctx = ctxca
+		lm = memory.NewLockManager(nil, logger.NewNop())
+	})
+
+	When("validating graphs", func() {
+		It("should order nodes after their predecessors", func() {
+			g, err := graph.New(lm, map[string][]string{
+				"deploy": {"test", "lint"},
+				"test":   {"build"},
+				"lint":   {},
+				"build":  nil,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			keys := []string{}
+			for _, n := range g.Nodes() {
+				Expect(n.State).To(Equal(graph.Pending))
+				keys = append(keys, n.Key)
+			}
+			Expect(keys).To(Equal([]string{"build", "lint", "test", "deploy"}))
+		})
+
+		It("should reject cycles", func() {
+			_, err := graph.New(lm, map[string][]string{
+				"a": {"c"},
+				"b": {"a"},
+				"c": {"b"},
+				"d": {"a"},
+			})
+			Expect(err).To(MatchError(graph.ErrCycle))
+			Expect(err.Error()).To(ContainSubstring("a -> b -> c -> a"))
+
+			_, err = graph.New(lm, map[string][]string{"a": {"a"}})
+			Expect(err).To(MatchError(graph.ErrCycle))
+		})
+
+		It("should reject unknown predecessors", func() {
+			_, err := graph.New(lm, map[string][]string{"a": {"b"}})
+			Expect(err).To(MatchError(graph.ErrUnknownNode))
+			g, err := graph.New(lm, map[string][]string{"a": nil})
+			Expect(err).NotTo(HaveOccurred())
+			_, err = g.NewLock("b")
+			Expect(err).To(MatchError(graph.ErrUnknownNode))
+		})
+	})
+
+	When("locking nodes", func() {
+		It("should wait for the predecessors to be done or held", func() {
+			g, err := graph.New(lm, map[string][]string{
+				"build":  nil,
+				"test":   {"build"},
+				"deploy": {"test"},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			test, err := g.NewLock("test")
+			Expect(err).NotTo(HaveOccurred())
+			acquired, _, err := test.TryLock(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(acquired).To(BeFalse())
+
+			locked := make(chan error, 1)
+			go func() {
+				_, err := test.Lock(ctx)
+				locked <- err
+			}()
+			Consistently(locked, 200*time.Millisecond).ShouldNot(Receive())
+
+			build, err := g.NewLock("build")
+			Expect(err).NotTo(HaveOccurred())
+			_, err = build.Lock(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			// held predecessors are ready
+			Eventually(locked).Should(Receive(BeNil()))
+			Expect(build.Unlock()).To(Succeed())
+			Expect(test.Unlock()).To(Succeed())
+
+			deploy, err := g.NewLock("deploy")
+			Expect(err).NotTo(HaveOccurred())
+			acquired, _, err = deploy.TryLock(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(acquired).To(BeTrue())
+			Expect(deploy.Unlock()).To(Succeed())
+			for _, n := range g.Nodes() {
+				Expect(n.State).To(Equal(graph.Done))
+			}
+		})
+
+		It("should count predecessors held outside of the graph as held", func() {
+			g, err := graph.New(lm, map[string][]string{
+				"build": nil,
+				"test":  {"build"},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			_, err = lm.NewLock("build").Lock(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			test, err := g.NewLock("test")
+			Expect(err).NotTo(HaveOccurred())
+			acquired, _, err := test.TryLock(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(acquired).To(BeTrue())
+			Expect(test.Unlock()).To(Succeed())
+		})
+
+		It("should share the progress of graphs with the same ID through the backend", func() {
+			markers := &latchLocks{LockManager: lm, counts: map[string]int64{}}
+			preds := map[string][]string{
+				"build": nil,
+				"test":  {"build"},
+			}
+			newGraph := func(opts ...graph.Option) *graph.Graph {
+				g, err := graph.New(markers, preds, opts...)
+				Expect(err).NotTo(HaveOccurred())
+				return g
+			}
+			g, same, other := newGraph(graph.WithID("release")), newGraph(graph.WithID("release")), newGraph()
+			nodes := newGraph(graph.WithID("release")).Observe(ctx)
+
+			build, err := g.NewLock("build")
+			Expect(err).NotTo(HaveOccurred())
+			_, err = build.Lock(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(build.Unlock()).To(Succeed())
+
+			test, err := other.NewLock("test")
+			Expect(err).NotTo(HaveOccurred())
+			acquired, _, err := test.TryLock(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(acquired).To(BeFalse())
+
+			test, err = same.NewLock("test")
+			Expect(err).NotTo(HaveOccurred())
+			acquired, _, err = test.TryLock(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(acquired).To(BeTrue())
+			Expect(test.Unlock()).To(Succeed())
+			for _, n := range same.Nodes() {
+				Expect(n.State).To(Equal(graph.Done))
+			}
+			Eventually(nodes, 2*time.Second).Should(Receive(And(HaveField("Key", "test"), HaveField("State", graph.Done))))
+			Eventually(nodes).Should(BeClosed())
+		})
+
+		It("should fail pending acquisitions once the graph is closed", func() {
+			g, err := graph.New(lm, map[string][]string{
+				"build": nil,
+				"test":  {"build"},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			test, err := g.NewLock("test")
+			Expect(err).NotTo(HaveOccurred())
+			locked := make(chan error, 1)
+			go func() {
+				_, err := test.Lock(ctx)
+				locked <- err
+			}()
+			Consistently(locked, 100*time.Millisecond).ShouldNot(Receive())
+			g.Close()
+			Eventually(locked).Should(Receive(MatchError(graph.ErrClosed)))
+		})
+	})
+
+	When("observing graphs", func() {
+		It("should stream node states until every node is done", func() {
+			g, err := graph.New(lm, map[string][]string{
+				"build": nil,
+				"test":  {"build"},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			nodes := g.Observe(ctx)
+			Eventually(nodes).Should(Receive(HaveField("Key", "build")))
+			Eventually(nodes).Should(Receive(HaveField("Key", "test")))
+
+			for _, key := range []string{"build", "test"} {
+				l, err := g.NewLock(key)
+				Expect(err).NotTo(HaveOccurred())
+				_, err = l.Lock(ctx)
+				Expect(err).NotTo(HaveOccurred())
+				Eventually(nodes).Should(Receive(And(HaveField("Key", key), HaveField("State", graph.Held))))
+				Expect(l.Unlock()).To(Succeed())
+				Eventually(nodes).Should(Receive(HaveField("State", graph.Done)))
+			}
+			Eventually(nodes).Should(BeClosed())
+		})
+	})
+})
+
+// latchLocks adds latches to a lock manager, so that graphs mark their nodes done in it
+type latchLocks struct {
+	lock.LockManager
+
+	mu     sync.Mutex
+	counts map[string]int64
+}
+
+func (l *latchLocks) NewBarrier(string, int64, ...lock.LockOption) lock.Barrier {
+	panic("unimplemented")
+}
+
+func (l *latchLocks) NewCountDownLatch(key string, n int64, _ ...lock.LockOption) lock.CountDownLatch {
+	return &latch{l: l, key: key, n: n}
+}
+
+type latch struct {
+	l   *latchLocks
+	key string
+	n   int64
+}
+
+func (c *latch) CountDown(context.Context) error {
+	c.l.mu.Lock()
+	defer c.l.mu.Unlock()
+	if _, ok := c.l.counts[c.key]; !ok {
+		c.l.counts[c.key] = c.n
+	}
+	c.l.counts[c.key] = max(c.l.counts[c.key]-1, 0)
+	return nil
+}
+
+func (c *latch) Await(ctx context.Context) error {
+	for {
+		c.l.mu.Lock()
+		count, ok := c.l.counts[c.key]
+		c.l.mu.Unlock()
+		if ok && count == 0 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
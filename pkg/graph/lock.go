@@ -0,0 +1,93 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+
+	"github.com/alexandreLamarre/dlock/pkg/lock"
+)
+
+// nodeLock locks the key of a node once its predecessors are ready, the node being held until it is unlocked,
+// and done once it is. A node whose lock expires goes back to its previous state.
+type nodeLock struct {
+	g   *Graph
+	key string
+	l   lock.Lock
+
+	mu sync.Mutex
+	// set while the node is held through this lock
+	held *acquisition
+}
+
+var _ lock.Lock = (*nodeLock)(nil)
+
+type acquisition struct {
+	// state of the node before it was acquired
+	prev State
+	// set once the acquisition is unlocked or expired
+	released atomic.Bool
+}
+
+func (n *nodeLock) Lock(ctx context.Context) (<-chan struct{}, error) {
+	if err := n.g.wait(ctx, n.key); err != nil {
+		return nil, err
+	}
+	expired, err := n.l.Lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	n.acquired(expired)
+	return expired, nil
+}
+
+// TryLock returns acquired=false when the predecessors of the node are not ready
+func (n *nodeLock) TryLock(ctx context.Context) (bool, <-chan struct{}, error) {
+	ready, err := n.g.ready(ctx, n.key)
+	if err != nil || !ready {
+		return false, nil, err
+	}
+	acquired, expired, err := n.l.TryLock(ctx)
+	if err != nil || !acquired {
+		return acquired, nil, err
+	}
+	n.acquired(expired)
+	return true, expired, nil
+}
+
+func (n *nodeLock) acquired(expired <-chan struct{}) {
+	a := &acquisition{
+		prev: n.g.set(n.key, Held),
+	}
+	n.mu.Lock()
+	n.held = a
+	n.mu.Unlock()
+	go func() {
+		<-expired
+		if a.released.CompareAndSwap(false, true) {
+			n.g.set(n.key, a.prev)
+		}
+	}()
+}
+
+// Unlock marks the node done in the graph & in the backend before releasing its lock, unless its lock
+// already expired
+func (n *nodeLock) Unlock() error {
+	n.mu.Lock()
+	a := n.held
+	n.held = nil
+	n.mu.Unlock()
+	var err error
+	if a != nil && a.released.CompareAndSwap(false, true) {
+		n.g.set(n.key, Done)
+		ctx, ca := context.WithTimeout(context.Background(), lock.DefaultTimeout)
+		err = n.g.markDone(ctx, n.key)
+		ca()
+	}
+	return errors.Join(err, n.l.Unlock())
+}
+
+func (n *nodeLock) FencingToken() uint64 {
+	return n.l.FencingToken()
+}
@@ -0,0 +1,77 @@
+package lock
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	ErrParticipants        = errors.New("barriers & latches require at least one participant")
+	ErrBarrierExpired      = errors.New("registration expired before the barrier was reached")
+	ErrBarriersUnsupported = errors.New("lock backend does not support barriers & latches")
+)
+
+var (
+	// LatchRetention is the time after which backends delete the count of a latch that was neither counted down
+	// nor awaited, so that the latches of crashed processes do not linger
+	LatchRetention = 24 * time.Hour
+)
+
+// Barrier is a distributed barrier, participants waiting on the same key are held until n of them wait.
+// Every participant of a key is expected to agree on n.
+//
+// Participants keep their registration alive while they wait, like the holders of a lock, so the registration
+// of a participant that crashes eventually expires and it is no longer counted. Once the barrier is reached,
+// later participants wait for the next n participants.
+type Barrier interface {
+	// Wait registers a participant and blocks until n participants wait on the barrier, including this one,
+	// or the context fails, in which case the participant is unregistered.
+	// It returns ErrParticipants if n is not positive, and ErrBarrierExpired if the registration expired
+	// before the barrier was reached.
+	Wait(ctx context.Context) error
+}
+
+// CountDownLatch is a distributed latch, whose awaiting processes are held until it is counted down n times.
+// Every process using a key is expected to agree on n, and the count is never reset once it reached zero,
+// until it was neither counted down nor awaited for LatchRetention.
+type CountDownLatch interface {
+	// CountDown decrements the count of the latch, releasing the processes awaiting it once it reaches zero.
+	// Counting down a released latch is a no-op.
+	CountDown(ctx context.Context) error
+	// Await blocks until the count of the latch reaches zero or the context fails
+	Await(ctx context.Context) error
+}
+
+// BarrierManager is implemented by lock managers whose backend supports barriers & latches.
+// Barriers & latches do not share their keys with locks.
+type BarrierManager interface {
+	NewBarrier(key string, n int64, opts ...LockOption) Barrier
+	NewCountDownLatch(key string, n int64, opts ...LockOption) CountDownLatch
+}
+
+// NewBarrier returns the barrier of n participants on the key, or ErrBarriersUnsupported
+func NewBarrier(lm LockManager, key string, n int64, opts ...LockOption) (Barrier, error) {
+	bm, ok := lm.(BarrierManager)
+	if !ok {
+		return nil, ErrBarriersUnsupported
+	}
+	return bm.NewBarrier(key, n, opts...), nil
+}
+
+// NewCountDownLatch returns the latch counted down n times on the key, or ErrBarriersUnsupported
+func NewCountDownLatch(lm LockManager, key string, n int64, opts ...LockOption) (CountDownLatch, error) {
+	bm, ok := lm.(BarrierManager)
+	if !ok {
+		return nil, ErrBarriersUnsupported
+	}
+	return bm.NewCountDownLatch(key, n, opts...), nil
+}
+
+// ValidateParticipants checks that a barrier or latch has at least one participant
+func ValidateParticipants(n int64) error {
+	if n < 1 {
+		return ErrParticipants
+	}
+	return nil
+}
@@ -32,9 +32,21 @@ func NewLockBroker(
 	}
 }
 
-// LockManager blocks until it acquires the client connection, or returns an error
-// when an unrecoverable error is hit
+// DefaultPrefix is the backend prefix of the keys of the default namespace
+const DefaultPrefix = "lock"
+
+// LockManager returns the lock manager of the default prefix, see LockManagers
 func (l LockBroker) LockManager(ctx context.Context) (lock.LockManager, error) {
+	lms, err := l.LockManagers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return lms(DefaultPrefix)
+}
+
+// LockManagers blocks until it acquires the client connection, or returns an error
+// when an unrecoverable error is hit
+func (l LockBroker) LockManagers(ctx context.Context) (LockManagers, error) {
 	backends := strings.Join(brokerKeys(), ",")
 	l.Lg.Info(fmt.Sprintf("Available lock managers : %s", backends))
 	if l.Config.EtcdClientSpec != nil {
@@ -60,5 +72,21 @@ func (l LockBroker) LockManager(ctx context.Context) (lock.LockManager, error) {
 		return broker(ctx, l)
 	}
 
+	if l.Config.FileClientSpec != nil {
+		broker, ok := GetLockBroker(constants.FileLockManager)
+		if !ok {
+			return nil, fmt.Errorf("file lock manager not registered")
+		}
+		return broker(ctx, l)
+	}
+
+	if l.Config.RaftClientSpec != nil {
+		broker, ok := GetLockBroker(constants.RaftLockManager)
+		if !ok {
+			return nil, fmt.Errorf("raft lock manager not registered")
+		}
+		return broker(ctx, l)
+	}
+
 	return nil, fmt.Errorf("unknown lock manager type in config : %s", util.Must(json.Marshal(l.Config)))
 }
@@ -8,7 +8,12 @@ import (
 	"github.com/samber/lo"
 )
 
-type lockBroker = func(context.Context, LockBroker) (lock.LockManager, error)
+// lockBroker connects to the backend, and returns the lock managers of its prefixes
+type lockBroker = func(context.Context, LockBroker) (LockManagers, error)
+
+// LockManagers returns the lock manager of the keys under the backend prefix, the lock managers of every prefix
+// sharing the connection of the broker
+type LockManagers = func(prefix string) (lock.LockManager, error)
 
 var (
 	brokerMu    sync.RWMutex
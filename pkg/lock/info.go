@@ -0,0 +1,50 @@
+package lock
+
+import (
+	"os"
+	"sync"
+	"time"
+)
+
+// Metadata identifies the holder of a lock. It is attached to every acquisition and reported by
+// LockManager.ListLocks & LockManager.DescribeLock, so that stuck locks can be traced back to their holder.
+type Metadata struct {
+	// Owner is a free-form identity of the holder, e.g. a service or user name
+	Owner    string            `json:"owner,omitempty"`
+	Hostname string            `json:"hostname,omitempty"`
+	Pid      int               `json:"pid,omitempty"`
+	Labels   map[string]string `json:"labels,omitempty"`
+}
+
+var processMetadata = sync.OnceValue(func() Metadata {
+	hostname, _ := os.Hostname()
+	return Metadata{
+		Hostname: hostname,
+		Pid:      os.Getpid(),
+	}
+})
+
+// ProcessMetadata returns the hostname & pid of the current process, it is the default metadata of acquisitions
+func ProcessMetadata() Metadata {
+	return processMetadata()
+}
+
+// HolderInfo describes a single acquisition of a lock
+type HolderInfo struct {
+	Metadata `json:"metadata"`
+	// Shared is set for holders of the read side of a lock
+	Shared     bool      `json:"shared,omitempty"`
+	AcquiredAt time.Time `json:"acquiredAt"`
+	// TTL is the time remaining before the acquisition expires if its holder stops keeping it alive,
+	// it is zero when the backend does not expire acquisitions on a deadline.
+	TTL time.Duration `json:"ttl,omitempty"`
+}
+
+// LockInfo describes the holders & waiters of the lock on a key.
+// A lock that is not held has no holders.
+type LockInfo struct {
+	Key     string
+	Holders []HolderInfo
+	// Waiters is the number of blocking acquisitions waiting for the lock
+	Waiters int
+}
@@ -13,6 +13,8 @@ import (
 var (
 	ErrLockActionRequested = errors.New("lock action already requested")
 	ErrLockScheduled       = errors.New("nothing scheduled")
+	ErrLockMode            = errors.New("lock is held in another mode")
+	ErrSemaphoreWeight     = errors.New("semaphore weight must be between 1 and its capacity")
 )
 
 var (
@@ -44,6 +46,62 @@ type Lock interface {
 	// expired by the server.
 	// It immediately signals to the lock's original expired channel that the lock is released.
 	Unlock() error
+	// FencingToken returns the fencing token issued for the current acquisition of the lock.
+	// Fencing tokens are monotonically increasing per key, so resources guarded by the lock can reject
+	// operations carrying a token lower than the highest one they have already observed.
+	// It returns 0 if the lock is not currently held.
+	FencingToken() uint64
+}
+
+// RWLock is a distributed reader/writer lock. Any number of readers can hold the lock at the same time,
+// but the write side is exclusive to a single holder and excludes all readers.
+// The write side shares its key with the Lock returned by LockManager.NewLock, so both exclude each other.
+//
+// RWLocks follow the same liveliness & atomicity guarantees as Lock.
+// A single RWLock instance holds at most one side of the lock at a time, concurrent readers
+// should each use their own instance.
+type RWLock interface {
+	// Lock, TryLock & Unlock act on the write side of the lock.
+	// FencingToken is only issued for acquisitions of the write side.
+	Lock
+
+	// RLock acquires a shared lock on the key. If the write side of the lock is held, it will block until the
+	// lock is acquired or the context fails.
+	RLock(ctx context.Context) (expired <-chan struct{}, err error)
+	// TryRLock tries to acquire a shared lock on the key and reports whether it succeeded.
+	// It returns acquired=false and no error if the write side is known to be held by someone else.
+	TryRLock(ctx context.Context) (acquired bool, expired <-chan struct{}, err error)
+	// RUnlock releases the shared lock on the key in a non-blocking fashion, with the same semantics as Unlock.
+	RUnlock() error
+}
+
+// Semaphore is a distributed counting semaphore, holders acquire a weight out of a fixed capacity
+// shared by every holder of the key, so the sum of the weights held at any time never exceeds the capacity.
+// Every holder of a key is expected to agree on its capacity.
+//
+// Semaphores follow the same liveliness guarantees as Lock, and a single Semaphore instance holds
+// at most one acquisition at a time.
+type Semaphore interface {
+	// Acquire acquires n units of the semaphore. If they are not available, it will block until they are
+	// acquired or the context fails.
+	// Acquire returns ErrSemaphoreWeight if n is not between 1 and the capacity of the semaphore.
+	Acquire(ctx context.Context, n int64) (expired <-chan struct{}, err error)
+	// TryAcquire tries to acquire n units of the semaphore and reports whether it succeeded.
+	// It returns acquired=false and no error if the units are known to be held by others.
+	TryAcquire(ctx context.Context, n int64) (acquired bool, expired <-chan struct{}, err error)
+	// Release releases the units held by the semaphore in a non-blocking fashion, with the same semantics as
+	// Lock.Unlock.
+	Release() error
+	// Capacity returns the total number of units of the semaphore
+	Capacity() int64
+}
+
+// ValidateWeight checks that n units can be acquired from a semaphore with the given capacity
+func ValidateWeight(n, capacity int64) error {
+	if n < 1 || n > capacity {
+		return ErrSemaphoreWeight
+	}
+	return nil
 }
 
 // LockManager is a factory for Lock instances
@@ -56,6 +114,101 @@ type LockManager interface {
 	//
 	// Defaults to lock.DefaultOptions if no options are provided.
 	NewLock(key string, opts ...LockOption) Lock
+
+	// Instantiates a new RWLock instance for the given key, with the given options.
+	//
+	// Defaults to lock.DefaultOptions if no options are provided.
+	NewRWLock(key string, opts ...LockOption) RWLock
+
+	// Instantiates a new Lock acquiring the locks of every key all-or-nothing, with the given options.
+	// Keys are acquired in their sorted order so that multi locks sharing keys never deadlock,
+	// and its expired channel fires once any of the locks expires, see MultiLock.
+	//
+	// Defaults to lock.DefaultOptions if no options are provided.
+	NewMultiLock(keys []string, opts ...LockOption) Lock
+
+	// Instantiates a new Semaphore instance for the given key and capacity, with the given options.
+	// Semaphores do not share their keys with locks.
+	//
+	// Defaults to lock.DefaultOptions if no options are provided.
+	NewSemaphore(key string, capacity int64, opts ...LockOption) Semaphore
+
+	// ListLocks describes the locks whose key starts with the prefix and that are held or waited on.
+	// Semaphores are not listed.
+	ListLocks(ctx context.Context, prefix string) ([]LockInfo, error)
+
+	// DescribeLock describes the holders & waiters of the lock on the key, or RWLock sharing its key.
+	DescribeLock(ctx context.Context, key string) (LockInfo, error)
+
+	// ForceRelease evicts every holder of the lock on the key, for operators to recover from wedged holders.
+	// The expired channels of the evicted holders fire, and their later calls to unlock are no-ops.
+	// It returns the evicted holders, and never evicts the holders of semaphores.
+	ForceRelease(ctx context.Context, key, reason string) (LockInfo, error)
+
+	// Watch streams the acquisitions, releases & expiries of the locks whose key starts with the prefix,
+	// until the context is done. Semaphores are not watched.
+	// The channel is closed before the context is done when the watch falls too far behind, see Watchers.
+	Watch(ctx context.Context, prefix string) (<-chan WatchEvent, error)
+}
+
+// TTLDefaulter is implemented by the lock managers whose acquisitions expire after a default TTL
+// when they do not set one
+type TTLDefaulter interface {
+	// DefaultTTL returns the TTL of the acquisitions that do not set one, zero when they never expire
+	DefaultTTL() time.Duration
+}
+
+// RLocker returns a Lock interface that implements the Lock, TryLock and Unlock methods
+// by calling rw.RLock, rw.TryRLock and rw.RUnlock.
+func RLocker(rw RWLock) Lock {
+	return rlocker{rw}
+}
+
+type rlocker struct {
+	rw RWLock
+}
+
+func (r rlocker) Lock(ctx context.Context) (<-chan struct{}, error) {
+	return r.rw.RLock(ctx)
+}
+
+func (r rlocker) TryLock(ctx context.Context) (bool, <-chan struct{}, error) {
+	return r.rw.TryRLock(ctx)
+}
+
+func (r rlocker) Unlock() error {
+	return r.rw.RUnlock()
+}
+
+func (r rlocker) FencingToken() uint64 {
+	return 0
+}
+
+// SemaphoreLocker returns a Lock interface that implements the Lock, TryLock and Unlock methods
+// by acquiring and releasing n units of the semaphore.
+func SemaphoreLocker(sem Semaphore, n int64) Lock {
+	return semaphoreLocker{sem: sem, n: n}
+}
+
+type semaphoreLocker struct {
+	sem Semaphore
+	n   int64
+}
+
+func (s semaphoreLocker) Lock(ctx context.Context) (<-chan struct{}, error) {
+	return s.sem.Acquire(ctx, s.n)
+}
+
+func (s semaphoreLocker) TryLock(ctx context.Context) (bool, <-chan struct{}, error) {
+	return s.sem.TryAcquire(ctx, s.n)
+}
+
+func (s semaphoreLocker) Unlock() error {
+	return s.sem.Release()
+}
+
+func (s semaphoreLocker) FencingToken() uint64 {
+	return 0
 }
 
 type LockScheduler struct {
@@ -130,10 +283,38 @@ func (o *OnceErr) doSlow(f func() error) error {
 
 type LockOptions struct {
 	Tracer trace.Tracer
+	// Metadata attached to acquisitions, defaults to ProcessMetadata
+	Metadata Metadata
+
+	// Timings of acquisitions, backends use their own defaults when they are unset.
+
+	// TTL after which an acquisition expires once its holder stops keeping it alive
+	TTL time.Duration
+	// KeepaliveInterval at which holders keep their acquisitions alive, it should be well below the TTL
+	KeepaliveInterval time.Duration
+	// RetryDelay between the attempts of blocking acquisitions
+	RetryDelay time.Duration
+
+	// Fair acquisitions of a lock are granted in the order they started waiting, so that new acquisitions
+	// never overtake waiters. Fair acquisitions are not ordered with the acquisitions that are not, and
+	// semaphores ignore it.
+	Fair bool
+
+	// Reentrant locks can be locked again by the owner of their metadata with the same ReentrantToken while it
+	// holds them, only its last unlock releasing the lock, see ReentrantLocks. Only exclusive locks are reentrant.
+	Reentrant bool
+	// ReentrantToken scopes the reentrancy of reentrant locks, see NewReentrantToken
+	ReentrantToken string
+
+	// OnQueued is called while blocking acquisitions of a lock wait for it, with their position in its queue :
+	// the number of acquisitions that started waiting before them, see NotifyPosition. Semaphores ignore it.
+	OnQueued func(position int)
 }
 
 func DefaultLockOptions() *LockOptions {
-	return &LockOptions{}
+	return &LockOptions{
+		Metadata: ProcessMetadata(),
+	}
 }
 
 func (o *LockOptions) Apply(opts ...LockOption) {
@@ -149,3 +330,82 @@ func WithTracer(tracer trace.Tracer) LockOption {
 		o.Tracer = tracer
 	}
 }
+
+func WithMetadata(md Metadata) LockOption {
+	return func(o *LockOptions) {
+		o.Metadata = md
+	}
+}
+
+func WithTTL(ttl time.Duration) LockOption {
+	return func(o *LockOptions) {
+		o.TTL = ttl
+	}
+}
+
+func WithKeepaliveInterval(interval time.Duration) LockOption {
+	return func(o *LockOptions) {
+		o.KeepaliveInterval = interval
+	}
+}
+
+func WithRetryDelay(delay time.Duration) LockOption {
+	return func(o *LockOptions) {
+		o.RetryDelay = delay
+	}
+}
+
+func WithFair(fair bool) LockOption {
+	return func(o *LockOptions) {
+		o.Fair = fair
+	}
+}
+
+func WithReentrant(reentrant bool) LockOption {
+	return func(o *LockOptions) {
+		o.Reentrant = reentrant
+	}
+}
+
+func WithReentrantToken(token string) LockOption {
+	return func(o *LockOptions) {
+		o.ReentrantToken = token
+	}
+}
+
+func WithOnQueued(onQueued func(position int)) LockOption {
+	return func(o *LockOptions) {
+		o.OnQueued = onQueued
+	}
+}
+
+// TTLOr returns the TTL of acquisitions, or the backend's default when unset
+func (o *LockOptions) TTLOr(def time.Duration) time.Duration {
+	return orDefault(o.TTL, def)
+}
+
+// KeepaliveIntervalOr returns the keepalive interval of acquisitions, or the backend's default when unset
+func (o *LockOptions) KeepaliveIntervalOr(def time.Duration) time.Duration {
+	return orDefault(o.KeepaliveInterval, def)
+}
+
+// RetryDelayOr returns the delay between the attempts of blocking acquisitions, or the backend's default when unset
+func (o *LockOptions) RetryDelayOr(def time.Duration) time.Duration {
+	return orDefault(o.RetryDelay, def)
+}
+
+func orDefault(d, def time.Duration) time.Duration {
+	if d > 0 {
+		return d
+	}
+	return def
+}
+
+// Holder returns the information stored by backends about an acquisition made with these options
+func (o *LockOptions) Holder(shared bool) HolderInfo {
+	return HolderInfo{
+		Metadata:   o.Metadata,
+		Shared:     shared,
+		AcquiredAt: time.Now(),
+	}
+}
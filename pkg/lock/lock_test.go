@@ -1,10 +1,15 @@
 package lock_test
 
 import (
+	"context"
 	"fmt"
+	"slices"
 	"sync"
+	"time"
 
+	"github.com/alexandreLamarre/dlock/internal/lock/backend/memory"
 	"github.com/alexandreLamarre/dlock/pkg/lock"
+	"github.com/alexandreLamarre/dlock/pkg/logger"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 )
@@ -78,4 +83,385 @@ var _ = Describe("Lock", Label("unit"), func() {
 		})
 	})
 
+	When("using multi locks", func() {
+		It("should acquire keys in their sorted order and release partial acquisitions", func() {
+			var mu sync.Mutex
+			events := []string{}
+			record := func(event string) {
+				mu.Lock()
+				defer mu.Unlock()
+				events = append(events, event)
+			}
+			m := lock.NewMultiLock([]string{"c", "a", "b", "a"}, func(key string) lock.Lock {
+				return &fakeLock{key: key, record: record, held: key == "c"}
+			})
+			Expect(m.Keys()).To(Equal([]string{"a", "b", "c"}))
+
+			acquired, _, err := m.TryLock(context.Background())
+			Expect(err).To(Succeed())
+			Expect(acquired).To(BeFalse())
+			Expect(events).To(Equal([]string{"lock a", "lock b", "lock c", "unlock b", "unlock a"}))
+		})
+
+		It("should expire once any of its locks expires", func() {
+			locks := map[string]*fakeLock{}
+			m := lock.NewMultiLock([]string{"a", "b"}, func(key string) lock.Lock {
+				locks[key] = &fakeLock{key: key, record: func(string) {}}
+				return locks[key]
+			})
+			done, err := m.Lock(context.Background())
+			Expect(err).To(Succeed())
+			Consistently(done).ShouldNot(Receive())
+			locks["b"].expired <- struct{}{}
+			Eventually(done).Should(Receive())
+		})
+	})
+
+	When("notifying queue positions", func() {
+		It("should notify positions once they change, until stopped", func() {
+			var mu sync.Mutex
+			notified := []int{}
+			options := lock.DefaultLockOptions()
+			options.Apply(lock.WithOnQueued(func(position int) {
+				mu.Lock()
+				defer mu.Unlock()
+				notified = append(notified, position)
+			}))
+			positions := []int{2, 2, 1, 1, 0}
+			stop := options.NotifyPosition(context.Background(), func(context.Context) (int, error) {
+				mu.Lock()
+				defer mu.Unlock()
+				if len(positions) == 0 {
+					return 0, lock.ErrNotQueued
+				}
+				position := positions[0]
+				positions = positions[1:]
+				return position, nil
+			})
+			Eventually(func() []int {
+				mu.Lock()
+				defer mu.Unlock()
+				return slices.Clone(notified)
+			}, 5*time.Second).Should(Equal([]int{2, 1, 0}))
+			stop()
+		})
+	})
+
+	When("using reentrant locks", func() {
+		reentrant := []lock.LockOption{
+			lock.WithReentrant(true),
+			lock.WithMetadata(lock.Metadata{Owner: "alice"}),
+			lock.WithReentrantToken("token"),
+		}
+		var lm *memory.LockManager
+		BeforeEach(func() {
+			lm = memory.NewLockManager(nil, logger.NewNop())
+		})
+		// newReentrant returns a reentrant lock of the owner & token on the key of the memory lock manager
+		newReentrant := func(r *lock.ReentrantLocks, key, token string) lock.Lock {
+			options := lock.DefaultLockOptions()
+			options.Apply(append(reentrant, lock.WithReentrantToken(token))...)
+			return r.NewLock(key, options, func() lock.Lock {
+				return lm.NewRWLock(key, lock.WithMetadata(options.Metadata))
+			})
+		}
+
+		It("should share a single acquisition between the locks of an owner", func() {
+			var mu sync.Mutex
+			events := []string{}
+			record := func(event string) {
+				mu.Lock()
+				defer mu.Unlock()
+				events = append(events, event)
+			}
+			options := lock.DefaultLockOptions()
+			options.Apply(reentrant...)
+			r := lock.NewReentrantLocks()
+			locks := make([]lock.Lock, 10)
+			for i := range locks {
+				locks[i] = r.NewLock("a", options, func() lock.Lock {
+					return &fakeLock{key: "a", record: record}
+				})
+			}
+
+			var wg sync.WaitGroup
+			for _, l := range locks {
+				wg.Add(1)
+				go func() {
+					defer GinkgoRecover()
+					defer wg.Done()
+					_, err := l.Lock(context.Background())
+					Expect(err).To(Succeed())
+				}()
+			}
+			wg.Wait()
+			Expect(events).To(Equal([]string{"lock a"}))
+
+			for _, l := range locks {
+				Expect(l.Unlock()).To(Succeed())
+			}
+			Eventually(func() []string {
+				mu.Lock()
+				defer mu.Unlock()
+				return slices.Clone(events)
+			}).Should(Equal([]string{"lock a", "unlock a"}))
+		})
+
+		It("should expire every hold once the acquisition expires", func() {
+			options := lock.DefaultLockOptions()
+			options.Apply(reentrant...)
+			r := lock.NewReentrantLocks()
+			fake := &fakeLock{key: "a", record: func(string) {}}
+			l := r.NewLock("a", options, func() lock.Lock {
+				return fake
+			})
+			done1, err := l.Lock(context.Background())
+			Expect(err).To(Succeed())
+			done2, err := l.Lock(context.Background())
+			Expect(err).To(Succeed())
+			Consistently(done1).ShouldNot(Receive())
+
+			fake.expired <- struct{}{}
+			Eventually(done1).Should(Receive())
+			Eventually(done2).Should(Receive())
+		})
+
+		It("should only reenter the locks of the same token", func() {
+			r := lock.NewReentrantLocks()
+			l := newReentrant(r, "a", "token")
+			done, err := l.Lock(context.Background())
+			Expect(err).To(Succeed())
+
+			acquired, _, err := newReentrant(r, "a", "other").TryLock(context.Background())
+			Expect(err).To(Succeed())
+			Expect(acquired).To(BeFalse())
+
+			_, err = newReentrant(r, "a", "").Lock(context.Background())
+			Expect(err).To(MatchError(lock.ErrReentrantToken))
+
+			Expect(l.Unlock()).To(Succeed())
+			Eventually(done).Should(Receive())
+		})
+
+		It("should not reenter the locks of other lock managers", func() {
+			ctx := context.Background()
+			a := newReentrant(lock.NewReentrantLocks(), "b", "token")
+			doneA, err := a.Lock(ctx)
+			Expect(err).To(Succeed())
+
+			// e.g. the same client presenting its token to another server
+			b := newReentrant(lock.NewReentrantLocks(), "b", "token")
+			acquired, _, err := b.TryLock(ctx)
+			Expect(err).To(Succeed())
+			Expect(acquired).To(BeFalse())
+
+			Expect(a.Unlock()).To(Succeed())
+			Eventually(doneA).Should(Receive())
+			acquired, doneB, err := b.TryLock(ctx)
+			Expect(err).To(Succeed())
+			Expect(acquired).To(BeTrue())
+			Expect(b.Unlock()).To(Succeed())
+			Eventually(doneB).Should(Receive())
+		})
+
+		It("should expire every hold once the acquisition is lost", func() {
+			ctx := context.Background()
+			r := lock.NewReentrantLocks()
+			a := newReentrant(r, "c", "token")
+			doneA, err := a.Lock(ctx)
+			Expect(err).To(Succeed())
+			b := newReentrant(r, "c", "token")
+			doneB, err := b.Lock(ctx)
+			Expect(err).To(Succeed())
+			Consistently(doneB).ShouldNot(Receive())
+
+			_, err = lm.ForceRelease(ctx, "c", "test")
+			Expect(err).To(Succeed())
+			Eventually(doneA).Should(Receive())
+			Eventually(doneB).Should(Receive())
+			Expect(a.Unlock()).To(Succeed())
+			Expect(b.Unlock()).To(Succeed())
+
+			By("acquiring the lock again once every hold was released")
+			doneA, err = a.Lock(ctx)
+			Expect(err).To(Succeed())
+			Expect(a.Unlock()).To(Succeed())
+			Eventually(doneA).Should(Receive())
+		})
+	})
+
+	When("watching locks", func() {
+		It("should only stream the events under the prefix of watches", func() {
+			ctx, ca := context.WithCancel(context.Background())
+			defer ca()
+			w := &lock.Watchers{}
+			events := w.Watch(ctx, "lock/", "a")
+			w.Publish(lock.WatchEvent{Type: lock.WatchAcquired, Key: "lock/b"})
+			w.Publish(lock.WatchEvent{Type: lock.WatchAcquired, Key: "lock/a"})
+			w.Publish(lock.WatchEvent{Type: lock.WatchReleased, Key: "lock/ab"})
+
+			var ev lock.WatchEvent
+			Eventually(events).Should(Receive(&ev))
+			Expect(ev).To(Equal(lock.WatchEvent{Type: lock.WatchAcquired, Key: "a"}))
+			Eventually(events).Should(Receive(&ev))
+			Expect(ev).To(Equal(lock.WatchEvent{Type: lock.WatchReleased, Key: "ab"}))
+			ca()
+			Eventually(events).Should(BeClosed())
+		})
+
+		It("should drop the watches falling too far behind", func() {
+			ctx, ca := context.WithCancel(context.Background())
+			defer ca()
+			size := lock.WatchBufferSize
+			lock.WatchBufferSize = 2
+			defer func() {
+				lock.WatchBufferSize = size
+			}()
+			w := &lock.Watchers{}
+			slow := w.Watch(ctx, "lock/", "")
+			for range 10 {
+				w.Publish(lock.WatchEvent{Type: lock.WatchAcquired, Key: "lock/a"})
+			}
+			received := 0
+			Eventually(func() bool {
+				_, ok := <-slow
+				if ok {
+					received++
+				}
+				return !ok
+			}).Should(BeTrue())
+			Expect(received).To(BeNumerically("<", 10))
+
+			By("streaming the events of the other watches")
+			events := w.Watch(ctx, "lock/", "")
+			w.Publish(lock.WatchEvent{Type: lock.WatchReleased, Key: "lock/a"})
+			Eventually(events).Should(Receive(Equal(lock.WatchEvent{Type: lock.WatchReleased, Key: "a"})))
+		})
+
+		It("should derive events from the descriptions of locks", func() {
+			ctx, ca := context.WithCancel(context.Background())
+			defer ca()
+			alice := lock.HolderInfo{Metadata: lock.Metadata{Owner: "alice"}, AcquiredAt: time.Unix(1, 0)}
+			bob := lock.HolderInfo{Metadata: lock.Metadata{Owner: "bob"}, AcquiredAt: time.Unix(2, 0)}
+			lm := &describedLocks{infos: map[string]lock.LockInfo{
+				"a": {Key: "a", Holders: []lock.HolderInfo{alice}},
+			}}
+			resync := lock.WatchResyncInterval
+			lock.WatchResyncInterval = time.Second
+			defer func() {
+				lock.WatchResyncInterval = resync
+			}()
+			changes := make(chan lock.WatchChange)
+			events, err := lock.WatchDescriptions(ctx, lm, "", changes)
+			Expect(err).To(Succeed())
+			Consistently(events).ShouldNot(Receive())
+
+			// remaining TTLs are not changes
+			alice.TTL = time.Second
+			lm.set(lock.LockInfo{Key: "a", Holders: []lock.HolderInfo{alice}})
+			changes <- lock.WatchChange{Key: "a"}
+			Consistently(events).ShouldNot(Receive())
+
+			lm.set(lock.LockInfo{Key: "a", Holders: []lock.HolderInfo{bob}})
+			changes <- lock.WatchChange{Key: "a", Expired: true}
+			var ev lock.WatchEvent
+			Eventually(events).Should(Receive(&ev))
+			Expect(ev.Type).To(Equal(lock.WatchExpired))
+			Expect(ev.Holder.Owner).To(Equal("alice"))
+			Eventually(events).Should(Receive(&ev))
+			Expect(ev.Type).To(Equal(lock.WatchAcquired))
+			Expect(ev.Holder.Owner).To(Equal("bob"))
+
+			// unnotified changes are noticed when every lock is described again
+			lm.set(lock.LockInfo{Key: "a"})
+			Eventually(events, 5*time.Second).Should(Receive(&ev))
+			Expect(ev.Type).To(Equal(lock.WatchReleased))
+			Expect(ev.Holder.Owner).To(Equal("bob"))
+		})
+
+		It("should describe locks again once their holders expire", func() {
+			ctx, ca := context.WithCancel(context.Background())
+			defer ca()
+			alice := lock.HolderInfo{Metadata: lock.Metadata{Owner: "alice"}, AcquiredAt: time.Unix(1, 0), TTL: time.Second}
+			lm := &describedLocks{infos: map[string]lock.LockInfo{
+				"a": {Key: "a", Holders: []lock.HolderInfo{alice}},
+			}}
+			events, err := lock.WatchDescriptions(ctx, lm, "", make(chan lock.WatchChange))
+			Expect(err).To(Succeed())
+
+			// extensions that were not notified are noticed once the TTL elapsed
+			Consistently(events, 500*time.Millisecond).ShouldNot(Receive())
+			lm.set(lock.LockInfo{Key: "a", Holders: []lock.HolderInfo{alice}})
+			Consistently(events, time.Second).ShouldNot(Receive())
+
+			lm.set(lock.LockInfo{Key: "a"})
+			var ev lock.WatchEvent
+			Eventually(events, 2*time.Second).Should(Receive(&ev))
+			Expect(ev.Type).To(Equal(lock.WatchExpired))
+			Expect(ev.Holder.Owner).To(Equal("alice"))
+		})
+	})
 })
+
+// describedLocks only implements the introspection of a lock manager
+type describedLocks struct {
+	lock.LockManager
+
+	mu    sync.Mutex
+	infos map[string]lock.LockInfo
+}
+
+func (d *describedLocks) set(info lock.LockInfo) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.infos[info.Key] = info
+}
+
+func (d *describedLocks) ListLocks(_ context.Context, _ string) ([]lock.LockInfo, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	infos := []lock.LockInfo{}
+	for _, info := range d.infos {
+		if len(info.Holders) > 0 {
+			infos = append(infos, info)
+		}
+	}
+	return infos, nil
+}
+
+func (d *describedLocks) DescribeLock(_ context.Context, key string) (lock.LockInfo, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.infos[key], nil
+}
+
+// fakeLock records its operations, and is never acquired when held is set
+type fakeLock struct {
+	key     string
+	record  func(event string)
+	held    bool
+	expired chan struct{}
+}
+
+func (f *fakeLock) Lock(ctx context.Context) (<-chan struct{}, error) {
+	_, expired, err := f.TryLock(ctx)
+	return expired, err
+}
+
+func (f *fakeLock) TryLock(_ context.Context) (bool, <-chan struct{}, error) {
+	f.record("lock " + f.key)
+	if f.held {
+		return false, nil, nil
+	}
+	f.expired = make(chan struct{}, 1)
+	return true, f.expired, nil
+}
+
+func (f *fakeLock) Unlock() error {
+	f.record("unlock " + f.key)
+	return nil
+}
+
+func (f *fakeLock) FencingToken() uint64 {
+	return 0
+}
@@ -0,0 +1,112 @@
+package lock
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+)
+
+var ErrNoKeys = errors.New("multi lock requires at least one key")
+
+// MultiLock acquires the locks of several keys all-or-nothing. Keys are deduplicated and acquired in their
+// sorted order, so that multi locks sharing keys never deadlock each other, and the locks already acquired are
+// released when the next one can't be acquired.
+//
+// Its expired channel receives a value once any of its locks expires or is unlocked.
+type MultiLock struct {
+	keys  []string
+	locks []Lock
+}
+
+var _ Lock = (*MultiLock)(nil)
+
+// NewMultiLock returns the multi lock of the keys, the lock of each key being created by newLock
+func NewMultiLock(keys []string, newLock func(key string) Lock) *MultiLock {
+	keys = slices.Compact(slices.Sorted(slices.Values(keys)))
+	locks := make([]Lock, len(keys))
+	for i, key := range keys {
+		locks[i] = newLock(key)
+	}
+	return &MultiLock{
+		keys:  keys,
+		locks: locks,
+	}
+}
+
+// Keys returns the keys of the multi lock, in the order they are acquired
+func (m *MultiLock) Keys() []string {
+	return slices.Clone(m.keys)
+}
+
+func (m *MultiLock) Lock(ctx context.Context) (<-chan struct{}, error) {
+	if len(m.locks) == 0 {
+		return nil, ErrNoKeys
+	}
+	expired := make([]<-chan struct{}, 0, len(m.locks))
+	for _, l := range m.locks {
+		done, err := l.Lock(ctx)
+		if err != nil {
+			return nil, errors.Join(err, m.release(len(expired)))
+		}
+		expired = append(expired, done)
+	}
+	return merge(expired), nil
+}
+
+func (m *MultiLock) TryLock(ctx context.Context) (bool, <-chan struct{}, error) {
+	if len(m.locks) == 0 {
+		return false, nil, ErrNoKeys
+	}
+	expired := make([]<-chan struct{}, 0, len(m.locks))
+	for _, l := range m.locks {
+		acquired, done, err := l.TryLock(ctx)
+		if err != nil || !acquired {
+			return false, nil, errors.Join(err, m.release(len(expired)))
+		}
+		expired = append(expired, done)
+	}
+	return true, merge(expired), nil
+}
+
+func (m *MultiLock) Unlock() error {
+	return m.release(len(m.locks))
+}
+
+// release unlocks the first n locks, in the reverse order they were acquired
+func (m *MultiLock) release(n int) error {
+	var errs error
+	for i := n - 1; i >= 0; i-- {
+		errs = errors.Join(errs, m.locks[i].Unlock())
+	}
+	return errs
+}
+
+// FencingToken returns 0, since each lock issues its own fencing token.
+func (m *MultiLock) FencingToken() uint64 {
+	return 0
+}
+
+// FencingTokens returns the fencing tokens issued for the current acquisition of each key, in the order of Keys
+func (m *MultiLock) FencingTokens() []uint64 {
+	tokens := make([]uint64, len(m.locks))
+	for i, l := range m.locks {
+		tokens[i] = l.FencingToken()
+	}
+	return tokens
+}
+
+// merge returns a channel receiving a single value once any of the channels receives one
+func merge(chans []<-chan struct{}) <-chan struct{} {
+	merged := make(chan struct{}, 1)
+	var once sync.Once
+	for _, c := range chans {
+		go func() {
+			<-c
+			once.Do(func() {
+				merged <- struct{}{}
+			})
+		}()
+	}
+	return merged
+}
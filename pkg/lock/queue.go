@@ -0,0 +1,51 @@
+package lock
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var ErrNotQueued = errors.New("acquisition is not waiting for the lock")
+
+var (
+	// PositionInterval is the interval at which waiting acquisitions check their position in the queue of the lock
+	PositionInterval = 250 * time.Millisecond
+)
+
+// NotifyPosition calls OnQueued with the position returned by position until the returned func is called,
+// once the acquisition is queued and then every time its position changes. Backends return ErrNotQueued
+// when the acquisition is not registered as a waiter, and positions are not reported on errors.
+//
+// The position of acquisitions that are not fair is the number of acquisitions that started waiting before them,
+// they may still be overtaken.
+//
+// The returned func returns once OnQueued is no longer called.
+func (o *LockOptions) NotifyPosition(ctx context.Context, position func(ctx context.Context) (int, error)) (stop func()) {
+	if o.OnQueued == nil {
+		return func() {}
+	}
+	ctx, ca := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		t := time.NewTicker(PositionInterval)
+		defer t.Stop()
+		last := -1
+		for {
+			if p, err := position(ctx); err == nil && p != last {
+				last = p
+				o.OnQueued(p)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+			}
+		}
+	}()
+	return func() {
+		ca()
+		<-done
+	}
+}
@@ -0,0 +1,262 @@
+package lock
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"maps"
+	"slices"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrReentrantOwner = errors.New("reentrant locks require an owner")
+	ErrReentrantToken = errors.New("reentrant locks require a token")
+)
+
+// ReentrantLabel labels the acquisitions of reentrant locks with the digest of their token
+const ReentrantLabel = "dlock.reentrant"
+
+// NewReentrantToken returns a random token, scoping the reentrancy of the locks it is given to
+func NewReentrantToken() string {
+	return uuid.NewString()
+}
+
+func reentrantDigest(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// HoldsReentrant reports whether the lock described by info is held by the reentrant locks of the owner & token
+func HoldsReentrant(info LockInfo, owner, token string) bool {
+	digest := reentrantDigest(token)
+	return slices.ContainsFunc(info.Holders, func(h HolderInfo) bool {
+		return !h.Shared && h.Owner == owner && h.Labels[ReentrantLabel] == digest
+	})
+}
+
+// ReentrantLocks tracks the reentrant locks of a lock manager. Reentrant locks of the same owner & token on the
+// same key share a single acquisition of the backend's lock, labeled with the digest of the token, so that only
+// the last unlock of any of them releases it.
+//
+// Holds are counted in the lock manager, reentrant locks of other lock managers, e.g. of other servers, presenting
+// the same owner & token are other holders of the lock.
+type ReentrantLocks struct {
+	mu           sync.Mutex
+	acquisitions map[reentrantKey]*reentrantAcquisition
+}
+
+func NewReentrantLocks() *ReentrantLocks {
+	return &ReentrantLocks{
+		acquisitions: map[reentrantKey]*reentrantAcquisition{},
+	}
+}
+
+type reentrantKey struct {
+	key   string
+	owner string
+	token string
+}
+
+// reentrantAcquisition is a single acquisition of the backend's lock, shared by the holds of its owner & token
+// in the lock manager
+type reentrantAcquisition struct {
+	l     Lock
+	holds []*reentrantHold
+	// closed once the first acquisition completed, acquired being set when it succeeded
+	ready    chan struct{}
+	acquired bool
+}
+
+// reentrantHold is a single hold of a reentrant acquisition
+type reentrantHold struct {
+	acquisition *reentrantAcquisition
+	// fires once the hold is unlocked or the acquisition expires
+	expired chan struct{}
+	once    sync.Once
+}
+
+func (h *reentrantHold) expire() {
+	h.once.Do(func() {
+		h.expired <- struct{}{}
+	})
+}
+
+// NewLock returns the lock created by newLock, or the reentrant lock of the owner & token of the options
+// on the key when they are reentrant.
+//
+// The acquisitions of reentrant locks are labeled with the digest of their token, newLock must create the lock
+// with the options once NewLock returned.
+func (r *ReentrantLocks) NewLock(key string, opts *LockOptions, newLock func() Lock) Lock {
+	if !opts.Reentrant {
+		return newLock()
+	}
+	if opts.ReentrantToken != "" {
+		labels := maps.Clone(opts.Metadata.Labels)
+		if labels == nil {
+			labels = map[string]string{}
+		}
+		labels[ReentrantLabel] = reentrantDigest(opts.ReentrantToken)
+		opts.Metadata.Labels = labels
+	}
+	return &reentrantLock{
+		locks:   r,
+		key:     reentrantKey{key: key, owner: opts.Metadata.Owner, token: opts.ReentrantToken},
+		newLock: newLock,
+	}
+}
+
+// hold counts a new hold of the acquisition, r.mu must be held
+func (r *ReentrantLocks) hold(a *reentrantAcquisition) *reentrantHold {
+	h := &reentrantHold{acquisition: a, expired: make(chan struct{}, 1)}
+	a.holds = append(a.holds, h)
+	return h
+}
+
+// expire expires every hold of the acquisition once the backend's lock expires or is released
+func (r *ReentrantLocks) expire(key reentrantKey, a *reentrantAcquisition, expired <-chan struct{}) {
+	<-expired
+	r.mu.Lock()
+	if r.acquisitions[key] == a {
+		delete(r.acquisitions, key)
+	}
+	holds := slices.Clone(a.holds)
+	r.mu.Unlock()
+	for _, h := range holds {
+		h.expire()
+	}
+}
+
+// release releases the hold, unlocking the backend's lock once its last hold in the lock manager is released
+func (r *ReentrantLocks) release(key reentrantKey, h *reentrantHold) error {
+	h.expire()
+	a := h.acquisition
+	r.mu.Lock()
+	a.holds = slices.DeleteFunc(a.holds, func(other *reentrantHold) bool {
+		return other == h
+	})
+	if len(a.holds) > 0 {
+		r.mu.Unlock()
+		return nil
+	}
+	if r.acquisitions[key] == a {
+		delete(r.acquisitions, key)
+	}
+	r.mu.Unlock()
+	return a.l.Unlock()
+}
+
+// reentrantLock can be locked again by the same instance, or by the other reentrant locks of its owner & token
+// in the lock manager. Each unlock releases the last hold of the instance.
+type reentrantLock struct {
+	locks   *ReentrantLocks
+	key     reentrantKey
+	newLock func() Lock
+
+	mu    sync.Mutex
+	holds []*reentrantHold
+}
+
+var _ Lock = (*reentrantLock)(nil)
+
+func (r *reentrantLock) Lock(ctx context.Context) (<-chan struct{}, error) {
+	_, expired, err := r.acquire(ctx, true)
+	return expired, err
+}
+
+func (r *reentrantLock) TryLock(ctx context.Context) (bool, <-chan struct{}, error) {
+	return r.acquire(ctx, false)
+}
+
+// acquire joins the acquisition of the owner & token if it holds the lock, or acquires the backend's lock otherwise
+func (r *reentrantLock) acquire(ctx context.Context, block bool) (bool, <-chan struct{}, error) {
+	if r.key.owner == "" {
+		return false, nil, ErrReentrantOwner
+	}
+	if r.key.token == "" {
+		return false, nil, ErrReentrantToken
+	}
+	for {
+		r.locks.mu.Lock()
+		a, ok := r.locks.acquisitions[r.key]
+		if ok {
+			r.locks.mu.Unlock()
+			// another lock of the owner is acquiring the backend's lock
+			select {
+			case <-a.ready:
+			case <-ctx.Done():
+				return false, nil, ctx.Err()
+			}
+			r.locks.mu.Lock()
+			if !a.acquired || r.locks.acquisitions[r.key] != a {
+				r.locks.mu.Unlock()
+				continue
+			}
+			h := r.locks.hold(a)
+			r.locks.mu.Unlock()
+			return true, r.push(h), nil
+		}
+		a = &reentrantAcquisition{
+			l:     r.newLock(),
+			ready: make(chan struct{}),
+		}
+		r.locks.acquisitions[r.key] = a
+		r.locks.mu.Unlock()
+
+		var acquired bool
+		var expired <-chan struct{}
+		var err error
+		if block {
+			expired, err = a.l.Lock(ctx)
+			acquired = err == nil
+		} else {
+			acquired, expired, err = a.l.TryLock(ctx)
+		}
+		r.locks.mu.Lock()
+		var h *reentrantHold
+		if acquired {
+			a.acquired = true
+			h = r.locks.hold(a)
+			go r.locks.expire(r.key, a, expired)
+		} else {
+			delete(r.locks.acquisitions, r.key)
+		}
+		close(a.ready)
+		r.locks.mu.Unlock()
+		if !acquired {
+			return false, nil, err
+		}
+		return true, r.push(h), nil
+	}
+}
+
+func (r *reentrantLock) push(h *reentrantHold) <-chan struct{} {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.holds = append(r.holds, h)
+	return h.expired
+}
+
+func (r *reentrantLock) Unlock() error {
+	r.mu.Lock()
+	if len(r.holds) == 0 {
+		r.mu.Unlock()
+		return ErrLockScheduled
+	}
+	held := r.holds[len(r.holds)-1]
+	r.holds = r.holds[:len(r.holds)-1]
+	r.mu.Unlock()
+	return r.locks.release(r.key, held)
+}
+
+func (r *reentrantLock) FencingToken() uint64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.holds) == 0 {
+		return 0
+	}
+	return r.holds[len(r.holds)-1].acquisition.l.FencingToken()
+}
@@ -0,0 +1,24 @@
+package lock
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	ErrNoTransferee = errors.New("no blocking acquisition of the owner is waiting for the lock")
+	ErrNotHeld      = errors.New("lock is no longer held")
+)
+
+// Transferer is implemented by the locks whose exclusive acquisitions can be handed over to a waiter
+type Transferer interface {
+	// Transfer atomically reassigns the exclusive acquisition of the lock to the earliest blocking exclusive
+	// acquisition of the owner waiting for it, which acquires the lock with a new fencing token. The lock is never
+	// released in between, so no other acquisition can overtake the waiter, even when locks are fair.
+	//
+	// Once transferred, the lock is no longer held like after Unlock, and its expired channel fires.
+	// It returns ErrNoTransferee when the owner has no such waiter, in which case the lock is still held,
+	// and ErrNotHeld when the acquisition was already lost, in which case the lock must still be unlocked.
+	// Shared acquisitions can't be transferred and return ErrLockMode.
+	Transfer(ctx context.Context, toOwner string) error
+}
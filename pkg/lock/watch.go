@@ -0,0 +1,356 @@
+package lock
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// WatchInterval is the interval at which watches describe every lock they watch again,
+	// when their backend can't notify them of changes
+	WatchInterval = time.Second
+	// WatchResyncInterval is the interval at which watches describe every lock they watch again,
+	// when their backend notifies them of changes, so that missed notifications are eventually noticed
+	WatchResyncInterval = time.Minute
+	// WatchBufferSize is the number of events buffered for each watch of Watchers, watches falling further behind
+	// are dropped
+	WatchBufferSize = 1024
+)
+
+type WatchEventType int
+
+const (
+	// WatchAcquired is sent once a holder acquired a lock
+	WatchAcquired WatchEventType = iota
+	// WatchReleased is sent once a holder released a lock
+	WatchReleased
+	// WatchExpired is sent once a holder lost a lock without releasing it, when its acquisition expired
+	// or was force released
+	WatchExpired
+)
+
+func (t WatchEventType) String() string {
+	switch t {
+	case WatchAcquired:
+		return "acquired"
+	case WatchReleased:
+		return "released"
+	case WatchExpired:
+		return "expired"
+	default:
+		return fmt.Sprintf("WatchEventType(%d)", int(t))
+	}
+}
+
+// WatchEvent is a change of the holders of a lock, semaphores are not watched
+type WatchEvent struct {
+	Type   WatchEventType
+	Key    string
+	Holder HolderInfo
+}
+
+// Watchers fans the watch events of a backend out to its watches. Events are buffered for each watch,
+// so that publishing them never blocks the backend. Watches whose buffer exceeds WatchBufferSize are dropped,
+// discarding their events and closing their channel. The zero value is ready to use.
+type Watchers struct {
+	mu      sync.Mutex
+	watches map[*watch]struct{}
+}
+
+type watch struct {
+	// keys of the watched locks start with the prefix, the strip part of it being trimmed from events
+	prefix string
+	strip  string
+
+	mu     sync.Mutex
+	events []WatchEvent
+	// set once the watch fell more than WatchBufferSize events behind
+	dropped bool
+	notify  chan struct{}
+}
+
+// Watch streams the events of the locks whose key starts with strip+prefix until the context is done,
+// strip being trimmed from the key of events
+func (w *Watchers) Watch(ctx context.Context, strip, prefix string) <-chan WatchEvent {
+	wt := &watch{
+		prefix: strip + prefix,
+		strip:  strip,
+		notify: make(chan struct{}, 1),
+	}
+	w.mu.Lock()
+	if w.watches == nil {
+		w.watches = map[*watch]struct{}{}
+	}
+	w.watches[wt] = struct{}{}
+	w.mu.Unlock()
+
+	ch := make(chan WatchEvent)
+	go func() {
+		defer close(ch)
+		defer func() {
+			w.mu.Lock()
+			defer w.mu.Unlock()
+			delete(w.watches, wt)
+		}()
+		for {
+			wt.mu.Lock()
+			events, dropped := wt.events, wt.dropped
+			wt.events = nil
+			wt.mu.Unlock()
+			if dropped {
+				return
+			}
+			for _, ev := range events {
+				select {
+				case ch <- ev:
+				case <-ctx.Done():
+					return
+				}
+			}
+			select {
+			case <-wt.notify:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch
+}
+
+// Publish buffers the event for the watches of its key, dropping the watches whose buffer is full
+func (w *Watchers) Publish(ev WatchEvent) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for wt := range w.watches {
+		if !strings.HasPrefix(ev.Key, wt.prefix) {
+			continue
+		}
+		stripped := ev
+		stripped.Key = strings.TrimPrefix(ev.Key, wt.strip)
+		wt.mu.Lock()
+		if len(wt.events) >= WatchBufferSize {
+			wt.events = nil
+			wt.dropped = true
+			delete(w.watches, wt)
+		} else {
+			wt.events = append(wt.events, stripped)
+		}
+		wt.mu.Unlock()
+		select {
+		case wt.notify <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// WatchChange notifies that the holders of the lock on the key may have changed, the holders no longer holding it
+// having expired when Expired is set
+type WatchChange struct {
+	Key     string
+	Expired bool
+}
+
+// WatchDescriptions derives the watch events of the locks under the prefix from successive descriptions of them,
+// for backends that are only notified that a lock changed. The lock of each change is described again, as is each
+// lock once the TTL of its holders elapsed, its holders having expired unless they were extended in between.
+// Every lock under the prefix is described again every WatchResyncInterval, or every WatchInterval when changes is
+// nil or closed, i.e. when the backend can't notify changes. Acquisitions shorter than the time between two
+// descriptions of their lock are not reported, and holders are released unless a change reports them expired.
+//
+// Backends should start watching for changes before calling it, since the current holders of the locks are
+// described when it is called.
+func WatchDescriptions(
+	ctx context.Context,
+	lm LockManager,
+	prefix string,
+	changes <-chan WatchChange,
+) (<-chan WatchEvent, error) {
+	infos, err := lm.ListLocks(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+	d := holderDiff{}
+	d.resync(infos)
+	expiries := holderExpiries{}
+	for _, info := range infos {
+		expiries.track(info)
+	}
+	ch := make(chan WatchEvent)
+	go func() {
+		defer close(ch)
+		interval := WatchResyncInterval
+		if changes == nil {
+			interval = WatchInterval
+		}
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		expiry := time.NewTimer(expiries.next())
+		defer expiry.Stop()
+		for {
+			var events []WatchEvent
+			select {
+			case <-ctx.Done():
+				return
+			case change, ok := <-changes:
+				if !ok {
+					changes = nil
+					t.Reset(WatchInterval)
+					continue
+				}
+				if !strings.HasPrefix(change.Key, prefix) {
+					continue
+				}
+				info, err := lm.DescribeLock(ctx, change.Key)
+				if err != nil {
+					continue
+				}
+				expiries.track(info)
+				events = d.update(info, change.Expired)
+			case <-expiry.C:
+				for _, key := range expiries.elapsed() {
+					info, err := lm.DescribeLock(ctx, key)
+					if err != nil {
+						// described again by the next resync
+						continue
+					}
+					expiries.track(info)
+					events = append(events, d.update(info, true)...)
+				}
+			case <-t.C:
+				infos, err := lm.ListLocks(ctx, prefix)
+				if err != nil {
+					continue
+				}
+				for key := range d {
+					expiries.forget(key)
+				}
+				for _, info := range infos {
+					expiries.track(info)
+				}
+				events = d.resync(infos)
+			}
+			expiry.Reset(expiries.next())
+			for _, ev := range events {
+				select {
+				case ch <- ev:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return ch, nil
+}
+
+// holderExpiries tracks the time at which the first holder of each lock expires, unless it is extended
+type holderExpiries map[string]time.Time
+
+func (e holderExpiries) track(info LockInfo) {
+	var ttl time.Duration
+	for _, h := range info.Holders {
+		if h.TTL > 0 && (ttl == 0 || h.TTL < ttl) {
+			ttl = h.TTL
+		}
+	}
+	if ttl == 0 {
+		delete(e, info.Key)
+		return
+	}
+	e[info.Key] = time.Now().Add(ttl)
+}
+
+func (e holderExpiries) forget(key string) {
+	delete(e, key)
+}
+
+// elapsed returns & forgets the locks whose first holder expired
+func (e holderExpiries) elapsed() []string {
+	now := time.Now()
+	keys := []string{}
+	for key, at := range e {
+		if !at.After(now) {
+			keys = append(keys, key)
+			delete(e, key)
+		}
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// next returns the time until the first holder of any lock expires
+func (e holderExpiries) next() time.Duration {
+	if len(e) == 0 {
+		return WatchResyncInterval
+	}
+	next := slices.MinFunc(slices.Collect(maps.Values(e)), func(a, b time.Time) int {
+		return a.Compare(b)
+	})
+	return max(time.Until(next), 0)
+}
+
+// holderDiff tracks the last described holders of each lock
+type holderDiff map[string][]HolderInfo
+
+// holderID identifies an acquisition, regardless of its remaining TTL
+func holderID(h HolderInfo) string {
+	return fmt.Sprintf("%d/%t/%s/%s/%d", h.AcquiredAt.UnixNano(), h.Shared, h.Owner, h.Hostname, h.Pid)
+}
+
+// update returns the events of the lock since its last description, releases first
+func (d holderDiff) update(info LockInfo, expired bool) []WatchEvent {
+	prev := map[string]HolderInfo{}
+	for _, h := range d[info.Key] {
+		prev[holderID(h)] = h
+	}
+	events := []WatchEvent{}
+	acquired := []WatchEvent{}
+	for _, h := range info.Holders {
+		h.TTL = 0
+		id := holderID(h)
+		if _, ok := prev[id]; ok {
+			delete(prev, id)
+			continue
+		}
+		acquired = append(acquired, WatchEvent{Type: WatchAcquired, Key: info.Key, Holder: h})
+	}
+	released := WatchReleased
+	if expired {
+		released = WatchExpired
+	}
+	for _, id := range slices.Sorted(maps.Keys(prev)) {
+		events = append(events, WatchEvent{Type: released, Key: info.Key, Holder: prev[id]})
+	}
+	if len(info.Holders) == 0 {
+		delete(d, info.Key)
+	} else {
+		holders := make([]HolderInfo, len(info.Holders))
+		for i, h := range info.Holders {
+			h.TTL = 0
+			holders[i] = h
+		}
+		d[info.Key] = holders
+	}
+	return append(events, acquired...)
+}
+
+// resync returns the events of every lock since their last description, locks that are not described
+// being no longer held
+func (d holderDiff) resync(infos []LockInfo) []WatchEvent {
+	described := map[string]struct{}{}
+	events := []WatchEvent{}
+	for _, info := range infos {
+		described[info.Key] = struct{}{}
+		events = append(events, d.update(info, false)...)
+	}
+	for _, key := range slices.Sorted(maps.Keys(d)) {
+		if _, ok := described[key]; !ok {
+			events = append(events, d.update(LockInfo{Key: key}, false)...)
+		}
+	}
+	return events
+}
@@ -0,0 +1,68 @@
+package server
+
+import (
+	"context"
+
+	"github.com/alexandreLamarre/dlock/api/v1alpha1"
+	"github.com/alexandreLamarre/dlock/pkg/auth"
+	"github.com/alexandreLamarre/dlock/pkg/logger"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// adminServer serves the operator APIs of a LockServer
+type adminServer struct {
+	*LockServer
+	v1alpha1.UnimplementedDlockAdminServer
+}
+
+var _ v1alpha1.DlockAdminServer = &adminServer{}
+
+func (s *adminServer) ForceRelease(ctx context.Context, in *v1alpha1.ForceReleaseRequest) (*v1alpha1.ForceReleaseResponse, error) {
+	if s.lm == nil {
+		s.lg.Error("no lock backend")
+		return nil, status.Errorf(codes.Unavailable, "no lock backend")
+	}
+	if err := in.Validate(); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	ns, err := s.namespace(in.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	if s.auth == nil {
+		return nil, status.Error(codes.PermissionDenied, "force releases require server auth")
+	}
+	if err := s.auth.check(ctx, v1alpha1.DlockAdmin_ForceRelease_FullMethodName, []auth.Request{{
+		Namespace: ns.name,
+		Key:       in.Key,
+		Operation: auth.OpForceRelease,
+	}}); err != nil {
+		return nil, err
+	}
+	caller := "unknown"
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		caller = p.Addr.String()
+	}
+	lg := s.lg.WithGroup("audit").With(
+		"op", "ForceRelease", "namespace", ns.name, "key", in.Key, "reason", in.Reason, "caller", caller,
+	)
+	if identity, ok := auth.IdentityFromContext(ctx); ok {
+		lg = lg.With("identity", identity)
+	}
+	info, err := ns.lm.ForceRelease(ctx, in.Key, in.Reason)
+	if err != nil {
+		lg.With(logger.Err(err)).Error("failed to force release lock")
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	owners := make([]string, 0, len(info.Holders))
+	for _, h := range info.Holders {
+		owners = append(owners, h.Owner)
+	}
+	lg.With("evicted", owners).Warn("force released lock")
+	LockForceReleaseCount.Add(ctx, 1)
+	return &v1alpha1.ForceReleaseResponse{
+		Evicted: lockInfo(info).Holders,
+	}, nil
+}
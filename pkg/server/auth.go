@@ -0,0 +1,275 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/alexandreLamarre/dlock/api/v1alpha1"
+	"github.com/alexandreLamarre/dlock/pkg/auth"
+	configv1alpha1 "github.com/alexandreLamarre/dlock/pkg/config/v1alpha1"
+	"github.com/alexandreLamarre/dlock/pkg/lock"
+	"github.com/alexandreLamarre/dlock/pkg/logger"
+	"github.com/samber/lo"
+	"go.opentelemetry.io/otel/attribute"
+	api "go.opentelemetry.io/otel/metric"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthv1 "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// serverAuth authenticates the clients of the lock server & authorizes their requests
+type serverAuth struct {
+	lg    *slog.Logger
+	authn auth.Authenticator
+	authz *auth.Authorizer
+}
+
+// newServerAuth returns the authentication & authorization of requests, or nil when they are not configured
+func newServerAuth(spec *configv1alpha1.ServerSpec, lg *slog.Logger) (*serverAuth, error) {
+	if spec == nil || spec.Auth == nil {
+		return nil, nil
+	}
+	authns := auth.Authenticators{}
+	if spec.Auth.MTLS {
+		if spec.TLS == nil || spec.TLS.ClientAuth == "" || spec.TLS.ClientAuth == "none" {
+			return nil, errors.New("mtls authentication requires server.tls.clientAuth to verify client certificates")
+		}
+		authns = append(authns, auth.MTLSAuthenticator{})
+	}
+	if len(spec.Auth.Tokens) > 0 {
+		tokens := map[string]string{}
+		for _, token := range spec.Auth.Tokens {
+			if _, ok := tokens[token.Token]; ok {
+				return nil, fmt.Errorf("duplicate token of identity %s", token.Identity)
+			}
+			tokens[token.Token] = token.Identity
+		}
+		authn, err := auth.NewTokenAuthenticator(tokens)
+		if err != nil {
+			return nil, err
+		}
+		authns = append(authns, authn)
+	}
+	if jwt := spec.Auth.JWT; jwt != nil {
+		jwks, err := os.ReadFile(jwt.JWKSFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read JWKS : %w", err)
+		}
+		authn, err := auth.NewJWTAuthenticator(jwks, auth.JWTOptions{
+			Issuer:        jwt.Issuer,
+			Audience:      jwt.Audience,
+			IdentityClaim: jwt.IdentityClaim,
+		})
+		if err != nil {
+			return nil, err
+		}
+		authns = append(authns, authn)
+	}
+	if len(authns) == 0 {
+		return nil, errors.New("auth must configure at least one of mtls, tokens or jwt")
+	}
+	authz, err := auth.NewAuthorizer(lo.Map(spec.Auth.Policies, func(p configv1alpha1.PolicySpec, _ int) auth.Policy {
+		return auth.Policy{
+			Identities: p.Identities,
+			Namespaces: p.Namespaces,
+			Prefixes:   p.Prefixes,
+			Operations: lo.Map(p.Operations, func(op string, _ int) auth.Operation {
+				return auth.Operation(op)
+			}),
+		}
+	}))
+	if err != nil {
+		return nil, err
+	}
+	return &serverAuth{
+		lg:    lg.WithGroup("auth"),
+		authn: authns,
+		authz: authz,
+	}, nil
+}
+
+// skipAuth is true for the methods any client can call, i.e. health checks
+func skipAuth(method string) bool {
+	return strings.HasPrefix(method, "/"+healthv1.Health_ServiceDesc.ServiceName+"/")
+}
+
+// authRequests returns the accesses of a request to its keys, and false for requests that are never authorized.
+// Requests on leases were authorized when the lease was acquired, and are only allowed to the identity that acquired
+// it by their handlers. Requests on submitted graphs are authorized on the keys of the graph's nodes by their
+// handlers, the keys being unknown until the graph is looked up.
+func authRequests(req any) ([]auth.Request, bool) {
+	ns := func(name string) string {
+		return lo.Ternary(name == "", defaultNamespace, name)
+	}
+	lockOp := func(try bool) auth.Operation {
+		return lo.Ternary(try, auth.OpTryLock, auth.OpLock)
+	}
+	switch in := req.(type) {
+	case *v1alpha1.LockRequest:
+		return lo.Map(lockKeys(in), func(key string, _ int) auth.Request {
+			return auth.Request{Namespace: ns(in.Namespace), Key: key, Operation: lockOp(in.TryLock)}
+		}), true
+	case *v1alpha1.AcquireRequest:
+		return []auth.Request{{Namespace: ns(in.Namespace), Key: in.Key, Operation: lockOp(in.TryLock)}}, true
+	case *v1alpha1.SemaphoreRequest:
+		return []auth.Request{{Namespace: ns(in.Namespace), Key: in.Key, Operation: lockOp(in.TryAcquire)}}, true
+	case *v1alpha1.CampaignRequest:
+		return []auth.Request{{Namespace: defaultNamespace, Key: in.Name, Operation: auth.OpLock}}, true
+	case *v1alpha1.SubmitGraphRequest:
+		return lo.Map(in.Nodes, func(node *v1alpha1.GraphNode, _ int) auth.Request {
+			return auth.Request{Namespace: ns(in.Namespace), Key: node.Key, Operation: auth.OpLock}
+		}), true
+	case *v1alpha1.ForceReleaseRequest:
+		return []auth.Request{{Namespace: ns(in.Namespace), Key: in.Key, Operation: auth.OpForceRelease}}, true
+	case *v1alpha1.ListLocksRequest:
+		return []auth.Request{{Namespace: ns(in.Namespace), Key: in.Prefix, Operation: auth.OpRead}}, true
+	case *v1alpha1.DescribeLockRequest:
+		return []auth.Request{{Namespace: ns(in.Namespace), Key: in.Key, Operation: auth.OpRead}}, true
+	case *v1alpha1.WatchRequest:
+		return []auth.Request{{Namespace: ns(in.Namespace), Key: in.Prefix, Operation: auth.OpRead}}, true
+	case *v1alpha1.LeaderRequest:
+		return []auth.Request{{Namespace: defaultNamespace, Key: in.Name, Operation: auth.OpRead}}, true
+	case *v1alpha1.ExtendRequest, *v1alpha1.ReleaseRequest, *v1alpha1.TransferRequest, *v1alpha1.GraphRequest:
+		return nil, true
+	}
+	return nil, false
+}
+
+// bindOwner attributes the acquisitions of a request to the identity of its client, rejecting requests claiming
+// to act on behalf of another owner
+func bindOwner(req any, identity string) error {
+	var md **v1alpha1.LockMetadata
+	switch in := req.(type) {
+	case *v1alpha1.LockRequest:
+		md = &in.Metadata
+	case *v1alpha1.AcquireRequest:
+		md = &in.Metadata
+	case *v1alpha1.CampaignRequest:
+		md = &in.Metadata
+	default:
+		return nil
+	}
+	if *md == nil {
+		process := lock.ProcessMetadata()
+		*md = &v1alpha1.LockMetadata{Hostname: process.Hostname, Pid: int64(process.Pid)}
+	}
+	if owner := (*md).Owner; owner != "" && owner != identity {
+		return fmt.Errorf("owner '%s' does not match the identity of the client", owner)
+	}
+	(*md).Owner = identity
+	return nil
+}
+
+// denied counts & returns the denial of a request
+func (a *serverAuth) denied(ctx context.Context, method string, err error) error {
+	AuthDeniedCount.Add(ctx, 1, api.WithAttributes(
+		attribute.String("method", method),
+		attribute.String("code", status.Code(err).String()),
+	))
+	return err
+}
+
+// authenticate returns the context of the request carrying the identity of its client
+func (a *serverAuth) authenticate(ctx context.Context, method string) (context.Context, error) {
+	identity, err := a.authn.Authenticate(ctx)
+	if err != nil {
+		a.lg.With("method", method, logger.Err(err)).Warn("failed to authenticate request")
+		return nil, a.denied(ctx, method, status.Error(codes.Unauthenticated, "missing or invalid credentials"))
+	}
+	return auth.WithIdentity(ctx, identity), nil
+}
+
+// authorize checks that a policy allows each access of the request, and attributes its acquisitions to its client
+func (a *serverAuth) authorize(ctx context.Context, method string, req any) error {
+	identity, _ := auth.IdentityFromContext(ctx)
+	accesses, ok := authRequests(req)
+	if !ok {
+		a.lg.With("identity", identity, "method", method).Warn("denied request of unknown type")
+		return a.denied(ctx, method, status.Errorf(codes.PermissionDenied, "%s requests are not authorized", method))
+	}
+	if err := a.check(ctx, method, accesses); err != nil {
+		return err
+	}
+	if err := bindOwner(req, identity); err != nil {
+		a.lg.With("identity", identity, "method", method, logger.Err(err)).Warn("denied request")
+		return a.denied(ctx, method, status.Error(codes.PermissionDenied, err.Error()))
+	}
+	return nil
+}
+
+// check checks that a policy allows each access of the client
+func (a *serverAuth) check(ctx context.Context, method string, accesses []auth.Request) error {
+	identity, _ := auth.IdentityFromContext(ctx)
+	for _, access := range accesses {
+		access.Identity = identity
+		if !a.authz.Authorize(access) {
+			a.lg.With(
+				"identity", identity, "method", method, "namespace", access.Namespace,
+				"key", access.Key, "op", access.Operation,
+			).Warn("denied request")
+			return a.denied(ctx, method, status.Errorf(codes.PermissionDenied,
+				"%s is not allowed to %s key '%s' in namespace %s", identity, access.Operation, access.Key, access.Namespace,
+			))
+		}
+	}
+	return nil
+}
+
+func (a *serverAuth) unaryInterceptor(
+	ctx context.Context,
+	req any,
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (any, error) {
+	if skipAuth(info.FullMethod) {
+		return handler(ctx, req)
+	}
+	ctx, err := a.authenticate(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	if err := a.authorize(ctx, info.FullMethod, req); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (a *serverAuth) streamInterceptor(
+	srv any,
+	ss grpc.ServerStream,
+	info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler,
+) error {
+	if skipAuth(info.FullMethod) {
+		return handler(srv, ss)
+	}
+	ctx, err := a.authenticate(ss.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+	return handler(srv, &authStream{ServerStream: ss, ctx: ctx, auth: a, method: info.FullMethod})
+}
+
+// authStream authorizes the request of a stream once it is received
+type authStream struct {
+	grpc.ServerStream
+	ctx    context.Context
+	auth   *serverAuth
+	method string
+}
+
+func (s *authStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *authStream) RecvMsg(m any) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	return s.auth.authorize(s.ctx, s.method, m)
+}
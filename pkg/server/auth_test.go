@@ -0,0 +1,131 @@
+package server
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/alexandreLamarre/dlock/api/v1alpha1"
+	"github.com/alexandreLamarre/dlock/internal/lock/backend/memory"
+	"github.com/alexandreLamarre/dlock/pkg/auth"
+	configv1alpha1 "github.com/alexandreLamarre/dlock/pkg/config/v1alpha1"
+	"github.com/alexandreLamarre/dlock/pkg/logger"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+var _ = Describe("Server auth", Label("unit"), func() {
+	var a *serverAuth
+	ctx := auth.WithIdentity(context.Background(), "ci")
+
+	BeforeEach(func() {
+		var err error
+		a, err = newServerAuth(&configv1alpha1.ServerSpec{Auth: &configv1alpha1.AuthSpec{
+			Tokens: []configv1alpha1.TokenSpec{{Identity: "ci", Token: "secret"}},
+			Policies: []configv1alpha1.PolicySpec{{
+				Identities: []string{"ci"},
+				Prefixes:   []string{"ci/"},
+				Operations: []string{"lock", "read"},
+			}, {
+				Identities: []string{"ops"},
+				Operations: []string{"force-release"},
+			}},
+		}}, slog.New(logger.NewNop().Handler()))
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should deny the requests of unknown types", func() {
+		err := a.authorize(ctx, "/unknown", &emptypb.Empty{})
+		Expect(status.Code(err)).To(Equal(codes.PermissionDenied))
+	})
+
+	It("should only authenticate the requests on leases & graphs", func() {
+		for _, req := range []any{
+			&v1alpha1.ExtendRequest{LeaseId: "lease"},
+			&v1alpha1.ReleaseRequest{LeaseId: "lease"},
+			&v1alpha1.TransferRequest{LeaseId: "lease", ToOwner: "ops"},
+			&v1alpha1.GraphRequest{Name: "graph"},
+		} {
+			Expect(a.authorize(ctx, "/method", req)).To(Succeed(), "%T", req)
+		}
+	})
+
+	It("should attribute acquisitions to the identity of their client", func() {
+		req := &v1alpha1.LockRequest{Key: "ci/deploy"}
+		Expect(a.authorize(ctx, "/lock", req)).To(Succeed())
+		Expect(req.Metadata.GetOwner()).To(Equal("ci"))
+		Expect(req.Metadata.GetHostname()).NotTo(BeEmpty())
+
+		acquire := &v1alpha1.AcquireRequest{Key: "ci/deploy", Metadata: &v1alpha1.LockMetadata{Hostname: "runner"}}
+		Expect(a.authorize(ctx, "/acquire", acquire)).To(Succeed())
+		Expect(acquire.Metadata.GetOwner()).To(Equal("ci"))
+		Expect(acquire.Metadata.GetHostname()).To(Equal("runner"))
+
+		By("denying requests on behalf of other owners")
+		err := a.authorize(ctx, "/lock", &v1alpha1.LockRequest{
+			Key:      "ci/deploy",
+			Metadata: &v1alpha1.LockMetadata{Owner: "ops"},
+		})
+		Expect(status.Code(err)).To(Equal(codes.PermissionDenied))
+	})
+
+	It("should authorize semaphores on the keys of their namespace", func() {
+		accesses, ok := authRequests(&v1alpha1.SemaphoreRequest{Namespace: "billing", Key: "ci/workers", TryAcquire: true})
+		Expect(ok).To(BeTrue())
+		Expect(accesses).To(ConsistOf(auth.Request{Namespace: "billing", Key: "ci/workers", Operation: auth.OpTryLock}))
+		accesses, _ = authRequests(&v1alpha1.SemaphoreRequest{Key: "ci/workers"})
+		Expect(accesses).To(ConsistOf(auth.Request{Namespace: defaultNamespace, Key: "ci/workers", Operation: auth.OpLock}))
+	})
+
+	It("should deny the accesses no policy allows", func() {
+		err := a.authorize(ctx, "/lock", &v1alpha1.LockRequest{Key: "prod/deploy"})
+		Expect(status.Code(err)).To(Equal(codes.PermissionDenied))
+		err = a.authorize(ctx, "/describe", &v1alpha1.DescribeLockRequest{Key: "ci/deploy"})
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should authorize the requests on graphs on the keys of their nodes", func(ctx SpecContext) {
+		lg := logger.NewNop()
+		lm := memory.NewLockManager(nil, lg)
+		s := &LockServer{
+			lg:         lg,
+			lm:         lm,
+			namespaces: map[string]*namespace{defaultNamespace: {name: defaultNamespace, lm: lm}},
+			graphs:     newGraphTable(),
+			auth:       a,
+		}
+		ci := auth.WithIdentity(ctx, "ci")
+		ops := auth.WithIdentity(ctx, "ops")
+		_, err := s.SubmitGraph(ci, &v1alpha1.SubmitGraphRequest{
+			Name:  "deploy",
+			Nodes: []*v1alpha1.GraphNode{{Key: "ci/build"}, {Key: "ci/deploy", Predecessors: []string{"ci/build"}}},
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = s.DeleteGraph(ops, &v1alpha1.GraphRequest{Name: "deploy"})
+		Expect(status.Code(err)).To(Equal(codes.PermissionDenied))
+		_, err = s.DeleteGraph(ci, &v1alpha1.GraphRequest{Name: "deploy"})
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should only force release locks for clients allowed to", func(ctx SpecContext) {
+		lg := logger.NewNop()
+		lm := memory.NewLockManager(nil, lg)
+		s := &adminServer{LockServer: &LockServer{
+			lg:         lg,
+			lm:         lm,
+			namespaces: map[string]*namespace{defaultNamespace: {name: defaultNamespace, lm: lm}},
+		}}
+		req := &v1alpha1.ForceReleaseRequest{Key: "ci/deploy", Reason: "wedged"}
+		_, err := s.ForceRelease(ctx, req)
+		Expect(status.Code(err)).To(Equal(codes.PermissionDenied))
+
+		s.auth = a
+		_, err = s.ForceRelease(auth.WithIdentity(ctx, "ci"), req)
+		Expect(status.Code(err)).To(Equal(codes.PermissionDenied))
+		_, err = s.ForceRelease(auth.WithIdentity(ctx, "ops"), req)
+		Expect(err).NotTo(HaveOccurred())
+	})
+})
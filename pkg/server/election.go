@@ -0,0 +1,93 @@
+package server
+
+import (
+	"context"
+	"errors"
+
+	"github.com/alexandreLamarre/dlock/api/v1alpha1"
+	"github.com/alexandreLamarre/dlock/pkg/election"
+	"github.com/alexandreLamarre/dlock/pkg/lock"
+	"github.com/alexandreLamarre/dlock/pkg/logger"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var errTryCampaign = errors.New("campaigns can't be tried")
+
+// electionLocker holds the leadership of an election like a lock, so that campaigns are streamed like locks
+type electionLocker struct {
+	e     election.Election
+	value string
+}
+
+var _ lock.Lock = (*electionLocker)(nil)
+
+func (l *electionLocker) Lock(ctx context.Context) (<-chan struct{}, error) {
+	return l.e.Campaign(ctx, l.value)
+}
+
+func (l *electionLocker) TryLock(_ context.Context) (bool, <-chan struct{}, error) {
+	return false, nil, errTryCampaign
+}
+
+func (l *electionLocker) Unlock() error {
+	return l.e.Resign(context.Background())
+}
+
+func (l *electionLocker) FencingToken() uint64 {
+	return 0
+}
+
+func (s *LockServer) Campaign(in *v1alpha1.CampaignRequest, stream v1alpha1.Dlock_CampaignServer) error {
+	LockRequestCount.Add(stream.Context(), 1)
+	lg := s.lg.With("election", in.Name, "value", in.Value)
+	lg.Debug("received campaign request")
+	if s.lm == nil {
+		s.lg.Error("no lock backend")
+		return status.Errorf(codes.Unavailable, "no lock backend")
+	}
+	if err := in.Validate(); err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	e := election.New(s.lm, in.Name, lock.WithTracer(s.tracer), lock.WithMetadata(metadata(in.Metadata)))
+	return s.hold(lg, nil, in.Name, false, &electionLocker{e: e, value: in.Value}, "", stream)
+}
+
+func (s *LockServer) Leader(ctx context.Context, in *v1alpha1.LeaderRequest) (*v1alpha1.LeaderResponse, error) {
+	if s.lm == nil {
+		s.lg.Error("no lock backend")
+		return nil, status.Errorf(codes.Unavailable, "no lock backend")
+	}
+	if err := in.Validate(); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	value, err := election.New(s.lm, in.Name).Leader(ctx)
+	if errors.Is(err, election.ErrNoLeader) {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	if err != nil {
+		s.lg.With("election", in.Name, logger.Err(err)).Error("failed to get leader")
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &v1alpha1.LeaderResponse{
+		Value: value,
+	}, nil
+}
+
+func (s *LockServer) Observe(in *v1alpha1.LeaderRequest, stream v1alpha1.Dlock_ObserveServer) error {
+	if s.lm == nil {
+		s.lg.Error("no lock backend")
+		return status.Errorf(codes.Unavailable, "no lock backend")
+	}
+	if err := in.Validate(); err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	for value := range election.New(s.lm, in.Name).Observe(stream.Context()) {
+		if err := stream.Send(&v1alpha1.LeaderResponse{
+			Value: value,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,183 @@
+package server
+
+import (
+	"context"
+	"sync"
+
+	"github.com/alexandreLamarre/dlock/api/v1alpha1"
+	"github.com/alexandreLamarre/dlock/pkg/auth"
+	"github.com/alexandreLamarre/dlock/pkg/graph"
+	"github.com/alexandreLamarre/dlock/pkg/lock"
+	"github.com/samber/lo"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+type graphKey struct {
+	namespace string
+	name      string
+}
+
+// graphTable holds the graphs submitted to the server, graphs of different namespaces may share their name
+type graphTable struct {
+	mu     sync.Mutex
+	graphs map[graphKey]*graph.Graph
+}
+
+func newGraphTable() *graphTable {
+	return &graphTable{
+		graphs: map[graphKey]*graph.Graph{},
+	}
+}
+
+func (t *graphTable) get(ns *namespace, name string) (*graph.Graph, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	g, ok := t.graphs[graphKey{namespace: ns.name, name: name}]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "unknown graph %s in namespace %s", name, ns.name)
+	}
+	return g, nil
+}
+
+// authorizeGraph checks that the client is allowed in the namespace of the graph, and that a policy allows the
+// operation on the key of each of its nodes
+func (s *LockServer) authorizeGraph(ctx context.Context, method string, ns *namespace, g *graph.Graph, op auth.Operation) error {
+	if err := ns.allow(ctx); err != nil {
+		return err
+	}
+	if s.auth == nil {
+		return nil
+	}
+	return s.auth.check(ctx, method, lo.Map(g.Nodes(), func(node graph.Node, _ int) auth.Request {
+		return auth.Request{Namespace: ns.name, Key: node.Key, Operation: op}
+	}))
+}
+
+var graphStates = map[graph.State]v1alpha1.GraphNodeState{
+	graph.Pending: v1alpha1.GraphNodeState_NodePending,
+	graph.Held:    v1alpha1.GraphNodeState_NodeHeld,
+	graph.Done:    v1alpha1.GraphNodeState_NodeDone,
+}
+
+// graphLocker returns the lock of the graph's node on the key
+func (s *LockServer) graphLocker(
+	ns *namespace,
+	name string,
+	key string,
+	md *v1alpha1.LockMetadata,
+	extra ...lock.LockOption,
+) (lock.Lock, error) {
+	g, err := s.graphs.get(ns, name)
+	if err != nil {
+		return nil, err
+	}
+	opts := append([]lock.LockOption{
+		lock.WithTracer(s.tracer),
+		lock.WithMetadata(metadata(md)),
+	}, extra...)
+	locker, err := g.NewLock(key, opts...)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return locker, nil
+}
+
+func (s *LockServer) SubmitGraph(ctx context.Context, in *v1alpha1.SubmitGraphRequest) (*emptypb.Empty, error) {
+	if s.lm == nil {
+		s.lg.Error("no lock backend")
+		return nil, status.Errorf(codes.Unavailable, "no lock backend")
+	}
+	if err := in.Validate(); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	ns, err := s.namespace(in.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	if err := ns.allow(ctx); err != nil {
+		return nil, err
+	}
+	preds := make(map[string][]string, len(in.Nodes))
+	for _, node := range in.Nodes {
+		if _, ok := preds[node.Key]; ok {
+			return nil, status.Errorf(codes.InvalidArgument, "duplicate node %s", node.Key)
+		}
+		preds[node.Key] = node.Predecessors
+	}
+	opts := []graph.Option{}
+	if in.Run != "" {
+		opts = append(opts, graph.WithID(in.Name+"/"+in.Run))
+	}
+	g, err := graph.New(ns.lm, preds, opts...)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	s.graphs.mu.Lock()
+	defer s.graphs.mu.Unlock()
+	key := graphKey{namespace: ns.name, name: in.Name}
+	if _, ok := s.graphs.graphs[key]; ok {
+		return nil, status.Errorf(codes.AlreadyExists, "graph %s already exists in namespace %s", in.Name, ns.name)
+	}
+	s.graphs.graphs[key] = g
+	s.lg.With("graph", in.Name, "namespace", ns.name, "nodes", len(in.Nodes)).Debug("submitted graph")
+	return &emptypb.Empty{}, nil
+}
+
+func (s *LockServer) ObserveGraph(in *v1alpha1.GraphRequest, stream v1alpha1.Dlock_ObserveGraphServer) error {
+	if err := in.Validate(); err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	ns, err := s.namespace(in.Namespace)
+	if err != nil {
+		return err
+	}
+	g, err := s.graphs.get(ns, in.Name)
+	if err != nil {
+		return err
+	}
+	if err := s.authorizeGraph(stream.Context(), v1alpha1.Dlock_ObserveGraph_FullMethodName, ns, g, auth.OpRead); err != nil {
+		return err
+	}
+	for node := range g.Observe(stream.Context()) {
+		if err := stream.Send(&v1alpha1.GraphNode{
+			Key:          node.Key,
+			Predecessors: node.Predecessors,
+			State:        graphStates[node.State],
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *LockServer) DeleteGraph(ctx context.Context, in *v1alpha1.GraphRequest) (*emptypb.Empty, error) {
+	if err := in.Validate(); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	ns, err := s.namespace(in.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	g, err := s.graphs.get(ns, in.Name)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.authorizeGraph(ctx, v1alpha1.Dlock_DeleteGraph_FullMethodName, ns, g, auth.OpLock); err != nil {
+		return nil, err
+	}
+	s.graphs.mu.Lock()
+	key := graphKey{namespace: ns.name, name: in.Name}
+	deleted := s.graphs.graphs[key] == g
+	if deleted {
+		delete(s.graphs.graphs, key)
+	}
+	s.graphs.mu.Unlock()
+	if !deleted {
+		return nil, status.Errorf(codes.NotFound, "unknown graph %s in namespace %s", in.Name, ns.name)
+	}
+	g.Close()
+	s.lg.With("graph", in.Name, "namespace", ns.name).Debug("deleted graph")
+	return &emptypb.Empty{}, nil
+}
@@ -11,7 +11,15 @@ import (
 	"google.golang.org/grpc/status"
 )
 
-func (l *LockServer) Check(ctx context.Context, req *healthv1.HealthCheckRequest) (*healthv1.HealthCheckResponse, error) {
+// healthServer serves the health of the backend of a LockServer, separately from its Dlock APIs
+// since both define a Watch method
+type healthServer struct {
+	*LockServer
+}
+
+var _ healthv1.HealthServer = &healthServer{}
+
+func (l *healthServer) Check(ctx context.Context, req *healthv1.HealthCheckRequest) (*healthv1.HealthCheckResponse, error) {
 	if !l.Initialized() {
 		return &healthv1.HealthCheckResponse{
 			Status: *healthv1.HealthCheckResponse_NOT_SERVING.Enum(),
@@ -35,7 +43,7 @@ func (l *LockServer) Check(ctx context.Context, req *healthv1.HealthCheckRequest
 		)
 }
 
-func (l *LockServer) List(ctx context.Context, _ *healthv1.HealthListRequest) (*healthv1.HealthListResponse, error) {
+func (l *healthServer) List(ctx context.Context, _ *healthv1.HealthListRequest) (*healthv1.HealthListResponse, error) {
 	ret, err := l.Check(ctx, &healthv1.HealthCheckRequest{})
 	if err != nil {
 		return nil, err
@@ -47,6 +55,6 @@ func (l *LockServer) List(ctx context.Context, _ *healthv1.HealthListRequest) (*
 	}, nil
 }
 
-func (l *LockServer) Watch(*healthv1.HealthCheckRequest, healthv1.Health_WatchServer) error {
+func (l *healthServer) Watch(*healthv1.HealthCheckRequest, healthv1.Health_WatchServer) error {
 	return status.Error(codes.Unimplemented, "method Watch not implemented")
 }
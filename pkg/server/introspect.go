@@ -0,0 +1,137 @@
+package server
+
+import (
+	"context"
+
+	"github.com/alexandreLamarre/dlock/api/v1alpha1"
+	"github.com/alexandreLamarre/dlock/pkg/lock"
+	"github.com/alexandreLamarre/dlock/pkg/logger"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// metadata returns the metadata of acquisitions made on behalf of a client,
+// clients that do not identify themselves are attributed to the server's process
+func metadata(in *v1alpha1.LockMetadata) lock.Metadata {
+	if in == nil {
+		return lock.ProcessMetadata()
+	}
+	return lock.Metadata{
+		Owner:    in.Owner,
+		Hostname: in.Hostname,
+		Pid:      int(in.Pid),
+		Labels:   in.Labels,
+	}
+}
+
+func lockInfo(info lock.LockInfo) *v1alpha1.LockInfo {
+	ret := &v1alpha1.LockInfo{
+		Key:     info.Key,
+		Holders: make([]*v1alpha1.LockHolder, 0, len(info.Holders)),
+		Waiters: int64(info.Waiters),
+	}
+	for _, h := range info.Holders {
+		ret.Holders = append(ret.Holders, lockHolder(h))
+	}
+	return ret
+}
+
+func lockHolder(h lock.HolderInfo) *v1alpha1.LockHolder {
+	holder := &v1alpha1.LockHolder{
+		Metadata: &v1alpha1.LockMetadata{
+			Owner:    h.Owner,
+			Hostname: h.Hostname,
+			Pid:      int64(h.Pid),
+			Labels:   h.Labels,
+		},
+		Mode:       v1alpha1.LockMode_EX,
+		AcquiredAt: timestamppb.New(h.AcquiredAt),
+	}
+	if h.Shared {
+		holder.Mode = v1alpha1.LockMode_PR
+	}
+	if h.TTL > 0 {
+		holder.Ttl = durationpb.New(h.TTL)
+	}
+	return holder
+}
+
+var watchEventTypes = map[lock.WatchEventType]v1alpha1.WatchEventType{
+	lock.WatchAcquired: v1alpha1.WatchEventType_LockAcquired,
+	lock.WatchReleased: v1alpha1.WatchEventType_LockReleased,
+	lock.WatchExpired:  v1alpha1.WatchEventType_LockExpired,
+}
+
+func (s *LockServer) ListLocks(ctx context.Context, in *v1alpha1.ListLocksRequest) (*v1alpha1.ListLocksResponse, error) {
+	if s.lm == nil {
+		s.lg.Error("no lock backend")
+		return nil, status.Errorf(codes.Unavailable, "no lock backend")
+	}
+	ns, err := s.namespace(in.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	infos, err := ns.lm.ListLocks(ctx, in.Prefix)
+	if err != nil {
+		s.lg.With("prefix", in.Prefix, logger.Err(err)).Error("failed to list locks")
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	resp := &v1alpha1.ListLocksResponse{
+		Locks: make([]*v1alpha1.LockInfo, 0, len(infos)),
+	}
+	for _, info := range infos {
+		resp.Locks = append(resp.Locks, lockInfo(info))
+	}
+	return resp, nil
+}
+
+func (s *LockServer) DescribeLock(ctx context.Context, in *v1alpha1.DescribeLockRequest) (*v1alpha1.LockInfo, error) {
+	if s.lm == nil {
+		s.lg.Error("no lock backend")
+		return nil, status.Errorf(codes.Unavailable, "no lock backend")
+	}
+	if err := in.Validate(); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	ns, err := s.namespace(in.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	info, err := ns.lm.DescribeLock(ctx, in.Key)
+	if err != nil {
+		s.lg.With("key", in.Key, logger.Err(err)).Error("failed to describe lock")
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return lockInfo(info), nil
+}
+
+func (s *LockServer) Watch(in *v1alpha1.WatchRequest, stream v1alpha1.Dlock_WatchServer) error {
+	if s.lm == nil {
+		s.lg.Error("no lock backend")
+		return status.Errorf(codes.Unavailable, "no lock backend")
+	}
+	ns, err := s.namespace(in.Namespace)
+	if err != nil {
+		return err
+	}
+	events, err := ns.lm.Watch(stream.Context(), in.Prefix)
+	if err != nil {
+		s.lg.With("prefix", in.Prefix, logger.Err(err)).Error("failed to watch locks")
+		return status.Error(codes.Internal, err.Error())
+	}
+	for ev := range events {
+		if err := stream.Send(&v1alpha1.WatchResponse{
+			Type:   watchEventTypes[ev.Type],
+			Key:    ev.Key,
+			Holder: lockHolder(ev.Holder),
+		}); err != nil {
+			return err
+		}
+	}
+	if stream.Context().Err() == nil {
+		return status.Error(codes.ResourceExhausted, "watch fell too far behind the events of the locks")
+	}
+	return nil
+}
@@ -0,0 +1,362 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/alexandreLamarre/dlock/api/v1alpha1"
+	"github.com/alexandreLamarre/dlock/pkg/auth"
+	"github.com/alexandreLamarre/dlock/pkg/lock"
+	"github.com/alexandreLamarre/dlock/pkg/logger"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+var (
+	DefaultLeaseTTL = 30 * time.Second
+	MaxLeaseTTL     = 10 * time.Minute
+)
+
+var (
+	errLeaseNotFound       = errors.New("lease not found or already expired")
+	errLeaseNotOwned       = errors.New("lease was acquired by another client")
+	errTransferUnsupported = errors.New("only exclusive, non reentrant leases can be transferred")
+)
+
+// lease binds a held lock to a TTL that must be periodically extended by the client,
+// instead of the lifetime of a stream
+type lease struct {
+	id  string
+	key string
+	// authenticated identity of the client that acquired the lease, empty without server auth
+	identity string
+	locker   lock.Lock
+	ttl      time.Duration
+	timer    *time.Timer
+	start    time.Time
+	// stops counting the lease as held in its namespace
+	unhold func()
+
+	released chan struct{}
+}
+
+type leaseTable struct {
+	lg *slog.Logger
+
+	mu     sync.Mutex
+	leases map[string]*lease
+}
+
+func newLeaseTable(lg *slog.Logger) *leaseTable {
+	return &leaseTable{
+		lg:     lg,
+		leases: map[string]*lease{},
+	}
+}
+
+// add tracks a newly acquired lock, releasing it when its TTL elapses without being extended
+// or when the lock expires from the storage backend
+func (t *leaseTable) add(
+	key string,
+	identity string,
+	locker lock.Lock,
+	expired <-chan struct{},
+	ttl time.Duration,
+	unhold func(),
+) *lease {
+	l := &lease{
+		id:       uuid.New().String(),
+		key:      key,
+		identity: identity,
+		locker:   locker,
+		ttl:      ttl,
+		start:    time.Now(),
+		unhold:   unhold,
+		released: make(chan struct{}),
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.leases[l.id] = l
+	l.timer = time.AfterFunc(ttl, func() {
+		if t.release(l.id) == nil {
+			t.lg.With("key", key, "lease", l.id).Warn("lease expired before being extended")
+		}
+	})
+	go func() {
+		select {
+		case <-l.released:
+		case <-expired:
+			if t.release(l.id) == nil {
+				t.lg.With("key", key, "lease", l.id).Warn("lease expired from storage backend")
+			}
+		}
+	}()
+	return l
+}
+
+// owned checks that the lease was acquired by the client of the identity
+func (t *leaseTable) owned(id, identity string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	l, ok := t.leases[id]
+	if !ok {
+		return errLeaseNotFound
+	}
+	if l.identity != identity {
+		return errLeaseNotOwned
+	}
+	return nil
+}
+
+// leaseError returns the status of errors checking the ownership of leases
+func leaseError(err error) error {
+	if errors.Is(err, errLeaseNotOwned) {
+		return status.Error(codes.PermissionDenied, err.Error())
+	}
+	return status.Error(codes.NotFound, err.Error())
+}
+
+func (t *leaseTable) extend(id string, ttl time.Duration) (time.Duration, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	l, ok := t.leases[id]
+	if !ok {
+		return 0, errLeaseNotFound
+	}
+	// the timer already fired, the lease is being released
+	if !l.timer.Stop() {
+		return 0, errLeaseNotFound
+	}
+	if ttl > 0 {
+		l.ttl = ttl
+	}
+	l.timer.Reset(l.ttl)
+	return l.ttl, nil
+}
+
+// release unlocks the lease's lock exactly once, returning errLeaseNotFound
+// if the lease was already released
+func (t *leaseTable) release(id string) error {
+	t.mu.Lock()
+	l, ok := t.leases[id]
+	if ok {
+		delete(t.leases, id)
+	}
+	t.mu.Unlock()
+	if !ok {
+		return errLeaseNotFound
+	}
+	l.timer.Stop()
+	close(l.released)
+	l.unhold()
+	LockHeldTime.Record(context.Background(), float64(time.Since(l.start).Milliseconds()))
+	return l.locker.Unlock()
+}
+
+// transfer hands the lease's lock over to a waiter of the owner, the lease is released once the lock is transferred.
+// When the lock was already lost the lease is released, and errLeaseNotFound is returned.
+func (t *leaseTable) transfer(ctx context.Context, id, toOwner string) error {
+	t.mu.Lock()
+	l, ok := t.leases[id]
+	t.mu.Unlock()
+	if !ok {
+		return errLeaseNotFound
+	}
+	transferer, ok := l.locker.(lock.Transferer)
+	if !ok {
+		return errTransferUnsupported
+	}
+	if err := transferer.Transfer(ctx, toOwner); err != nil {
+		switch {
+		case errors.Is(err, lock.ErrLockScheduled):
+			// the lease was released concurrently
+			return errLeaseNotFound
+		case errors.Is(err, lock.ErrNotHeld):
+			_ = t.release(id)
+			return errLeaseNotFound
+		}
+		return err
+	}
+	t.mu.Lock()
+	_, ok = t.leases[id]
+	if ok {
+		delete(t.leases, id)
+	}
+	t.mu.Unlock()
+	// the lease may have been released as the lock expired once transferred, which is a no-op
+	if ok {
+		l.timer.Stop()
+		close(l.released)
+		l.unhold()
+		LockHeldTime.Record(context.Background(), float64(time.Since(l.start).Milliseconds()))
+	}
+	return nil
+}
+
+func leaseTTL(requested *durationpb.Duration) time.Duration {
+	if requested == nil || requested.AsDuration() == 0 {
+		return DefaultLeaseTTL
+	}
+	return min(requested.AsDuration(), MaxLeaseTTL)
+}
+
+func (s *LockServer) Acquire(ctx context.Context, in *v1alpha1.AcquireRequest) (*v1alpha1.AcquireResponse, error) {
+	LockRequestCount.Add(ctx, 1)
+	lg := s.lg.With("key", in.Key, "block", !in.TryLock, "mode", in.Mode.String())
+	lg.Debug("received acquire request")
+	if s.lm == nil {
+		s.lg.Error("no lock backend")
+		return nil, status.Errorf(codes.Unavailable, "no lock backend")
+	}
+	if err := in.Validate(); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	ns, err := s.namespace(in.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	if err := ns.allow(ctx); err != nil {
+		return nil, err
+	}
+
+	opts, token, err := s.lockOptions(ctx, ns, []string{in.Key}, in)
+	if err != nil {
+		return nil, err
+	}
+	locker := s.newLocker(ns.lm, in.Key, in.Mode, in.Metadata, opts...)
+	ctx, lockSpan := s.tracer.Start(ctx, "acquire-lease", trace.WithAttributes(
+		attribute.KeyValue{
+			Key:   "key",
+			Value: attribute.StringValue(in.Key),
+		},
+		attribute.KeyValue{
+			Key:   "block",
+			Value: attribute.BoolValue(!in.TryLock),
+		}),
+	)
+	defer lockSpan.End()
+
+	var expiredC <-chan struct{}
+	if in.TryLock {
+		acquired, expired, err := locker.TryLock(ctx)
+		if err != nil {
+			lg.With(logger.Err(err)).Error("failed to acquire lock")
+			lockSpan.RecordError(err)
+			return nil, lockError(err)
+		}
+		if !acquired {
+			lg.Warn("failed to acquire non-blocking lock")
+			return &v1alpha1.AcquireResponse{
+				Acquired: false,
+			}, nil
+		}
+		expiredC = expired
+	} else {
+		expired, err := locker.Lock(ctx)
+		if err != nil {
+			lg.With(logger.Err(err)).Error("failed to acquire blocking lock")
+			lockSpan.RecordError(err)
+			return nil, lockError(err)
+		}
+		expiredC = expired
+	}
+	unholdNs, err := ns.hold()
+	if err != nil {
+		lg.With(logger.Err(err)).Warn("namespace holds too many locks")
+		if err := locker.Unlock(); err != nil {
+			lg.With(logger.Err(err)).Error("failed to unlock lock")
+		}
+		return nil, err
+	}
+	unholdToken := s.reentrant.hold(token)
+	unhold := func() {
+		unholdNs()
+		unholdToken()
+	}
+	LockAcquisitionCount.Add(ctx, 1)
+
+	identity, _ := auth.IdentityFromContext(ctx)
+	l := s.leases.add(in.Key, identity, locker, expiredC, ns.leaseTTL(in), unhold)
+	lg.With("lease", l.id, "ttl", l.ttl).Debug("acquired lease")
+	return &v1alpha1.AcquireResponse{
+		Acquired:       true,
+		LeaseId:        l.id,
+		Ttl:            durationpb.New(l.ttl),
+		FencingToken:   locker.FencingToken(),
+		ReentrantToken: token,
+	}, nil
+}
+
+func (s *LockServer) Extend(ctx context.Context, in *v1alpha1.ExtendRequest) (*v1alpha1.ExtendResponse, error) {
+	if err := in.Validate(); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	identity, _ := auth.IdentityFromContext(ctx)
+	if err := s.leases.owned(in.LeaseId, identity); err != nil {
+		return nil, leaseError(err)
+	}
+	var ttl time.Duration
+	if in.Ttl != nil && in.Ttl.AsDuration() > 0 {
+		ttl = leaseTTL(in.Ttl)
+	}
+	ttl, err := s.leases.extend(in.LeaseId, ttl)
+	if err != nil {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	s.lg.With("lease", in.LeaseId, "ttl", ttl).Debug("extended lease")
+	return &v1alpha1.ExtendResponse{
+		Ttl: durationpb.New(ttl),
+	}, nil
+}
+
+func (s *LockServer) Release(ctx context.Context, in *v1alpha1.ReleaseRequest) (*emptypb.Empty, error) {
+	UnlockRequestCount.Add(ctx, 1)
+	if err := in.Validate(); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	identity, _ := auth.IdentityFromContext(ctx)
+	if err := s.leases.owned(in.LeaseId, identity); err != nil {
+		return nil, leaseError(err)
+	}
+	if err := s.leases.release(in.LeaseId); err != nil {
+		if errors.Is(err, errLeaseNotFound) {
+			return nil, status.Error(codes.NotFound, err.Error())
+		}
+		s.lg.With("lease", in.LeaseId, logger.Err(err)).Error("failed to release lease")
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	UnlockSuccessCount.Add(ctx, 1)
+	s.lg.With("lease", in.LeaseId).Debug("released lease")
+	return &emptypb.Empty{}, nil
+}
+
+func (s *LockServer) Transfer(ctx context.Context, in *v1alpha1.TransferRequest) (*emptypb.Empty, error) {
+	if err := in.Validate(); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	identity, _ := auth.IdentityFromContext(ctx)
+	if err := s.leases.owned(in.LeaseId, identity); err != nil {
+		return nil, leaseError(err)
+	}
+	lg := s.lg.With("lease", in.LeaseId, "to", in.ToOwner)
+	if err := s.leases.transfer(ctx, in.LeaseId, in.ToOwner); err != nil {
+		switch {
+		case errors.Is(err, errLeaseNotFound):
+			return nil, status.Error(codes.NotFound, err.Error())
+		case errors.Is(err, errTransferUnsupported), errors.Is(err, lock.ErrNoTransferee), errors.Is(err, lock.ErrLockMode):
+			return nil, status.Error(codes.FailedPrecondition, err.Error())
+		}
+		lg.With(logger.Err(err)).Error("failed to transfer lease")
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	lg.Debug("transferred lease")
+	return &emptypb.Empty{}, nil
+}
@@ -0,0 +1,256 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/alexandreLamarre/dlock/api/v1alpha1"
+	"github.com/alexandreLamarre/dlock/internal/lock/backend/memory"
+	"github.com/alexandreLamarre/dlock/pkg/auth"
+	configv1alpha1 "github.com/alexandreLamarre/dlock/pkg/config/v1alpha1"
+	"github.com/alexandreLamarre/dlock/pkg/lock"
+	"github.com/alexandreLamarre/dlock/pkg/logger"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.opentelemetry.io/otel/trace/noop"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+var _ = Describe("Leases", Label("unit"), func() {
+	var s *LockServer
+	var lm lock.LockManager
+
+	BeforeEach(func() {
+		lg := logger.NewNop()
+		lm = memory.NewLockManager(nil, lg)
+		s = &LockServer{
+			lg:         lg,
+			tracer:     noop.NewTracerProvider().Tracer(""),
+			lm:         lm,
+			namespaces: map[string]*namespace{defaultNamespace: {name: defaultNamespace, lm: lm}},
+			leases:     newLeaseTable(lg),
+			graphs:     newGraphTable(),
+		}
+	})
+
+	acquire := func(ctx context.Context, key string, ttl time.Duration) *v1alpha1.AcquireResponse {
+		resp, err := s.Acquire(ctx, &v1alpha1.AcquireRequest{Key: key, Ttl: durationpb.New(ttl), TryLock: true})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.Acquired).To(BeTrue())
+		Expect(resp.LeaseId).NotTo(BeEmpty())
+		return resp
+	}
+	held := func(ctx context.Context, key string) bool {
+		other := lm.NewLock(key)
+		acquired, _, err := other.TryLock(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		if acquired {
+			Expect(other.Unlock()).To(Succeed())
+		}
+		return !acquired
+	}
+
+	It("should hold the lock of an acquired lease until it is released", func(ctx SpecContext) {
+		resp := acquire(ctx, "lease", time.Minute)
+		Expect(resp.Ttl.AsDuration()).To(Equal(time.Minute))
+		Expect(held(ctx, "lease")).To(BeTrue())
+
+		By("not acquiring leases on held locks")
+		other, err := s.Acquire(ctx, &v1alpha1.AcquireRequest{Key: "lease", TryLock: true})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(other.Acquired).To(BeFalse())
+
+		_, err = s.Release(ctx, &v1alpha1.ReleaseRequest{LeaseId: resp.LeaseId})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(held(ctx, "lease")).To(BeFalse())
+	})
+
+	It("should only reenter the locks of leases with the reentrant token they were issued", func(ctx SpecContext) {
+		reentrant := func(key, owner, token string) (*v1alpha1.AcquireResponse, error) {
+			return s.Acquire(ctx, &v1alpha1.AcquireRequest{
+				Key:            key,
+				TryLock:        true,
+				Reentrant:      true,
+				Metadata:       &v1alpha1.LockMetadata{Owner: owner},
+				ReentrantToken: token,
+			})
+		}
+		first, err := reentrant("reentrant", "alice", "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(first.Acquired).To(BeTrue())
+		Expect(first.ReentrantToken).NotTo(BeEmpty())
+
+		second, err := reentrant("reentrant", "alice", first.ReentrantToken)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(second.Acquired).To(BeTrue())
+		Expect(second.ReentrantToken).To(Equal(first.ReentrantToken))
+
+		By("issuing a new token to the acquisitions without one")
+		other, err := reentrant("reentrant", "alice", "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(other.Acquired).To(BeFalse())
+
+		By("rejecting tokens that do not hold the key for the owner")
+		_, err = reentrant("reentrant", "bob", first.ReentrantToken)
+		Expect(status.Code(err)).To(Equal(codes.FailedPrecondition))
+		_, err = reentrant("unheld", "alice", first.ReentrantToken)
+		Expect(status.Code(err)).To(Equal(codes.FailedPrecondition))
+
+		By("rejecting tokens issued by other servers")
+		otherServer := &LockServer{
+			lg:         s.lg,
+			tracer:     s.tracer,
+			lm:         lm,
+			namespaces: s.namespaces,
+			leases:     newLeaseTable(s.lg),
+			graphs:     newGraphTable(),
+		}
+		_, err = otherServer.Acquire(ctx, &v1alpha1.AcquireRequest{
+			Key:            "reentrant",
+			Reentrant:      true,
+			Metadata:       &v1alpha1.LockMetadata{Owner: "alice"},
+			ReentrantToken: first.ReentrantToken,
+		})
+		Expect(status.Code(err)).To(Equal(codes.FailedPrecondition))
+
+		_, err = s.Release(ctx, &v1alpha1.ReleaseRequest{LeaseId: first.LeaseId})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(held(ctx, "reentrant")).To(BeTrue())
+		_, err = s.Release(ctx, &v1alpha1.ReleaseRequest{LeaseId: second.LeaseId})
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(func() bool {
+			return held(ctx, "reentrant")
+		}).Should(BeFalse())
+		_, err = reentrant("reentrant", "alice", first.ReentrantToken)
+		Expect(status.Code(err)).To(Equal(codes.FailedPrecondition))
+	})
+
+	It("should bound the TTL of leases", func(ctx SpecContext) {
+		resp := acquire(ctx, "bounded", 2*MaxLeaseTTL)
+		Expect(resp.Ttl.AsDuration()).To(Equal(MaxLeaseTTL))
+
+		resp = acquire(ctx, "default", 0)
+		Expect(resp.Ttl.AsDuration()).To(Equal(DefaultLeaseTTL))
+	})
+
+	It("should bound the timings of leases by the server's limits", func(ctx SpecContext) {
+		s.limits = lockLimits{ttl: bounds{min: time.Second}, retryDelay: bounds{max: time.Second}}
+		_, err := s.Acquire(ctx, &v1alpha1.AcquireRequest{Key: "limited", Ttl: durationpb.New(time.Millisecond)})
+		Expect(status.Code(err)).To(Equal(codes.InvalidArgument))
+		_, err = s.Acquire(ctx, &v1alpha1.AcquireRequest{Key: "limited", RetryDelay: durationpb.New(time.Minute)})
+		Expect(status.Code(err)).To(Equal(codes.InvalidArgument))
+		acquire(ctx, "limited", time.Minute)
+	})
+
+	It("should bound the TTL of leases by the default min TTL when the limits do not set one", func(ctx SpecContext) {
+		for _, spec := range []*configv1alpha1.LockLimitsSpec{nil, {MaxTTL: "1m"}} {
+			limits, err := newLockLimits(spec)
+			Expect(err).NotTo(HaveOccurred())
+			s.limits = limits
+			_, err = s.Acquire(ctx, &v1alpha1.AcquireRequest{Key: "limited", Ttl: durationpb.New(DefaultMinTTL / 2)})
+			Expect(status.Code(err)).To(Equal(codes.InvalidArgument))
+		}
+		acquire(ctx, "limited", DefaultMinTTL)
+	})
+
+	It("should only keep leases alive more often than the TTL of their locks", func(ctx SpecContext) {
+		expiring := memory.NewLockManager(nil, logger.NewNop(), memory.WithTTL(time.Second))
+		s.namespaces["expiring"] = &namespace{name: "expiring", lm: expiring}
+		s.namespaces["short"] = &namespace{name: "short", lm: lm, defaultTTL: time.Second}
+		for _, ns := range []string{"expiring", "short"} {
+			_, err := s.Acquire(ctx, &v1alpha1.AcquireRequest{
+				Key: "keepalive", Namespace: ns, KeepaliveInterval: durationpb.New(time.Second), TryLock: true,
+			})
+			Expect(status.Code(err)).To(Equal(codes.InvalidArgument))
+		}
+
+		By("checking the keepalive interval against the requested TTL over the default ones")
+		resp, err := s.Acquire(ctx, &v1alpha1.AcquireRequest{
+			Key: "keepalive", Namespace: "expiring", TryLock: true,
+			Ttl: durationpb.New(time.Minute), KeepaliveInterval: durationpb.New(time.Second),
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.Acquired).To(BeTrue())
+	})
+
+	It("should report blocking acquisitions that are cancelled as such", func(ctx SpecContext) {
+		acquire(ctx, "blocked", time.Minute)
+		blockedCtx, ca := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer ca()
+		_, err := s.Acquire(blockedCtx, &v1alpha1.AcquireRequest{Key: "blocked", Fair: true})
+		Expect(status.Code(err)).To(Equal(codes.DeadlineExceeded))
+	})
+
+	It("should keep leases extended before they expire", func(ctx SpecContext) {
+		resp := acquire(ctx, "extended", 300*time.Millisecond)
+		for range 5 {
+			time.Sleep(100 * time.Millisecond)
+			extended, err := s.Extend(ctx, &v1alpha1.ExtendRequest{LeaseId: resp.LeaseId})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(extended.Ttl.AsDuration()).To(Equal(300 * time.Millisecond))
+		}
+		Expect(held(ctx, "extended")).To(BeTrue())
+
+		By("extending leases with a new TTL")
+		extended, err := s.Extend(ctx, &v1alpha1.ExtendRequest{LeaseId: resp.LeaseId, Ttl: durationpb.New(time.Minute)})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(extended.Ttl.AsDuration()).To(Equal(time.Minute))
+		_, err = s.Release(ctx, &v1alpha1.ReleaseRequest{LeaseId: resp.LeaseId})
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should free the lock of leases that are not extended", func(ctx SpecContext) {
+		resp := acquire(ctx, "expired", 100*time.Millisecond)
+		Eventually(func() bool {
+			return held(ctx, "expired")
+		}).Should(BeFalse())
+
+		By("not extending expired leases")
+		_, err := s.Extend(ctx, &v1alpha1.ExtendRequest{LeaseId: resp.LeaseId})
+		Expect(status.Code(err)).To(Equal(codes.NotFound))
+
+		By("not releasing expired leases")
+		_, err = s.Release(ctx, &v1alpha1.ReleaseRequest{LeaseId: resp.LeaseId})
+		Expect(status.Code(err)).To(Equal(codes.NotFound))
+	})
+
+	It("should not find unknown or released leases", func(ctx SpecContext) {
+		_, err := s.Extend(ctx, &v1alpha1.ExtendRequest{LeaseId: "unknown"})
+		Expect(status.Code(err)).To(Equal(codes.NotFound))
+		_, err = s.Release(ctx, &v1alpha1.ReleaseRequest{LeaseId: "unknown"})
+		Expect(status.Code(err)).To(Equal(codes.NotFound))
+
+		resp := acquire(ctx, "released", time.Minute)
+		_, err = s.Release(ctx, &v1alpha1.ReleaseRequest{LeaseId: resp.LeaseId})
+		Expect(err).NotTo(HaveOccurred())
+		_, err = s.Release(ctx, &v1alpha1.ReleaseRequest{LeaseId: resp.LeaseId})
+		Expect(status.Code(err)).To(Equal(codes.NotFound))
+		_, err = s.Extend(ctx, &v1alpha1.ExtendRequest{LeaseId: resp.LeaseId})
+		Expect(status.Code(err)).To(Equal(codes.NotFound))
+
+		By("rejecting requests without a lease")
+		_, err = s.Release(ctx, &v1alpha1.ReleaseRequest{})
+		Expect(status.Code(err)).To(Equal(codes.InvalidArgument))
+	})
+
+	It("should only allow requests on leases to the identity that acquired them", func(ctx SpecContext) {
+		alice := auth.WithIdentity(ctx, "alice")
+		bob := auth.WithIdentity(ctx, "bob")
+		resp := acquire(alice, "owned", time.Minute)
+
+		_, err := s.Extend(bob, &v1alpha1.ExtendRequest{LeaseId: resp.LeaseId})
+		Expect(status.Code(err)).To(Equal(codes.PermissionDenied))
+		_, err = s.Transfer(bob, &v1alpha1.TransferRequest{LeaseId: resp.LeaseId, ToOwner: "bob"})
+		Expect(status.Code(err)).To(Equal(codes.PermissionDenied))
+		_, err = s.Release(bob, &v1alpha1.ReleaseRequest{LeaseId: resp.LeaseId})
+		Expect(status.Code(err)).To(Equal(codes.PermissionDenied))
+		Expect(held(ctx, "owned")).To(BeTrue())
+
+		_, err = s.Extend(alice, &v1alpha1.ExtendRequest{LeaseId: resp.LeaseId})
+		Expect(err).NotTo(HaveOccurred())
+		_, err = s.Release(alice, &v1alpha1.ReleaseRequest{LeaseId: resp.LeaseId})
+		Expect(err).NotTo(HaveOccurred())
+	})
+})
@@ -0,0 +1,129 @@
+package server
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/alexandreLamarre/dlock/api/v1alpha1"
+	configv1alpha1 "github.com/alexandreLamarre/dlock/pkg/config/v1alpha1"
+	"github.com/alexandreLamarre/dlock/pkg/lock"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// DefaultMinTTL is the minimum TTL that clients can request when the configuration does not set one,
+// TTLs shorter than a second being rounded up or rejected by some backends, e.g. etcd leases
+var DefaultMinTTL = time.Second
+
+// bounds of a timing, it is unbounded on the sides that are zero
+type bounds struct {
+	min, max time.Duration
+}
+
+func parseBounds(name, minS, maxS string) (bounds, error) {
+	var b bounds
+	var err error
+	if minS != "" {
+		if b.min, err = time.ParseDuration(minS); err != nil {
+			return b, fmt.Errorf("invalid min %s : %w", name, err)
+		}
+	}
+	if maxS != "" {
+		if b.max, err = time.ParseDuration(maxS); err != nil {
+			return b, fmt.Errorf("invalid max %s : %w", name, err)
+		}
+	}
+	if b.max > 0 && b.min > b.max {
+		return b, fmt.Errorf("min %s must not exceed its max", name)
+	}
+	return b, nil
+}
+
+func (b bounds) check(name string, d time.Duration) error {
+	if b.min > 0 && d < b.min {
+		return fmt.Errorf("%s must be at least %s", name, b.min)
+	}
+	if b.max > 0 && d > b.max {
+		return fmt.Errorf("%s must be at most %s", name, b.max)
+	}
+	return nil
+}
+
+// lockLimits bound the timings that clients can request for their locks
+type lockLimits struct {
+	ttl               bounds
+	keepaliveInterval bounds
+	retryDelay        bounds
+}
+
+func newLockLimits(spec *configv1alpha1.LockLimitsSpec) (lockLimits, error) {
+	l := lockLimits{ttl: bounds{min: DefaultMinTTL}}
+	if spec == nil {
+		return l, nil
+	}
+	var err error
+	if l.ttl, err = parseBounds("ttl", spec.MinTTL, spec.MaxTTL); err != nil {
+		return l, err
+	}
+	if spec.MinTTL == "" && (l.ttl.max == 0 || l.ttl.max >= DefaultMinTTL) {
+		l.ttl.min = DefaultMinTTL
+	}
+	if l.keepaliveInterval, err = parseBounds("keepaliveInterval", spec.MinKeepaliveInterval, spec.MaxKeepaliveInterval); err != nil {
+		return l, err
+	}
+	if l.retryDelay, err = parseBounds("retryDelay", spec.MinRetryDelay, spec.MaxRetryDelay); err != nil {
+		return l, err
+	}
+	return l, nil
+}
+
+// lockTimings are the timings requested for a lock, unset or zero timings being left to the defaults
+type lockTimings interface {
+	GetTtl() *durationpb.Duration
+	GetKeepaliveInterval() *durationpb.Duration
+	GetRetryDelay() *durationpb.Duration
+}
+
+var (
+	_ lockTimings = (*v1alpha1.LockRequest)(nil)
+	_ lockTimings = (*v1alpha1.AcquireRequest)(nil)
+	_ lockTimings = (*v1alpha1.SemaphoreRequest)(nil)
+)
+
+// options returns the timings requested for a lock, unset timings are left to the backend's defaults
+func (l lockLimits) options(in lockTimings) ([]lock.LockOption, error) {
+	opts := []lock.LockOption{}
+	for _, t := range []struct {
+		name   string
+		d      *durationpb.Duration
+		bounds bounds
+		option func(time.Duration) lock.LockOption
+	}{
+		{"ttl", in.GetTtl(), l.ttl, lock.WithTTL},
+		{"keepaliveInterval", in.GetKeepaliveInterval(), l.keepaliveInterval, lock.WithKeepaliveInterval},
+		{"retryDelay", in.GetRetryDelay(), l.retryDelay, lock.WithRetryDelay},
+	} {
+		if t.d.AsDuration() == 0 {
+			continue
+		}
+		if err := t.bounds.check(t.name, t.d.AsDuration()); err != nil {
+			return nil, err
+		}
+		opts = append(opts, t.option(t.d.AsDuration()))
+	}
+	return opts, nil
+}
+
+// checkKeepalive checks that the keepalive interval of the options is less than their effective TTL,
+// which is the default TTL of the lock manager when the options do not set one
+func checkKeepalive(lm lock.LockManager, opts []lock.LockOption) error {
+	o := lock.DefaultLockOptions()
+	o.Apply(opts...)
+	var def time.Duration
+	if defaulter, ok := lm.(lock.TTLDefaulter); ok {
+		def = defaulter.DefaultTTL()
+	}
+	if ttl := o.TTLOr(def); o.KeepaliveInterval > 0 && ttl > 0 && o.KeepaliveInterval >= ttl {
+		return fmt.Errorf("keepaliveInterval must be less than the ttl %s", ttl)
+	}
+	return nil
+}
@@ -4,16 +4,21 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net"
 	"net/url"
 	"os"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/BurntSushi/toml"
 	"github.com/alexandreLamarre/dlock/api/v1alpha1"
 	configv1alpha1 "github.com/alexandreLamarre/dlock/pkg/config/v1alpha1"
+	"github.com/alexandreLamarre/dlock/pkg/graph"
 	"github.com/alexandreLamarre/dlock/pkg/lock"
 	"github.com/alexandreLamarre/dlock/pkg/lock/broker"
 	"github.com/alexandreLamarre/dlock/pkg/logger"
@@ -26,6 +31,7 @@ import (
 	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
 	healthv1 "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/keepalive"
 	"google.golang.org/grpc/status"
@@ -38,11 +44,20 @@ type LockServer struct {
 	lg     *slog.Logger
 	tracer trace.Tracer
 
-	lm lock.LockManager
+	// lock manager of the default namespace
+	lm         lock.LockManager
+	namespaces map[string]*namespace
+	leases     *leaseTable
+	graphs     *graphTable
+	limits     lockLimits
+	reentrant  reentrantTokens
+	// transport credentials of the listener, nil when it serves plaintext
+	creds credentials.TransportCredentials
+	// authentication & authorization of requests, nil when every request is allowed
+	auth *serverAuth
 }
 
 var _ v1alpha1.DlockServer = &LockServer{}
-var _ healthv1.HealthServer = &LockServer{}
 
 func NewLockServer(
 	ctx context.Context,
@@ -54,6 +69,8 @@ func NewLockServer(
 	ls := &LockServer{
 		lg:     lg,
 		tracer: tracer,
+		leases: newLeaseTable(lg),
+		graphs: newGraphTable(),
 	}
 	if err := ls.Initialize(
 		ctx,
@@ -103,23 +120,181 @@ func (s *LockServer) Initialize(
 			retErr = err
 			return
 		}
+		creds, err := serverCredentials(config.Server)
+		if err != nil {
+			lg.With(logger.Err(err)).Error("invalid server TLS config")
+			retErr = err
+			return
+		}
+		s.creds = creds
+		auth, err := newServerAuth(config.Server, lg)
+		if err != nil {
+			lg.With(logger.Err(err)).Error("invalid server auth config")
+			retErr = err
+			return
+		}
+		s.auth = auth
+		limits, err := newLockLimits(config.LockLimits)
+		if err != nil {
+			lg.With(logger.Err(err)).Error("invalid lock limits")
+			retErr = err
+			return
+		}
+		s.limits = limits
 		broker := broker.NewLockBroker(lg, config, s.tracer)
 
-		lm, err := broker.LockManager(ctx)
+		lms, err := broker.LockManagers(ctx)
 		if err != nil {
 			retErr = fmt.Errorf("failed to acquire lock manager backend : %w", err)
 			return
 		}
+		namespaces, err := newNamespaces(config.Namespaces, limits, auth != nil, lms)
+		if err != nil {
+			lg.With(logger.Err(err)).Error("invalid namespaces")
+			retErr = err
+			return
+		}
 		lg.Info("successfully acquired lock manager backend")
-		s.lm = lm
+		s.namespaces = namespaces
+		s.lm = namespaces[defaultNamespace].lm
 	})
 	return retErr
 }
 
+// newLocker returns the side of the lock matching the requested mode
+func (s *LockServer) newLocker(
+	lm lock.LockManager,
+	key string,
+	mode v1alpha1.LockMode,
+	md *v1alpha1.LockMetadata,
+	extra ...lock.LockOption,
+) lock.Lock {
+	opts := append([]lock.LockOption{
+		lock.WithTracer(s.tracer),
+		lock.WithMetadata(metadata(md)),
+	}, extra...)
+	if mode == v1alpha1.LockMode_PR {
+		return lock.RLocker(lm.NewRWLock(key, opts...))
+	}
+	return lm.NewLock(key, opts...)
+}
+
+// reentrantTokens counts the locks held through the server with each reentrant token, so that tokens only reenter
+// the locks of the server that issued them. Holds are counted by the lock manager of the namespace, which does not
+// share them with other servers.
+type reentrantTokens struct {
+	mu    sync.Mutex
+	holds map[string]int
+}
+
+// hold counts a lock held with the token until the returned func is called
+func (t *reentrantTokens) hold(token string) func() {
+	if token == "" {
+		return func() {}
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.holds == nil {
+		t.holds = map[string]int{}
+	}
+	t.holds[token]++
+	return sync.OnceFunc(func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		if t.holds[token]--; t.holds[token] == 0 {
+			delete(t.holds, token)
+		}
+	})
+}
+
+func (t *reentrantTokens) held(token string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.holds[token] > 0
+}
+
+// reentrantToken returns the token scoping the reentrancy of a request, issuing a new token to requests without one.
+// Requests presenting a token reenter the locks of their owner held through the server,
+// so the owner & token must hold every key of the request.
+func (s *LockServer) reentrantToken(ctx context.Context, lm lock.LockManager, keys []string, owner, token string) (string, error) {
+	if token == "" {
+		return lock.NewReentrantToken(), nil
+	}
+	if !s.reentrant.held(token) {
+		return "", status.Errorf(codes.FailedPrecondition, "reentrant token of '%s' holds no lock through this server", owner)
+	}
+	for _, key := range keys {
+		info, err := lm.DescribeLock(ctx, key)
+		if err != nil {
+			return "", status.Error(codes.Internal, err.Error())
+		}
+		if !lock.HoldsReentrant(info, owner, token) {
+			return "", status.Errorf(codes.FailedPrecondition, "reentrant token of '%s' does not hold key '%s'", owner, key)
+		}
+	}
+	return token, nil
+}
+
+// lockRequest is a request acquiring locks, held for the lifetime of a stream or of a lease
+type lockRequest interface {
+	lockTimings
+	GetMetadata() *v1alpha1.LockMetadata
+	GetFair() bool
+	GetReentrant() bool
+	GetReentrantToken() string
+}
+
+var (
+	_ lockRequest = (*v1alpha1.LockRequest)(nil)
+	_ lockRequest = (*v1alpha1.AcquireRequest)(nil)
+)
+
+// lockOptions returns the options of the locks of a request on the keys of the namespace, i.e. its timings bounded
+// timings returns the timings requested for a lock within the limits of the server, the TTL defaulting to the namespace's,
+// and checks that the keepalive interval is less than the TTL the lock will be acquired with
+func (s *LockServer) timings(ns *namespace, in lockTimings) ([]lock.LockOption, error) {
+	opts, err := s.limits.options(in)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	opts = append(opts, ns.options(in)...)
+	if err := checkKeepalive(ns.lm, opts); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return opts, nil
+}
+
+// by the server's limits & defaulting to the namespace's, its fairness and its reentrancy, along with its reentrant token
+func (s *LockServer) lockOptions(ctx context.Context, ns *namespace, keys []string, in lockRequest) ([]lock.LockOption, string, error) {
+	timings, err := s.timings(ns, in)
+	if err != nil {
+		return nil, "", err
+	}
+	opts := append(timings, lock.WithFair(in.GetFair()), lock.WithReentrant(in.GetReentrant()))
+	if !in.GetReentrant() {
+		return opts, "", nil
+	}
+	token, err := s.reentrantToken(ctx, ns.lm, keys, in.GetMetadata().GetOwner(), in.GetReentrantToken())
+	if err != nil {
+		return nil, "", err
+	}
+	return append(opts, lock.WithReentrantToken(token)), token, nil
+}
+
+// lockKeys returns the keys of the request, a single key being locked on its own
+func lockKeys(in *v1alpha1.LockRequest) []string {
+	keys := slices.Clone(in.Keys)
+	if in.Key != "" {
+		keys = append(keys, in.Key)
+	}
+	return slices.Compact(slices.Sorted(slices.Values(keys)))
+}
+
 // Distributed locking server
 func (s *LockServer) Lock(in *v1alpha1.LockRequest, stream v1alpha1.Dlock_LockServer) error {
 	LockRequestCount.Add(stream.Context(), 1)
-	lg := s.lg.With("key", in.Key, "block", !in.TryLock)
+	keys := lockKeys(in)
+	lg := s.lg.With("key", strings.Join(keys, ","), "block", !in.TryLock, "mode", in.Mode.String())
 	lg.Debug("received lock request")
 	if s.lm == nil {
 		s.lg.Error("no lock backend")
@@ -129,26 +304,113 @@ func (s *LockServer) Lock(in *v1alpha1.LockRequest, stream v1alpha1.Dlock_LockSe
 	if err := in.Validate(); err != nil {
 		return status.Error(codes.InvalidArgument, err.Error())
 	}
+	ns, err := s.namespace(in.Namespace)
+	if err != nil {
+		return err
+	}
+	if err := ns.allow(stream.Context()); err != nil {
+		return err
+	}
+	opts, token, err := s.lockOptions(stream.Context(), ns, keys, in)
+	if err != nil {
+		return err
+	}
+	if !in.TryLock {
+		opts = append(opts, queueEvents(lg, stream))
+	}
+	if in.Graph != "" {
+		locker, err := s.graphLocker(ns, in.Graph, in.Key, in.Metadata, opts...)
+		if err != nil {
+			return err
+		}
+		return s.hold(lg.With("graph", in.Graph), ns, in.Key, in.TryLock, locker, "", stream)
+	}
+	if len(keys) == 1 {
+		return s.hold(lg, ns, keys[0], in.TryLock, s.newLocker(ns.lm, keys[0], in.Mode, in.Metadata, opts...), token, stream)
+	}
+	locker := lock.NewMultiLock(keys, func(key string) lock.Lock {
+		return s.newLocker(ns.lm, key, in.Mode, in.Metadata, opts...)
+	})
+	return s.hold(lg, ns, strings.Join(keys, ","), in.TryLock, locker, token, stream)
+}
+
+// queueEvents streams the position of a blocking acquisition while it waits, its first position being sent
+// as a Queued event
+func queueEvents(lg *slog.Logger, stream v1alpha1.Dlock_LockServer) lock.LockOption {
+	queued := false
+	return lock.WithOnQueued(func(position int) {
+		event := v1alpha1.LockEvent_Position
+		if !queued {
+			event = v1alpha1.LockEvent_Queued
+			queued = true
+		}
+		if err := stream.Send(&v1alpha1.LockResponse{
+			Event:    event,
+			Position: int64(position),
+		}); err != nil {
+			lg.With(logger.Err(err)).Warn("failed to send queue position")
+		}
+	})
+}
+
+// Distributed counting semaphores, held for the lifetime of the stream like locks
+func (s *LockServer) Semaphore(in *v1alpha1.SemaphoreRequest, stream v1alpha1.Dlock_SemaphoreServer) error {
+	LockRequestCount.Add(stream.Context(), 1)
+	lg := s.lg.With("key", in.Key, "block", !in.TryAcquire, "weight", in.Weight, "capacity", in.Capacity)
+	lg.Debug("received semaphore request")
+	if s.lm == nil {
+		s.lg.Error("no lock backend")
+		return status.Errorf(codes.Unavailable, "no lock backend")
+	}
 
-	locker := s.lm.NewLock(in.Key, lock.WithTracer(s.tracer))
+	if err := in.Validate(); err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	ns, err := s.namespace(in.Namespace)
+	if err != nil {
+		return err
+	}
+	if err := ns.allow(stream.Context()); err != nil {
+		return err
+	}
+	timings, err := s.timings(ns, in)
+	if err != nil {
+		return err
+	}
+
+	sem := ns.lm.NewSemaphore(in.Key, in.Capacity, append(timings, lock.WithTracer(s.tracer))...)
+	return s.hold(lg, ns, in.Key, in.TryAcquire, lock.SemaphoreLocker(sem, in.Weight), "", stream)
+}
+
+// hold acquires the locker and holds it until the stream is done or the locker expires,
+// counting it as held in the namespace when it is set. The reentrant token of the locker is sent once it is acquired.
+func (s *LockServer) hold(
+	lg *slog.Logger,
+	ns *namespace,
+	key string,
+	tryLock bool,
+	locker lock.Lock,
+	reentrantToken string,
+	stream grpc.ServerStreamingServer[v1alpha1.LockResponse],
+) error {
 	ctx, lockSpan := s.tracer.Start(stream.Context(), "acquire-lock", trace.WithAttributes(
 		attribute.KeyValue{
 			Key:   "key",
-			Value: attribute.StringValue(in.Key),
+			Value: attribute.StringValue(key),
 		},
 		attribute.KeyValue{
 			Key:   "block",
-			Value: attribute.BoolValue(!in.TryLock),
+			Value: attribute.BoolValue(!tryLock),
 		}),
 	)
 	var expiredC <-chan struct{}
-	if in.TryLock {
+	if tryLock {
 		acquired, expired, err := locker.TryLock(ctx)
 		if err != nil {
 			lg.With(logger.Err(err)).Error("failed to acquire lock")
 			lockSpan.RecordError(err)
 			lockSpan.End()
-			return status.Errorf(codes.Internal, "%s", err.Error())
+			return lockError(err)
 		}
 		expiredC = expired
 		if !acquired {
@@ -165,10 +427,10 @@ func (s *LockServer) Lock(in *v1alpha1.LockRequest, stream v1alpha1.Dlock_LockSe
 	} else {
 		expired, err := locker.Lock(ctx)
 		if err != nil {
-			lg.With(logger.Err(err)).Error("failed to acquire blocking lock", "key", in.Key)
+			lg.With(logger.Err(err)).Error("failed to acquire blocking lock")
 			lockSpan.RecordError(err)
 			lockSpan.End()
-			return status.Errorf(codes.Internal, "%s", err.Error())
+			return lockError(err)
 		}
 		expiredC = expired
 	}
@@ -179,18 +441,29 @@ func (s *LockServer) Lock(in *v1alpha1.LockRequest, stream v1alpha1.Dlock_LockSe
 			s.lg.Error("failed to unlock lock")
 		}
 	}()
+	defer s.reentrant.hold(reentrantToken)()
+	if ns != nil {
+		unhold, err := ns.hold()
+		if err != nil {
+			lg.With(logger.Err(err)).Warn("namespace holds too many locks")
+			return err
+		}
+		defer unhold()
+	}
 	LockAcquisitionCount.Add(stream.Context(), 1)
 	lockHoldStart := time.Now()
-	lg.Debug("acquired lock")
+	lg.Debug("acquired lock", "fencingToken", locker.FencingToken())
 	if err := stream.Send(&v1alpha1.LockResponse{
-		Event: v1alpha1.LockEvent_Acquired,
+		Event:          v1alpha1.LockEvent_Acquired,
+		FencingToken:   locker.FencingToken(),
+		ReentrantToken: reentrantToken,
 	}); err != nil {
 		return err
 	}
 	var streamErr error
 	select {
 	case <-stream.Context().Done():
-		lg.Debug("lock request terminated due to stream context deadline", "key", in.Key)
+		lg.Debug("lock request terminated due to stream context deadline")
 		streamErr = stream.Context().Err()
 		if status.FromContextError(streamErr).Code() == codes.Canceled { //nolint
 			lg.Debug("lock cancelled normally")
@@ -208,6 +481,19 @@ func (s *LockServer) Lock(in *v1alpha1.LockRequest, stream v1alpha1.Dlock_LockSe
 	return streamErr
 }
 
+// lockError returns the status of the errors of acquisitions
+func lockError(err error) error {
+	switch {
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return status.FromContextError(err).Err()
+	case errors.Is(err, graph.ErrClosed):
+		return status.Error(codes.Aborted, err.Error())
+	case errors.Is(err, lock.ErrReentrantOwner), errors.Is(err, lock.ErrReentrantToken):
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	return status.Error(codes.Internal, err.Error())
+}
+
 func (s *LockServer) ListenAndServe(ctx context.Context, addr string) error {
 	url, err := url.Parse(addr)
 	if err != nil {
@@ -221,7 +507,7 @@ func (s *LockServer) ListenAndServe(ctx context.Context, addr string) error {
 		return err
 	}
 
-	server := grpc.NewServer(
+	opts := []grpc.ServerOption{
 		grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{
 			MinTime:             15 * time.Second,
 			PermitWithoutStream: true,
@@ -231,11 +517,27 @@ func (s *LockServer) ListenAndServe(ctx context.Context, addr string) error {
 			Timeout: 5 * time.Second,
 		}),
 		grpc.StatsHandler(otelgrpc.NewServerHandler()),
-	)
+	}
+	if s.creds != nil {
+		opts = append(opts, grpc.Creds(s.creds))
+	}
+	if s.auth != nil {
+		opts = append(opts,
+			grpc.ChainUnaryInterceptor(s.auth.unaryInterceptor),
+			grpc.ChainStreamInterceptor(s.auth.streamInterceptor),
+		)
+	}
+	server := grpc.NewServer(opts...)
 	server.RegisterService(&v1alpha1.Dlock_ServiceDesc, s)
-	server.RegisterService(&healthv1.Health_ServiceDesc, s)
+	// force releases evict the holders of any lock, so they are only served to clients authorized to run them
+	if s.auth != nil {
+		server.RegisterService(&v1alpha1.DlockAdmin_ServiceDesc, &adminServer{LockServer: s})
+	} else {
+		s.lg.Warn("not serving the admin API, it requires server auth")
+	}
+	server.RegisterService(&healthv1.Health_ServiceDesc, &healthServer{LockServer: s})
 	errC := lo.Async(func() error {
-		s.lg.With("addr", addr).Info(fmt.Sprintf("starting distributed lock server version : %s...", version.FriendlyVersion()))
+		s.lg.With("addr", addr, "tls", s.creds != nil, "auth", s.auth != nil).Info(fmt.Sprintf("starting distributed lock server version : %s...", version.FriendlyVersion()))
 		return server.Serve(listener)
 	})
 
@@ -14,6 +14,10 @@ var (
 	LockAcquisitionCount api.Float64Counter
 	LockRequestCount     api.Float64Counter
 	LockHeldTime         api.Float64Histogram
+	// number of locks force released by operators
+	LockForceReleaseCount api.Float64Counter
+	// number of requests denied for missing credentials or permissions, by method & code
+	AuthDeniedCount api.Float64Counter
 
 	// TODO : unused
 	LockAcquisitionLatency api.Float64Histogram
@@ -66,6 +70,16 @@ func createMetrics() {
 		panic(err)
 	}
 
+	lockForceReleaseCount, err := meter.Float64Counter("lock_force_release_count")
+	if err != nil {
+		panic(err)
+	}
+
+	authDeniedCount, err := meter.Float64Counter("auth_denied_count")
+	if err != nil {
+		panic(err)
+	}
+
 	LockAcquisitionCount = lockAcquisitionCount
 	LockAcquisitionLatency = lockAcquisitionLatency
 	LockRequestCount = lockRequestCount
@@ -74,6 +88,8 @@ func createMetrics() {
 	UnlockRequestCount = unlockRequestCount
 	UnlockSuccessCount = unlockSuccessCount
 	LockHeldTime = lockHeldTime
+	LockForceReleaseCount = lockForceReleaseCount
+	AuthDeniedCount = authDeniedCount
 }
 
 func init() {
@@ -0,0 +1,173 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/alexandreLamarre/dlock/api/v1alpha1"
+	"github.com/alexandreLamarre/dlock/pkg/auth"
+	configv1alpha1 "github.com/alexandreLamarre/dlock/pkg/config/v1alpha1"
+	"github.com/alexandreLamarre/dlock/pkg/lock"
+	"github.com/alexandreLamarre/dlock/pkg/lock/broker"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// defaultNamespace is used by requests without a namespace, its keys are under the broker's default prefix
+const defaultNamespace = "default"
+
+// names & prefixes of namespaces are restricted to characters every backend accepts in its keys
+var validNamespace = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// namespace is a collection of locks with its own backend prefix & policy
+type namespace struct {
+	name string
+	lm   lock.LockManager
+
+	// TTL of the locks & leases that do not request one, the defaults are used when it is zero
+	defaultTTL time.Duration
+	// unbounded when zero
+	maxHolders int64
+	// identities of the clients allowed to acquire locks, every client is allowed when empty
+	allowed []string
+
+	holders atomic.Int64
+}
+
+// newNamespaces returns the configured namespaces along with the default namespace, each holding the lock manager
+// of its prefix. Namespaces can only restrict their clients when requests are authenticated.
+func newNamespaces(
+	specs []configv1alpha1.NamespaceSpec,
+	limits lockLimits,
+	authenticated bool,
+	lms broker.LockManagers,
+) (map[string]*namespace, error) {
+	if !slices.ContainsFunc(specs, func(spec configv1alpha1.NamespaceSpec) bool {
+		return spec.Name == defaultNamespace
+	}) {
+		specs = append(slices.Clone(specs), configv1alpha1.NamespaceSpec{Name: defaultNamespace})
+	}
+	prefixes := map[string]string{}
+	namespaces := map[string]*namespace{}
+	for _, spec := range specs {
+		if !validNamespace.MatchString(spec.Name) {
+			return nil, fmt.Errorf("invalid namespace name '%s'", spec.Name)
+		}
+		if _, ok := namespaces[spec.Name]; ok {
+			return nil, fmt.Errorf("duplicate namespace %s", spec.Name)
+		}
+		prefix := spec.Prefix
+		switch {
+		case spec.Name == defaultNamespace && prefix != "" && prefix != broker.DefaultPrefix:
+			return nil, fmt.Errorf("the prefix of the default namespace can't be changed from '%s'", broker.DefaultPrefix)
+		case spec.Name == defaultNamespace:
+			prefix = broker.DefaultPrefix
+		case prefix == "":
+			prefix = spec.Name
+		}
+		if !validNamespace.MatchString(prefix) {
+			return nil, fmt.Errorf("invalid prefix '%s' for namespace %s", prefix, spec.Name)
+		}
+		// keys of a namespace would otherwise be listed & watched as keys of the other namespace
+		for other, otherPrefix := range prefixes {
+			if strings.HasPrefix(prefix, otherPrefix) || strings.HasPrefix(otherPrefix, prefix) {
+				return nil, fmt.Errorf("prefix '%s' of namespace %s overlaps prefix '%s' of namespace %s",
+					prefix, spec.Name, otherPrefix, other)
+			}
+		}
+		prefixes[spec.Name] = prefix
+
+		if len(spec.AllowedClients) > 0 && !authenticated {
+			return nil, fmt.Errorf("allowedClients of namespace %s requires server auth", spec.Name)
+		}
+		if spec.MaxHolders < 0 {
+			return nil, fmt.Errorf("maxHolders of namespace %s must not be negative", spec.Name)
+		}
+		ns := &namespace{
+			name:       spec.Name,
+			maxHolders: int64(spec.MaxHolders),
+			allowed:    spec.AllowedClients,
+		}
+		if spec.DefaultTTL != "" {
+			ttl, err := time.ParseDuration(spec.DefaultTTL)
+			if err != nil {
+				return nil, fmt.Errorf("invalid defaultTTL of namespace %s : %w", spec.Name, err)
+			}
+			if ttl <= 0 {
+				return nil, fmt.Errorf("defaultTTL of namespace %s must be positive", spec.Name)
+			}
+			if err := limits.ttl.check("defaultTTL of namespace "+spec.Name, ttl); err != nil {
+				return nil, err
+			}
+			ns.defaultTTL = ttl
+		}
+		lm, err := lms(prefix)
+		if err != nil {
+			return nil, fmt.Errorf("failed to acquire lock manager of namespace %s : %w", spec.Name, err)
+		}
+		ns.lm = lm
+		namespaces[spec.Name] = ns
+	}
+	return namespaces, nil
+}
+
+// namespace returns the namespace of a request, requests without a namespace use the default namespace
+func (s *LockServer) namespace(name string) (*namespace, error) {
+	if name == "" {
+		name = defaultNamespace
+	}
+	ns, ok := s.namespaces[name]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "unknown namespace %s", name)
+	}
+	return ns, nil
+}
+
+// allow checks that the authenticated client of the request is allowed to acquire locks in the namespace
+func (n *namespace) allow(ctx context.Context) error {
+	if len(n.allowed) == 0 {
+		return nil
+	}
+	identity, ok := auth.IdentityFromContext(ctx)
+	if !ok {
+		return status.Errorf(codes.PermissionDenied, "namespace %s only allows authenticated clients", n.name)
+	}
+	if !slices.Contains(n.allowed, identity) {
+		return status.Errorf(codes.PermissionDenied, "client '%s' is not allowed in namespace %s", identity, n.name)
+	}
+	return nil
+}
+
+// options returns the default TTL of the namespace for lock requests that do not set one
+func (n *namespace) options(in lockTimings) []lock.LockOption {
+	if in.GetTtl().AsDuration() > 0 || n.defaultTTL == 0 {
+		return nil
+	}
+	return []lock.LockOption{lock.WithTTL(n.defaultTTL)}
+}
+
+// leaseTTL returns the TTL of a lease, leases that do not request one get the default TTL of the namespace
+func (n *namespace) leaseTTL(in *v1alpha1.AcquireRequest) time.Duration {
+	if (in.Ttl == nil || in.Ttl.AsDuration() == 0) && n.defaultTTL > 0 {
+		return min(n.defaultTTL, MaxLeaseTTL)
+	}
+	return leaseTTL(in.Ttl)
+}
+
+// hold counts a lock held in the namespace until the returned func is called,
+// failing when the namespace already holds its maximum number of locks
+func (n *namespace) hold() (func(), error) {
+	if n.holders.Add(1) > n.maxHolders && n.maxHolders > 0 {
+		n.holders.Add(-1)
+		return nil, status.Errorf(codes.ResourceExhausted, "namespace %s already holds %d locks", n.name, n.maxHolders)
+	}
+	return sync.OnceFunc(func() {
+		n.holders.Add(-1)
+	}), nil
+}
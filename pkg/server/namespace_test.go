@@ -0,0 +1,139 @@
+package server
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/alexandreLamarre/dlock/pkg/auth"
+	configv1alpha1 "github.com/alexandreLamarre/dlock/pkg/config/v1alpha1"
+	"github.com/alexandreLamarre/dlock/pkg/lock"
+	"github.com/alexandreLamarre/dlock/pkg/lock/broker"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var _ = Describe("Namespaces", Label("unit"), func() {
+	// prefixes of the lock managers acquired by the namespaces
+	var prefixes []string
+	lms := func(prefix string) (lock.LockManager, error) {
+		prefixes = append(prefixes, prefix)
+		return nil, nil
+	}
+	limits, err := newLockLimits(&configv1alpha1.LockLimitsSpec{MinTTL: "1s", MaxTTL: "1m"})
+	Expect(err).NotTo(HaveOccurred())
+
+	BeforeEach(func() {
+		prefixes = nil
+	})
+
+	DescribeTable("should validate their specs",
+		func(specs []configv1alpha1.NamespaceSpec, authenticated bool, expected string) {
+			_, err := newNamespaces(specs, limits, authenticated, lms)
+			if expected == "" {
+				Expect(err).NotTo(HaveOccurred())
+			} else {
+				Expect(err).To(MatchError(ContainSubstring(expected)))
+			}
+		},
+		Entry("no namespaces", nil, false, ""),
+		Entry("invalid names", []configv1alpha1.NamespaceSpec{{Name: "a/b"}}, false, "invalid namespace name"),
+		Entry("invalid prefixes", []configv1alpha1.NamespaceSpec{{Name: "a", Prefix: "a.b"}}, false, "invalid prefix"),
+		Entry("duplicate names", []configv1alpha1.NamespaceSpec{{Name: "a"}, {Name: "a", Prefix: "b"}}, false,
+			"duplicate namespace"),
+		Entry("overlapping prefixes", []configv1alpha1.NamespaceSpec{{Name: "billing"}, {Name: "bill"}}, false,
+			"overlaps"),
+		Entry("prefixes overlapping the default prefix", []configv1alpha1.NamespaceSpec{{Name: "locks"}}, false,
+			"overlaps"),
+		Entry("the default prefix", []configv1alpha1.NamespaceSpec{{Name: defaultNamespace, Prefix: broker.DefaultPrefix}},
+			false, ""),
+		Entry("another prefix of the default namespace", []configv1alpha1.NamespaceSpec{{Name: defaultNamespace, Prefix: "other"}},
+			false, "can't be changed"),
+		Entry("negative maxHolders", []configv1alpha1.NamespaceSpec{{Name: "a", MaxHolders: -1}}, false,
+			"must not be negative"),
+		Entry("invalid defaultTTL", []configv1alpha1.NamespaceSpec{{Name: "a", DefaultTTL: "soon"}}, false,
+			"invalid defaultTTL"),
+		Entry("non-positive defaultTTL", []configv1alpha1.NamespaceSpec{{Name: "a", DefaultTTL: "0s"}}, false,
+			"must be positive"),
+		Entry("defaultTTL below the limits", []configv1alpha1.NamespaceSpec{{Name: "a", DefaultTTL: "500ms"}}, false,
+			"defaultTTL of namespace a"),
+		Entry("defaultTTL above the limits", []configv1alpha1.NamespaceSpec{{Name: "a", DefaultTTL: "1h"}}, false,
+			"defaultTTL of namespace a"),
+		Entry("defaultTTL within the limits", []configv1alpha1.NamespaceSpec{{Name: "a", DefaultTTL: "30s"}}, false, ""),
+		Entry("allowed clients without auth", []configv1alpha1.NamespaceSpec{{Name: "a", AllowedClients: []string{"ci"}}},
+			false, "requires server auth"),
+		Entry("allowed clients with auth", []configv1alpha1.NamespaceSpec{{Name: "a", AllowedClients: []string{"ci"}}},
+			true, ""),
+	)
+
+	It("should map namespaces to their prefixes", func() {
+		namespaces, err := newNamespaces([]configv1alpha1.NamespaceSpec{
+			{Name: "billing", DefaultTTL: "30s"},
+			{Name: "ci", Prefix: "builds"},
+		}, limits, false, lms)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(namespaces).To(HaveLen(3))
+		Expect(namespaces).To(HaveKey(defaultNamespace))
+		Expect(namespaces["billing"].defaultTTL).To(Equal(30 * time.Second))
+		Expect(prefixes).To(ConsistOf("billing", "builds", broker.DefaultPrefix))
+	})
+
+	It("should only allow the identities of their clients", func() {
+		ns := &namespace{name: "billing", allowed: []string{"invoicer"}}
+		Expect(ns.allow(auth.WithIdentity(context.Background(), "invoicer"))).To(Succeed())
+		Expect(status.Code(ns.allow(auth.WithIdentity(context.Background(), "reporter")))).To(Equal(codes.PermissionDenied))
+		Expect(status.Code(ns.allow(context.Background()))).To(Equal(codes.PermissionDenied))
+		Expect((&namespace{name: "open"}).allow(context.Background())).To(Succeed())
+	})
+
+	It("should bound the locks held at once", func() {
+		ns := &namespace{name: "billing", maxHolders: 2}
+		release, err := ns.hold()
+		Expect(err).NotTo(HaveOccurred())
+		_, err = ns.hold()
+		Expect(err).NotTo(HaveOccurred())
+		_, err = ns.hold()
+		Expect(status.Code(err)).To(Equal(codes.ResourceExhausted))
+
+		By("counting each release once")
+		release()
+		release()
+		Expect(ns.holders.Load()).To(BeEquivalentTo(1))
+		_, err = ns.hold()
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should never exceed maxHolders under concurrency", func() {
+		ns := &namespace{name: "billing", maxHolders: 5}
+		var mu sync.Mutex
+		held, peak := 0, 0
+		var wg sync.WaitGroup
+		for range 50 {
+			wg.Add(1)
+			go func() {
+				defer GinkgoRecover()
+				defer wg.Done()
+				for range 100 {
+					release, err := ns.hold()
+					if err != nil {
+						Expect(status.Code(err)).To(Equal(codes.ResourceExhausted))
+						continue
+					}
+					mu.Lock()
+					held++
+					peak = max(peak, held)
+					mu.Unlock()
+					mu.Lock()
+					held--
+					mu.Unlock()
+					release()
+				}
+			}()
+		}
+		wg.Wait()
+		Expect(peak).To(BeNumerically("<=", 5))
+		Expect(ns.holders.Load()).To(BeZero())
+	})
+})
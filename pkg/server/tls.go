@@ -0,0 +1,23 @@
+package server
+
+import (
+	configv1alpha1 "github.com/alexandreLamarre/dlock/pkg/config/v1alpha1"
+	"github.com/alexandreLamarre/dlock/pkg/util"
+	"google.golang.org/grpc/credentials"
+)
+
+// serverCredentials returns the transport credentials of the listener, or nil when TLS is not configured
+func serverCredentials(spec *configv1alpha1.ServerSpec) (credentials.TransportCredentials, error) {
+	if spec == nil || spec.TLS == nil {
+		return nil, nil
+	}
+	clientAuth, err := util.ParseClientAuth(spec.TLS.ClientAuth)
+	if err != nil {
+		return nil, err
+	}
+	config, err := util.LoadServerTLSConfig(spec.TLS.CertsSpec, clientAuth)
+	if err != nil {
+		return nil, err
+	}
+	return credentials.NewTLS(config), nil
+}
@@ -0,0 +1,81 @@
+//go:build !minimal
+
+package integration
+
+import (
+	"context"
+	"time"
+
+	"github.com/alexandreLamarre/dlock/pkg/lock"
+	"github.com/alexandreLamarre/dlock/pkg/util/future"
+
+	//nolint:all
+	. "github.com/onsi/ginkgo/v2"
+	//nolint:all
+	. "github.com/onsi/gomega"
+
+	"github.com/samber/lo"
+)
+
+// Crash returns a lock manager on a client connection of its own, along with a func closing that connection
+// without releasing anything acquired through it, as if the process holding it crashed
+type Crash func() (lock.LockManager, func())
+
+// CrashTestSuite checks that the backend stops counting the acquisitions of crashed processes once their TTL
+// elapses
+func CrashTestSuite(
+	lmSetF future.Future[lo.Tuple3[
+		lock.LockManager, lock.LockManager, lock.LockManager,
+	]],
+	crashF future.Future[Crash],
+) func() {
+	return func() {
+		var lmSet lo.Tuple3[lock.LockManager, lock.LockManager, lock.LockManager]
+		var crash Crash
+		var ctx context.Context
+
+		BeforeAll(func() {
+			ctxca, ca := context.WithCancel(context.Background())
+			DeferCleanup(func() {
+				ca()
+			})
+			ctx = ctxca
+			lmSet = lmSetF.Get()
+			crash = crashF.Get()
+		})
+
+		When("using barriers", func() {
+			BeforeEach(func() {
+				if _, ok := lmSet.A.(lock.BarrierManager); !ok {
+					Skip("lock backend does not support barriers & latches")
+				}
+			})
+
+			It("should stop counting crashed participants once their TTL elapses", func() {
+				ttl := 2 * time.Second
+				opts := []lock.LockOption{lock.WithTTL(ttl), lock.WithKeepaliveInterval(200 * time.Millisecond)}
+
+				By("registering a participant that crashes while it waits")
+				crashed, kill := crash()
+				errCrashed := make(chan error, 1)
+				go func() {
+					// the context of the crashed participant is never canceled, so that it never unregisters
+					errCrashed <- lo.Must(lock.NewBarrier(crashed, "barrier-crashed", 2, opts...)).Wait(context.Background())
+				}()
+				Consistently(errCrashed, 500*time.Millisecond).ShouldNot(Receive())
+				kill()
+
+				By("verifying the crashed participant is no longer counted after its TTL")
+				time.Sleep(2 * ttl)
+				errA := make(chan error, 1)
+				go func() {
+					errA <- lo.Must(lock.NewBarrier(lmSet.A, "barrier-crashed", 2, opts...)).Wait(ctx)
+				}()
+				Consistently(errA, time.Second).ShouldNot(Receive())
+
+				Expect(lo.Must(lock.NewBarrier(lmSet.B, "barrier-crashed", 2, opts...)).Wait(ctx)).To(Succeed())
+				Eventually(errA, 10*time.Second).Should(Receive(BeNil()))
+			})
+		})
+	}
+}
@@ -6,9 +6,11 @@ import (
 	"context"
 	"math/rand"
 	"runtime"
+	"slices"
 	"sync"
 	"time"
 
+	"github.com/alexandreLamarre/dlock/pkg/election"
 	"github.com/alexandreLamarre/dlock/pkg/lock"
 	"github.com/alexandreLamarre/dlock/pkg/util"
 	"github.com/alexandreLamarre/dlock/pkg/util/future"
@@ -159,6 +161,32 @@ func LockManagerTestSuite(
 				Expect(num).To(Equal(len(locks)))
 			})
 
+			It("should issue monotonically increasing fencing tokens", func() {
+				lock1 := lm.NewLock("fenced")
+				Expect(lock1.FencingToken()).To(BeZero())
+				done1, err := lock1.Lock(ctx)
+				Expect(err).To(Succeed())
+				token1 := lock1.FencingToken()
+				Expect(token1).NotTo(BeZero())
+				Expect(lock1.Unlock()).To(Succeed())
+				Eventually(done1).Should(Receive())
+				Expect(lock1.FencingToken()).To(BeZero())
+
+				lock2 := lm.NewLock("fenced")
+				done2, err := lock2.Lock(ctx)
+				Expect(err).To(Succeed())
+				token2 := lock2.FencingToken()
+				Expect(token2).To(BeNumerically(">", token1))
+				Expect(lock2.Unlock()).To(Succeed())
+				Eventually(done2).Should(Receive())
+
+				done1, err = lock1.Lock(ctx)
+				Expect(err).To(Succeed())
+				Expect(lock1.FencingToken()).To(BeNumerically(">", token2))
+				Expect(lock1.Unlock()).To(Succeed())
+				Eventually(done1).Should(Receive())
+			})
+
 			It("is 'safe' to reuse a lock", func() {
 				lock1 := lm.NewLock("todo")
 				done1, err := lock1.Lock(ctx)
@@ -253,6 +281,19 @@ func LockManagerTestSuite(
 					Eventually(doneZ).Should(Receive())
 				})
 
+				It("should issue monotonically increasing fencing tokens", func() {
+					var last uint64
+					for _, lm := range []lock.LockManager{lmSet.A, lmSet.B, lmSet.C} {
+						l := lm.NewLock("fenced-conns")
+						done, err := l.Lock(ctx)
+						Expect(err).To(Succeed())
+						Expect(l.FencingToken()).To(BeNumerically(">", last))
+						last = l.FencingToken()
+						Expect(l.Unlock()).To(Succeed())
+						Eventually(done).Should(Receive())
+					}
+				})
+
 				Specify("try lock should fail quickly is another manager is holding a lock", func() {
 					x := lmSet.A.NewLock("qu")
 					y := lmSet.B.NewLock("qu")
@@ -276,6 +317,1006 @@ func LockManagerTestSuite(
 				})
 			})
 
+			When("using distributed read/write locks", func() {
+				It("should allow concurrent readers", func() {
+					r1 := lm.NewRWLock("rw-readers")
+					r2 := lmSet.A.NewRWLock("rw-readers")
+					done1, err := r1.RLock(ctx)
+					Expect(err).To(Succeed())
+					ack, done2, err := r2.TryRLock(ctx)
+					Expect(err).To(Succeed())
+					Expect(ack).To(BeTrue())
+					Expect(r1.RUnlock()).To(Succeed())
+					Expect(r2.RUnlock()).To(Succeed())
+					Eventually(done1).Should(Receive())
+					Eventually(done2).Should(Receive())
+				})
+
+				It("should exclude writers while readers hold the lock", func() {
+					r := lm.NewRWLock("rw-writers")
+					w := lmSet.B.NewRWLock("rw-writers")
+					exclusive := lmSet.C.NewLock("rw-writers")
+					doneR, err := r.RLock(ctx)
+					Expect(err).To(Succeed())
+
+					ack, doneW, err := w.TryLock(ctx)
+					Expect(err).To(Succeed())
+					Expect(ack).To(BeFalse())
+					Expect(doneW).To(BeNil())
+
+					ack, doneEx, err := exclusive.TryLock(ctx)
+					Expect(err).To(Succeed())
+					Expect(ack).To(BeFalse())
+					Expect(doneEx).To(BeNil())
+
+					Expect(r.RUnlock()).To(Succeed())
+					Eventually(doneR).Should(Receive())
+
+					doneW, err = w.Lock(ctx)
+					Expect(err).To(Succeed())
+					Expect(w.FencingToken()).NotTo(BeZero())
+					Expect(w.Unlock()).To(Succeed())
+					Eventually(doneW).Should(Receive())
+				})
+
+				It("should exclude readers while a writer holds the lock", func() {
+					w := lm.NewRWLock("rw-exclusive")
+					r := lmSet.A.NewRWLock("rw-exclusive")
+					doneW, err := w.Lock(ctx)
+					Expect(err).To(Succeed())
+
+					ack, doneR, err := r.TryRLock(ctx)
+					Expect(err).To(Succeed())
+					Expect(ack).To(BeFalse())
+					Expect(doneR).To(BeNil())
+
+					acquired := make(chan (<-chan struct{}))
+					go func() {
+						defer GinkgoRecover()
+						done, err := r.RLock(ctx)
+						Expect(err).To(Succeed())
+						acquired <- done
+					}()
+					Consistently(acquired, 200*time.Millisecond).ShouldNot(Receive())
+					Expect(w.Unlock()).To(Succeed())
+					Eventually(doneW).Should(Receive())
+
+					var doneRead <-chan struct{}
+					Eventually(acquired, 10*time.Second).Should(Receive(&doneRead))
+					Expect(r.FencingToken()).To(BeZero())
+					Expect(r.RUnlock()).To(Succeed())
+					Eventually(doneRead).Should(Receive())
+				})
+
+				It("should not release a side of the lock that is not held", func() {
+					rw := lm.NewRWLock("rw-mode")
+					done, err := rw.RLock(ctx)
+					Expect(err).To(Succeed())
+					Expect(rw.Unlock()).To(MatchError(lock.ErrLockMode))
+					Expect(rw.RUnlock()).To(Succeed())
+					Eventually(done).Should(Receive())
+				})
+			})
+
+			When("using distributed semaphores", func() {
+				It("should not exceed the capacity of the semaphore", func() {
+					s1 := lm.NewSemaphore("sem-capacity", 3)
+					s2 := lmSet.A.NewSemaphore("sem-capacity", 3)
+					s3 := lmSet.B.NewSemaphore("sem-capacity", 3)
+					Expect(s1.Capacity()).To(Equal(int64(3)))
+
+					done1, err := s1.Acquire(ctx, 2)
+					Expect(err).To(Succeed())
+					ack, done2, err := s2.TryAcquire(ctx, 1)
+					Expect(err).To(Succeed())
+					Expect(ack).To(BeTrue())
+
+					ack, done3, err := s3.TryAcquire(ctx, 1)
+					Expect(err).To(Succeed())
+					Expect(ack).To(BeFalse())
+					Expect(done3).To(BeNil())
+
+					Expect(s2.Release()).To(Succeed())
+					Eventually(done2).Should(Receive())
+					Eventually(func() bool {
+						ack, done3, err = s3.TryAcquire(ctx, 1)
+						Expect(err).To(Succeed())
+						return ack
+					}, 10*time.Second).Should(BeTrue())
+
+					Expect(s1.Release()).To(Succeed())
+					Expect(s3.Release()).To(Succeed())
+					Eventually(done1).Should(Receive())
+					Eventually(done3).Should(Receive())
+				})
+
+				It("should block until enough units are released", func() {
+					s1 := lm.NewSemaphore("sem-block", 2)
+					s2 := lmSet.C.NewSemaphore("sem-block", 2)
+					done1, err := s1.Acquire(ctx, 1)
+					Expect(err).To(Succeed())
+
+					acquired := make(chan (<-chan struct{}))
+					go func() {
+						defer GinkgoRecover()
+						done, err := s2.Acquire(ctx, 2)
+						Expect(err).To(Succeed())
+						acquired <- done
+					}()
+					Consistently(acquired, 200*time.Millisecond).ShouldNot(Receive())
+					Expect(s1.Release()).To(Succeed())
+					Eventually(done1).Should(Receive())
+
+					var done2 <-chan struct{}
+					Eventually(acquired, 10*time.Second).Should(Receive(&done2))
+					Expect(s2.Release()).To(Succeed())
+					Eventually(done2).Should(Receive())
+				})
+
+				It("should reject weights outside of the capacity", func() {
+					sem := lm.NewSemaphore("sem-weight", 2)
+					_, err := sem.Acquire(ctx, 3)
+					Expect(err).To(MatchError(lock.ErrSemaphoreWeight))
+					_, _, err = sem.TryAcquire(ctx, 0)
+					Expect(err).To(MatchError(lock.ErrSemaphoreWeight))
+				})
+
+				It("should not share keys with locks", func() {
+					sem := lm.NewSemaphore("sem-lock", 1)
+					l := lmSet.A.NewLock("sem-lock")
+					doneS, err := sem.Acquire(ctx, 1)
+					Expect(err).To(Succeed())
+					ack, doneL, err := l.TryLock(ctx)
+					Expect(err).To(Succeed())
+					Expect(ack).To(BeTrue())
+					Expect(sem.Release()).To(Succeed())
+					Expect(l.Unlock()).To(Succeed())
+					Eventually(doneS).Should(Receive())
+					Eventually(doneL).Should(Receive())
+				})
+			})
+
+			When("describing locks", func() {
+				It("should describe the holders of a lock", func() {
+					info, err := lm.DescribeLock(ctx, "introspect-free")
+					Expect(err).To(Succeed())
+					Expect(info.Key).To(Equal("introspect-free"))
+					Expect(info.Holders).To(BeEmpty())
+
+					md := lock.Metadata{
+						Owner:    "conformance",
+						Hostname: "host",
+						Pid:      42,
+						Labels:   map[string]string{"job": "test"},
+					}
+					before := time.Now().Add(-time.Second)
+					l := lmSet.A.NewLock("introspect-held", lock.WithMetadata(md))
+					done, err := l.Lock(ctx)
+					Expect(err).To(Succeed())
+
+					info, err = lm.DescribeLock(ctx, "introspect-held")
+					Expect(err).To(Succeed())
+					Expect(info.Key).To(Equal("introspect-held"))
+					Expect(info.Holders).To(HaveLen(1))
+					Expect(info.Holders[0].Metadata).To(Equal(md))
+					Expect(info.Holders[0].Shared).To(BeFalse())
+					Expect(info.Holders[0].AcquiredAt).To(BeTemporally(">", before))
+					Expect(info.Holders[0].TTL).To(BeNumerically(">=", 0))
+
+					Expect(l.Unlock()).To(Succeed())
+					Eventually(done).Should(Receive())
+					Eventually(func() []lock.HolderInfo {
+						info, err := lm.DescribeLock(ctx, "introspect-held")
+						Expect(err).To(Succeed())
+						return info.Holders
+					}, 10*time.Second).Should(BeEmpty())
+				})
+
+				It("should describe the readers of a lock", func() {
+					r1 := lmSet.A.NewRWLock("introspect-readers", lock.WithMetadata(lock.Metadata{Owner: "r1"}))
+					r2 := lmSet.B.NewRWLock("introspect-readers", lock.WithMetadata(lock.Metadata{Owner: "r2"}))
+					done1, err := r1.RLock(ctx)
+					Expect(err).To(Succeed())
+					done2, err := r2.RLock(ctx)
+					Expect(err).To(Succeed())
+
+					info, err := lm.DescribeLock(ctx, "introspect-readers")
+					Expect(err).To(Succeed())
+					Expect(info.Holders).To(HaveLen(2))
+					owners := []string{}
+					for _, h := range info.Holders {
+						Expect(h.Shared).To(BeTrue())
+						owners = append(owners, h.Owner)
+					}
+					Expect(owners).To(ConsistOf("r1", "r2"))
+
+					Expect(r1.RUnlock()).To(Succeed())
+					Expect(r2.RUnlock()).To(Succeed())
+					Eventually(done1).Should(Receive())
+					Eventually(done2).Should(Receive())
+				})
+
+				It("should count the waiters of a lock", func() {
+					l := lmSet.A.NewLock("introspect-waiters")
+					waiter := lmSet.B.NewLock("introspect-waiters")
+					done, err := l.Lock(ctx)
+					Expect(err).To(Succeed())
+
+					acquired := make(chan (<-chan struct{}))
+					go func() {
+						defer GinkgoRecover()
+						done, err := waiter.Lock(ctx)
+						Expect(err).To(Succeed())
+						acquired <- done
+					}()
+					Eventually(func() int {
+						info, err := lm.DescribeLock(ctx, "introspect-waiters")
+						Expect(err).To(Succeed())
+						return info.Waiters
+					}, 10*time.Second).Should(Equal(1))
+
+					Expect(l.Unlock()).To(Succeed())
+					Eventually(done).Should(Receive())
+					var doneWaiter <-chan struct{}
+					Eventually(acquired, 10*time.Second).Should(Receive(&doneWaiter))
+					Eventually(func() int {
+						info, err := lm.DescribeLock(ctx, "introspect-waiters")
+						Expect(err).To(Succeed())
+						return info.Waiters
+					}, 10*time.Second).Should(BeZero())
+					Expect(waiter.Unlock()).To(Succeed())
+					Eventually(doneWaiter).Should(Receive())
+				})
+
+				It("should list the locks held under a prefix", func() {
+					a := lmSet.A.NewLock("introspect-list-a")
+					b := lmSet.B.NewRWLock("introspect-list-b")
+					other := lmSet.C.NewLock("introspect-other")
+					doneA, err := a.Lock(ctx)
+					Expect(err).To(Succeed())
+					doneB, err := b.RLock(ctx)
+					Expect(err).To(Succeed())
+					doneOther, err := other.Lock(ctx)
+					Expect(err).To(Succeed())
+
+					infos, err := lm.ListLocks(ctx, "introspect-list-")
+					Expect(err).To(Succeed())
+					keys := lo.Map(infos, func(info lock.LockInfo, _ int) string {
+						return info.Key
+					})
+					Expect(keys).To(ConsistOf("introspect-list-a", "introspect-list-b"))
+					for _, info := range infos {
+						Expect(info.Holders).To(HaveLen(1))
+					}
+
+					Expect(a.Unlock()).To(Succeed())
+					Expect(b.RUnlock()).To(Succeed())
+					Expect(other.Unlock()).To(Succeed())
+					Eventually(doneA).Should(Receive())
+					Eventually(doneB).Should(Receive())
+					Eventually(doneOther).Should(Receive())
+					Eventually(func() []lock.LockInfo {
+						infos, err := lm.ListLocks(ctx, "introspect-list-")
+						Expect(err).To(Succeed())
+						return infos
+					}, 10*time.Second).Should(BeEmpty())
+				})
+			})
+
+			When("force releasing locks", func() {
+				It("should evict the holder of a lock", func() {
+					info, err := lm.ForceRelease(ctx, "force-free", "conformance")
+					Expect(err).To(Succeed())
+					Expect(info.Holders).To(BeEmpty())
+
+					l := lmSet.A.NewLock("force-held", lock.WithMetadata(lock.Metadata{Owner: "wedged"}))
+					done, err := l.Lock(ctx)
+					Expect(err).To(Succeed())
+
+					info, err = lm.ForceRelease(ctx, "force-held", "conformance")
+					Expect(err).To(Succeed())
+					Expect(info.Key).To(Equal("force-held"))
+					Expect(info.Holders).To(HaveLen(1))
+					Expect(info.Holders[0].Owner).To(Equal("wedged"))
+					Eventually(done, 10*time.Second).Should(Receive())
+
+					other := lmSet.B.NewLock("force-held")
+					var acquired bool
+					var doneOther <-chan struct{}
+					Eventually(func() bool {
+						var err error
+						acquired, doneOther, err = other.TryLock(ctx)
+						Expect(err).To(Succeed())
+						return acquired
+					}, 10*time.Second).Should(BeTrue())
+
+					By("verifying the evicted holder can't release the new holder's lock")
+					Expect(l.Unlock()).To(Succeed())
+					third := lmSet.C.NewLock("force-held")
+					Consistently(func() bool {
+						acquired, done, err := third.TryLock(ctx)
+						Expect(err).To(Succeed())
+						if acquired {
+							Expect(third.Unlock()).To(Succeed())
+							Eventually(done).Should(Receive())
+						}
+						return acquired
+					}, time.Second, 100*time.Millisecond).Should(BeFalse())
+					Expect(other.Unlock()).To(Succeed())
+					Eventually(doneOther).Should(Receive())
+				})
+
+				It("should evict the readers of a lock", func() {
+					r1 := lmSet.A.NewRWLock("force-readers")
+					r2 := lmSet.B.NewRWLock("force-readers")
+					done1, err := r1.RLock(ctx)
+					Expect(err).To(Succeed())
+					done2, err := r2.RLock(ctx)
+					Expect(err).To(Succeed())
+
+					info, err := lm.ForceRelease(ctx, "force-readers", "conformance")
+					Expect(err).To(Succeed())
+					Expect(info.Holders).To(HaveLen(2))
+					Eventually(done1, 10*time.Second).Should(Receive())
+					Eventually(done2, 10*time.Second).Should(Receive())
+
+					writer := lmSet.C.NewLock("force-readers")
+					var doneWriter <-chan struct{}
+					Eventually(func() bool {
+						acquired, done, err := writer.TryLock(ctx)
+						Expect(err).To(Succeed())
+						doneWriter = done
+						return acquired
+					}, 10*time.Second).Should(BeTrue())
+					Expect(r1.RUnlock()).To(Succeed())
+					Expect(r2.RUnlock()).To(Succeed())
+					Expect(writer.Unlock()).To(Succeed())
+					Eventually(doneWriter).Should(Receive())
+				})
+			})
+
+			When("using per lock timings", func() {
+				It("should keep locks with a short TTL alive", func() {
+					opts := []lock.LockOption{
+						lock.WithTTL(2 * time.Second),
+						lock.WithKeepaliveInterval(200 * time.Millisecond),
+						lock.WithRetryDelay(20 * time.Millisecond),
+					}
+					l := lmSet.A.NewLock("timings", opts...)
+					done, err := l.Lock(ctx)
+					Expect(err).To(Succeed())
+
+					info, err := lm.DescribeLock(ctx, "timings")
+					Expect(err).To(Succeed())
+					Expect(info.Holders).To(HaveLen(1))
+					Expect(info.Holders[0].TTL).To(BeNumerically("<=", 2*time.Second))
+
+					By("verifying the lock outlives its TTL while it is kept alive")
+					other := lmSet.B.NewLock("timings", opts...)
+					Consistently(func() bool {
+						acquired, _, err := other.TryLock(ctx)
+						Expect(err).To(Succeed())
+						return acquired
+					}, 3*time.Second, 500*time.Millisecond).Should(BeFalse())
+					Expect(done).NotTo(Receive())
+
+					waiter := lmSet.C.NewLock("timings", opts...)
+					doneC := make(chan (<-chan struct{}), 1)
+					go func() {
+						defer GinkgoRecover()
+						done, err := waiter.Lock(ctx)
+						Expect(err).To(Succeed())
+						doneC <- done
+					}()
+					Expect(l.Unlock()).To(Succeed())
+					Eventually(done).Should(Receive())
+					var doneWaiter <-chan struct{}
+					Eventually(doneC, 5*time.Second).Should(Receive(&doneWaiter))
+					Expect(waiter.Unlock()).To(Succeed())
+					Eventually(doneWaiter).Should(Receive())
+				})
+			})
+
+			When("using fair locks", func() {
+				It("should grant blocking acquisitions in the order they started waiting", func() {
+					opts := []lock.LockOption{lock.WithFair(true)}
+					lms := []lock.LockManager{lmSet.A, lmSet.B, lmSet.C}
+					l := lmSet.A.NewLock("fair", opts...)
+					done, err := l.Lock(ctx)
+					Expect(err).To(Succeed())
+
+					By("queueing waiters one after the other")
+					n := 4
+					var mu sync.Mutex
+					order := []int{}
+					var wg sync.WaitGroup
+					for i := range n {
+						waiter := lms[i%len(lms)].NewLock("fair", opts...)
+						wg.Add(1)
+						go func() {
+							defer GinkgoRecover()
+							defer wg.Done()
+							done, err := waiter.Lock(ctx)
+							Expect(err).To(Succeed())
+							mu.Lock()
+							order = append(order, i)
+							mu.Unlock()
+							time.Sleep(50 * time.Millisecond)
+							Expect(waiter.Unlock()).To(Succeed())
+							Eventually(done).Should(Receive())
+						}()
+						Eventually(func() int {
+							info, err := lm.DescribeLock(ctx, "fair")
+							Expect(err).To(Succeed())
+							return info.Waiters
+						}, 10*time.Second).Should(Equal(i + 1))
+					}
+
+					By("verifying new acquisitions never overtake the waiters")
+					waitersDone := make(chan struct{})
+					go func() {
+						wg.Wait()
+						close(waitersDone)
+					}()
+					barger := lmSet.B.NewLock("fair", opts...)
+					Expect(l.Unlock()).To(Succeed())
+					Eventually(done).Should(Receive())
+				spin:
+					for {
+						select {
+						case <-waitersDone:
+							break spin
+						default:
+						}
+						acquired, doneBarger, err := barger.TryLock(ctx)
+						Expect(err).To(Succeed())
+						if acquired {
+							mu.Lock()
+							order = append(order, -1)
+							mu.Unlock()
+							Expect(barger.Unlock()).To(Succeed())
+							Eventually(doneBarger).Should(Receive())
+						}
+					}
+					Expect(order[:n]).To(Equal(lo.Range(n)))
+				})
+			})
+
+			When("using multi locks", func() {
+				It("should acquire every key all-or-nothing", func() {
+					m := lmSet.A.NewMultiLock([]string{"multi-b", "multi-a", "multi-b"})
+					done, err := m.Lock(ctx)
+					Expect(err).To(Succeed())
+					for _, key := range []string{"multi-a", "multi-b"} {
+						acquired, _, err := lmSet.B.NewLock(key).TryLock(ctx)
+						Expect(err).To(Succeed())
+						Expect(acquired).To(BeFalse())
+					}
+					Expect(m.Unlock()).To(Succeed())
+					Eventually(done).Should(Receive())
+
+					other := lmSet.B.NewMultiLock([]string{"multi-a", "multi-b"})
+					acquired, doneOther, err := other.TryLock(ctx)
+					Expect(err).To(Succeed())
+					Expect(acquired).To(BeTrue())
+					Expect(other.Unlock()).To(Succeed())
+					Eventually(doneOther).Should(Receive())
+				})
+
+				It("should release partial acquisitions when a key can't be acquired", func() {
+					l := lmSet.B.NewLock("multi-partial-c")
+					done, err := l.Lock(ctx)
+					Expect(err).To(Succeed())
+
+					m := lmSet.A.NewMultiLock([]string{"multi-partial-c", "multi-partial-a"})
+					acquired, _, err := m.TryLock(ctx)
+					Expect(err).To(Succeed())
+					Expect(acquired).To(BeFalse())
+
+					ctxT, ca := context.WithTimeout(ctx, time.Second)
+					defer ca()
+					_, err = m.Lock(ctxT)
+					Expect(err).To(HaveOccurred())
+
+					partial := lmSet.C.NewLock("multi-partial-a")
+					Eventually(func() bool {
+						acquired, done, err := partial.TryLock(ctx)
+						Expect(err).To(Succeed())
+						if acquired {
+							Expect(partial.Unlock()).To(Succeed())
+							Eventually(done).Should(Receive())
+						}
+						return acquired
+					}, 10*time.Second).Should(BeTrue())
+
+					Expect(l.Unlock()).To(Succeed())
+					Eventually(done).Should(Receive())
+				})
+
+				It("should not deadlock multi locks sharing keys", func() {
+					keys := []string{"multi-deadlock-a", "multi-deadlock-b", "multi-deadlock-c"}
+					lms := []lock.LockManager{lmSet.A, lmSet.B, lmSet.C}
+					ctxT, ca := context.WithTimeout(ctx, 60*time.Second)
+					defer ca()
+					var eg errgroup.Group
+					for i := range 6 {
+						eg.Go(func() error {
+							defer GinkgoRecover()
+							ordered := slices.Clone(keys)
+							if i%2 == 1 {
+								slices.Reverse(ordered)
+							}
+							m := lms[i%len(lms)].NewMultiLock(ordered)
+							for range 3 {
+								done, err := m.Lock(ctxT)
+								if err != nil {
+									return err
+								}
+								if err := m.Unlock(); err != nil {
+									return err
+								}
+								Eventually(done).Should(Receive())
+							}
+							return nil
+						})
+					}
+					Expect(eg.Wait()).To(Succeed())
+				})
+
+				It("should expire once any of its locks expires", func() {
+					m := lmSet.A.NewMultiLock([]string{"multi-expire-a", "multi-expire-b"})
+					done, err := m.Lock(ctx)
+					Expect(err).To(Succeed())
+					_, err = lm.ForceRelease(ctx, "multi-expire-b", "test")
+					Expect(err).To(Succeed())
+					Eventually(done, 10*time.Second).Should(Receive())
+					Expect(m.Unlock()).To(Succeed())
+				})
+			})
+
+			When("waiting in the queue of locks", func() {
+				It("should notify blocking acquisitions of their position", func() {
+					l := lmSet.A.NewLock("queued")
+					done, err := l.Lock(ctx)
+					Expect(err).To(Succeed())
+
+					lms := []lock.LockManager{lmSet.A, lmSet.B, lmSet.C}
+					var mu sync.Mutex
+					positions := make([][]int, len(lms))
+					lastPosition := func(i int) int {
+						mu.Lock()
+						defer mu.Unlock()
+						if len(positions[i]) == 0 {
+							return -1
+						}
+						return positions[i][len(positions[i])-1]
+					}
+					waiters := make([]lock.Lock, len(lms))
+					acquired := make(chan int, len(lms))
+					for i, lm := range lms {
+						waiters[i] = lm.NewLock("queued", lock.WithOnQueued(func(position int) {
+							mu.Lock()
+							defer mu.Unlock()
+							positions[i] = append(positions[i], position)
+						}))
+						go func() {
+							defer GinkgoRecover()
+							_, err := waiters[i].Lock(ctx)
+							Expect(err).To(Succeed())
+							acquired <- i
+						}()
+						Eventually(func() int {
+							return lastPosition(i)
+						}, 10*time.Second).Should(Equal(i))
+					}
+					for i := range lms {
+						mu.Lock()
+						Expect(positions[i]).To(Equal([]int{i}))
+						mu.Unlock()
+					}
+
+					Expect(l.Unlock()).To(Succeed())
+					Eventually(done).Should(Receive())
+					remaining := lo.Range(len(lms))
+					for range lms {
+						var next int
+						Eventually(acquired, 30*time.Second).Should(Receive(&next))
+						remaining = lo.Without(remaining, next)
+						for position, i := range remaining {
+							Eventually(func() int {
+								return lastPosition(i)
+							}, 10*time.Second).Should(Equal(position))
+						}
+						Expect(waiters[next].Unlock()).To(Succeed())
+					}
+				})
+			})
+
+			When("using reentrant locks", func() {
+				owner := func(owner string) []lock.LockOption {
+					return []lock.LockOption{
+						lock.WithReentrant(true),
+						lock.WithMetadata(lock.Metadata{Owner: owner}),
+						lock.WithReentrantToken(owner + "-token"),
+					}
+				}
+
+				It("should only release the lock on the last unlock of its owner", func() {
+					l := lmSet.A.NewLock("reentrant", owner("alice")...)
+					done1, err := l.Lock(ctx)
+					Expect(err).To(Succeed())
+					done2, err := l.Lock(ctx)
+					Expect(err).To(Succeed())
+
+					other := lmSet.A.NewLock("reentrant", owner("alice")...)
+					acquired, done3, err := other.TryLock(ctx)
+					Expect(err).To(Succeed())
+					Expect(acquired).To(BeTrue())
+					Expect(l.FencingToken()).To(Equal(other.FencingToken()))
+
+					for _, opts := range [][]lock.LockOption{owner("bob"), nil} {
+						acquired, _, err := lmSet.A.NewLock("reentrant", opts...).TryLock(ctx)
+						Expect(err).To(Succeed())
+						Expect(acquired).To(BeFalse())
+					}
+
+					Expect(other.Unlock()).To(Succeed())
+					Eventually(done3).Should(Receive())
+					Expect(l.Unlock()).To(Succeed())
+					Eventually(done2).Should(Receive())
+					Consistently(done1).ShouldNot(Receive())
+					acquired, _, err = lmSet.B.NewLock("reentrant").TryLock(ctx)
+					Expect(err).To(Succeed())
+					Expect(acquired).To(BeFalse())
+
+					Expect(l.Unlock()).To(Succeed())
+					Eventually(done1).Should(Receive())
+					Expect(l.Unlock()).To(MatchError(lock.ErrLockScheduled))
+
+					released := lmSet.B.NewLock("reentrant")
+					Eventually(func() bool {
+						acquired, done, err := released.TryLock(ctx)
+						Expect(err).To(Succeed())
+						if acquired {
+							Expect(released.Unlock()).To(Succeed())
+							Eventually(done).Should(Receive())
+						}
+						return acquired
+					}, 10*time.Second).Should(BeTrue())
+				})
+
+				It("should block other owners until the lock is released", func() {
+					l := lmSet.A.NewLock("reentrant-block", owner("alice")...)
+					done, err := l.Lock(ctx)
+					Expect(err).To(Succeed())
+
+					acquiredOther := make(chan (<-chan struct{}), 1)
+					other := lmSet.A.NewLock("reentrant-block", owner("bob")...)
+					go func() {
+						defer GinkgoRecover()
+						done, err := other.Lock(ctx)
+						Expect(err).To(Succeed())
+						acquiredOther <- done
+					}()
+					Consistently(acquiredOther).ShouldNot(Receive())
+
+					Expect(l.Unlock()).To(Succeed())
+					Eventually(done).Should(Receive())
+					var doneOther <-chan struct{}
+					Eventually(acquiredOther, 10*time.Second).Should(Receive(&doneOther))
+					Expect(other.Unlock()).To(Succeed())
+					Eventually(doneOther).Should(Receive())
+				})
+
+				It("should only reenter the locks of the same token", func() {
+					l := lmSet.A.NewLock("reentrant-token", owner("alice")...)
+					done, err := l.Lock(ctx)
+					Expect(err).To(Succeed())
+
+					other := lmSet.A.NewLock("reentrant-token", append(owner("alice"), lock.WithReentrantToken("other"))...)
+					acquired, _, err := other.TryLock(ctx)
+					Expect(err).To(Succeed())
+					Expect(acquired).To(BeFalse())
+
+					Expect(l.Unlock()).To(Succeed())
+					Eventually(done).Should(Receive())
+				})
+
+				It("should not reenter the locks of other lock managers", func() {
+					if lmSet.A == lmSet.B {
+						Skip("the set shares a single lock manager")
+					}
+					l := lmSet.A.NewLock("reentrant-shared", owner("alice")...)
+					doneA, err := l.Lock(ctx)
+					Expect(err).To(Succeed())
+					other := lmSet.B.NewLock("reentrant-shared", owner("alice")...)
+					acquired, _, err := other.TryLock(ctx)
+					Expect(err).To(Succeed())
+					Expect(acquired).To(BeFalse())
+
+					Expect(l.Unlock()).To(Succeed())
+					Eventually(doneA).Should(Receive())
+					Eventually(func() bool {
+						acquired, done, err := other.TryLock(ctx)
+						Expect(err).To(Succeed())
+						if acquired {
+							Expect(other.Unlock()).To(Succeed())
+							Eventually(done).Should(Receive())
+						}
+						return acquired
+					}, 10*time.Second).Should(BeTrue())
+				})
+
+				It("should require an owner & a token", func() {
+					_, err := lmSet.A.NewLock("reentrant-owner", lock.WithReentrant(true)).Lock(ctx)
+					Expect(err).To(MatchError(lock.ErrReentrantOwner))
+					_, err = lmSet.A.NewLock("reentrant-owner",
+						lock.WithReentrant(true),
+						lock.WithMetadata(lock.Metadata{Owner: "alice"}),
+					).Lock(ctx)
+					Expect(err).To(MatchError(lock.ErrReentrantToken))
+				})
+			})
+
+			When("watching locks", func() {
+				It("should stream the acquisitions & releases of the locks under the prefix", func() {
+					ctxca, ca := context.WithCancel(ctx)
+					defer ca()
+					events, err := lmSet.B.Watch(ctxca, "watched")
+					Expect(err).To(Succeed())
+
+					unwatched := lmSet.A.NewLock("unwatched")
+					_, err = unwatched.Lock(ctx)
+					Expect(err).To(Succeed())
+					defer func() {
+						Expect(unwatched.Unlock()).To(Succeed())
+					}()
+
+					l := lmSet.A.NewLock("watched", lock.WithMetadata(lock.Metadata{Owner: "alice"}))
+					done, err := l.Lock(ctx)
+					Expect(err).To(Succeed())
+					var ev lock.WatchEvent
+					Eventually(events, 10*time.Second).Should(Receive(&ev))
+					Expect(ev.Type).To(Equal(lock.WatchAcquired))
+					Expect(ev.Key).To(Equal("watched"))
+					Expect(ev.Holder.Owner).To(Equal("alice"))
+					Expect(ev.Holder.Shared).To(BeFalse())
+
+					Expect(l.Unlock()).To(Succeed())
+					Eventually(done).Should(Receive())
+					Eventually(events, 10*time.Second).Should(Receive(&ev))
+					Expect(ev.Type).To(Equal(lock.WatchReleased))
+					Expect(ev.Key).To(Equal("watched"))
+					Expect(ev.Holder.Owner).To(Equal("alice"))
+
+					ca()
+					Eventually(events, 10*time.Second).Should(BeClosed())
+				})
+			})
+
+			When("transferring locks", func() {
+				It("should hand the lock over to a waiter of the owner without releasing it", func() {
+					l := lmSet.A.NewLock("transfer", lock.WithMetadata(lock.Metadata{Owner: "alice"}))
+					done, err := l.Lock(ctx)
+					Expect(err).To(Succeed())
+					token := l.FencingToken()
+
+					By("queueing a waiter of another owner before the transferee")
+					acquiredCarol := make(chan (<-chan struct{}), 1)
+					carol := lmSet.C.NewLock("transfer", lock.WithMetadata(lock.Metadata{Owner: "carol"}))
+					go func() {
+						defer GinkgoRecover()
+						done, err := carol.Lock(ctx)
+						Expect(err).To(Succeed())
+						acquiredCarol <- done
+					}()
+					Eventually(func() int {
+						info, err := lm.DescribeLock(ctx, "transfer")
+						Expect(err).To(Succeed())
+						return info.Waiters
+					}, 10*time.Second).Should(Equal(1))
+					acquiredBob := make(chan (<-chan struct{}), 1)
+					bob := lmSet.B.NewLock("transfer", lock.WithMetadata(lock.Metadata{Owner: "bob"}))
+					go func() {
+						defer GinkgoRecover()
+						done, err := bob.Lock(ctx)
+						Expect(err).To(Succeed())
+						acquiredBob <- done
+					}()
+					Eventually(func() int {
+						info, err := lm.DescribeLock(ctx, "transfer")
+						Expect(err).To(Succeed())
+						return info.Waiters
+					}, 10*time.Second).Should(Equal(2))
+
+					By("transferring the lock to the waiter of the owner")
+					Expect(l.(lock.Transferer).Transfer(ctx, "dave")).To(MatchError(lock.ErrNoTransferee))
+					Expect(l.(lock.Transferer).Transfer(ctx, "bob")).To(Succeed())
+					Eventually(done).Should(Receive())
+					Expect(l.Unlock()).To(MatchError(lock.ErrLockScheduled))
+					var doneBob <-chan struct{}
+					Eventually(acquiredBob, 10*time.Second).Should(Receive(&doneBob))
+					Expect(bob.FencingToken()).To(BeNumerically(">", token))
+					info, err := lm.DescribeLock(ctx, "transfer")
+					Expect(err).To(Succeed())
+					Expect(info.Holders).To(HaveLen(1))
+					Expect(info.Holders[0].Owner).To(Equal("bob"))
+					Consistently(acquiredCarol, time.Second).ShouldNot(Receive())
+
+					Expect(bob.Unlock()).To(Succeed())
+					Eventually(doneBob).Should(Receive())
+					var doneCarol <-chan struct{}
+					Eventually(acquiredCarol, 10*time.Second).Should(Receive(&doneCarol))
+					Expect(carol.Unlock()).To(Succeed())
+					Eventually(doneCarol).Should(Receive())
+				})
+
+				It("should keep the lock when the owner has no waiter", func() {
+					l := lmSet.A.NewLock("transfer-no-waiter")
+					done, err := l.Lock(ctx)
+					Expect(err).To(Succeed())
+					Expect(l.(lock.Transferer).Transfer(ctx, "bob")).To(MatchError(lock.ErrNoTransferee))
+
+					other := lmSet.B.NewLock("transfer-no-waiter")
+					acquired, _, err := other.TryLock(ctx)
+					Expect(err).To(Succeed())
+					Expect(acquired).To(BeFalse())
+					Expect(l.Unlock()).To(Succeed())
+					Eventually(done).Should(Receive())
+				})
+
+				It("should not transfer shared acquisitions", func() {
+					rw := lmSet.A.NewRWLock("transfer-shared")
+					done, err := rw.RLock(ctx)
+					Expect(err).To(Succeed())
+					Expect(rw.(lock.Transferer).Transfer(ctx, "bob")).To(MatchError(lock.ErrLockMode))
+					Expect(rw.RUnlock()).To(Succeed())
+					Eventually(done).Should(Receive())
+				})
+			})
+
+			When("electing leaders", func() {
+				It("should elect a single leader at a time", func() {
+					e1 := election.New(lmSet.A, "election-single")
+					e2 := election.New(lmSet.B, "election-single")
+					observer := election.New(lmSet.C, "election-single")
+
+					_, err := observer.Leader(ctx)
+					Expect(err).To(MatchError(election.ErrNoLeader))
+					ctxObserve, ca := context.WithCancel(ctx)
+					defer ca()
+					leaders := observer.Observe(ctxObserve)
+
+					lost1, err := e1.Campaign(ctx, "candidate-1")
+					Expect(err).To(Succeed())
+					Eventually(leaders, 10*time.Second).Should(Receive(Equal("candidate-1")))
+					Expect(observer.Leader(ctx)).To(Equal("candidate-1"))
+					_, err = e1.Campaign(ctx, "candidate-1")
+					Expect(err).To(MatchError(election.ErrCampaigning))
+
+					elected := make(chan (<-chan struct{}), 1)
+					go func() {
+						defer GinkgoRecover()
+						lost, err := e2.Campaign(ctx, "candidate-2")
+						Expect(err).To(Succeed())
+						elected <- lost
+					}()
+					Consistently(elected, time.Second).ShouldNot(Receive())
+
+					Expect(e1.Resign(ctx)).To(Succeed())
+					Eventually(lost1).Should(Receive())
+					Expect(e1.Resign(ctx)).To(MatchError(election.ErrNotLeader))
+					var lost2 <-chan struct{}
+					Eventually(elected, 10*time.Second).Should(Receive(&lost2))
+					Eventually(leaders, 10*time.Second).Should(Receive(Equal("candidate-2")))
+					Expect(observer.Leader(ctx)).To(Equal("candidate-2"))
+
+					Expect(e2.Resign(ctx)).To(Succeed())
+					Eventually(lost2).Should(Receive())
+					Eventually(func() error {
+						_, err := observer.Leader(ctx)
+						return err
+					}, 10*time.Second).Should(MatchError(election.ErrNoLeader))
+				})
+
+				It("should not share its key with locks", func() {
+					e := election.New(lmSet.A, "election-key")
+					_, err := e.Campaign(ctx, "candidate")
+					Expect(err).To(Succeed())
+					l := lmSet.B.NewLock("election-key")
+					acquired, _, err := l.TryLock(ctx)
+					Expect(err).To(Succeed())
+					Expect(acquired).To(BeTrue())
+					Expect(l.Unlock()).To(Succeed())
+					Expect(e.Resign(ctx)).To(Succeed())
+				})
+
+				It("should let a candidate campaign again once it resigned", func() {
+					e := election.New(lmSet.A, "election-again")
+					for range 3 {
+						lost, err := e.Campaign(ctx, "candidate")
+						Expect(err).To(Succeed())
+						Expect(e.Resign(ctx)).To(Succeed())
+						Eventually(lost).Should(Receive())
+					}
+				})
+			})
+
+			When("using barriers & latches", func() {
+				BeforeEach(func() {
+					if _, ok := lm.(lock.BarrierManager); !ok {
+						Skip("lock backend does not support barriers & latches")
+					}
+				})
+
+				wait := func(b lock.Barrier, ctx context.Context) <-chan error {
+					errC := make(chan error, 1)
+					go func() {
+						errC <- b.Wait(ctx)
+					}()
+					return errC
+				}
+
+				It("should hold participants until enough of them wait", func() {
+					errA := wait(lo.Must(lock.NewBarrier(lmSet.A, "barrier", 3)), ctx)
+					errB := wait(lo.Must(lock.NewBarrier(lmSet.B, "barrier", 3)), ctx)
+					Consistently(errA, time.Second).ShouldNot(Receive())
+					Consistently(errB).ShouldNot(Receive())
+
+					Expect(lo.Must(lock.NewBarrier(lmSet.C, "barrier", 3)).Wait(ctx)).To(Succeed())
+					Eventually(errA, 10*time.Second).Should(Receive(BeNil()))
+					Eventually(errB, 10*time.Second).Should(Receive(BeNil()))
+				})
+
+				It("should hold the next participants once the barrier is reached", func() {
+					b := lo.Must(lock.NewBarrier(lmSet.A, "barrier-again", 2))
+					for range 3 {
+						errB := wait(lo.Must(lock.NewBarrier(lmSet.B, "barrier-again", 2)), ctx)
+						Consistently(errB, 500*time.Millisecond).ShouldNot(Receive())
+						Expect(b.Wait(ctx)).To(Succeed())
+						Eventually(errB, 10*time.Second).Should(Receive(BeNil()))
+					}
+				})
+
+				It("should not count participants that stopped waiting", func() {
+					ctxT, ca := context.WithTimeout(ctx, 500*time.Millisecond)
+					defer ca()
+					Expect(lo.Must(lock.NewBarrier(lmSet.A, "barrier-left", 2)).Wait(ctxT)).To(MatchError(context.DeadlineExceeded))
+
+					errB := wait(lo.Must(lock.NewBarrier(lmSet.B, "barrier-left", 2)), ctx)
+					Consistently(errB, time.Second).ShouldNot(Receive())
+					Expect(lo.Must(lock.NewBarrier(lmSet.C, "barrier-left", 2)).Wait(ctx)).To(Succeed())
+					Eventually(errB, 10*time.Second).Should(Receive(BeNil()))
+				})
+
+				It("should release awaiting processes once the latch is counted down", func() {
+					errC := make(chan error, 1)
+					go func() {
+						errC <- lo.Must(lock.NewCountDownLatch(lmSet.A, "latch", 2)).Await(ctx)
+					}()
+					Consistently(errC, 500*time.Millisecond).ShouldNot(Receive())
+
+					Expect(lo.Must(lock.NewCountDownLatch(lmSet.B, "latch", 2)).CountDown(ctx)).To(Succeed())
+					Consistently(errC, 500*time.Millisecond).ShouldNot(Receive())
+					Expect(lo.Must(lock.NewCountDownLatch(lmSet.C, "latch", 2)).CountDown(ctx)).To(Succeed())
+					Eventually(errC, 10*time.Second).Should(Receive(BeNil()))
+
+					By("verifying the latch stays released")
+					latch := lo.Must(lock.NewCountDownLatch(lmSet.C, "latch", 2))
+					Expect(latch.CountDown(ctx)).To(Succeed())
+					ctxT, ca := context.WithTimeout(ctx, 5*time.Second)
+					defer ca()
+					Expect(latch.Await(ctxT)).To(Succeed())
+				})
+
+				It("should require participants", func() {
+					Expect(lo.Must(lock.NewBarrier(lm, "barrier-empty", 0)).Wait(ctx)).To(MatchError(lock.ErrParticipants))
+					latch := lo.Must(lock.NewCountDownLatch(lm, "latch-empty", 0))
+					Expect(latch.CountDown(ctx)).To(MatchError(lock.ErrParticipants))
+					Expect(latch.Await(ctx)).To(MatchError(lock.ErrParticipants))
+				})
+			})
+
 			Context("others", func() {
 				Specify("calling 'unlock' on a lock that was never acquired should error", func() {
 					lock := lm.NewLock("todo")
@@ -7,20 +7,26 @@ import (
 )
 
 type MTLSSpecShape = struct {
-	// Path to the server CA certificate.
+	// Path to the server CA certificate, the system's CA certificates are used when unset.
 	ServerCA string `json:"serverCA,omitempty" toml:"serverCA"`
 	// Path to the client CA certificate (not needed in all cases).
 	ClientCA string `json:"clientCA,omitempty" toml:"clientCA"`
-	// Path to the certificate used for client-cert auth.
+	// Path to the certificate used for client-cert auth, no client certificate is presented when unset.
 	ClientCert string `json:"clientCert,omitempty" toml:"clientCert"`
 	// Path to the private key used for client-cert auth.
 	ClientKey string `json:"clientKey,omitempty" toml:"clientKey"`
 }
 
+// LoadClientMTLSConfig returns the TLS config of clients, which only present a client certificate when it is set
+// and verify servers against the system's CA certificates when the server CA is unset
 func LoadClientMTLSConfig(certs MTLSSpecShape) (*tls.Config, error) {
-	clientCert, err := tls.LoadX509KeyPair(certs.ClientCert, certs.ClientKey)
-	if err != nil {
-		return nil, err
+	var clientCerts []tls.Certificate
+	if certs.ClientCert != "" || certs.ClientKey != "" {
+		clientCert, err := tls.LoadX509KeyPair(certs.ClientCert, certs.ClientKey)
+		if err != nil {
+			return nil, err
+		}
+		clientCerts = append(clientCerts, clientCert)
 	}
 
 	clientCAPool := x509.NewCertPool()
@@ -36,8 +42,9 @@ func LoadClientMTLSConfig(certs MTLSSpecShape) (*tls.Config, error) {
 		clientCAPool.AddCert(clientCA)
 	}
 
-	serverCAPool := x509.NewCertPool()
+	var serverCAPool *x509.CertPool
 	if certs.ServerCA != "" {
+		serverCAPool = x509.NewCertPool()
 		serverCAData, err := os.ReadFile(certs.ServerCA)
 		if err != nil {
 			return nil, err
@@ -51,7 +58,7 @@ func LoadClientMTLSConfig(certs MTLSSpecShape) (*tls.Config, error) {
 
 	return &tls.Config{
 		MinVersion:   tls.VersionTLS12,
-		Certificates: []tls.Certificate{clientCert},
+		Certificates: clientCerts,
 		ClientCAs:    clientCAPool,
 		RootCAs:      serverCAPool,
 	}, nil
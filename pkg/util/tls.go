@@ -0,0 +1,91 @@
+package util
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// client certificate verifications of servers, by their name in configs
+var clientAuthTypes = map[string]tls.ClientAuthType{
+	"":              tls.NoClientCert,
+	"none":          tls.NoClientCert,
+	"verifyIfGiven": tls.VerifyClientCertIfGiven,
+	"require":       tls.RequireAndVerifyClientCert,
+}
+
+// ParseClientAuth returns the verification of client certificates named "none", "verifyIfGiven" or "require",
+// an empty name meaning "none"
+func ParseClientAuth(name string) (tls.ClientAuthType, error) {
+	clientAuth, ok := clientAuthTypes[name]
+	if !ok {
+		return tls.NoClientCert, fmt.Errorf("invalid client auth '%s', must be one of none, verifyIfGiven or require", name)
+	}
+	return clientAuth, nil
+}
+
+// LoadServerTLSConfig returns the TLS config serving the certificate bundle, client certificates being verified
+// against its CA certificate
+func LoadServerTLSConfig(certsSpec CertsSpecShape, clientAuth tls.ClientAuthType) (*tls.Config, error) {
+	servingCert, caPool, err := LoadServingCertBundle(certsSpec)
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{*servingCert},
+		ClientCAs:    caPool,
+		ClientAuth:   clientAuth,
+	}, nil
+}
+
+// LoadPeerTLSConfig returns the TLS config of peers which both serve & present the certificate bundle, and
+// verify each other against its CA certificate
+func LoadPeerTLSConfig(certsSpec CertsSpecShape) (*tls.Config, error) {
+	cert, caPool, err := LoadServingCertBundle(certsSpec)
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{*cert},
+		RootCAs:      caPool,
+		ClientCAs:    caPool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	}, nil
+}
+
+// LoadClientTLSConfig returns the TLS config of clients verifying servers against the CA certificate, or the
+// system's CA certificates when caCert is empty, and presenting the client certificate when cert & key are set
+func LoadClientTLSConfig(caCert, cert, key string) (*tls.Config, error) {
+	config := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+	if caCert != "" {
+		data, err := os.ReadFile(caCert)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load CA cert: %w", err)
+		}
+		certs, err := ParsePEMEncodedCertChain(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse CA cert: %w", err)
+		}
+		config.RootCAs = x509.NewCertPool()
+		for _, cert := range certs {
+			config.RootCAs.AddCert(cert)
+		}
+	}
+	if (cert == "") != (key == "") {
+		return nil, errors.New("client cert and key must be set together")
+	}
+	if cert != "" {
+		clientCert, err := tls.LoadX509KeyPair(cert, key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client cert: %w", err)
+		}
+		config.Certificates = []tls.Certificate{clientCert}
+	}
+	return config, nil
+}
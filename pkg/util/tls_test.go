@@ -0,0 +1,220 @@
+//go:build !minimal
+
+package util_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"io"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/alexandreLamarre/dlock/pkg/config/v1alpha1"
+	"github.com/alexandreLamarre/dlock/pkg/test/testdata"
+	"github.com/alexandreLamarre/dlock/pkg/util"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/samber/lo"
+)
+
+// generateCert writes a certificate for localhost & its key to the directory, signed by the parent or self-signed
+func generateCert(dir, name string, isCA bool, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	Expect(err).NotTo(HaveOccurred())
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  isCA,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	Expect(err).NotTo(HaveOccurred())
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	Expect(err).NotTo(HaveOccurred())
+	Expect(os.WriteFile(filepath.Join(dir, name+".crt"), pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600)).To(Succeed())
+	Expect(os.WriteFile(filepath.Join(dir, name+".key"), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0o600)).To(Succeed())
+	cert, err := x509.ParseCertificate(der)
+	Expect(err).NotTo(HaveOccurred())
+	return cert, key
+}
+
+// handshake returns the errors of the server & client sides of a TLS handshake
+func handshake(server, client *tls.Config) (serverErr, clientErr error) {
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", server)
+	Expect(err).NotTo(HaveOccurred())
+	defer listener.Close()
+	errC := make(chan error, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			errC <- err
+			return
+		}
+		defer conn.Close()
+		errC <- conn.(*tls.Conn).Handshake()
+	}()
+	conn, err := tls.Dial("tcp", listener.Addr().String(), client)
+	if err == nil {
+		// client certificates are rejected after the client side of TLS 1.3 handshakes completes,
+		// the server closing the connection otherwise
+		_, err = conn.Read(make([]byte, 1))
+		if errors.Is(err, io.EOF) {
+			err = nil
+		}
+		conn.Close()
+	}
+	return <-errC, err
+}
+
+// presentCert makes the client present its certificate even when it isn't signed by a CA the server accepts
+func presentCert(client *tls.Config) *tls.Config {
+	client.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+		return &client.Certificates[0], nil
+	}
+	return client
+}
+
+var _ = Describe("TLS Utils", Label("unit"), func() {
+	var dir string
+	var serving v1alpha1.CertsSpec
+	BeforeEach(func() {
+		dir = GinkgoT().TempDir()
+		ca, caKey := generateCert(dir, "ca", true, nil, nil)
+		generateCert(dir, "server", false, ca, caKey)
+		generateCert(dir, "client", false, ca, caKey)
+		other, otherKey := generateCert(dir, "other-ca", true, nil, nil)
+		generateCert(dir, "other-client", false, other, otherKey)
+		serving = v1alpha1.CertsSpec{
+			CACert:      lo.ToPtr(filepath.Join(dir, "ca.crt")),
+			ServingCert: lo.ToPtr(filepath.Join(dir, "server.crt")),
+			ServingKey:  lo.ToPtr(filepath.Join(dir, "server.key")),
+		}
+	})
+
+	It("should parse client certificate verifications", func() {
+		Expect(util.ParseClientAuth("")).To(Equal(tls.NoClientCert))
+		Expect(util.ParseClientAuth("none")).To(Equal(tls.NoClientCert))
+		Expect(util.ParseClientAuth("verifyIfGiven")).To(Equal(tls.VerifyClientCertIfGiven))
+		Expect(util.ParseClientAuth("require")).To(Equal(tls.RequireAndVerifyClientCert))
+		_, err := util.ParseClientAuth("always")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should serve clients verifying the server against the CA", func() {
+		server, err := util.LoadServerTLSConfig(serving, tls.NoClientCert)
+		Expect(err).NotTo(HaveOccurred())
+		client, err := util.LoadClientTLSConfig(filepath.Join(dir, "ca.crt"), "", "")
+		Expect(err).NotTo(HaveOccurred())
+		serverErr, clientErr := handshake(server, client)
+		Expect(serverErr).NotTo(HaveOccurred())
+		Expect(clientErr).NotTo(HaveOccurred())
+
+		By("verifying clients reject servers signed by another CA")
+		client, err = util.LoadClientTLSConfig(filepath.Join(dir, "other-ca.crt"), "", "")
+		Expect(err).NotTo(HaveOccurred())
+		_, clientErr = handshake(server, client)
+		Expect(clientErr).To(HaveOccurred())
+	})
+
+	It("should require client certificates signed by the CA for mutual TLS", func() {
+		server, err := util.LoadServerTLSConfig(serving, tls.RequireAndVerifyClientCert)
+		Expect(err).NotTo(HaveOccurred())
+
+		client, err := util.LoadClientTLSConfig(filepath.Join(dir, "ca.crt"), filepath.Join(dir, "client.crt"), filepath.Join(dir, "client.key"))
+		Expect(err).NotTo(HaveOccurred())
+		serverErr, clientErr := handshake(server, client)
+		Expect(serverErr).NotTo(HaveOccurred())
+		Expect(clientErr).NotTo(HaveOccurred())
+
+		By("rejecting clients without a certificate")
+		client, err = util.LoadClientTLSConfig(filepath.Join(dir, "ca.crt"), "", "")
+		Expect(err).NotTo(HaveOccurred())
+		serverErr, _ = handshake(server, client)
+		Expect(serverErr).To(HaveOccurred())
+
+		By("rejecting clients with a certificate of another CA")
+		client, err = util.LoadClientTLSConfig(filepath.Join(dir, "ca.crt"), filepath.Join(dir, "other-client.crt"), filepath.Join(dir, "other-client.key"))
+		Expect(err).NotTo(HaveOccurred())
+		serverErr, _ = handshake(server, presentCert(client))
+		Expect(serverErr).To(HaveOccurred())
+	})
+
+	It("should only verify the certificates clients present when they are optional", func() {
+		server, err := util.LoadServerTLSConfig(serving, tls.VerifyClientCertIfGiven)
+		Expect(err).NotTo(HaveOccurred())
+		client, err := util.LoadClientTLSConfig(filepath.Join(dir, "ca.crt"), "", "")
+		Expect(err).NotTo(HaveOccurred())
+		serverErr, _ := handshake(server, client)
+		Expect(serverErr).NotTo(HaveOccurred())
+
+		client, err = util.LoadClientTLSConfig(filepath.Join(dir, "ca.crt"), filepath.Join(dir, "other-client.crt"), filepath.Join(dir, "other-client.key"))
+		Expect(err).NotTo(HaveOccurred())
+		serverErr, _ = handshake(server, presentCert(client))
+		Expect(serverErr).To(HaveOccurred())
+	})
+
+	It("should authenticate peers to each other against the CA", func() {
+		peer, err := util.LoadPeerTLSConfig(serving)
+		Expect(err).NotTo(HaveOccurred())
+		serverErr, clientErr := handshake(peer, peer)
+		Expect(serverErr).NotTo(HaveOccurred())
+		Expect(clientErr).NotTo(HaveOccurred())
+
+		By("rejecting peers with a certificate of another CA")
+		other, err := util.LoadPeerTLSConfig(v1alpha1.CertsSpec{
+			CACert:      lo.ToPtr(filepath.Join(dir, "ca.crt")),
+			ServingCert: lo.ToPtr(filepath.Join(dir, "other-client.crt")),
+			ServingKey:  lo.ToPtr(filepath.Join(dir, "other-client.key")),
+		})
+		Expect(err).NotTo(HaveOccurred())
+		serverErr, _ = handshake(peer, presentCert(other))
+		Expect(serverErr).To(HaveOccurred())
+		_, clientErr = handshake(other, peer)
+		Expect(clientErr).To(HaveOccurred())
+	})
+
+	It("should serve the certificates of the test data", func() {
+		server, err := util.LoadServerTLSConfig(v1alpha1.CertsSpec{
+			CACertData:      testdata.TestData("root_ca.crt"),
+			ServingCertData: testdata.TestData("localhost.crt"),
+			ServingKeyData:  testdata.TestData("localhost.key"),
+		}, tls.RequireAndVerifyClientCert)
+		Expect(err).NotTo(HaveOccurred())
+		client, err := util.LoadClientTLSConfig(
+			"../test/testdata/testdata/root_ca.crt",
+			"../test/testdata/testdata/client.crt",
+			"../test/testdata/testdata/client.key",
+		)
+		Expect(err).NotTo(HaveOccurred())
+		serverErr, clientErr := handshake(server, client)
+		Expect(serverErr).NotTo(HaveOccurred())
+		Expect(clientErr).NotTo(HaveOccurred())
+	})
+
+	It("should handle errors when loading client TLS configs", func() {
+		_, err := util.LoadClientTLSConfig(filepath.Join(dir, "missing.crt"), "", "")
+		Expect(err).To(HaveOccurred())
+		_, err = util.LoadClientTLSConfig("", filepath.Join(dir, "client.crt"), "")
+		Expect(err).To(MatchError("client cert and key must be set together"))
+		_, err = util.LoadClientTLSConfig("", filepath.Join(dir, "client.crt"), filepath.Join(dir, "server.key"))
+		Expect(err).To(HaveOccurred())
+	})
+})
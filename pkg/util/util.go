@@ -3,7 +3,9 @@ package util
 import (
 	"context"
 	"errors"
+	"fmt"
 	"reflect"
+	"time"
 )
 
 func Must[T any](t T, err ...error) T {
@@ -19,6 +21,21 @@ func Must[T any](t T, err ...error) T {
 	return t
 }
 
+// ParseOptionalDuration parses the duration of a config field, unset durations being zero
+func ParseOptionalDuration(name, value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s : %w", name, err)
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("%s must not be negative", name)
+	}
+	return d, nil
+}
+
 // WaitAll waits for all the given channels to be closed, under the
 // following rules:
 // 1. The lifetime of the task represented by each channel is directly tied to
@@ -0,0 +1,26 @@
+package memory
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/alexandreLamarre/dlock/internal/lock/backend/memory"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type LockManager = memory.LockManager
+
+type LockManagerOption = memory.LockManagerOption
+
+// WithTTL expires every acquisition after the ttl, simulating holders that stopped keeping their locks alive
+func WithTTL(ttl time.Duration) LockManagerOption {
+	return memory.WithTTL(ttl)
+}
+
+func NewLockManager(
+	tracer trace.Tracer,
+	lg *slog.Logger,
+	opts ...LockManagerOption,
+) *LockManager {
+	return memory.NewLockManager(tracer, lg, opts...)
+}